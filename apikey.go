@@ -0,0 +1,68 @@
+package imagor
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/cshum/imagor/imagorpath"
+	"net/http"
+	"strings"
+)
+
+type apiKeyNameKey struct{}
+
+// APIKeyName returns name of the API key that authorized the request context,
+// empty if request not authorized by API key
+func APIKeyName(ctx context.Context) string {
+	name, _ := ctx.Value(apiKeyNameKey{}).(string)
+	return name
+}
+
+// withAPIKeyName returns request with API key name in context if API key matched
+func (app *Imagor) withAPIKeyName(r *http.Request) *http.Request {
+	if name := app.apiKeyName(r); name != "" {
+		return r.WithContext(context.WithValue(r.Context(), apiKeyNameKey{}, name))
+	}
+	return r
+}
+
+// apiKeyName returns name of the API key matching Authorization bearer token
+// or api_key query of the request, empty if not matched
+func (app *Imagor) apiKeyName(r *http.Request) string {
+	if len(app.APIKeys) == 0 {
+		return ""
+	}
+	token := r.URL.Query().Get("api_key")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if token == "" {
+		return ""
+	}
+	var matched string
+	for name, key := range app.APIKeys {
+		// compare against all keys to avoid leaking which key matched by timing
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			matched = name
+		}
+	}
+	return matched
+}
+
+// authorize checks API key and signature of request params.
+// API key substitutes URL signature, unless API key required on top of signature
+func (app *Imagor) authorize(r *http.Request, p imagorpath.Params) error {
+	name := APIKeyName(r.Context())
+	if name == "" {
+		name = app.apiKeyName(r)
+	}
+	if name == "" && app.APIKeyRequired {
+		return ErrUnauthorized
+	}
+	if name != "" && !app.APIKeyRequired {
+		return nil
+	}
+	if !app.isSignatureValid(r, p) {
+		return ErrSignatureMismatch
+	}
+	return nil
+}
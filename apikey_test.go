@@ -0,0 +1,75 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithAPIKey(t *testing.T) {
+	var names []string
+	loader := loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+		names = append(names, APIKeyName(r.Context()))
+		return NewBlobFromBytes([]byte(image)), nil
+	})
+	doGet := func(app *Imagor, path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	signer := imagorpath.NewDefaultSigner("1234")
+	signed := "/" + imagorpath.Generate(imagorpath.Params{Image: "foo"}, signer)
+
+	app := New(
+		WithSigner(signer),
+		WithLoaders(loader),
+		WithAPIKey("backend", "k3y1"),
+		WithAPIKey("worker", "k3y2"),
+		WithAPIKey("", "ignored"),
+	)
+	assert.Len(t, app.APIKeys, 2)
+
+	w := doGet(app, "/fit-in/foo", "")
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, jsonStr(ErrSignatureMismatch), w.Body.String())
+
+	w = doGet(app, "/fit-in/foo", "wrong")
+	assert.Equal(t, 403, w.Code)
+
+	w = doGet(app, "/fit-in/foo", "k3y1")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foo", w.Body.String())
+
+	w = doGet(app, "/fit-in/foo?api_key=k3y2", "")
+	assert.Equal(t, 200, w.Code)
+
+	w = doGet(app, signed, "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, []string{"backend", "worker", ""}, names)
+
+	app = New(
+		WithSigner(signer),
+		WithLoaders(loader),
+		WithAPIKey("backend", "k3y1"),
+		WithAPIKeyRequired(true),
+	)
+	w = doGet(app, signed, "")
+	assert.Equal(t, 401, w.Code)
+	assert.Equal(t, jsonStr(ErrUnauthorized), w.Body.String())
+
+	w = doGet(app, "/fit-in/foo", "k3y1")
+	assert.Equal(t, 403, w.Code)
+
+	w = doGet(app, signed, "k3y1")
+	assert.Equal(t, 200, w.Code)
+
+	res := app.Warm(context.Background(), "foo", []string{"fit-in/100x100"})
+	assert.Equal(t, 200, res[0].Status)
+}
@@ -0,0 +1,185 @@
+package imagor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/cshum/imagor/imagorpath"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobStatus async job status
+type JobStatus string
+
+const (
+	JobPending JobStatus = "pending"
+	JobDone    JobStatus = "done"
+	JobFailed  JobStatus = "failed"
+)
+
+// Job async processing job
+type Job struct {
+	ID       string     `json:"id"`
+	Path     string     `json:"path"`
+	Status   JobStatus  `json:"status"`
+	Location string     `json:"location,omitempty"`
+	Error    *Error     `json:"error,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+type asyncJobKey struct{}
+
+// isAsyncJob returns if context is of async job, which has its own timeout
+func isAsyncJob(ctx context.Context) bool {
+	_, ok := ctx.Value(asyncJobKey{}).(bool)
+	return ok
+}
+
+// isAsyncRequest checks async query or header of the request
+func isAsyncRequest(r *http.Request) bool {
+	v := r.URL.Query().Get("async")
+	if v == "" {
+		v = r.Header.Get("Imagor-Async")
+	}
+	return v == "1" || v == "true"
+}
+
+type jobStore struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+func (s *jobStore) get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		return *job, true
+	}
+	return Job{}, false
+}
+
+// add adds job if pending jobs within maxPending, false if exceeded.
+// Finished jobs exceeded ttl, and pending jobs exceeded timeout plus ttl e.g. stuck processing, are evicted
+func (s *jobStore) add(job *Job, timeout, ttl time.Duration, maxPending int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs == nil {
+		s.jobs = map[string]*Job{}
+	}
+	now := time.Now()
+	var pending int
+	for id, j := range s.jobs {
+		if j.Finished != nil && now.Sub(*j.Finished) > ttl ||
+			j.Finished == nil && now.Sub(j.Created) > timeout+ttl {
+			delete(s.jobs, id)
+		} else if j.Finished == nil {
+			pending++
+		}
+	}
+	if maxPending > 0 && pending >= maxPending {
+		return false
+	}
+	s.jobs[job.ID] = job
+	return true
+}
+
+func (s *jobStore) update(id string, fn func(job *Job)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if job, ok := s.jobs[id]; ok {
+		fn(job)
+	}
+}
+
+func newJobID() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// serveAsync enqueues processing of params as async job and responds 202 with the job.
+// Request is authorized and checked against policies before the job is accepted
+func (app *Imagor) serveAsync(w http.ResponseWriter, r *http.Request, path string, p imagorpath.Params) {
+	err := app.authorize(r, p)
+	if err == nil {
+		err = app.checkPolicy(p)
+	}
+	if err == nil {
+		err = tenantFrom(r.Context()).checkSource(p.Image)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	job := &Job{
+		ID:      newJobID(),
+		Path:    strings.TrimPrefix(path, "/"),
+		Status:  JobPending,
+		Created: time.Now(),
+	}
+	if !app.jobs.add(job, app.AsyncTimeout, app.AsyncJobTTL, app.AsyncMaxPending) {
+		w.Header().Set("Retry-After", app.retryAfter())
+		writeError(w, r, ErrTooManyRequests)
+		return
+	}
+	res := *job
+	ctx := context.WithValue(DetachContext(r.Context()), asyncJobKey{}, true)
+	ctx, cancel := context.WithTimeout(ctx, app.AsyncTimeout)
+	jobReq := r.Clone(ctx)
+	jobReq.URL.RawQuery = ""
+	// tracked the same way as saves, that Shutdown drains jobs in progress
+	done := app.trackSave()
+	go func() {
+		defer done()
+		defer cancel()
+		_, err := checkBlob(app.Do(jobReq, p))
+		app.jobs.update(job.ID, func(job *Job) {
+			now := time.Now()
+			job.Finished = &now
+			if err != nil {
+				e := WrapError(err)
+				job.Status = JobFailed
+				job.Error = &e
+			} else {
+				job.Status = JobDone
+				job.Location = app.tenantLocation(jobReq, job.Path)
+			}
+		})
+		if err != nil {
+			app.Logger.Warn("async", zap.String("id", job.ID), zap.Error(err))
+		} else if app.Debug {
+			app.Logger.Debug("async-done", zap.String("id", job.ID))
+		}
+	}()
+	w.Header().Set("Location", app.tenantLocation(r, "jobs/"+job.ID))
+	writeJSONStatus(w, r, http.StatusAccepted, res)
+}
+
+// location returns URL path with path prefix
+func (app *Imagor) location(path string) string {
+	return strings.TrimSuffix(app.PathPrefix, "/") + "/" + path
+}
+
+// tenantLocation returns URL path with path prefix and path prefix of the tenant matched by request
+func (app *Imagor) tenantLocation(r *http.Request, path string) string {
+	if tenant := tenantFrom(r.Context()); tenant != nil && tenant.PathPrefix != "" {
+		path = strings.Trim(tenant.PathPrefix, "/") + "/" + path
+	}
+	return app.location(path)
+}
+
+// serveJob handles GET /jobs/<id>
+func (app *Imagor) serveJob(w http.ResponseWriter, r *http.Request, id string) {
+	job, ok := app.jobs.get(id)
+	if !ok {
+		writeError(w, r, ErrNotFound)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	writeJSON(w, r, job)
+}
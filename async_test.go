@@ -0,0 +1,182 @@
+package imagor
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithAsyncTimeout(t *testing.T) {
+	resultStore := newMapStore()
+	release := make(chan struct{})
+	app := New(
+		WithUnsafe(true),
+		WithRequestTimeout(time.Millisecond*10),
+		WithAsyncTimeout(time.Second),
+		WithPathPrefix("/img"),
+		WithResultStorages(resultStore),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			<-release
+			if image == "missing" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	getJob := func(location string) (job Job) {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com"+location, nil))
+		require.Equal(t, 200, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+		return
+	}
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/img/unsafe/100x100/foo?async=1", nil))
+	assert.Equal(t, 202, w.Code)
+	var job Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, JobPending, job.Status)
+	assert.Equal(t, "unsafe/100x100/foo", job.Path)
+	assert.Equal(t, "/img/jobs/"+job.ID, w.Header().Get("Location"))
+
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/img/unsafe/missing", nil)
+	r.Header.Set("Imagor-Async", "1")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 202, w.Code)
+	failedLocation := w.Header().Get("Location")
+
+	// exceeds request timeout but within async timeout
+	time.Sleep(time.Millisecond * 20)
+	assert.Equal(t, JobPending, getJob("/img/jobs/"+job.ID).Status)
+	close(release)
+	time.Sleep(time.Millisecond * 20)
+
+	job = getJob("/img/jobs/" + job.ID)
+	assert.Equal(t, JobDone, job.Status)
+	assert.Equal(t, "/img/unsafe/100x100/foo", job.Location)
+	assert.NotNil(t, job.Finished)
+	assert.Contains(t, resultStore.Map, "100x100/foo")
+
+	job = getJob(failedLocation)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.Empty(t, job.Location)
+	assert.Equal(t, &ErrNotFound, job.Error)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/img/jobs/abc", nil))
+	assert.Equal(t, 404, w.Code)
+
+	app = New(WithUnsafe(true), WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+		return NewBlobFromBytes([]byte(image)), nil
+	})))
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo?async=1", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foo", w.Body.String())
+}
+
+func TestAsyncTenantLocation(t *testing.T) {
+	var loads int64
+	release := make(chan struct{})
+	app := New(
+		WithUnsafe(true),
+		WithAsyncTimeout(time.Second),
+		WithPathPrefix("/img"),
+		WithTenant(Tenant{Name: "shop", PathPrefix: "/shop/"}),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			<-release
+			atomic.AddInt64(&loads, 1)
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/img/shop/unsafe/100x100/foo?async=1", nil))
+	assert.Equal(t, 202, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var job Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, "/img/shop/jobs/"+job.ID, w.Header().Get("Location"))
+
+	close(release)
+	time.Sleep(time.Millisecond * 20)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/img/shop/jobs/"+job.ID, nil))
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	assert.Equal(t, JobDone, job.Status)
+	assert.Equal(t, "/img/shop/unsafe/100x100/foo", job.Location)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&loads))
+
+	// without result storage or cache, location resolves by processing again
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com"+job.Location, nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foo", w.Body.String())
+	assert.Equal(t, int64(2), atomic.LoadInt64(&loads))
+}
+
+func TestAsyncRejected(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	app := New(
+		WithSigner(imagorpath.NewDefaultSigner("1234")),
+		WithAsyncTimeout(time.Second),
+		WithAsyncMaxPending(1),
+		WithAllowedSizes("100x100"),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			<-release
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	doAsync := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/"+path+"?async=1", nil))
+		return w
+	}
+	w := doAsync("unsafe/100x100/foo")
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, jsonStr(ErrSignatureMismatch), w.Body.String())
+
+	w = doAsync(imagorpath.Generate(imagorpath.Params{Image: "foo", Width: 200, Height: 200}, app.Signer))
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, jsonStr(ErrSizeNotAllowed), w.Body.String())
+	assert.Empty(t, app.jobs.jobs)
+
+	w = doAsync(imagorpath.Generate(imagorpath.Params{Image: "foo", Width: 100, Height: 100}, app.Signer))
+	assert.Equal(t, 202, w.Code)
+
+	w = doAsync(imagorpath.Generate(imagorpath.Params{Image: "bar", Width: 100, Height: 100}, app.Signer))
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, jsonStr(ErrTooManyRequests), w.Body.String())
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+}
+
+func TestAsyncShutdown(t *testing.T) {
+	release := make(chan struct{})
+	app := New(
+		WithUnsafe(true),
+		WithAsyncTimeout(time.Second),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			<-release
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo?async=1", nil))
+	require.Equal(t, 202, w.Code)
+	var job Job
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job))
+	time.AfterFunc(time.Millisecond*20, func() { close(release) })
+	require.NoError(t, app.Shutdown(context.Background()))
+	job, _ = app.jobs.get(job.ID)
+	assert.Equal(t, JobDone, job.Status, "shutdown waits for job in progress")
+}
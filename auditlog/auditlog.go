@@ -0,0 +1,146 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cshum/imagor"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// rotateTimeFormat time suffix of rotated audit log files
+const rotateTimeFormat = "20060102T150405.000000000"
+
+// AuditLog appends imagor events as JSON lines to an append-only file, implements imagor.EventHandler.
+// File is rotated when exceeding MaxSize, with rotated files kept up to MaxBackups
+type AuditLog struct {
+	Path       string
+	Events     []imagor.EventType
+	MaxSize    int64
+	MaxBackups int
+	Logger     *zap.Logger
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// New creates AuditLog of file path, logging processed events by default
+func New(path string, options ...Option) *AuditLog {
+	l := &AuditLog{
+		Path:    path,
+		Events:  []imagor.EventType{imagor.EventProcessed},
+		MaxSize: 100 << 20,
+		Logger:  zap.NewNop(),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// HandleEvent appends event as a JSON line, rotating file if exceeded max size
+func (l *AuditLog) HandleEvent(_ context.Context, event imagor.Event) {
+	if !l.accepts(event.Type) {
+		return
+	}
+	buf, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	buf = append(buf, '\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err = l.write(buf); err != nil {
+		l.Logger.Warn("audit-log", zap.String("path", l.Path), zap.Error(err))
+	}
+}
+
+// Shutdown implements imagor.Shutdowner, closes the audit log file
+func (l *AuditLog) Shutdown(_ context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+func (l *AuditLog) accepts(t imagor.EventType) bool {
+	if len(l.Events) == 0 {
+		return true
+	}
+	for _, e := range l.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *AuditLog) write(buf []byte) error {
+	if l.file != nil && l.MaxSize > 0 && l.size+int64(len(buf)) > l.MaxSize && l.size > 0 {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(buf)
+	l.size += int64(n)
+	return err
+}
+
+func (l *AuditLog) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(l.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	l.file = file
+	l.size = stat.Size()
+	if l.MaxSize > 0 && l.size >= l.MaxSize {
+		return l.rotate()
+	}
+	return nil
+}
+
+// rotate renames current file with time suffix, removing rotated files exceeding max backups
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		l.Logger.Warn("audit-log-close", zap.String("path", l.Path), zap.Error(err))
+	}
+	l.file = nil
+	l.size = 0
+	rotated := l.Path + "." + time.Now().UTC().Format(rotateTimeFormat)
+	if err := os.Rename(l.Path, rotated); err != nil {
+		return err
+	}
+	if l.MaxBackups > 0 {
+		if matches, err := filepath.Glob(l.Path + ".*"); err == nil && len(matches) > l.MaxBackups {
+			sort.Strings(matches)
+			for _, match := range matches[:len(matches)-l.MaxBackups] {
+				if err := os.Remove(match); err != nil {
+					l.Logger.Warn("audit-log-remove", zap.String("path", match), zap.Error(err))
+				}
+			}
+		}
+	}
+	return l.open()
+}
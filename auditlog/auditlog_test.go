@@ -0,0 +1,103 @@
+package auditlog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type loaderFunc func(r *http.Request, image string) (*imagor.Blob, error)
+
+func (f loaderFunc) Get(r *http.Request, image string) (*imagor.Blob, error) {
+	return f(r, image)
+}
+
+func readEvents(t *testing.T, path string) (events []imagor.Event) {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event imagor.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	return
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+	l := New(path)
+	app := imagor.New(
+		imagor.WithUnsafe(true),
+		imagor.WithAPIKey("backend", "k3y1"),
+		imagor.WithEventHandlers(l),
+		imagor.WithLoaders(loaderFunc(func(r *http.Request, image string) (*imagor.Blob, error) {
+			if image == "missing.jpg" {
+				return nil, imagor.ErrNotFound
+			}
+			return imagor.NewBlobFromBytes([]byte("foo")), nil
+		})),
+	)
+	require.NoError(t, app.Startup(context.Background()))
+	for _, path := range []string{
+		imagorpath.GenerateUnsafe(imagorpath.Params{Image: "foo.jpg", Width: 100}),
+		imagorpath.GenerateUnsafe(imagorpath.Params{Image: "missing.jpg"}),
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/"+path, nil)
+		r.RemoteAddr = "1.2.3.4:5678"
+		r.Header.Set("Authorization", "Bearer k3y1")
+		app.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	require.NoError(t, app.Shutdown(context.Background()))
+
+	events := readEvents(t, path)
+	require.Len(t, events, 1, "processed events only by default")
+	assert.Equal(t, imagor.EventProcessed, events[0].Type)
+	assert.Equal(t, "100x0/foo.jpg", events[0].Path)
+	assert.Equal(t, "foo.jpg", events[0].Image)
+	assert.Equal(t, int64(3), events[0].Size)
+	assert.Equal(t, "1.2.3.4", events[0].ClientIP)
+	assert.Equal(t, "backend", events[0].APIKey)
+	assert.False(t, events[0].Time.IsZero())
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	l := New(path, WithEvents(), WithMaxSize(200), WithMaxBackups(2))
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		l.HandleEvent(ctx, imagor.Event{Type: imagor.EventLoadFailed, Image: "foo.jpg", Error: "not found"})
+	}
+	require.NoError(t, l.Shutdown(ctx))
+
+	stat, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, stat.Size(), int64(200))
+	rotated, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Len(t, rotated, 2)
+	for _, file := range rotated {
+		events := readEvents(t, file)
+		assert.NotEmpty(t, events)
+		assert.Equal(t, "foo.jpg", events[0].Image)
+	}
+
+	// reopen appends to existing file
+	n := len(readEvents(t, path))
+	l = New(path, WithEvents(imagor.EventLoadFailed), WithMaxSize(1<<20))
+	l.HandleEvent(ctx, imagor.Event{Type: imagor.EventProcessed})
+	l.HandleEvent(ctx, imagor.Event{Type: imagor.EventLoadFailed})
+	require.NoError(t, l.Shutdown(ctx))
+	assert.Len(t, readEvents(t, path), n+1)
+}
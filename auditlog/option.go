@@ -0,0 +1,41 @@
+package auditlog
+
+import (
+	"github.com/cshum/imagor"
+	"go.uber.org/zap"
+)
+
+type Option func(l *AuditLog)
+
+// WithEvents events to be logged in place of processed events, all events if empty
+func WithEvents(events ...imagor.EventType) Option {
+	return func(l *AuditLog) {
+		l.Events = events
+	}
+}
+
+// WithMaxSize file size in bytes exceeding which audit log is rotated, default 100MB
+func WithMaxSize(size int64) Option {
+	return func(l *AuditLog) {
+		if size > 0 {
+			l.MaxSize = size
+		}
+	}
+}
+
+// WithMaxBackups number of rotated files kept, all kept if 0
+func WithMaxBackups(n int) Option {
+	return func(l *AuditLog) {
+		if n >= 0 {
+			l.MaxBackups = n
+		}
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(l *AuditLog) {
+		if logger != nil {
+			l.Logger = logger
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package imagor
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// maxBatchSize maximum number of imagor paths per batch request
+const maxBatchSize = 100
+
+// maxBatchBodySize maximum batch request body size
+const maxBatchBodySize = 1 << 20
+
+type batchResult struct {
+	Path        string `json:"path"`
+	Status      int    `json:"status"`
+	Size        int64  `json:"size,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// serveBatch handles POST /batch of a JSON array of imagor paths,
+// processing them with bounded parallelism and responds status per entry
+func (app *Imagor) serveBatch(w http.ResponseWriter, r *http.Request) {
+	var paths []string
+	err := readJSONBody(r, &paths)
+	if err == nil && len(paths) > maxBatchSize {
+		err = ErrMaxSizeExceeded
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var results = make([]batchResult, len(paths))
+	parallel(len(paths), app.BatchConcurrency, func(i int) {
+		results[i] = app.batchDo(r, paths[i])
+	})
+	writeJSON(w, r, results)
+}
+
+// readJSONBody decodes size limited JSON request body
+func readJSONBody(r *http.Request, v interface{}) error {
+	buf, err := io.ReadAll(io.LimitReader(r.Body, maxBatchBodySize+1))
+	if err != nil {
+		return err
+	}
+	if len(buf) > maxBatchBodySize {
+		return ErrMaxSizeExceeded
+	}
+	if err = json.Unmarshal(buf, v); err != nil {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// parallel calls fn for index 0 to n-1 with bounded concurrency
+func parallel(n, concurrency int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var sema = make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sema <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (app *Imagor) batchDo(r *http.Request, path string) batchResult {
+	var res = batchResult{Path: path, Status: http.StatusOK}
+	p, err := app.parse("/" + strings.TrimLeft(path, "/"))
+	if err != nil {
+		err = ErrSignatureMismatch
+	} else if p.Params || p.Meta {
+		err = ErrInvalid
+	}
+	if err == nil {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		var blob *Blob
+		if blob, err = checkBlob(app.Do(r.Clone(ctx), p)); err == nil && !isBlobEmpty(blob) {
+			res.Size = blob.Size()
+			res.ContentType = blob.ContentType()
+		}
+	}
+	if err != nil {
+		e := WrapError(err)
+		res.Status = e.Code
+		res.Error = e.Message
+	}
+	return res
+}
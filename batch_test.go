@@ -0,0 +1,78 @@
+package imagor
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithBatchConcurrency(t *testing.T) {
+	var active, maxActive int64
+	app := New(
+		WithUnsafe(true),
+		WithSigner(imagorpath.NewDefaultSigner("1234")),
+		WithBatchConcurrency(2),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			n := atomic.AddInt64(&active, 1)
+			defer atomic.AddInt64(&active, -1)
+			for {
+				m := atomic.LoadInt64(&maxActive)
+				if n <= m || atomic.CompareAndSwapInt64(&maxActive, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 10)
+			if image == "missing" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	doPost := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodPost, "https://example.com/batch", strings.NewReader(body)))
+		return w
+	}
+	signed := imagorpath.Generate(imagorpath.Params{Image: "signed"}, app.Signer)
+
+	w := doPost(fmt.Sprintf(
+		`["unsafe/foo", "/unsafe/barr", "%s", "abcdefghij/bar", "unsafe/missing", "params/unsafe/foo"]`, signed))
+	assert.Equal(t, 200, w.Code)
+	var results []batchResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	assert.Equal(t, []batchResult{
+		{Path: "unsafe/foo", Status: 200, Size: 3, ContentType: "text/plain; charset=utf-8"},
+		{Path: "/unsafe/barr", Status: 200, Size: 4, ContentType: "text/plain; charset=utf-8"},
+		{Path: signed, Status: 200, Size: 6, ContentType: "text/plain; charset=utf-8"},
+		{Path: "abcdefghij/bar", Status: 403, Error: ErrSignatureMismatch.Message},
+		{Path: "unsafe/missing", Status: 404, Error: ErrNotFound.Message},
+		{Path: "params/unsafe/foo", Status: 400, Error: ErrInvalid.Message},
+	}, results)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&maxActive))
+
+	w = doPost(`{"foo": "bar"}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, jsonStr(ErrInvalid), w.Body.String())
+
+	w = doPost("[" + strings.Repeat(`"unsafe/foo",`, maxBatchSize) + `"unsafe/foo"]`)
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, jsonStr(ErrMaxSizeExceeded), w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodPost, "https://example.com/unsafe/foo", strings.NewReader(`[]`)))
+	assert.Equal(t, 405, w.Code)
+
+	app = New(WithUnsafe(true))
+	w = doPost(`["unsafe/foo"]`)
+	assert.Equal(t, 405, w.Code)
+}
@@ -3,6 +3,7 @@ package imagor
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/cshum/imagor/bufpool"
 	"github.com/cshum/imagor/fanoutreader"
 	"github.com/cshum/imagor/seekstream"
 	"io"
@@ -81,8 +82,15 @@ func NewBlobFromFile(filepath string, checks ...func(os.FileInfo) error) *Blob {
 			if err != nil {
 				return nil, 0, err
 			}
-			reader, err := os.Open(filepath)
-			return reader, stat.Size(), err
+			file, err := os.Open(filepath)
+			if err != nil {
+				return nil, 0, err
+			}
+			// size of the opened file in case file replaced since stat
+			if fi, err := file.Stat(); err == nil {
+				return file, fi.Size(), nil
+			}
+			return file, stat.Size(), nil
 		},
 	}
 	if err == nil && stat != nil {
@@ -335,6 +343,11 @@ func (b *Blob) BlobType() BlobType {
 	return b.blobType
 }
 
+// Extension returns file extension of blob type e.g. .jpg, empty if unknown
+func (b *Blob) Extension() string {
+	return getExtension(b.BlobType())
+}
+
 func (b *Blob) Sniff() []byte {
 	b.init()
 	return b.sniffBuf
@@ -421,7 +434,7 @@ func (b *Blob) ReadAll() ([]byte, error) {
 			}
 			return buf, err2
 		} else {
-			buf, err2 := io.ReadAll(reader)
+			buf, err2 := bufpool.ReadAll(reader)
 			if err != nil {
 				return buf, err
 			}
@@ -0,0 +1,195 @@
+package imagor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"golang.org/x/image/tiff"
+	"golang.org/x/image/webp"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// sourcePixels returns pixel count width × height × frames of image source by reading headers
+// without full decode, ok false if image type not supported or headers invalid
+func sourcePixels(blob *Blob) (pixels int64, ok bool) {
+	if _, width, height, _, ok := blob.Memory(); ok {
+		return int64(width) * int64(height), true
+	}
+	var decodeConfig func(io.Reader) (image.Config, error)
+	var countFrames func(*bufio.Reader) int
+	switch blob.BlobType() {
+	case BlobTypeJPEG:
+		decodeConfig = jpeg.DecodeConfig
+	case BlobTypePNG:
+		decodeConfig, countFrames = png.DecodeConfig, pngFrames
+	case BlobTypeGIF:
+		decodeConfig, countFrames = gif.DecodeConfig, gifFrames
+	case BlobTypeWEBP:
+		decodeConfig, countFrames = webp.DecodeConfig, webpFrames
+	case BlobTypeTIFF:
+		decodeConfig = tiff.DecodeConfig
+	case BlobTypeAVIF, BlobTypeHEIF:
+		decodeConfig = heifConfig
+	default:
+		return 0, false
+	}
+	reader, _, err := blob.NewReader()
+	if err != nil {
+		return 0, false
+	}
+	cfg, err := decodeConfig(reader)
+	_ = reader.Close()
+	if err != nil {
+		return 0, false
+	}
+	var frames = 1
+	if countFrames != nil {
+		if reader, _, err = blob.NewReader(); err == nil {
+			if n := countFrames(bufio.NewReader(reader)); n > 1 {
+				frames = n
+			}
+			_ = reader.Close()
+		}
+	}
+	return int64(cfg.Width) * int64(cfg.Height) * int64(frames), true
+}
+
+// maxHEIFHeaderSize maximum bytes read for ispe properties of AVIF and HEIF,
+// which are of the meta box preceding image data
+const maxHEIFHeaderSize = 1 << 20
+
+var ispe = []byte("ispe")
+
+// heifConfig returns dimensions of AVIF and HEIF by the largest ispe image spatial extents property,
+// i.e. the grid of tiled image
+func heifConfig(r io.Reader) (cfg image.Config, err error) {
+	buf, err := io.ReadAll(io.LimitReader(r, maxHEIFHeaderSize))
+	if err != nil {
+		return
+	}
+	for {
+		i := bytes.Index(buf, ispe)
+		// ispe box: version and flags, then width and height
+		if i < 0 || len(buf) < i+16 {
+			break
+		}
+		width := binary.BigEndian.Uint32(buf[i+8:])
+		height := binary.BigEndian.Uint32(buf[i+12:])
+		if uint64(width)*uint64(height) > uint64(cfg.Width)*uint64(cfg.Height) {
+			cfg.Width, cfg.Height = int(width), int(height)
+		}
+		buf = buf[i+16:]
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		err = errors.New("ispe not found")
+	}
+	return
+}
+
+// pngFrames returns number of frames from APNG acTL chunk
+func pngFrames(r *bufio.Reader) int {
+	if _, err := r.Discard(8); err != nil {
+		return 0
+	}
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return 0
+		}
+		length := int(binary.BigEndian.Uint32(hdr[:4]))
+		switch string(hdr[4:8]) {
+		case "acTL":
+			var n [4]byte
+			if _, err := io.ReadFull(r, n[:]); err != nil {
+				return 0
+			}
+			return int(binary.BigEndian.Uint32(n[:]))
+		case "IDAT", "IEND":
+			// acTL must appear before image data
+			return 1
+		}
+		if _, err := r.Discard(length + 4); err != nil {
+			return 0
+		}
+	}
+}
+
+// gifFrames counts GIF image descriptors by walking blocks without LZW decoding
+func gifFrames(r *bufio.Reader) (frames int) {
+	var hdr [13]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return
+	}
+	if hdr[10]&0x80 != 0 {
+		if _, err := r.Discard(3 << ((hdr[10] & 0x07) + 1)); err != nil {
+			return
+		}
+	}
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		switch c {
+		case 0x21: // extension
+			if _, err = r.Discard(1); err != nil || skipGIFSubBlocks(r) != nil {
+				return
+			}
+		case 0x2C: // image descriptor
+			var desc [9]byte
+			if _, err = io.ReadFull(r, desc[:]); err != nil {
+				return
+			}
+			if desc[8]&0x80 != 0 {
+				if _, err = r.Discard(3 << ((desc[8] & 0x07) + 1)); err != nil {
+					return
+				}
+			}
+			// lzw minimum code size
+			if _, err = r.Discard(1); err != nil || skipGIFSubBlocks(r) != nil {
+				return
+			}
+			frames++
+		default: // trailer
+			return
+		}
+	}
+}
+
+func skipGIFSubBlocks(r *bufio.Reader) error {
+	for {
+		n, err := r.ReadByte()
+		if err != nil || n == 0 {
+			return err
+		}
+		if _, err = r.Discard(int(n)); err != nil {
+			return err
+		}
+	}
+}
+
+// webpFrames counts ANMF chunks of animated WebP
+func webpFrames(r *bufio.Reader) (frames int) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil || !bytes.Equal(hdr[8:12], webpHeader) {
+		return
+	}
+	var chunk [8]byte
+	for {
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			return
+		}
+		if string(chunk[:4]) == "ANMF" {
+			frames++
+		}
+		size := int(binary.LittleEndian.Uint32(chunk[4:]))
+		if _, err := r.Discard(size + size&1); err != nil {
+			return
+		}
+	}
+}
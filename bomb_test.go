@@ -0,0 +1,144 @@
+package imagor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func pngChunk(typ string, data []byte) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	buf.WriteString(typ)
+	buf.Write(data)
+	_ = binary.Write(&buf, binary.BigEndian, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+	return buf.Bytes()
+}
+
+// pngBomb creates PNG header of large dimensions without image data
+func pngBomb(width, height uint32, frames uint32) []byte {
+	var buf bytes.Buffer
+	buf.Write(pngHeader)
+	buf.Write([]byte("\r\n\x1a\n"))
+	var ihdr = make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8], ihdr[9] = 8, 6
+	buf.Write(pngChunk("IHDR", ihdr))
+	if frames > 0 {
+		var actl = make([]byte, 8)
+		binary.BigEndian.PutUint32(actl, frames)
+		buf.Write(pngChunk("acTL", actl))
+	}
+	buf.Write(pngChunk("IDAT", make([]byte, 16)))
+	buf.Write(pngChunk("IEND", nil))
+	return buf.Bytes()
+}
+
+func TestSourcePixels(t *testing.T) {
+	buf, err := os.ReadFile("testdata/gopher.png")
+	require.NoError(t, err)
+	cfg, err := png.DecodeConfig(bytes.NewReader(buf))
+	require.NoError(t, err)
+	pixels, ok := sourcePixels(NewBlobFromBytes(buf))
+	assert.True(t, ok)
+	assert.Equal(t, int64(cfg.Width*cfg.Height), pixels)
+
+	pixels, ok = sourcePixels(NewBlobFromBytes(pngBomb(60000, 60000, 0)))
+	assert.True(t, ok)
+	assert.Equal(t, int64(3600000000), pixels)
+
+	pixels, ok = sourcePixels(NewBlobFromBytes(pngBomb(100, 100, 5)))
+	assert.True(t, ok)
+	assert.Equal(t, int64(50000), pixels)
+
+	var anim = &gif.GIF{}
+	for i := 0; i < 3; i++ {
+		anim.Image = append(anim.Image, image.NewPaletted(
+			image.Rect(0, 0, 20, 10), color.Palette{color.Black, color.White}))
+		anim.Delay = append(anim.Delay, 0)
+	}
+	var gifBuf bytes.Buffer
+	require.NoError(t, gif.EncodeAll(&gifBuf, anim))
+	pixels, ok = sourcePixels(NewBlobFromBytes(gifBuf.Bytes()))
+	assert.True(t, ok)
+	assert.Equal(t, int64(600), pixels)
+
+	var jpegBuf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpegBuf, image.NewGray(image.Rect(0, 0, 30, 20)), nil))
+	pixels, ok = sourcePixels(NewBlobFromBytes(jpegBuf.Bytes()))
+	assert.True(t, ok)
+	assert.Equal(t, int64(600), pixels)
+
+	pixels, ok = sourcePixels(NewBlobFromBytes(avifBomb(50000, 40000)))
+	assert.True(t, ok)
+	assert.Equal(t, int64(2000000000), pixels)
+
+	pixels, ok = sourcePixels(NewBlobFromMemory(make([]byte, 30*20*3), 30, 20, 3))
+	assert.True(t, ok)
+	assert.Equal(t, int64(600), pixels)
+
+	_, ok = sourcePixels(NewBlobFromBytes([]byte("foo")))
+	assert.False(t, ok)
+
+	_, ok = sourcePixels(NewBlobFromBytes(append(pngBomb(100, 100, 0)[:16], make([]byte, 16)...)))
+	assert.False(t, ok)
+}
+
+// avifBomb AVIF header of tile and grid ispe properties without image data
+func avifBomb(width, height uint32) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte("\x00\x00\x00\x1cftypavif\x00\x00\x00\x00avifmif1miaf"))
+	for _, size := range [][2]uint32{{512, 512}, {width, height}} {
+		var prop = make([]byte, 20)
+		binary.BigEndian.PutUint32(prop, 20)
+		copy(prop[4:], "ispe")
+		binary.BigEndian.PutUint32(prop[12:], size[0])
+		binary.BigEndian.PutUint32(prop[16:], size[1])
+		buf.Write(prop)
+	}
+	return buf.Bytes()
+}
+
+func TestWithMaxSourcePixels(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithMaxSourcePixels(10000*10000),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			switch image {
+			case "bomb.png":
+				return NewBlobFromBytes(pngBomb(60000, 60000, 0)), nil
+			case "bomb.avif":
+				return NewBlobFromBytes(avifBomb(50000, 40000)), nil
+			case "image.svg":
+				return NewBlobFromBytes([]byte(`<svg xmlns="http://www.w3.org/2000/svg" width="100000" height="100000"></svg>`)), nil
+			}
+			return NewBlobFromBytes(pngBomb(100, 100, 0)), nil
+		})),
+	)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/bomb.png", nil))
+	assert.Equal(t, 422, w.Code)
+	assert.Equal(t, jsonStr(ErrMaxResolutionExceeded), w.Body.String())
+
+	for _, image := range []string{"bomb.avif", "image.svg"} {
+		w = httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+image, nil))
+		assert.Equal(t, 422, w.Code, image)
+	}
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/small.png", nil))
+	assert.Equal(t, 200, w.Code)
+}
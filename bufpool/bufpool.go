@@ -0,0 +1,59 @@
+package bufpool
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// MaxSize maximum capacity of buffer returned to pool,
+// that occasional large images do not pin memory of the pool
+const MaxSize = 32 << 20
+
+// copySize size of copy buffers
+const copySize = 32 << 10
+
+var buffers = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
+var copyBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, copySize)
+		return &buf
+	},
+}
+
+// Get returns empty bytes.Buffer from pool, to be returned by Put once its bytes are no longer referenced
+func Get() *bytes.Buffer {
+	return buffers.Get().(*bytes.Buffer)
+}
+
+// Put returns buffer to pool, dropped if capacity exceeded MaxSize
+func Put(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > MaxSize {
+		return
+	}
+	buf.Reset()
+	buffers.Put(buf)
+}
+
+// Copy copies from src to dst same as io.Copy, using pooled copy buffer
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+	return io.CopyBuffer(dst, src, *buf)
+}
+
+// ReadAll reads from r until EOF same as io.ReadAll, using pooled buffer while reading
+// that the returned bytes are allocated once at exact size
+func ReadAll(r io.Reader) ([]byte, error) {
+	buf := Get()
+	defer Put(buf)
+	_, err := buf.ReadFrom(r)
+	res := make([]byte, buf.Len())
+	copy(res, buf.Bytes())
+	return res, err
+}
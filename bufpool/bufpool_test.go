@@ -0,0 +1,60 @@
+package bufpool
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"strings"
+	"testing"
+)
+
+// writerOnly hides io.ReaderFrom of the writer, that copy goes through buffer
+type writerOnly struct {
+	io.Writer
+}
+
+func TestCopy(t *testing.T) {
+	src := strings.Repeat("abcdefgh", 10000)
+	var dst bytes.Buffer
+	n, err := Copy(writerOnly{&dst}, strings.NewReader(src))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(src)), n)
+	assert.Equal(t, src, dst.String())
+}
+
+func TestReadAll(t *testing.T) {
+	src := strings.Repeat("abcdefgh", 10000)
+	buf, err := ReadAll(strings.NewReader(src))
+	require.NoError(t, err)
+	assert.Equal(t, src, string(buf))
+	assert.Equal(t, len(buf), cap(buf))
+
+	// returned bytes not overwritten by reuse of pooled buffer
+	buf2, err := ReadAll(strings.NewReader("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(buf2))
+	assert.Equal(t, src, string(buf))
+
+	buf, err = ReadAll(io.MultiReader(strings.NewReader("bar"), errReader{}))
+	assert.Equal(t, "bar", string(buf))
+	assert.EqualError(t, err, "broken")
+}
+
+func TestPut(t *testing.T) {
+	buf := Get()
+	buf.WriteString("foo")
+	Put(buf)
+	assert.Zero(t, buf.Len())
+
+	large := bytes.NewBuffer(make([]byte, 0, MaxSize+1))
+	Put(large)
+	Put(nil)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("broken")
+}
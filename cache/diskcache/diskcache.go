@@ -0,0 +1,299 @@
+package diskcache
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/cshum/imagor"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const ext = ".cache"
+
+// header of cache file, written as JSON line in front of the content
+type header struct {
+	Key         string       `json:"key"`
+	ContentType string       `json:"content_type,omitempty"`
+	Stat        *imagor.Stat `json:"stat,omitempty"`
+	Expires     time.Time    `json:"expires,omitempty"`
+}
+
+type entry struct {
+	key     string
+	name    string
+	size    int64
+	expires time.Time
+}
+
+// DiskCache size bounded on-disk LRU cache implements imagor.Cache.
+// Entries persist across restarts, indexed from the cache directory on creation
+type DiskCache struct {
+	Dir     string
+	MaxSize int64
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	size  int64
+}
+
+// New creates DiskCache of directory bounded by max total bytes size,
+// with existing entries of the directory loaded
+func New(dir string, maxSize int64) (*DiskCache, error) {
+	c := &DiskCache{
+		Dir:     dir,
+		MaxSize: maxSize,
+		ll:      list.New(),
+		items:   map[string]*list.Element{},
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *DiskCache) Get(_ context.Context, key string) (*imagor.Blob, error) {
+	c.mu.Lock()
+	elem, ok := c.items[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil, imagor.ErrNotFound
+	}
+	e := elem.Value.(*entry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(elem)
+		c.mu.Unlock()
+		return nil, imagor.ErrNotFound
+	}
+	c.ll.MoveToFront(elem)
+	c.mu.Unlock()
+	h, buf, err := readFile(filepath.Join(c.Dir, e.name))
+	if err != nil || h.Key != key {
+		_ = c.Delete(context.Background(), key)
+		return nil, imagor.ErrNotFound
+	}
+	blob := imagor.NewBlobFromBytes(buf)
+	blob.SetContentType(h.ContentType)
+	blob.Stat = h.Stat
+	return blob, nil
+}
+
+func (c *DiskCache) Set(_ context.Context, key string, blob *imagor.Blob, ttl time.Duration) error {
+	buf, err := blob.ReadAll()
+	if err != nil {
+		return err
+	}
+	size := int64(len(buf))
+	if c.MaxSize > 0 && size > c.MaxSize {
+		return imagor.ErrMaxSizeExceeded
+	}
+	h := header{
+		Key:         key,
+		ContentType: blob.ContentType(),
+		Stat:        blob.Stat,
+	}
+	if ttl > 0 {
+		h.Expires = time.Now().Add(ttl)
+	}
+	name := fileName(key)
+	if err := writeFile(c.Dir, name, h, buf); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.ll.Remove(elem)
+		c.size -= elem.Value.(*entry).size
+	}
+	c.items[key] = c.ll.PushFront(&entry{
+		key: key, name: name, size: size, expires: h.Expires,
+	})
+	c.size += size
+	c.evict()
+	return nil
+}
+
+func (c *DiskCache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	return nil
+}
+
+// DeletePrefix deletes all items with key prefix, implements imagor.PrefixDeleter
+func (c *DiskCache) DeletePrefix(_ context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Expires returns expiry time of entry, zero if not found or without expiry
+func (c *DiskCache) Expires(key string) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		return elem.Value.(*entry).expires
+	}
+	return time.Time{}
+}
+
+// Size returns current total bytes size of the cache
+func (c *DiskCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Len returns current number of items of the cache
+func (c *DiskCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// load indexes cache files of the directory by modified time,
+// removing expired and unreadable files
+func (c *DiskCache) load() error {
+	files, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return err
+	}
+	type loaded struct {
+		entry   *entry
+		modTime time.Time
+	}
+	var entries []loaded
+	var now = time.Now()
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+		p := filepath.Join(c.Dir, file.Name())
+		if strings.Contains(file.Name(), ext+".tmp") {
+			// incomplete write of previous run
+			_ = os.Remove(p)
+			continue
+		}
+		if !strings.HasSuffix(file.Name(), ext) {
+			continue
+		}
+		info, err1 := file.Info()
+		h, n, err2 := readHeader(p)
+		if err1 != nil || err2 != nil || (!h.Expires.IsZero() && now.After(h.Expires)) {
+			_ = os.Remove(p)
+			continue
+		}
+		entries = append(entries, loaded{
+			entry: &entry{
+				key: h.Key, name: file.Name(), size: info.Size() - n, expires: h.Expires,
+			},
+			modTime: info.ModTime(),
+		})
+	}
+	// oldest first so that most recent ends up in front
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, l := range entries {
+		c.items[l.entry.key] = c.ll.PushFront(l.entry)
+		c.size += l.entry.size
+	}
+	c.evict()
+	return nil
+}
+
+func (c *DiskCache) evict() {
+	for c.MaxSize > 0 && c.size > c.MaxSize {
+		if elem := c.ll.Back(); elem != nil {
+			c.remove(elem)
+		}
+	}
+}
+
+func (c *DiskCache) remove(elem *list.Element) {
+	e := c.ll.Remove(elem).(*entry)
+	delete(c.items, e.key)
+	c.size -= e.size
+	_ = os.Remove(filepath.Join(c.Dir, e.name))
+}
+
+func fileName(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:]) + ext
+}
+
+func readFile(p string) (h header, buf []byte, err error) {
+	if buf, err = os.ReadFile(p); err != nil {
+		return
+	}
+	i := bytes.IndexByte(buf, '\n')
+	if i < 0 {
+		err = imagor.ErrNotFound
+		return
+	}
+	if err = json.Unmarshal(buf[:i], &h); err != nil {
+		return
+	}
+	buf = buf[i+1:]
+	return
+}
+
+// readHeader reads header of cache file, returns header with its bytes length
+func readHeader(p string) (h header, n int64, err error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(line, &h)
+	n = int64(len(line))
+	return
+}
+
+// writeFile writes to temp file then renames, so that readers never see partial content
+func writeFile(dir, name string, h header, buf []byte) error {
+	hdr, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, name+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(hdr, '\n')); err == nil {
+		_, err = file.Write(buf)
+	}
+	if e := file.Close(); err == nil {
+		err = e
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+	return err
+}
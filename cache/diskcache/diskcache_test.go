@@ -0,0 +1,90 @@
+package diskcache
+
+import (
+	"context"
+	"github.com/cshum/imagor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiskCache(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c, err := New(dir, 10)
+	require.NoError(t, err)
+
+	_, err = c.Get(ctx, "a")
+	assert.Equal(t, imagor.ErrNotFound, err)
+
+	blob := imagor.NewBlobFromBytes([]byte("aaaa"))
+	blob.SetContentType("image/foo")
+	blob.Stat = &imagor.Stat{ETag: "abc"}
+	require.NoError(t, c.Set(ctx, "a", blob, 0))
+	require.NoError(t, c.Set(ctx, "b", imagor.NewBlobFromBytes([]byte("bbbb")), 0))
+	assert.Equal(t, int64(8), c.Size())
+	assert.Equal(t, 2, c.Len())
+
+	res, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	buf, _ := res.ReadAll()
+	assert.Equal(t, "aaaa", string(buf))
+	assert.Equal(t, "image/foo", res.ContentType())
+	assert.Equal(t, "abc", res.Stat.ETag)
+
+	// b is least recently used hence evicted with its file
+	require.NoError(t, c.Set(ctx, "c", imagor.NewBlobFromBytes([]byte("cccc")), 0))
+	_, err = c.Get(ctx, "b")
+	assert.Equal(t, imagor.ErrNotFound, err)
+	files, _ := os.ReadDir(dir)
+	assert.Len(t, files, 2)
+
+	assert.Equal(t, imagor.ErrMaxSizeExceeded,
+		c.Set(ctx, "d", imagor.NewBlobFromBytes([]byte(strings.Repeat("d", 11))), 0))
+
+	// entries persist across instances
+	c, err = New(dir, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(8), c.Size())
+	res, err = c.Get(ctx, "c")
+	require.NoError(t, err)
+	buf, _ = res.ReadAll()
+	assert.Equal(t, "cccc", string(buf))
+
+	require.NoError(t, c.Delete(ctx, "c"))
+	_, err = c.Get(ctx, "c")
+	assert.Equal(t, imagor.ErrNotFound, err)
+	require.NoError(t, c.DeletePrefix(ctx, "a"))
+	assert.Equal(t, 0, c.Len())
+	files, _ = os.ReadDir(dir)
+	assert.Len(t, files, 0)
+}
+
+func TestDiskCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c, err := New(dir, 0)
+	require.NoError(t, err)
+	require.NoError(t, c.Set(ctx, "a", imagor.NewBlobFromBytes([]byte("a")), time.Millisecond*20))
+	require.NoError(t, c.Set(ctx, "b", imagor.NewBlobFromBytes([]byte("b")), 0))
+	assert.False(t, c.Expires("a").IsZero())
+	assert.True(t, c.Expires("b").IsZero())
+	_, err = c.Get(ctx, "a")
+	assert.NoError(t, err)
+
+	// expired and incomplete files removed on load
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo"+ext+".tmp123"), []byte("foo"), 0644))
+	time.Sleep(time.Millisecond * 30)
+	c, err = New(dir, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c.Len())
+	files, _ := os.ReadDir(dir)
+	assert.Len(t, files, 1)
+	_, err = c.Get(ctx, "a")
+	assert.Equal(t, imagor.ErrNotFound, err)
+}
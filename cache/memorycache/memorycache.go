@@ -0,0 +1,191 @@
+package memorycache
+
+import (
+	"container/list"
+	"context"
+	"github.com/cshum/imagor"
+	"strings"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key         string
+	buf         []byte
+	contentType string
+	stat        *imagor.Stat
+	expires     time.Time
+}
+
+// MemoryCache size bounded in-memory LRU cache implements imagor.Cache
+type MemoryCache struct {
+	MaxSize int64
+	// Overflow cache e.g. disk cache that entries evicted by size spill to,
+	// and looked up on miss with hit promoted back to memory
+	Overflow imagor.Cache
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	size  int64
+}
+
+// New creates MemoryCache bounded by max total bytes size
+func New(maxSize int64) *MemoryCache {
+	return &MemoryCache{
+		MaxSize: maxSize,
+		ll:      list.New(),
+		items:   map[string]*list.Element{},
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (*imagor.Blob, error) {
+	if blob, ok := c.get(key); ok {
+		return blob, nil
+	}
+	if c.Overflow == nil {
+		return nil, imagor.ErrNotFound
+	}
+	blob, err := c.Overflow.Get(ctx, key)
+	if err != nil {
+		return blob, err
+	}
+	var ttl time.Duration
+	if e, ok := c.Overflow.(expirer); ok {
+		if expires := e.Expires(key); !expires.IsZero() {
+			if ttl = time.Until(expires); ttl <= 0 {
+				return blob, nil
+			}
+		}
+	}
+	_ = c.Set(ctx, key, blob, ttl)
+	return blob, nil
+}
+
+func (c *MemoryCache) get(key string) (*imagor.Blob, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := elem.Value.(*entry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+	c.ll.MoveToFront(elem)
+	return e.blob(), true
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, blob *imagor.Blob, ttl time.Duration) error {
+	buf, err := blob.ReadAll()
+	if err != nil {
+		return err
+	}
+	size := int64(len(buf))
+	if c.MaxSize > 0 && size > c.MaxSize {
+		return imagor.ErrMaxSizeExceeded
+	}
+	e := &entry{
+		key:         key,
+		buf:         buf,
+		contentType: blob.ContentType(),
+		stat:        blob.Stat,
+	}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+	var evicted []*entry
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	c.items[key] = c.ll.PushFront(e)
+	c.size += size
+	for c.MaxSize > 0 && c.size > c.MaxSize {
+		if elem := c.ll.Back(); elem != nil {
+			evicted = append(evicted, c.remove(elem))
+		}
+	}
+	c.mu.Unlock()
+	c.spill(ctx, evicted)
+	return nil
+}
+
+// spill writes entries evicted by size to overflow cache with their remaining TTL
+func (c *MemoryCache) spill(ctx context.Context, evicted []*entry) {
+	if c.Overflow == nil {
+		return
+	}
+	now := time.Now()
+	for _, e := range evicted {
+		var ttl time.Duration
+		if !e.expires.IsZero() {
+			if ttl = e.expires.Sub(now); ttl <= 0 {
+				continue
+			}
+		}
+		_ = c.Overflow.Set(ctx, e.key, e.blob(), ttl)
+	}
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+	c.mu.Unlock()
+	if c.Overflow != nil {
+		return c.Overflow.Delete(ctx, key)
+	}
+	return nil
+}
+
+// DeletePrefix deletes all items with key prefix, implements imagor.PrefixDeleter
+func (c *MemoryCache) DeletePrefix(ctx context.Context, prefix string) error {
+	c.mu.Lock()
+	for key, elem := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(elem)
+		}
+	}
+	c.mu.Unlock()
+	if d, ok := c.Overflow.(imagor.PrefixDeleter); ok {
+		return d.DeletePrefix(ctx, prefix)
+	}
+	return nil
+}
+
+// Size returns current total bytes size of the cache
+func (c *MemoryCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Len returns current number of items of the cache
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *MemoryCache) remove(elem *list.Element) *entry {
+	e := c.ll.Remove(elem).(*entry)
+	delete(c.items, e.key)
+	c.size -= int64(len(e.buf))
+	return e
+}
+
+func (e *entry) blob() *imagor.Blob {
+	blob := imagor.NewBlobFromBytes(e.buf)
+	blob.SetContentType(e.contentType)
+	blob.Stat = e.stat
+	return blob
+}
+
+// expirer overflow cache that reports expiry of entry, for TTL of entry promoted to memory
+type expirer interface {
+	Expires(key string) time.Time
+}
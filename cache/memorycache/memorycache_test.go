@@ -0,0 +1,116 @@
+package memorycache
+
+import (
+	"context"
+	"github.com/cshum/imagor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	c := New(10)
+
+	_, err := c.Get(ctx, "a")
+	assert.Equal(t, imagor.ErrNotFound, err)
+
+	blob := imagor.NewBlobFromBytes([]byte("aaaa"))
+	blob.SetContentType("image/foo")
+	blob.Stat = &imagor.Stat{ETag: "abc"}
+	require.NoError(t, c.Set(ctx, "a", blob, 0))
+	require.NoError(t, c.Set(ctx, "b", imagor.NewBlobFromBytes([]byte("bbbb")), 0))
+	assert.Equal(t, int64(8), c.Size())
+	assert.Equal(t, 2, c.Len())
+
+	res, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	buf, _ := res.ReadAll()
+	assert.Equal(t, "aaaa", string(buf))
+	assert.Equal(t, "image/foo", res.ContentType())
+	assert.Equal(t, "abc", res.Stat.ETag)
+
+	// b is least recently used hence evicted
+	require.NoError(t, c.Set(ctx, "c", imagor.NewBlobFromBytes([]byte("cccc")), 0))
+	_, err = c.Get(ctx, "b")
+	assert.Equal(t, imagor.ErrNotFound, err)
+	_, err = c.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), c.Size())
+
+	// replace existing key
+	require.NoError(t, c.Set(ctx, "c", imagor.NewBlobFromBytes([]byte("cc")), 0))
+	assert.Equal(t, int64(6), c.Size())
+
+	require.NoError(t, c.Delete(ctx, "c"))
+	_, err = c.Get(ctx, "c")
+	assert.Equal(t, imagor.ErrNotFound, err)
+	assert.Equal(t, int64(4), c.Size())
+
+	assert.Equal(t, imagor.ErrMaxSizeExceeded,
+		c.Set(ctx, "d", imagor.NewBlobFromBytes([]byte(strings.Repeat("d", 11))), 0))
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	ctx := context.Background()
+	c := New(0)
+	require.NoError(t, c.Set(ctx, "a", imagor.NewBlobFromBytes([]byte("a")), time.Millisecond*5))
+	_, err := c.Get(ctx, "a")
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond * 10)
+	_, err = c.Get(ctx, "a")
+	assert.Equal(t, imagor.ErrNotFound, err)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestMemoryCacheDeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	c := New(100)
+	for _, key := range []string{"foo/a", "foo/b", "foobar", "bar/a"} {
+		require.NoError(t, c.Set(ctx, key, imagor.NewBlobFromBytes([]byte(key)), 0))
+	}
+	require.NoError(t, c.DeletePrefix(ctx, "foo/"))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, int64(11), c.Size())
+	_, err := c.Get(ctx, "foo/a")
+	assert.Equal(t, imagor.ErrNotFound, err)
+	_, err = c.Get(ctx, "foobar")
+	assert.NoError(t, err)
+}
+
+func TestMemoryCacheOverflow(t *testing.T) {
+	ctx := context.Background()
+	overflow := New(100)
+	c := New(8)
+	c.Overflow = overflow
+
+	require.NoError(t, c.Set(ctx, "a", imagor.NewBlobFromBytes([]byte("aaaa")), time.Minute))
+	require.NoError(t, c.Set(ctx, "b", imagor.NewBlobFromBytes([]byte("bbbb")), 0))
+	assert.Equal(t, 0, overflow.Len())
+
+	// a evicted by size spills to overflow
+	require.NoError(t, c.Set(ctx, "c", imagor.NewBlobFromBytes([]byte("cccc")), 0))
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, 1, overflow.Len())
+
+	// miss found in overflow promoted back to memory, evicting b
+	blob, err := c.Get(ctx, "a")
+	require.NoError(t, err)
+	buf, _ := blob.ReadAll()
+	assert.Equal(t, "aaaa", string(buf))
+	_, ok := c.get("a")
+	assert.True(t, ok)
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	_, err = overflow.Get(ctx, "b")
+	assert.NoError(t, err)
+
+	require.NoError(t, c.Delete(ctx, "b"))
+	_, err = c.Get(ctx, "b")
+	assert.Equal(t, imagor.ErrNotFound, err)
+	require.NoError(t, c.DeletePrefix(ctx, "a"))
+	_, err = c.Get(ctx, "a")
+	assert.Equal(t, imagor.ErrNotFound, err)
+}
@@ -0,0 +1,39 @@
+package rediscache
+
+import (
+	"crypto/tls"
+	"time"
+)
+
+type Option func(c *RedisCache)
+
+// WithPrefix with key prefix of cache entries, default imagor:
+func WithPrefix(prefix string) Option {
+	return func(c *RedisCache) {
+		c.Prefix = prefix
+	}
+}
+
+func WithPoolSize(size int) Option {
+	return func(c *RedisCache) {
+		if size > 0 {
+			c.PoolSize = size
+		}
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *RedisCache) {
+		if timeout > 0 {
+			c.Timeout = timeout
+		}
+	}
+}
+
+// WithTLSConfig with TLS config of connections, e.g. of custom root CAs or client certificates.
+// TLS is enabled by rediss:// URL without this option
+func WithTLSConfig(config *tls.Config) Option {
+	return func(c *RedisCache) {
+		c.TLSConfig = config
+	}
+}
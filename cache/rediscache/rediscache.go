@@ -0,0 +1,205 @@
+package rediscache
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/bufpool"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisCache Redis backed cache implements imagor.Cache,
+// so that cached results are shared among multiple imagor instances
+type RedisCache struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+	Prefix   string
+	PoolSize int
+	Timeout  time.Duration
+	// TLSConfig enables TLS connections if not nil, set by rediss:// URL
+	TLSConfig *tls.Config
+
+	pool chan *conn
+}
+
+// New creates RedisCache by Redis URL in format of redis://[[user]:password@]host[:port][/db],
+// or rediss:// for TLS connections
+func New(redisURL string, options ...Option) (*RedisCache, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "redis" && u.Scheme != "rediss") || u.Host == "" {
+		return nil, errors.New("rediscache: invalid redis url")
+	}
+	c := &RedisCache{
+		Addr:     u.Host,
+		Prefix:   "imagor:",
+		PoolSize: 10,
+		Timeout:  time.Second * 5,
+	}
+	if u.Port() == "" {
+		c.Addr += ":6379"
+	}
+	if u.Scheme == "rediss" {
+		c.TLSConfig = &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}
+	}
+	if u.User != nil {
+		c.Username = u.User.Username()
+		c.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if c.DB, err = strconv.Atoi(db); err != nil {
+			return nil, errors.New("rediscache: invalid redis db")
+		}
+	}
+	for _, option := range options {
+		option(c)
+	}
+	c.pool = make(chan *conn, c.PoolSize)
+	return c, nil
+}
+
+// entryMeta metadata of cached blob, stored as JSON line before the blob bytes
+type entryMeta struct {
+	ContentType string       `json:"type,omitempty"`
+	Stat        *imagor.Stat `json:"stat,omitempty"`
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (*imagor.Blob, error) {
+	reply, err := c.do(ctx, "GET", c.Prefix+key)
+	if err != nil {
+		return nil, err
+	}
+	buf, ok := reply.([]byte)
+	if !ok {
+		return nil, imagor.ErrNotFound
+	}
+	idx := bytes.IndexByte(buf, '\n')
+	if idx < 0 {
+		return nil, imagor.ErrNotFound
+	}
+	var meta entryMeta
+	if err = json.Unmarshal(buf[:idx], &meta); err != nil {
+		return nil, err
+	}
+	blob := imagor.NewBlobFromBytes(buf[idx+1:])
+	if meta.ContentType != "" {
+		blob.SetContentType(meta.ContentType)
+	}
+	blob.Stat = meta.Stat
+	return blob, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, blob *imagor.Blob, ttl time.Duration) error {
+	meta, err := json.Marshal(entryMeta{ContentType: blob.ContentType(), Stat: blob.Stat})
+	if err != nil {
+		return err
+	}
+	reader, _, err := blob.NewReader()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	// value of meta line and blob bytes written into pooled buffer, that is released once sent
+	value := bufpool.Get()
+	defer bufpool.Put(value)
+	value.Write(meta)
+	value.WriteByte('\n')
+	if _, err = value.ReadFrom(reader); err != nil {
+		return err
+	}
+	if ttl > 0 {
+		_, err = c.do(ctx, "SET", c.Prefix+key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	} else {
+		_, err = c.do(ctx, "SET", c.Prefix+key, value)
+	}
+	return err
+}
+
+func (c *RedisCache) Delete(ctx context.Context, key string) error {
+	_, err := c.do(ctx, "DEL", c.Prefix+key)
+	return err
+}
+
+// DeletePrefix deletes all keys with prefix by SCAN, implements imagor.PrefixDeleter
+func (c *RedisCache) DeletePrefix(ctx context.Context, prefix string) error {
+	match := globEscaper.Replace(c.Prefix+prefix) + "*"
+	cursor := "0"
+	for {
+		reply, err := c.do(ctx, "SCAN", cursor, "MATCH", match, "COUNT", "100")
+		if err != nil {
+			return err
+		}
+		items, ok := reply.([]any)
+		if !ok || len(items) != 2 {
+			return errProtocol
+		}
+		next, _ := items[0].([]byte)
+		keys, _ := items[1].([]any)
+		if len(keys) > 0 {
+			args := []any{"DEL"}
+			for _, k := range keys {
+				if b, ok := k.([]byte); ok {
+					args = append(args, b)
+				}
+			}
+			if _, err = c.do(ctx, args...); err != nil {
+				return err
+			}
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			return nil
+		}
+	}
+}
+
+// Close closes pooled connections
+func (c *RedisCache) Close() error {
+	for {
+		select {
+		case cn := <-c.pool:
+			_ = cn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+var globEscaper = strings.NewReplacer(
+	`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// do executes command with pooled connection, which is discarded on network error
+func (c *RedisCache) do(ctx context.Context, args ...any) (any, error) {
+	var cn *conn
+	select {
+	case cn = <-c.pool:
+	default:
+		var err error
+		if cn, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := cn.do(ctx, c.Timeout, args...)
+	var rerr redisError
+	if err != nil && !errors.As(err, &rerr) {
+		_ = cn.Close()
+		return nil, err
+	}
+	select {
+	case c.pool <- cn:
+	default:
+		_ = cn.Close()
+	}
+	return reply, err
+}
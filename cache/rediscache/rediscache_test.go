@@ -0,0 +1,235 @@
+package rediscache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/cshum/imagor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis minimal in-memory Redis server of the commands used by RedisCache
+type fakeRedis struct {
+	net.Listener
+	password string
+	mu       sync.Mutex
+	data     map[string]string
+	expires  map[string]time.Time
+	commands []string
+}
+
+func newFakeRedis(t *testing.T, password string, tlsConfig *tls.Config) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	s := &fakeRedis{Listener: ln, password: password, data: map[string]string{}, expires: map[string]time.Time{}}
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nc)
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+func (s *fakeRedis) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	authed := s.password == ""
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, item := range reply.([]any) {
+			args = append(args, string(item.([]byte)))
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args[0])
+		var res string
+		switch {
+		case args[0] == "AUTH":
+			if authed = args[len(args)-1] == s.password; authed {
+				res = "+OK\r\n"
+			} else {
+				res = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			res = "-NOAUTH Authentication required\r\n"
+		case args[0] == "SELECT":
+			res = "+OK\r\n"
+		case args[0] == "SET":
+			s.data[args[1]] = args[2]
+			delete(s.expires, args[1])
+			if len(args) == 5 {
+				var ms int64
+				_, _ = fmt.Sscan(args[4], &ms)
+				s.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			}
+			res = "+OK\r\n"
+		case args[0] == "GET":
+			v, ok := s.data[args[1]]
+			if exp, has := s.expires[args[1]]; has && time.Now().After(exp) {
+				ok = false
+			}
+			if ok {
+				res = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				res = "$-1\r\n"
+			}
+		case args[0] == "DEL":
+			n := 0
+			for _, k := range args[1:] {
+				if _, ok := s.data[k]; ok {
+					delete(s.data, k)
+					n++
+				}
+			}
+			res = fmt.Sprintf(":%d\r\n", n)
+		case args[0] == "SCAN":
+			pattern := strings.NewReplacer(`\*`, "*").Replace(args[3])
+			var keys []string
+			for k := range s.data {
+				if ok, _ := path.Match(pattern, k); ok {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			res = fmt.Sprintf("*2\r\n$1\r\n0\r\n*%d\r\n", len(keys))
+			for _, k := range keys {
+				res += fmt.Sprintf("$%d\r\n%s\r\n", len(k), k)
+			}
+		default:
+			res = "-ERR unknown command\r\n"
+		}
+		s.mu.Unlock()
+		if _, err = nc.Write([]byte(res)); err != nil {
+			return
+		}
+	}
+}
+
+func TestRedisCache(t *testing.T) {
+	srv := newFakeRedis(t, "s3cret", nil)
+	c, err := New("redis://:s3cret@"+srv.Addr().String()+"/1", WithPrefix("img:"), WithPoolSize(2), WithTimeout(time.Second))
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Equal(t, 1, c.DB)
+	ctx := context.Background()
+
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, imagor.ErrNotFound, err)
+
+	blob := imagor.NewBlobFromBytes([]byte("bar\nbaz"))
+	blob.SetContentType("image/jpeg")
+	blob.Stat = &imagor.Stat{ETag: "abc", Size: 7}
+	require.NoError(t, c.Set(ctx, "foo", blob, 0))
+	require.NoError(t, c.Set(ctx, "prefix/a", imagor.NewBlobFromBytes([]byte("a")), time.Minute))
+	require.NoError(t, c.Set(ctx, "prefix/b", imagor.NewBlobFromBytes([]byte("b")), time.Millisecond))
+	srv.mu.Lock()
+	assert.Contains(t, srv.data, "img:foo")
+	srv.mu.Unlock()
+
+	b, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	buf, _ := b.ReadAll()
+	assert.Equal(t, "bar\nbaz", string(buf))
+	assert.Equal(t, "image/jpeg", b.ContentType())
+	assert.Equal(t, &imagor.Stat{ETag: "abc", Size: 7}, b.Stat)
+
+	time.Sleep(time.Millisecond * 5)
+	_, err = c.Get(ctx, "prefix/b")
+	assert.Equal(t, imagor.ErrNotFound, err)
+	_, err = c.Get(ctx, "prefix/a")
+	assert.NoError(t, err)
+
+	require.NoError(t, c.DeletePrefix(ctx, "prefix/"))
+	_, err = c.Get(ctx, "prefix/a")
+	assert.Equal(t, imagor.ErrNotFound, err)
+
+	require.NoError(t, c.Delete(ctx, "foo"))
+	_, err = c.Get(ctx, "foo")
+	assert.Equal(t, imagor.ErrNotFound, err)
+
+	c, err = New("redis://:wrong@" + srv.Addr().String())
+	require.NoError(t, err)
+	_, err = c.Get(ctx, "foo")
+	assert.EqualError(t, err, "redis: WRONGPASS invalid password")
+
+	_, err = New("http://localhost")
+	assert.Error(t, err)
+	_, err = New("redis://localhost/abc")
+	assert.Error(t, err)
+	c, err = New("redis://localhost")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost:6379", c.Addr)
+}
+
+func TestRedisCacheTLS(t *testing.T) {
+	// certificate of httptest TLS server, valid for 127.0.0.1
+	hs := httptest.NewTLSServer(nil)
+	defer hs.Close()
+	srv := newFakeRedis(t, "s3cret", &tls.Config{Certificates: hs.TLS.Certificates})
+	ctx := context.Background()
+
+	c, err := New("rediss://:s3cret@" + srv.Addr().String())
+	require.NoError(t, err)
+	require.NotNil(t, c.TLSConfig)
+	assert.Equal(t, "127.0.0.1", c.TLSConfig.ServerName)
+	// untrusted certificate rejected
+	assert.Error(t, c.Set(ctx, "foo", imagor.NewBlobFromBytes([]byte("bar")), 0))
+	c.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(hs.Certificate())
+	c, err = New("rediss://:s3cret@"+srv.Addr().String(), WithTLSConfig(&tls.Config{RootCAs: roots}))
+	require.NoError(t, err)
+	defer c.Close()
+	require.NoError(t, c.Set(ctx, "foo", imagor.NewBlobFromBytes([]byte("bar")), 0))
+	b, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	buf, _ := b.ReadAll()
+	assert.Equal(t, "bar", string(buf))
+
+	// plain connection to TLS server fails
+	c, err = New("redis://:s3cret@" + srv.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	assert.Error(t, c.Set(ctx, "foo", imagor.NewBlobFromBytes([]byte("bar")), 0))
+}
+
+func TestWriteArg(t *testing.T) {
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	require.NoError(t, writeHeader(w, '*', 4))
+	require.NoError(t, writeArg(w, "SET"))
+	require.NoError(t, writeArg(w, []byte("foo")))
+	require.NoError(t, writeArg(w, bytes.NewBufferString("bar\r\nbaz")))
+	require.NoError(t, writeArg(w, []byte(nil)))
+	assert.Error(t, writeArg(w, 1))
+	require.NoError(t, w.Flush())
+	assert.Equal(t, "*4\r\n$3\r\nSET\r\n$3\r\nfoo\r\n$8\r\nbar\r\nbaz\r\n$0\r\n\r\n", out.String())
+
+	reply, err := readReply(bufio.NewReader(strings.NewReader("$8\r\nbar\r\nbaz\r\n")))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("bar\r\nbaz"), reply)
+}
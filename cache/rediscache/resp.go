@@ -0,0 +1,171 @@
+package rediscache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// redisError error reply from Redis server
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+var errProtocol = errors.New("redis: protocol error")
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+	w *bufio.Writer
+}
+
+// dial connects to Redis server, over TLS if configured,
+// and authenticates and selects database if configured
+func (c *RedisCache) dial(ctx context.Context) (*conn, error) {
+	var nc net.Conn
+	var err error
+	if c.TLSConfig != nil {
+		d := tls.Dialer{Config: c.TLSConfig}
+		nc, err = d.DialContext(ctx, "tcp", c.Addr)
+	} else {
+		var d net.Dialer
+		nc, err = d.DialContext(ctx, "tcp", c.Addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+	if c.Password != "" {
+		args := []any{"AUTH", c.Password}
+		if c.Username != "" {
+			args = []any{"AUTH", c.Username, c.Password}
+		}
+		if _, err = cn.do(ctx, c.Timeout, args...); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+	}
+	if c.DB > 0 {
+		if _, err = cn.do(ctx, c.Timeout, "SELECT", strconv.Itoa(c.DB)); err != nil {
+			_ = nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// do writes command of string, []byte or *bytes.Buffer args and reads its reply
+func (cn *conn) do(ctx context.Context, timeout time.Duration, args ...any) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := cn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+	if err := writeHeader(cn.w, '*', len(args)); err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		if err := writeArg(cn.w, arg); err != nil {
+			return nil, err
+		}
+	}
+	if err := cn.w.Flush(); err != nil {
+		return nil, err
+	}
+	return readReply(cn.r)
+}
+
+// writeHeader writes RESP type prefix and length line
+func writeHeader(w *bufio.Writer, typ byte, n int) error {
+	var buf [24]byte
+	b := append(buf[:0], typ)
+	b = strconv.AppendInt(b, int64(n), 10)
+	b = append(b, '\r', '\n')
+	_, err := w.Write(b)
+	return err
+}
+
+// writeArg writes bulk string arg without copying []byte and *bytes.Buffer values
+func writeArg(w *bufio.Writer, arg any) (err error) {
+	switch v := arg.(type) {
+	case string:
+		if err = writeHeader(w, '$', len(v)); err == nil {
+			_, err = w.WriteString(v)
+		}
+	case []byte:
+		if err = writeHeader(w, '$', len(v)); err == nil {
+			_, err = w.Write(v)
+		}
+	case *bytes.Buffer:
+		if err = writeHeader(w, '$', v.Len()); err == nil {
+			_, err = w.Write(v.Bytes())
+		}
+	default:
+		return fmt.Errorf("redis: unsupported arg type %T", arg)
+	}
+	if err == nil {
+		_, err = w.WriteString("\r\n")
+	}
+	return
+}
+
+// readReply reads RESP reply as string, int64, []byte, []any or nil, with redisError of error reply
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errProtocol
+	}
+	typ, line := line[0], line[1:len(line)-2]
+	switch typ {
+	case '+':
+		return line, nil
+	case '-':
+		return nil, redisError(line)
+	case ':':
+		return strconv.ParseInt(line, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, errProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, errProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, errProtocol
+	}
+}
@@ -0,0 +1,64 @@
+package tiercache
+
+import (
+	"context"
+	"github.com/cshum/imagor"
+	"time"
+)
+
+// TierCache two-level cache of local cache in front of shared remote cache, implements imagor.Cache
+type TierCache struct {
+	Local    imagor.Cache
+	Remote   imagor.Cache
+	LocalTTL time.Duration
+}
+
+// New creates TierCache with local cache e.g. memory cache, remote cache e.g. Redis cache,
+// and TTL of local cache entries that bounds staleness among instances
+func New(local, remote imagor.Cache, localTTL time.Duration) *TierCache {
+	return &TierCache{
+		Local:    local,
+		Remote:   remote,
+		LocalTTL: localTTL,
+	}
+}
+
+func (c *TierCache) Get(ctx context.Context, key string) (*imagor.Blob, error) {
+	if blob, err := c.Local.Get(ctx, key); err == nil && blob != nil {
+		return blob, nil
+	}
+	blob, err := c.Remote.Get(ctx, key)
+	if err != nil {
+		return blob, err
+	}
+	_ = c.Local.Set(ctx, key, blob, c.localTTL(0))
+	return blob, nil
+}
+
+func (c *TierCache) Set(ctx context.Context, key string, blob *imagor.Blob, ttl time.Duration) error {
+	_ = c.Local.Set(ctx, key, blob, c.localTTL(ttl))
+	return c.Remote.Set(ctx, key, blob, ttl)
+}
+
+func (c *TierCache) Delete(ctx context.Context, key string) error {
+	_ = c.Local.Delete(ctx, key)
+	return c.Remote.Delete(ctx, key)
+}
+
+// DeletePrefix deletes keys with prefix of both caches that implement imagor.PrefixDeleter
+func (c *TierCache) DeletePrefix(ctx context.Context, prefix string) error {
+	if d, ok := c.Local.(imagor.PrefixDeleter); ok {
+		_ = d.DeletePrefix(ctx, prefix)
+	}
+	if d, ok := c.Remote.(imagor.PrefixDeleter); ok {
+		return d.DeletePrefix(ctx, prefix)
+	}
+	return nil
+}
+
+func (c *TierCache) localTTL(ttl time.Duration) time.Duration {
+	if c.LocalTTL > 0 && (ttl <= 0 || ttl > c.LocalTTL) {
+		return c.LocalTTL
+	}
+	return ttl
+}
@@ -0,0 +1,47 @@
+package tiercache
+
+import (
+	"context"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/cache/memorycache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTierCache(t *testing.T) {
+	ctx := context.Background()
+	local := memorycache.New(1000)
+	remote := memorycache.New(1000)
+	c := New(local, remote, time.Millisecond*10)
+
+	_, err := c.Get(ctx, "foo")
+	assert.Equal(t, imagor.ErrNotFound, err)
+
+	require.NoError(t, c.Set(ctx, "foo", imagor.NewBlobFromBytes([]byte("bar")), time.Minute))
+	assert.Equal(t, 1, local.Len())
+	assert.Equal(t, 1, remote.Len())
+
+	// local entry expires before remote
+	time.Sleep(time.Millisecond * 20)
+	_, err = local.Get(ctx, "foo")
+	assert.Equal(t, imagor.ErrNotFound, err)
+
+	// filled from remote
+	require.NoError(t, remote.Set(ctx, "foo", imagor.NewBlobFromBytes([]byte("baz")), 0))
+	blob, err := c.Get(ctx, "foo")
+	require.NoError(t, err)
+	buf, _ := blob.ReadAll()
+	assert.Equal(t, "baz", string(buf))
+	blob, err = local.Get(ctx, "foo")
+	require.NoError(t, err)
+	buf, _ = blob.ReadAll()
+	assert.Equal(t, "baz", string(buf))
+
+	require.NoError(t, c.Set(ctx, "prefix/a", imagor.NewBlobFromBytes([]byte("a")), 0))
+	require.NoError(t, c.DeletePrefix(ctx, "prefix/"))
+	require.NoError(t, c.Delete(ctx, "foo"))
+	assert.Equal(t, 0, local.Len())
+	assert.Equal(t, 0, remote.Len())
+}
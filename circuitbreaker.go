@@ -0,0 +1,70 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// circuitBreaker trips open after consecutive loader failures,
+// failing fast until cool-down elapsed, then allows a single trial request
+type circuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+	now      func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow checks if request should go through
+func (cb *circuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.failures < cb.Threshold {
+		return true
+	}
+	if cb.trial || cb.now().Sub(cb.openedAt) < cb.Cooldown {
+		return false
+	}
+	// half-open: let one trial request through
+	cb.trial = true
+	return true
+}
+
+// Done records result of a request that was allowed through
+func (cb *circuitBreaker) Done(err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.trial = false
+	if !isLoaderFailure(err) {
+		cb.failures = 0
+		return
+	}
+	cb.failures++
+	if cb.failures >= cb.Threshold {
+		cb.openedAt = cb.now()
+	}
+}
+
+// isLoaderFailure determines if error indicates an unhealthy origin,
+// as opposed to client errors such as not found or invalid image
+func isLoaderFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	e := WrapError(err)
+	return e.Code >= http.StatusInternalServerError || e.Timeout()
+}
@@ -0,0 +1,82 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	cb := newCircuitBreaker(2, time.Second)
+	cb.now = func() time.Time { return now }
+
+	assert.True(t, cb.Allow())
+	cb.Done(ErrNotFound)
+	assert.True(t, cb.Allow())
+	cb.Done(context.Canceled)
+	assert.True(t, cb.Allow())
+	cb.Done(errors.New("connection refused"))
+	assert.True(t, cb.Allow())
+	cb.Done(ErrTimeout)
+	assert.False(t, cb.Allow(), "should trip open")
+
+	now = now.Add(time.Second)
+	assert.True(t, cb.Allow(), "should allow trial after cool-down")
+	assert.False(t, cb.Allow(), "should allow one trial only")
+	cb.Done(ErrInternal)
+	assert.False(t, cb.Allow(), "failed trial should reopen")
+
+	now = now.Add(time.Second)
+	assert.True(t, cb.Allow())
+	cb.Done(nil)
+	assert.True(t, cb.Allow(), "succeeded trial should close")
+	assert.True(t, cb.Allow())
+}
+
+func TestWithLoaderCircuitBreaker(t *testing.T) {
+	var calls int64
+	app := New(
+		WithUnsafe(true),
+		WithLoaderCircuitBreaker(3, time.Hour),
+		WithLoaders(
+			loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+				atomic.AddInt64(&calls, 1)
+				return nil, ErrInternal
+			}),
+			loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+				return NewBlobFromBytes([]byte("foo")), nil
+			}),
+		),
+	)
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodGet, "https://example.com/unsafe/foo.jpg", nil))
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "foo", w.Body.String())
+	}
+	assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+
+	app = New(
+		WithUnsafe(true),
+		WithLoaderCircuitBreaker(1, time.Hour),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return nil, ErrInternal
+		})),
+	)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/foo.jpg", nil))
+	assert.Equal(t, 500, w.Code)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/bar.jpg", nil))
+	assert.Equal(t, 503, w.Code)
+	assert.Equal(t, jsonStr(ErrLoaderUnavailable), w.Body.String())
+}
@@ -0,0 +1,45 @@
+package imagor
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ClientIP returns the client IP address of the request.
+// X-Forwarded-For is honored only if the immediate peer is one of the trusted proxies,
+// in which case the right-most address that is not a trusted proxy is returned
+func ClientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	remoteIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		remoteIP = host
+	}
+	if len(trustedProxies) == 0 || !containsIP(trustedProxies, remoteIP) {
+		return remoteIP
+	}
+	addresses := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(addresses) - 1; i >= 0; i-- {
+		address := strings.TrimSpace(addresses[i])
+		if address == "" {
+			continue
+		}
+		if !containsIP(trustedProxies, address) {
+			return address
+		}
+		remoteIP = address
+	}
+	return remoteIP
+}
+
+func containsIP(networks []*net.IPNet, address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package imagor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func mustParseCIDRs(cidrs ...string) (networks []*net.IPNet) {
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := mustParseCIDRs("10.0.0.0/8", "::1/128")
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		trusted    bool
+		expected   string
+	}{
+		{"remote addr", "1.2.3.4:1234", "", true, "1.2.3.4"},
+		{"remote addr without port", "1.2.3.4", "", true, "1.2.3.4"},
+		{"untrusted proxy", "1.2.3.4:1234", "5.6.7.8", true, "1.2.3.4"},
+		{"no trusted proxies", "10.0.0.1:1234", "5.6.7.8", false, "10.0.0.1"},
+		{"trusted proxy", "10.0.0.1:1234", "5.6.7.8", true, "5.6.7.8"},
+		{"trusted proxy chain", "10.0.0.1:1234", "9.9.9.9, 5.6.7.8, 10.0.0.2", true, "5.6.7.8"},
+		{"trusted proxy ipv6", "[::1]:1234", "5.6.7.8", true, "5.6.7.8"},
+		{"all trusted", "10.0.0.1:1234", "10.0.0.3, 10.0.0.2", true, "10.0.0.3"},
+		{"trusted proxy empty xff", "10.0.0.1:1234", "", true, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "https://example.com/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			var proxies []*net.IPNet
+			if tt.trusted {
+				proxies = trusted
+			}
+			assert.Equal(t, tt.expected, ClientIP(r, proxies))
+		})
+	}
+}
@@ -1,20 +1,30 @@
 package main
 
 import (
+	"fmt"
 	"github.com/cshum/imagor/config"
 	"github.com/cshum/imagor/config/awsconfig"
 	"github.com/cshum/imagor/config/gcloudconfig"
+	"github.com/cshum/imagor/config/otelconfig"
 	"github.com/cshum/imagor/config/vipsconfig"
 	"os"
 )
 
 func main() {
-	var server = config.CreateServer(
-		os.Args[1:],
+	var funcs = []config.Func{
 		vipsconfig.WithVips,
 		awsconfig.WithAWS,
 		gcloudconfig.WithGCloud,
-	)
+		otelconfig.WithOTel,
+	}
+	if ok, err := config.RunCommand(os.Args[1:], os.Stdout, funcs...); ok {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	var server = config.CreateServer(os.Args[1:], funcs...)
 	if server != nil {
 		server.Run()
 	}
@@ -0,0 +1,179 @@
+package imagor
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CompressorFunc creates compressing writer of content encoding e.g. gzip, br
+type CompressorFunc func(w io.Writer) io.WriteCloser
+
+// builtinCompressors content encodings available by name for WithCompression
+var builtinCompressors = map[string]CompressorFunc{
+	"gzip": func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	},
+	"deflate": func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	},
+}
+
+// compressionOrder preferred content encodings when equally accepted by client
+var compressionOrder = []string{"br", "zstd", "gzip", "deflate"}
+
+// isCompressible checks if content type is textual that benefits from compression,
+// such as JSON, SVG and error bodies. Binary images are already compressed
+func isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "image/svg+xml",
+		mediaType == "application/json",
+		mediaType == "application/xml",
+		mediaType == "application/javascript",
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
+
+// negotiateEncoding returns content encoding of compressors most preferred by Accept-Encoding header,
+// empty if none acceptable
+func negotiateEncoding(header string, compressors map[string]CompressorFunc) string {
+	if header == "" || len(compressors) == 0 {
+		return ""
+	}
+	var (
+		qs       = map[string]float64{}
+		wildcard = -1.0
+	)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		var q = 1.0
+		if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				q = f
+			}
+		}
+		if coding == "*" {
+			wildcard = q
+		} else if coding != "" {
+			qs[coding] = q
+		}
+	}
+	var (
+		best  string
+		bestQ float64
+	)
+	consider := func(encoding string) {
+		q, ok := qs[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	for _, encoding := range compressionOrder {
+		if _, ok := compressors[encoding]; ok {
+			consider(encoding)
+		}
+	}
+	var others []string
+	for encoding := range compressors {
+		if !isPreferredEncoding(encoding) {
+			others = append(others, encoding)
+		}
+	}
+	sort.Strings(others)
+	for _, encoding := range others {
+		consider(encoding)
+	}
+	return best
+}
+
+func isPreferredEncoding(encoding string) bool {
+	for _, e := range compressionOrder {
+		if e == encoding {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter compresses response of compressible content type by negotiated content encoding,
+// adding Vary: Accept-Encoding regardless of negotiation result
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	compressor  CompressorFunc
+	writer      io.WriteCloser
+	compress    bool
+	wroteHeader bool
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.ResponseWriter.Header()
+	if status != http.StatusPartialContent && status != http.StatusNotModified &&
+		status != http.StatusNoContent && h.Get("Content-Encoding") == "" &&
+		isCompressible(h.Get("Content-Type")) {
+		h.Add("Vary", "Accept-Encoding")
+		if w.compressor != nil {
+			w.compress = true
+			h.Set("Content-Encoding", w.encoding)
+			h.Del("Content-Length")
+			h.Del("Accept-Ranges")
+			if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				// compressed bytes no longer match strong validator
+				h.Set("ETag", "W/"+etag)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *compressWriter) Write(buf []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compress {
+		return w.ResponseWriter.Write(buf)
+	}
+	if w.writer == nil {
+		w.writer = w.compressor(w.ResponseWriter)
+	}
+	return w.writer.Write(buf)
+}
+
+// close flushes compressed content if any written
+func (w *compressWriter) close() {
+	if w.writer != nil {
+		_ = w.writer.Close()
+	}
+}
+
+// newCompressWriter returns compressWriter of content encoding negotiated by request
+func (app *Imagor) newCompressWriter(w http.ResponseWriter, r *http.Request) *compressWriter {
+	cw := &compressWriter{ResponseWriter: w}
+	if encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), app.Compressors); encoding != "" {
+		cw.encoding = encoding
+		cw.compressor = app.Compressors[encoding]
+	}
+	return cw
+}
@@ -0,0 +1,98 @@
+package imagor
+
+import (
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithCompression(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg">` + strings.Repeat(`<rect width="1" height="1"/>`, 100) + `</svg>`
+	app := New(
+		WithUnsafe(true),
+		WithCompression("gzip", "deflate", "foo"),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			switch image {
+			case "foo.svg":
+				blob := NewBlobFromBytes([]byte(svg))
+				blob.SetContentType("image/svg+xml")
+				return blob, nil
+			case "foo.jpg":
+				blob := NewBlobFromBytes([]byte(strings.Repeat("j", 1000)))
+				blob.SetContentType("image/jpeg")
+				return blob, nil
+			}
+			return nil, ErrNotFound
+		})),
+	)
+	assert.Len(t, app.Compressors, 2)
+	doGet := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+path, nil)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		app.ServeHTTP(w, r)
+		return w
+	}
+	gunzip := func(w *httptest.ResponseRecorder) string {
+		reader, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		buf, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(buf)
+	}
+
+	w := doGet("foo.svg", "gzip, deflate, br")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Less(t, w.Body.Len(), len(svg))
+	assert.Equal(t, svg, gunzip(w))
+
+	w = doGet("foo.svg", "gzip;q=0.5, deflate")
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+
+	w = doGet("foo.svg", "")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, svg, w.Body.String())
+
+	w = doGet("foo.jpg", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Header().Get("Vary"))
+	assert.Equal(t, "1000", w.Header().Get("Content-Length"))
+
+	w = doGet("missing.jpg", "*")
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, gunzip(w), "not found")
+
+	w = doGet("meta/foo.svg", "identity")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	compressors := map[string]CompressorFunc{"gzip": nil, "deflate": nil, "br": nil, "x-foo": nil}
+	for header, expected := range map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"gzip":                      "gzip",
+		"GZIP":                      "gzip",
+		"deflate, gzip":             "gzip",
+		"gzip, deflate, br":         "br",
+		"br;q=0.8, gzip":            "gzip",
+		"br;q=0, *":                 "gzip",
+		"*;q=0":                     "",
+		"x-foo, compress":           "x-foo",
+		"gzip;q=0.5, deflate;q=0.6": "deflate",
+	} {
+		assert.Equal(t, expected, negotiateEncoding(header, compressors), header)
+	}
+}
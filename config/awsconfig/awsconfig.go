@@ -6,6 +6,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/invalidator/cloudfront"
+	"github.com/cshum/imagor/queue/sqsqueue"
 	"github.com/cshum/imagor/storage/s3storage"
 	"go.uber.org/zap"
 )
@@ -82,10 +84,16 @@ func WithAWS(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Option {
 		s3ResultStorageExpiration = fs.Duration("s3-result-storage-expiration", 0,
 			"S3 Result Storage expiration duration e.g. 24h. Default no expiration")
 
+		cloudFrontDistributionID = fs.String("aws-cloudfront-distribution-id", "",
+			"AWS CloudFront distribution ID that invalidation is created on DELETE /purge. Enable CloudFront invalidation only if this value present")
+		sqsQueueURL = fs.String("aws-sqs-queue-url", "",
+			"AWS SQS queue URL of warm jobs or S3 event notifications consumed by queue workers. Enable SQS queue worker only if this value present")
+
 		_, _ = cb()
 	)
 	return func(app *imagor.Imagor) {
-		if *s3StorageBucket == "" && *s3LoaderBucket == "" && *s3ResultStorageBucket == "" {
+		if *s3StorageBucket == "" && *s3LoaderBucket == "" && *s3ResultStorageBucket == "" &&
+			*cloudFrontDistributionID == "" && *sqsQueueURL == "" {
 			return
 		}
 		var loaderSess, storageSess, resultStorageSess *session.Session
@@ -164,6 +172,21 @@ func WithAWS(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Option {
 				),
 			)
 		}
+		if *cloudFrontDistributionID != "" {
+			// CloudFront is of global endpoint regardless of S3 endpoint
+			app.Invalidators = append(app.Invalidators,
+				cloudfront.New(sess.Copy(&aws.Config{Endpoint: aws.String("")}), *cloudFrontDistributionID),
+			)
+		}
+		if *sqsQueueURL != "" {
+			// S3 event notification keys are mapped to images the same way as S3 Loader
+			app.JobQueues = append(app.JobQueues,
+				sqsqueue.New(sess.Copy(&aws.Config{Endpoint: aws.String("")}), *sqsQueueURL,
+					sqsqueue.WithBaseDir(*s3LoaderBaseDir),
+					sqsqueue.WithPathPrefix(*s3LoaderPathPrefix),
+				),
+			)
+		}
 		if resultStorageSess != nil && *s3ResultStorageBucket != "" {
 			// activate S3 ResultStorage only if bucket config presents
 			app.ResultStorages = append(app.ResultStorages,
@@ -1,10 +1,14 @@
 package awsconfig
 
 import (
+	awscloudfront "github.com/aws/aws-sdk-go/service/cloudfront"
 	"github.com/cshum/imagor"
 	"github.com/cshum/imagor/config"
+	"github.com/cshum/imagor/invalidator/cloudfront"
+	"github.com/cshum/imagor/queue/sqsqueue"
 	"github.com/cshum/imagor/storage/s3storage"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"testing"
 )
 
@@ -120,3 +124,39 @@ func TestS3SessionOverride(t *testing.T) {
 	assert.Equal(t, "/bcda/", resultStorage.PathPrefix)
 	assert.Equal(t, "!", resultStorage.SafeChars)
 }
+
+func TestCloudFront(t *testing.T) {
+	srv := config.CreateServer([]string{
+		"-aws-region", "asdf",
+		"-aws-access-key-id", "asdf",
+		"-aws-secret-access-key", "asdf",
+		"-s3-endpoint", "asdfasdf",
+		"-aws-cloudfront-distribution-id", "DIST1",
+	}, WithAWS)
+	app := srv.App.(*imagor.Imagor)
+	assert.Empty(t, app.Storages)
+	require.Len(t, app.Invalidators, 1)
+	invalidator := app.Invalidators[0].(*cloudfront.CloudFront)
+	assert.Equal(t, "DIST1", invalidator.DistributionID)
+	assert.NotEqual(t, "asdfasdf", invalidator.CloudFront.(*awscloudfront.CloudFront).Endpoint)
+}
+
+func TestSQSQueue(t *testing.T) {
+	srv := config.CreateServer([]string{
+		"-aws-region", "asdf",
+		"-aws-access-key-id", "asdf",
+		"-aws-secret-access-key", "asdf",
+		"-s3-loader-base-dir", "foo",
+		"-s3-loader-path-prefix", "abcd",
+		"-aws-sqs-queue-url", "https://sqs.asdf.amazonaws.com/123/jobs",
+		"-imagor-worker-concurrency", "4",
+	}, WithAWS)
+	app := srv.App.(*imagor.Imagor)
+	assert.Empty(t, app.Storages)
+	assert.Equal(t, 4, app.WorkerConcurrency)
+	require.Len(t, app.JobQueues, 1)
+	queue := app.JobQueues[0].(*sqsqueue.SQSQueue)
+	assert.Equal(t, "https://sqs.asdf.amazonaws.com/123/jobs", queue.QueueURL)
+	assert.Equal(t, "/foo/", queue.BaseDir)
+	assert.Equal(t, "/abcd/", queue.PathPrefix)
+}
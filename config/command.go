@@ -0,0 +1,140 @@
+package config
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/cshum/imagor/server"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// RunCommand runs imagor subcommand sign, verify, healthcheck or process of args, with imagor configured
+// by the rest of args, env and config file same as CreateServer.
+// Returns false if args is not a subcommand
+func RunCommand(args []string, w io.Writer, funcs ...Func) (ok bool, err error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	var run func(app *imagor.Imagor, path string) (string, error)
+	switch args[0] {
+	case "process":
+		return true, runProcess(args[1:], w, funcs...)
+	case "sign":
+		run = signPath
+	case "verify":
+		run = verifyPath
+	case "healthcheck":
+	default:
+		return false, nil
+	}
+	fs := flag.NewFlagSet("imagor "+args[0], flag.ExitOnError)
+	srv := createServer(fs, args[1:], funcs...)
+	if srv == nil {
+		return true, nil
+	}
+	if run == nil {
+		if err = healthcheck(srv); err != nil {
+			return true, err
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+		return true, nil
+	}
+	app := srv.App.(*imagor.Imagor)
+	if fs.NArg() == 0 {
+		return true, fmt.Errorf("usage: imagor %s [flags] <path>", args[0])
+	}
+	if f := fs.Lookup("imagor-secret"); f == nil || f.Value.String() == "" {
+		return true, errors.New("imagor-secret is required")
+	}
+	for _, path := range fs.Args() {
+		out, err := run(app, path)
+		if err != nil {
+			return true, fmt.Errorf("%s: %w", path, err)
+		}
+		_, _ = fmt.Fprintln(w, out)
+	}
+	return true, nil
+}
+
+// signPath returns signed URL path of imagor path or params JSON,
+// re-signing path that is unsafe or already signed
+func signPath(app *imagor.Imagor, path string) (string, error) {
+	var p imagorpath.Params
+	if strings.HasPrefix(strings.TrimSpace(path), "{") {
+		if err := json.Unmarshal([]byte(path), &p); err != nil {
+			return "", err
+		}
+		p.Path = imagorpath.GeneratePath(p)
+	} else {
+		p = imagorpath.Parse(trimPathPrefix(app, path))
+	}
+	if p.Image == "" {
+		return "", imagor.ErrInvalid
+	}
+	prefix := app.PathPrefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	return prefix + app.Signer.Sign(p.Path) + "/" + p.Path, nil
+}
+
+// verifyPath verifies signature of signed imagor path
+func verifyPath(app *imagor.Imagor, path string) (string, error) {
+	p := imagorpath.Parse(trimPathPrefix(app, path))
+	if !p.Verify(app.Signer) {
+		return "", imagor.ErrSignatureMismatch
+	}
+	return "ok", nil
+}
+
+// healthcheck requests readiness endpoint of the server running locally
+// by the same address, port, socket and TLS config
+func healthcheck(srv *server.Server) error {
+	var (
+		transport = &http.Transport{}
+		scheme    = "http"
+		host      = srv.Address
+	)
+	if srv.CertFile != "" && srv.KeyFile != "" {
+		// local certificate may not be valid for the loopback address
+		scheme = "https"
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if srv.Socket != "" {
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", srv.Socket)
+		}
+		host = "localhost"
+	} else if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+	client := &http.Client{Transport: transport, Timeout: srv.HealthTimeout}
+	u := scheme + "://" + net.JoinHostPort(host, strconv.Itoa(srv.Port)) + srv.PathPrefix + "/readyz"
+	resp, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s %s", u, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func trimPathPrefix(app *imagor.Imagor, path string) string {
+	if app.PathPrefix != "" {
+		path = strings.TrimPrefix("/"+strings.TrimPrefix(path, "/"), app.PathPrefix)
+	}
+	return strings.TrimPrefix(path, "/")
+}
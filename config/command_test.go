@@ -0,0 +1,97 @@
+package config
+
+import (
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	var signer = imagorpath.NewDefaultSigner("1234")
+	var path = "fit-in/100x100/filters:quality(80)/foo.jpg"
+	var signed = signer.Sign(path) + "/" + path
+
+	t.Run("not command", func(t *testing.T) {
+		ok, err := RunCommand([]string{"-imagor-secret", "1234"}, &strings.Builder{})
+		assert.False(t, ok)
+		assert.NoError(t, err)
+		ok, err = RunCommand(nil, &strings.Builder{})
+		assert.False(t, ok)
+		assert.NoError(t, err)
+	})
+	t.Run("sign", func(t *testing.T) {
+		var w strings.Builder
+		ok, err := RunCommand([]string{
+			"sign", "-imagor-secret", "1234", path, "/unsafe/" + path, "/" + imagorpath.NewDefaultSigner("4321").Sign(path) + "/" + path,
+			`{"fit_in":true,"width":100,"height":100,"filters":[{"name":"quality","args":"80"}],"image":"foo.jpg"}`,
+		}, &w)
+		assert.True(t, ok)
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("/"+signed+"\n", 4), w.String())
+	})
+	t.Run("sign with path prefix", func(t *testing.T) {
+		var w strings.Builder
+		ok, err := RunCommand([]string{
+			"sign", "-imagor-secret", "1234", "-imagor-path-prefix", "img", "/img/unsafe/" + path,
+		}, &w)
+		assert.True(t, ok)
+		assert.NoError(t, err)
+		assert.Equal(t, "/img/"+signed+"\n", w.String())
+	})
+	t.Run("sign errors", func(t *testing.T) {
+		ok, err := RunCommand([]string{"sign", path}, &strings.Builder{})
+		assert.True(t, ok)
+		assert.Error(t, err)
+		_, err = RunCommand([]string{"sign", "-imagor-secret", "1234"}, &strings.Builder{})
+		assert.Error(t, err)
+		_, err = RunCommand([]string{"sign", "-imagor-secret", "1234", "fit-in/100x100/"}, &strings.Builder{})
+		assert.ErrorIs(t, err, imagor.ErrInvalid)
+	})
+	t.Run("verify", func(t *testing.T) {
+		var w strings.Builder
+		ok, err := RunCommand([]string{"verify", "-imagor-secret", "1234", "/" + signed}, &w)
+		assert.True(t, ok)
+		assert.NoError(t, err)
+		assert.Equal(t, "ok\n", w.String())
+
+		_, err = RunCommand([]string{"verify", "-imagor-secret", "4321", "/" + signed}, &strings.Builder{})
+		assert.ErrorIs(t, err, imagor.ErrSignatureMismatch)
+		_, err = RunCommand([]string{"verify", "-imagor-secret", "1234", "unsafe/" + path}, &strings.Builder{})
+		assert.ErrorIs(t, err, imagor.ErrSignatureMismatch)
+	})
+	t.Run("healthcheck", func(t *testing.T) {
+		var status = http.StatusOK
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/img/readyz" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(status)
+		}))
+		defer ts.Close()
+		u, err := url.Parse(ts.URL)
+		require.NoError(t, err)
+		var args = []string{"healthcheck", "-port", u.Port(), "-server-path-prefix", "/img"}
+
+		var w strings.Builder
+		ok, err := RunCommand(args, &w)
+		assert.True(t, ok)
+		assert.NoError(t, err)
+		assert.Equal(t, "ok\n", w.String())
+
+		status = http.StatusServiceUnavailable
+		ok, err = RunCommand(args, &strings.Builder{})
+		assert.True(t, ok)
+		assert.ErrorContains(t, err, "503")
+
+		ts.Close()
+		_, err = RunCommand(args, &strings.Builder{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/cshum/imagor"
+	"gopkg.in/yaml.v3"
+	"os"
+	"strings"
+)
+
+// componentConfig component entry of components file, by registered name and options,
+// or name only as string
+type componentConfig struct {
+	Type    string         `yaml:"type"`
+	Options map[string]any `yaml:"options"`
+}
+
+func (c *componentConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&c.Type)
+	}
+	type plain componentConfig
+	return node.Decode((*plain)(c))
+}
+
+// componentsConfig components file of loaders, storages, result storages and processors
+type componentsConfig struct {
+	Loaders        []componentConfig `yaml:"loaders"`
+	Storages       []componentConfig `yaml:"storages"`
+	ResultStorages []componentConfig `yaml:"result_storages"`
+	Processors     []componentConfig `yaml:"processors"`
+}
+
+// readComponents reads YAML or JSON components file, returns option of components
+// created by registered factories in order of entries
+func readComponents(file string) (imagor.Option, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var c componentsConfig
+	dec := yaml.NewDecoder(bytes.NewReader(buf))
+	dec.KnownFields(true)
+	if err = dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	loaders, err := newComponents[imagor.Loader](c.Loaders, "loaders", "loader")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	storages, err := newComponents[imagor.Storage](c.Storages, "storages", "storage")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	resultStorages, err := newComponents[imagor.Storage](c.ResultStorages, "result_storages", "storage")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	processors, err := newComponents[imagor.Processor](c.Processors, "processors", "processor")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return func(app *imagor.Imagor) {
+		imagor.WithLoaders(loaders...)(app)
+		imagor.WithStorages(storages...)(app)
+		imagor.WithResultStorages(resultStorages...)(app)
+		imagor.WithProcessors(processors...)(app)
+	}, nil
+}
+
+// newComponents creates components of entries by registered factories, that must implement T of the role
+func newComponents[T any](entries []componentConfig, key, role string) (components []T, err error) {
+	for i, entry := range entries {
+		var options = imagor.ComponentOptions{}
+		for name, value := range entry.Options {
+			if values, ok := value.([]any); ok {
+				// list as csv
+				var strs = make([]string, len(values))
+				for j, v := range values {
+					strs[j] = toString(v)
+				}
+				options[name] = strings.Join(strs, ",")
+			} else {
+				options[name] = toString(value)
+			}
+		}
+		component, err := imagor.NewComponent(entry.Type, options)
+		if err != nil {
+			return nil, fmt.Errorf("%s %d: %w", key, i, err)
+		}
+		c, ok := component.(T)
+		if !ok {
+			return nil, fmt.Errorf("%s %d: %s is not a %s", key, i, entry.Type, role)
+		}
+		components = append(components, c)
+	}
+	return
+}
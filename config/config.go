@@ -2,16 +2,31 @@ package config
 
 import (
 	"crypto/sha1"
-	"crypto/sha256"
-	"crypto/sha512"
 	"flag"
 	"fmt"
 	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/auditlog"
+	"github.com/cshum/imagor/cache/diskcache"
+	"github.com/cshum/imagor/cache/memorycache"
+	"github.com/cshum/imagor/cache/rediscache"
+	"github.com/cshum/imagor/cache/tiercache"
+	"github.com/cshum/imagor/grpcserver"
+	"github.com/cshum/imagor/hook/exprhook"
 	"github.com/cshum/imagor/imagorpath"
+	"github.com/cshum/imagor/instrumented"
+	"github.com/cshum/imagor/invalidator/cloudflare"
+	"github.com/cshum/imagor/invalidator/fastly"
+	"github.com/cshum/imagor/sentry"
 	"github.com/cshum/imagor/server"
+	"github.com/cshum/imagor/webhook"
 	"github.com/peterbourgon/ff/v3"
+	"github.com/rs/cors"
 	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -27,54 +42,390 @@ func NewImagor(
 	var (
 		imagorSecret = fs.String("imagor-secret", "",
 			"Secret key for signing imagor URL")
+		imagorSecretKeyID = fs.String("imagor-secret-key-id", "",
+			"Key ID of imagor secret embedded in URL signature in format of <id>_<signature>, for identifying secret during rotation")
+		imagorPreviousSecrets = fs.String("imagor-previous-secrets", "",
+			"Previous secret keys still accepted for URL signature during secret rotation, by csv of secret or id:secret with key ID")
+		imagorEncryptionKey = fs.String("imagor-encryption-key", "",
+			"Secret key for imagor encrypted URL /enc/{token}. Enable encrypted URL only if this value present")
+		imagorThumborLegacyURLs = fs.Bool("imagor-thumbor-legacy-urls", false,
+			"Accept thumbor legacy encrypted URL /{token}/{image} i.e. ALLOW_OLD_URLS of thumbor, using imagor secret as security key")
 		imagorUnsafe = fs.Bool("imagor-unsafe", false,
 			"Unsafe imagor that does not require URL signature. Prone to URL tampering")
 		imagorAutoWebP = fs.Bool("imagor-auto-webp", false,
 			"Output WebP format automatically if browser supports")
 		imagorAutoAVIF = fs.Bool("imagor-auto-avif", false,
 			"Output AVIF format automatically if browser supports (experimental)")
+		imagorDPRClientHints = fs.Bool("imagor-dpr-client-hints", false,
+			"Scale image dimensions by Sec-CH-DPR or DPR client hint of browser")
+		imagorSaveDataQuality = fs.Int("imagor-save-data-quality", 0,
+			"Default output quality for clients with Save-Data: on or slow ECT client hint, also picking AVIF or WebP by Accept header. Disabled if 0")
+		imagorMaxAutoWidth = fs.Int("imagor-max-auto-width", 0,
+			"Resize width of image URL with auto_width() filter by Sec-CH-Width or Width client hint, capped by the maximum width. Disabled if 0")
 		imagorRequestTimeout = fs.Duration("imagor-request-timeout",
 			time.Second*30, "Timeout for performing imagor request")
 		imagorLoadTimeout = fs.Duration("imagor-load-timeout",
-			0, "Timeout for imagor Loader request, should be smaller than imagor-request-timeout")
+			0, "Timeout for imagor Loader request, should be smaller than imagor-request-timeout. Defaults to imagor-request-timeout")
 		imagorSaveTimeout = fs.Duration("imagor-save-timeout",
-			0, "Timeout for saving image to imagor Storage")
+			0, "Timeout for saving image to imagor Storage. Defaults to imagor-request-timeout")
 		imagorProcessTimeout = fs.Duration("imagor-process-timeout",
-			0, "Timeout for image processing")
+			0, "Timeout for image processing. Defaults to imagor-request-timeout")
 		imagorBasePathRedirect = fs.String("imagor-base-path-redirect", "",
 			"URL to redirect for imagor / base path e.g. https://www.google.com")
+		imagorFallbackImage = fs.String("imagor-fallback-image", "",
+			"imagor fallback image key to be served when image load or process failed e.g. placeholder.jpg")
+		imagorFallbackStatusCode = fs.Int("imagor-fallback-status-code", 0,
+			"imagor HTTP status code for fallback image response. Default the status code of the error")
+		imagorPathPrefix = fs.String("imagor-path-prefix", "",
+			"imagor endpoint path prefix to be stripped before URL signature verification e.g. /img/")
 		imagorBaseParams = fs.String("imagor-base-params", "",
 			"imagor endpoint base params that applies to all resulting images e.g. fitlers:watermark(example.jpg)")
 		imagorProcessConcurrency = fs.Int64("imagor-process-concurrency",
 			-1, "Maximum number of image process to be executed simultaneously. Requests that exceed this limit are put in the queue. Set -1 for no limit")
 		imagorProcessQueueSize = fs.Int64("imagor-process-queue-size",
 			-1, "Maximum number of image process that can be put in the queue. Requests that exceed this limit are rejected with HTTP status 429. Set -1 for no limit")
+		imagorProcessQueueTimeout = fs.Duration("imagor-process-queue-timeout",
+			0, "Maximum duration of image process waiting in the queue. Requests that exceed this limit are rejected with HTTP status 429")
+		imagorProcessMemoryLimitAuto = fs.Bool("imagor-process-memory-limit-auto", false,
+			"Limit estimated memory of image process to 75% of the container memory limit detected by cgroup, if -imagor-process-memory-limit not set")
+		imagorLoaderBreakerThreshold = fs.Int("imagor-loader-circuit-breaker-threshold",
+			0, "Number of consecutive loader failures before skipping the loader until cool-down elapsed. 0 means disabled")
+		imagorLoaderBreakerCooldown = fs.Duration("imagor-loader-circuit-breaker-cooldown",
+			time.Second*30, "Duration of a tripped loader being skipped before retrying")
+		imagorHealthCheckInterval = fs.Duration("imagor-health-check-interval",
+			0, "Interval of background health checks of loaders, storages and processors, with status logged and exposed by expvar. 0 means disabled")
+		imagorInstrument = fs.Bool("imagor-instrument", false,
+			"Record latency, errors and payload bytes of each loader, storage and processor, exposed by expvar for comparing components")
+		imagorDebugDir = fs.String("imagor-debug-dir", "",
+			"Directory to dump loaded source, processor stages and output of requests in debug mode or with debug() filter of signed URL, by request ID. Disabled if empty")
+		imagorPresignRedirect = fs.Duration("imagor-presign-redirect", 0,
+			"Redirect requests without transformation to presigned URL of S3 or Google Cloud Storage source expiring in duration e.g. 5m. 0 means disabled")
 		imagorCacheHeaderTTL = fs.Duration("imagor-cache-header-ttl",
 			time.Hour*24*7, "imagor HTTP Cache-Control header TTL for successful image response")
 		imagorCacheHeaderSWR = fs.Duration("imagor-cache-header-swr",
 			time.Hour*24, "imagor HTTP Cache-Control header stale-while-revalidate for successful image response")
 		imagorCacheHeaderNoCache = fs.Bool("imagor-cache-header-no-cache",
 			false, "imagor HTTP Cache-Control header no-cache for successful image response")
+		imagorResultCacheTTL = fs.Duration("imagor-result-cache-ttl", 0,
+			"imagor in-memory cache TTL for processed result e.g. 1h. Requires imagor-cache-size")
+		imagorErrorCacheTTL = fs.Duration("imagor-error-cache-ttl", 0,
+			"imagor cache TTL for source image load failures of not found or origin errors e.g. 30s. Requires imagor-cache-size or redis-cache-url")
+		imagorCacheDiskDir = fs.String("imagor-cache-disk-dir", "",
+			"Directory of on-disk cache that entries evicted from in-memory cache spill to, kept across restarts. Requires imagor-cache-size")
+		imagorSourceCacheTTL = fs.Duration("imagor-source-cache-ttl", time.Hour,
+			"imagor source cache TTL for loaded source images e.g. 10m. Requires imagor-source-cache-size")
+		redisCacheURL = fs.String("redis-cache-url", "",
+			"Redis URL of cache shared among imagor instances e.g. redis://:password@localhost:6379/0, or rediss:// for TLS. Two-level cache with in-memory cache if imagor-cache-size also set")
+		redisCachePrefix = fs.String("redis-cache-prefix", "imagor:",
+			"Key prefix of Redis cache")
+		redisCacheLocalTTL = fs.Duration("redis-cache-local-ttl", time.Minute,
+			"TTL of in-memory cache entries in front of Redis cache, that bounds staleness among instances")
+		imagorWebhookURL = fs.String("imagor-webhook-url", "",
+			"imagor webhook URL that processing events are posted to as JSON")
+		imagorWebhookSecret = fs.String("imagor-webhook-secret", "",
+			"imagor webhook secret for HMAC-SHA256 signature of timestamp and request body in X-Imagor-Signature header")
+		imagorWebhookEvents = fs.String("imagor-webhook-events", "",
+			"imagor webhook events in csv: processed, load_failed, save_failed. All events if empty")
+		imagorAuditLog = fs.String("imagor-audit-log", "",
+			"File path of append-only audit log that processing events are written to as JSON lines, with client IP, API key, source, params and result size. Audit log is disabled if empty")
+		imagorAuditLogEvents = fs.String("imagor-audit-log-events", "processed",
+			"imagor audit log events in csv: processed, load_failed, save_failed. All events if empty")
+		imagorAuditLogMaxBackups = fs.Int("imagor-audit-log-max-backups", 0,
+			"Number of rotated audit log files kept. All kept if 0")
+		cloudflareZoneID = fs.String("cloudflare-zone-id", "",
+			"Cloudflare zone ID that cache is purged on DELETE /purge. Enable Cloudflare invalidation only if this value present")
+		cloudflareAPIToken = fs.String("cloudflare-api-token", "",
+			"Cloudflare API token with cache purge permission")
+		cloudflareBaseURL = fs.String("cloudflare-base-url", "",
+			"Public base URL of imagor served by Cloudflare e.g. https://img.example.com")
+		fastlyAPIKey = fs.String("fastly-api-key", "",
+			"Fastly API key that cache is purged with on DELETE /purge. Enable Fastly invalidation only if this value present")
+		fastlyServiceID = fs.String("fastly-service-id", "",
+			"Fastly service ID that is purged all on prefix purge, as Fastly does not support wildcard purge")
+		fastlyBaseURL = fs.String("fastly-base-url", "",
+			"Public base URL of imagor served by Fastly e.g. https://img.example.com")
+		imagorSentryDSN = fs.String("imagor-sentry-dsn", "",
+			"Sentry DSN that imagor internal errors of load, process and save are reported to")
+		imagorSentryEnvironment = fs.String("imagor-sentry-environment", "",
+			"Sentry environment of reported errors")
 		imagorModifiedTimeCheck = fs.Bool("imagor-modified-time-check", false,
 			"Check modified time of result image against the source image. This eliminates stale result but require more lookups")
 		imagorDisableErrorBody       = fs.Bool("imagor-disable-error-body", false, "imagor disable response body on error")
+		imagorDiagnosticHeaders      = fs.Bool("imagor-diagnostic-headers", false, "imagor response headers of X-Imagor-Cache, X-Imagor-Processor and Server-Timing of load, process and save durations")
 		imagorDisableParamsEndpoint  = fs.Bool("imagor-disable-params-endpoint", false, "imagor disable /params endpoint")
-		imagorSignerType             = fs.String("imagor-signer-type", "sha1", "imagor URL signature hasher type: sha1, sha256, sha512")
-		imagorSignerTruncate         = fs.Int("imagor-signer-truncate", 0, "imagor URL signature truncate at length")
+		imagorUploadSecret           = fs.String("imagor-upload-secret", "", "Secret for bearer token authorization of PUT /upload endpoint. Upload is disabled if empty")
+		imagorAPIKeyRequired         = fs.Bool("imagor-api-key-required", false, "Require API key on top of URL signature for the image endpoint, responds 401 if missing")
+		imagorRateLimit              = fs.Float64("imagor-rate-limit", 0, "Rate limit of requests per second per client IP, responds 429 if exceeded. No limit if 0")
+		imagorRateLimitBurst         = fs.Int("imagor-rate-limit-burst", 0, "Rate limit burst size per client IP (default rate limit rounded up)")
+		imagorAllowedSizes           = fs.String("imagor-allowed-sizes", "", "Restrict image URL dimensions to the sizes by csv if set e.g. 100x100,200x0 where 0 means auto")
+		imagorAllowedFilters         = fs.String("imagor-allowed-filters", "", "Restrict image URL to the filters by csv if set e.g. format,quality,fill")
+		imagorDeniedFilters          = fs.String("imagor-denied-filters", "", "Reject image URL with any of the filters by csv e.g. label,watermark")
+		imagorDeniedFeatures         = fs.String("imagor-denied-features", "", "Reject image URL with any of the features by csv: meta, trim, crop, fit-in, stretch, padding, flip, smart")
+		imagorAllowedReferers        = fs.String("imagor-allowed-referers", "", "Restrict image requests to Referer or Origin of the hosts by csv with glob pattern if set e.g. example.com,*.example.com, responds 403 otherwise")
+		imagorDenyEmptyReferer       = fs.Bool("imagor-deny-empty-referer", false, "Reject image requests without Referer and Origin header if allowed referers are set")
+		imagorComponentsFile         = fs.String("imagor-components-file", "", "YAML or JSON file of loaders, storages, result storages and processors created by registered component name with options, in addition to the configured ones")
+		imagorParamsHook             = fs.String("imagor-params-hook", "", "expr-lang expression that rewrites params server-side before processing, evaluated with params and request, resulting in map of params fields to set, or false to reject the request")
+		imagorParamsHookFile         = fs.String("imagor-params-hook-file", "", "File of imagor-params-hook expression, applied after imagor-params-hook if both set")
+		imagorTenantsFile            = fs.String("imagor-tenants-file", "", "JSON file of tenant configs matched by hosts or path prefix, with separate secret, allowed sources, storage prefix and base params per tenant")
+		imagorMaxSourcePixels        = fs.Int64("imagor-max-source-pixels", 0, "Maximum pixel count width x height x frames of source image, checked by image headers before decode. Source of which dimensions cannot be read e.g. SVG is rejected. No limit if 0")
+		imagorValidateSource         = fs.Bool("imagor-validate-source", false, "Validate loaded source is an image before processing, otherwise responds 422")
+		imagorSourcePassthroughTypes = fs.String("imagor-source-passthrough-types", "", "Content types allowed by source validation in addition to images by csv e.g. image/svg+xml,application/pdf")
+		imagorUnsupportedSource      = fs.String("imagor-unsupported-source", "", "Policy of source not supported by any processor e.g. videos, fonts: passthrough responds source untouched, reject responds 415. Default responds 406")
+		imagorBatchConcurrency       = fs.Int("imagor-batch-concurrency", 0, "Number of concurrent image processes per POST /batch request. Batch is disabled if 0")
+		imagorPurgeSecret            = fs.String("imagor-purge-secret", "", "Secret for bearer token authorization of DELETE /purge endpoint. Purge is disabled if empty")
+		imagorPurgeSource            = fs.Bool("imagor-purge-source", false, "Purge also deletes source images from storages. Not to be enabled if storages keep the originals e.g. uploads")
+		imagorWarmSecret             = fs.String("imagor-warm-secret", "", "Secret for bearer token authorization of POST /warm endpoint. Warm-up is disabled if empty")
+		imagorSrcsetSecret           = fs.String("imagor-srcset-secret", "", "Secret for bearer token authorization of POST /srcset endpoint. Srcset is disabled if empty")
+		imagorSrcsetWidths           = fs.String("imagor-srcset-widths", "320,640,960,1280,1920", "Default widths of POST /srcset URLs, separated by comma")
+		imagorCompression            = fs.String("imagor-compression", "",
+			"Content encodings for compression of JSON, SVG and error responses negotiated by Accept-Encoding, in csv of gzip, deflate e.g. gzip,deflate. Compression is disabled if empty")
+		imagorStatsSecret            = fs.String("imagor-stats-secret", "", "Secret for bearer token authorization of GET /stats runtime statistics endpoint. Stats is disabled if empty")
+		imagorPresets                = fs.String("imagor-presets", "", "Named imagor params presets for warm-up sizes, in format of name=params separated by semicolon e.g. thumb=fit-in/100x100;cover=1200x630/smart")
+		imagorWorkerConcurrency      = fs.Int("imagor-worker-concurrency", 1, "Number of queue workers consuming warm jobs of each job queue e.g. -aws-sqs-queue-url")
+		imagorWatchWarm              = fs.Bool("imagor-watch-warm", false, "Generate all presets of images added or modified on watched loaders e.g. -file-loader-watch-interval into result storages by queue workers")
+		imagorAsyncTimeout           = fs.Duration("imagor-async-timeout", 0, "Timeout of async processing job requested by async=1 query or Imagor-Async header. Async is disabled if 0")
+		imagorAsyncJobTTL            = fs.Duration("imagor-async-job-ttl", time.Hour, "Duration of finished async job status being kept for GET /jobs/<id>")
+		imagorAsyncMaxPending        = fs.Int("imagor-async-max-pending", 1000, "Maximum number of pending async jobs, responds 429 if exceeded")
+		imagorSignerType             = fs.String("imagor-signer-type", "sha1", "imagor URL signature hasher type: sha1, sha256, sha512, or thumbor for thumbor HMAC method that also accepts unpadded signature")
+		imagorSignerTruncate         = fs.Int("imagor-signer-truncate", 0, "imagor URL signature truncate at length, minimum 8 if set")
 		imagorStoragePathStyle       = fs.String("imagor-storage-path-style", "original", "imagor storage path style: original, digest")
 		imagorResultStoragePathStyle = fs.String("imagor-result-storage-path-style", "original", "imagor result storage path style: original, digest, suffix")
 
+		imagorUnsafeAllowedNetworks []*net.IPNet
+		imagorTrustedProxies        []*net.IPNet
+		imagorResponseHeaders       http.Header
+		imagorAPIKeys               map[string]string
+		imagorProcessorRoutes       map[string]string
+		imagorCacheSize             int64
+		imagorSourceCacheSize       int64
+		imagorCacheDiskSize         int64
+		imagorMaxSourceSize         int64
+		imagorUploadMaxSize         int64
+		imagorProcessMemoryLimit    int64
+		imagorAuditLogMaxSize       int64
+	)
+	fs.Var((*MapFlag)(&imagorAPIKeys), "imagor-api-keys",
+		"Named API keys accepted by Authorization bearer token or api_key query in place of URL signature, in format of name=key by csv e.g. backend=k3y1,worker=k3y2")
+	fs.Var((*MapFlag)(&imagorProcessorRoutes), "imagor-processor-routes",
+		"Route sources by content type to processor by type name instead of trying processors in order, in format of content-type=processor by csv e.g. image/svg+xml=svg.Rasterizer,image/*=vips.Processor. Can be repeated")
+	fs.Var((*SizeFlag)(&imagorCacheSize), "imagor-cache-size",
+		"imagor in-memory cache maximum size in bytes or with unit e.g. 64MB. Enable in-memory cache only if this value present")
+	fs.Var((*SizeFlag)(&imagorCacheDiskSize), "imagor-cache-disk-size",
+		"imagor on-disk cache maximum size in bytes or with unit e.g. 10GB (default 1GB)")
+	fs.Var((*SizeFlag)(&imagorSourceCacheSize), "imagor-source-cache-size",
+		"imagor in-memory LRU cache maximum size of hot source images in bytes or with unit e.g. 512MB, shared by different sizes and crops of the same image. Enable source cache only if this value present")
+	fs.Var((*SizeFlag)(&imagorMaxSourceSize), "imagor-max-source-size",
+		"Maximum size of loaded source image in bytes or with unit e.g. 20MB. No limit if 0")
+	fs.Var((*SizeFlag)(&imagorProcessMemoryLimit), "imagor-process-memory-limit",
+		"Maximum estimated memory of decoded source images processed simultaneously in bytes or with unit e.g. 2GB. Requests that exceed this limit are put in the queue. No limit if 0")
+	fs.Var((*SizeFlag)(&imagorUploadMaxSize), "imagor-upload-max-size",
+		"Maximum size of PUT /upload request body in bytes or with unit e.g. 1.5GiB (default 100MB)")
+	fs.Var((*SizeFlag)(&imagorAuditLogMaxSize), "imagor-audit-log-max-size",
+		"Size of audit log file in bytes or with unit e.g. 1GB exceeding which the file is rotated (default 100MB)")
+	fs.Var((*CIDRSliceFlag)(&imagorUnsafeAllowedNetworks), "imagor-unsafe-allowed-networks",
+		"Restrict unsafe imagor URL to client IP within the networks if set. Accept csv of networks in CIDR notation e.g. 10.0.0.0/8,::1/128")
+	fs.Var((*CIDRSliceFlag)(&imagorTrustedProxies), "imagor-trusted-proxies",
+		"Trusted proxy networks that X-Forwarded-For header is honored for resolving client IP. Accept csv of networks in CIDR notation e.g. 10.0.0.0/8")
+	fs.Var((*HeaderFlag)(&imagorResponseHeaders), "imagor-response-header",
+		"Static header added to image responses in format of Name: value e.g. X-Content-Type-Options: nosniff. Can be repeated")
+
+	var (
 		options, logger, isDebug = applyFuncs(fs, cb, append(funcs, baseConfig...)...)
 
-		alg          = sha1.New
-		hasher       imagorpath.StorageHasher
-		resultHasher imagorpath.ResultStorageHasher
+		cache         imagor.Cache
+		sourceCache   imagor.Cache
+		handlers      []imagor.EventHandler
+		reporter      imagor.ErrorReporter
+		invalidators  []imagor.Invalidator
+		signer        imagorpath.Signer
+		crypter       imagorpath.Crypter
+		legacyCrypter imagorpath.Crypter
+		hasher        imagorpath.StorageHasher
+		resultHasher  imagorpath.ResultStorageHasher
 	)
 
-	if strings.ToLower(*imagorSignerType) == "sha256" {
-		alg = sha256.New
-	} else if strings.ToLower(*imagorSignerType) == "sha512" {
-		alg = sha512.New
+	isThumborSigner := strings.EqualFold(*imagorSignerType, "thumbor")
+	alg, ok := imagorpath.SignerAlgorithm(*imagorSignerType)
+	if !ok && !isThumborSigner {
+		logger.Warn("unsupported signer type, fallback to sha1", zap.String("type", *imagorSignerType))
+		alg = sha1.New
+	}
+	if n := *imagorSignerTruncate; n > 0 && n < imagorpath.MinSignerTruncate {
+		logger.Warn("signer truncate below minimum, fallback to minimum",
+			zap.Int("truncate", n), zap.Int("minimum", imagorpath.MinSignerTruncate))
+		*imagorSignerTruncate = imagorpath.MinSignerTruncate
+	}
+	newSigner := func(secret string) imagorpath.Signer {
+		if isThumborSigner {
+			return imagorpath.NewThumborSigner(secret)
+		}
+		return imagorpath.NewHMACSigner(alg, *imagorSignerTruncate, secret)
+	}
+
+	signer = newSigner(*imagorSecret)
+	if *imagorSecretKeyID != "" {
+		signer = imagorpath.NewKeyIDSigner(*imagorSecretKeyID, signer)
+	}
+	if secrets := splitCSV(*imagorPreviousSecrets); len(secrets) > 0 {
+		var signers = []imagorpath.Signer{signer}
+		for _, secret := range secrets {
+			id, key, ok := strings.Cut(secret, ":")
+			if !ok {
+				key = secret
+			}
+			s := newSigner(key)
+			if ok {
+				s = imagorpath.NewKeyIDSigner(id, s)
+			}
+			signers = append(signers, s)
+		}
+		signer = imagorpath.NewMultiSigner(signers...)
+	}
+
+	if *imagorComponentsFile != "" {
+		option, err := readComponents(*imagorComponentsFile)
+		if err != nil {
+			panic(err)
+		}
+		options = append(options, option)
+	}
+
+	if *imagorParamsHook != "" {
+		hook, err := exprhook.New(*imagorParamsHook)
+		if err != nil {
+			panic(err)
+		}
+		options = append(options, imagor.WithParamsHook(hook.Rewrite))
+	}
+
+	if *imagorParamsHookFile != "" {
+		code, err := os.ReadFile(*imagorParamsHookFile)
+		if err != nil {
+			panic(err)
+		}
+		hook, err := exprhook.New(string(code))
+		if err != nil {
+			panic(fmt.Errorf("%s: %w", *imagorParamsHookFile, err))
+		}
+		options = append(options, imagor.WithParamsHook(hook.Rewrite))
+	}
+
+	if *imagorTenantsFile != "" {
+		tenants, err := readTenants(*imagorTenantsFile, newSigner)
+		if err != nil {
+			panic(err)
+		}
+		for _, tenant := range tenants {
+			options = append(options, imagor.WithTenant(tenant))
+		}
+	}
+
+	if imagorCacheSize > 0 {
+		c := memorycache.New(imagorCacheSize)
+		if *imagorCacheDiskDir != "" {
+			if imagorCacheDiskSize <= 0 {
+				imagorCacheDiskSize = 1 << 30
+			}
+			if dc, err := diskcache.New(*imagorCacheDiskDir, imagorCacheDiskSize); err != nil {
+				logger.Warn("cache-disk", zap.Error(err))
+			} else {
+				c.Overflow = dc
+			}
+		}
+		cache = c
+	}
+	if imagorSourceCacheSize > 0 {
+		sourceCache = memorycache.New(imagorSourceCacheSize)
+	}
+	if *redisCacheURL != "" {
+		if c, err := rediscache.New(*redisCacheURL, rediscache.WithPrefix(*redisCachePrefix)); err != nil {
+			logger.Warn("redis-cache", zap.Error(err))
+		} else if cache != nil {
+			cache = tiercache.New(cache, c, *redisCacheLocalTTL)
+		} else {
+			cache = c
+		}
+	}
+
+	for key, values := range imagorResponseHeaders {
+		for _, value := range values {
+			options = append(options, imagor.WithResponseHeader(key, value))
+		}
+	}
+
+	for name, key := range imagorAPIKeys {
+		options = append(options, imagor.WithAPIKey(name, key))
+	}
+
+	if imagorProcessMemoryLimit == 0 && *imagorProcessMemoryLimitAuto {
+		imagorProcessMemoryLimit = -1
+	}
+
+	for _, preset := range strings.Split(*imagorPresets, ";") {
+		if name, params, ok := strings.Cut(preset, "="); ok {
+			options = append(options, imagor.WithPreset(strings.TrimSpace(name), strings.TrimSpace(params)))
+		}
+	}
+
+	var srcsetWidths []int
+	for _, width := range splitCSV(*imagorSrcsetWidths) {
+		if w, err := strconv.Atoi(width); err == nil {
+			srcsetWidths = append(srcsetWidths, w)
+		}
+	}
+
+	if *imagorWebhookURL != "" {
+		var events []imagor.EventType
+		for _, event := range splitCSV(*imagorWebhookEvents) {
+			events = append(events, imagor.EventType(event))
+		}
+		handlers = append(handlers, webhook.New(
+			*imagorWebhookURL, *imagorWebhookSecret,
+			webhook.WithEvents(events...),
+			webhook.WithLogger(logger),
+		))
+	}
+
+	if *imagorAuditLog != "" {
+		var events []imagor.EventType
+		for _, event := range splitCSV(*imagorAuditLogEvents) {
+			events = append(events, imagor.EventType(event))
+		}
+		handlers = append(handlers, auditlog.New(
+			*imagorAuditLog,
+			auditlog.WithEvents(events...),
+			auditlog.WithMaxSize(imagorAuditLogMaxSize),
+			auditlog.WithMaxBackups(*imagorAuditLogMaxBackups),
+			auditlog.WithLogger(logger),
+		))
+	}
+
+	if *cloudflareZoneID != "" {
+		invalidators = append(invalidators, cloudflare.New(
+			*cloudflareZoneID, *cloudflareAPIToken, *cloudflareBaseURL))
+	}
+	if *fastlyAPIKey != "" {
+		invalidators = append(invalidators, fastly.New(
+			*fastlyAPIKey, *fastlyBaseURL, fastly.WithServiceID(*fastlyServiceID)))
+	}
+
+	if *imagorSentryDSN != "" {
+		if s, err := sentry.New(
+			*imagorSentryDSN,
+			sentry.WithEnvironment(*imagorSentryEnvironment),
+			sentry.WithLogger(logger),
+		); err != nil {
+			logger.Warn("sentry", zap.Error(err))
+		} else {
+			reporter = s
+		}
+	}
+
+	if *imagorEncryptionKey != "" {
+		crypter = imagorpath.NewAESCrypter(*imagorEncryptionKey)
+	}
+	if *imagorThumborLegacyURLs {
+		legacyCrypter = imagorpath.NewThumborLegacyCrypter(*imagorSecret)
 	}
 
 	if strings.ToLower(*imagorStoragePathStyle) == "digest" {
@@ -87,48 +438,104 @@ func NewImagor(
 		resultHasher = imagorpath.SuffixResultStorageHasher
 	}
 
-	return imagor.New(append(
+	app := imagor.New(append(
 		options,
-		imagor.WithSigner(imagorpath.NewHMACSigner(
-			alg, *imagorSignerTruncate, *imagorSecret,
-		)),
+		imagor.WithSigner(signer),
+		imagor.WithCrypter(crypter),
+		imagor.WithLegacyCrypter(legacyCrypter),
+		imagor.WithCache(cache),
+		imagor.WithEventHandlers(handlers...),
+		imagor.WithInvalidators(invalidators...),
+		imagor.WithErrorReporter(reporter),
+		imagor.WithResultCacheTTL(*imagorResultCacheTTL),
+		imagor.WithSourceCache(sourceCache, *imagorSourceCacheTTL),
+		imagor.WithErrorCacheTTL(*imagorErrorCacheTTL),
 		imagor.WithBasePathRedirect(*imagorBasePathRedirect),
+		imagor.WithPathPrefix(*imagorPathPrefix),
 		imagor.WithBaseParams(*imagorBaseParams),
+		imagor.WithFallbackImage(*imagorFallbackImage),
+		imagor.WithFallbackStatusCode(*imagorFallbackStatusCode),
 		imagor.WithRequestTimeout(*imagorRequestTimeout),
 		imagor.WithLoadTimeout(*imagorLoadTimeout),
 		imagor.WithSaveTimeout(*imagorSaveTimeout),
 		imagor.WithProcessTimeout(*imagorProcessTimeout),
 		imagor.WithProcessConcurrency(*imagorProcessConcurrency),
 		imagor.WithProcessQueueSize(*imagorProcessQueueSize),
+		imagor.WithProcessQueueTimeout(*imagorProcessQueueTimeout),
+		imagor.WithProcessMemoryLimit(imagorProcessMemoryLimit),
+		imagor.WithLoaderCircuitBreaker(*imagorLoaderBreakerThreshold, *imagorLoaderBreakerCooldown),
+		imagor.WithHealthCheckInterval(*imagorHealthCheckInterval),
+		imagor.WithPresignRedirect(*imagorPresignRedirect),
+		imagor.WithDebugDir(*imagorDebugDir),
 		imagor.WithCacheHeaderTTL(*imagorCacheHeaderTTL),
 		imagor.WithCacheHeaderSWR(*imagorCacheHeaderSWR),
 		imagor.WithCacheHeaderNoCache(*imagorCacheHeaderNoCache),
 		imagor.WithAutoWebP(*imagorAutoWebP),
 		imagor.WithAutoAVIF(*imagorAutoAVIF),
+		imagor.WithDPRClientHints(*imagorDPRClientHints),
+		imagor.WithSaveDataQuality(*imagorSaveDataQuality),
+		imagor.WithMaxAutoWidth(*imagorMaxAutoWidth),
 		imagor.WithModifiedTimeCheck(*imagorModifiedTimeCheck),
 		imagor.WithDisableErrorBody(*imagorDisableErrorBody),
+		imagor.WithDiagnosticHeaders(*imagorDiagnosticHeaders),
 		imagor.WithDisableParamsEndpoint(*imagorDisableParamsEndpoint),
+		imagor.WithAPIKeyRequired(*imagorAPIKeyRequired),
+		imagor.WithRateLimit(*imagorRateLimit, *imagorRateLimitBurst),
+		imagor.WithAllowedSizes(splitCSV(*imagorAllowedSizes)...),
+		imagor.WithAllowedFilters(splitCSV(*imagorAllowedFilters)...),
+		imagor.WithDeniedFilters(splitCSV(*imagorDeniedFilters)...),
+		imagor.WithDeniedFeatures(splitCSV(*imagorDeniedFeatures)...),
+		imagor.WithAllowedReferers(splitCSV(*imagorAllowedReferers)...),
+		imagor.WithDenyEmptyReferer(*imagorDenyEmptyReferer),
+		imagor.WithMaxSourceSize(imagorMaxSourceSize),
+		imagor.WithMaxSourcePixels(*imagorMaxSourcePixels),
+		imagor.WithSourceValidation(*imagorValidateSource, splitCSV(*imagorSourcePassthroughTypes)...),
+		imagor.WithUnsupportedSource(*imagorUnsupportedSource),
+		imagor.WithProcessorRoutes(imagorProcessorRoutes),
+		imagor.WithUpload(*imagorUploadSecret, imagorUploadMaxSize),
+		imagor.WithBatchConcurrency(*imagorBatchConcurrency),
+		imagor.WithWorkerConcurrency(*imagorWorkerConcurrency),
+		imagor.WithWatchWarm(*imagorWatchWarm),
+		imagor.WithPurge(*imagorPurgeSecret),
+		imagor.WithPurgeSource(*imagorPurgeSource),
+		imagor.WithWarm(*imagorWarmSecret),
+		imagor.WithSrcset(*imagorSrcsetSecret, srcsetWidths...),
+		imagor.WithStats(*imagorStatsSecret),
+		imagor.WithCompression(splitCSV(*imagorCompression)...),
+		imagor.WithAsyncTimeout(*imagorAsyncTimeout),
+		imagor.WithAsyncJobTTL(*imagorAsyncJobTTL),
+		imagor.WithAsyncMaxPending(*imagorAsyncMaxPending),
 		imagor.WithStoragePathStyle(hasher),
 		imagor.WithResultStoragePathStyle(resultHasher),
 		imagor.WithUnsafe(*imagorUnsafe),
+		imagor.WithUnsafeAllowedNetworks(imagorUnsafeAllowedNetworks...),
+		imagor.WithTrustedProxies(imagorTrustedProxies...),
 		imagor.WithLogger(logger),
 		imagor.WithDebug(isDebug),
 	)...)
+	if *imagorInstrument {
+		instrumented.Wrap(app)
+	}
+	return app
 }
 
 func CreateServer(args []string, funcs ...Func) (srv *server.Server) {
+	return createServer(flag.NewFlagSet("imagor", flag.ExitOnError), args, funcs...)
+}
+
+func createServer(fs *flag.FlagSet, args []string, funcs ...Func) (srv *server.Server) {
 	var (
-		fs     = flag.NewFlagSet("imagor", flag.ExitOnError)
 		logger *zap.Logger
 		err    error
 		app    *imagor.Imagor
 
 		debug        = fs.Bool("debug", false, "Debug mode")
 		version      = fs.Bool("version", false, "imagor version")
+		validateOnly = fs.Bool("validate", false, "Validate config by startup and health checks of loaders, storages and processors, print effective config and exit. Exit code 1 if failed")
 		port         = fs.Int("port", 8000, "Sever port")
 		goMaxProcess = fs.Int("gomaxprocs", 0, "GOMAXPROCS")
 
-		_ = fs.String("config", ".env", "Retrieve configuration from the given file")
+		_ = fs.String("config", ".env", "Retrieve configuration from the given file of .env, .yml or .toml format")
 
 		serverAddress = fs.String("server-address", "",
 			"Server address")
@@ -136,22 +543,54 @@ func CreateServer(args []string, funcs ...Func) (srv *server.Server) {
 			"Server path prefix")
 		serverCORS = fs.Bool("server-cors", false,
 			"Enable CORS")
+		serverCORSAllowedOrigins = fs.String("server-cors-allowed-origins", "*",
+			"CORS allowed origins by csv e.g. https://foo.com,https://*.bar.com")
+		serverCORSAllowedMethods = fs.String("server-cors-allowed-methods", "GET,HEAD",
+			"CORS allowed methods by csv")
+		serverCORSMaxAge = fs.Duration("server-cors-max-age", 0,
+			"CORS preflight response max age e.g. 1h")
 		serverStripQueryString = fs.Bool("server-strip-query-string", false,
 			"Enable strip query string redirection")
 		serverAccessLog = fs.Bool("server-access-log", false,
 			"Enable server access log")
+		serverSocket = fs.String("server-socket", "",
+			"Server listen on unix socket path instead of address and port")
+		serverSocketMode = fs.String("server-socket-mode", "",
+			"Server unix socket file mode in octal e.g. 0660")
+		serverSocketOwner = fs.String("server-socket-owner", "",
+			"Server unix socket owner by numeric uid:gid e.g. 33:33")
+		serverSystemdSocket = fs.Bool("server-systemd-socket", false,
+			"Server listen on socket inherited by systemd socket activation")
+		serverTLSCertFile = fs.String("server-tls-cert-file", "",
+			"Server TLS certificate file. Serves HTTPS with HTTP/2 if both certificate and key files are set")
+		serverTLSKeyFile = fs.String("server-tls-key-file", "",
+			"Server TLS private key file")
+		serverH2C = fs.Bool("server-h2c", false,
+			"Enable cleartext HTTP/2 (h2c) for HTTP/2 prior knowledge clients and load balancers")
+		serverAdminAddress = fs.String("server-admin-address", "",
+			"Server admin address serving pprof and expvar debug endpoints e.g. localhost:6060. Disabled if empty")
+		serverAdminSecret = fs.String("server-admin-secret", "",
+			"Secret for bearer token authorization of server admin endpoints")
+		serverGRPCAddress = fs.String("server-grpc-address", "",
+			"Server gRPC address serving imagor Process service alongside HTTP e.g. :9000. Disabled if empty")
+		serverGRPCSecret = fs.String("server-grpc-secret", "",
+			"Secret for bearer token authorization of server gRPC service")
 	)
 
 	app = NewImagor(fs, func() (*zap.Logger, bool) {
+		var readSecretFiles = withSecretFiles(fs)
 		if err = ff.Parse(fs, args,
 			ff.WithEnvVars(),
 			ff.WithConfigFileFlag("config"),
 			ff.WithIgnoreUndefined(true),
 			ff.WithAllowMissingConfigFile(true),
-			ff.WithConfigFileParser(ff.EnvParser),
+			ff.WithConfigFileParser(newConfigFileParser(fs)),
 		); err != nil {
 			panic(err)
 		}
+		if err = readSecretFiles(); err != nil {
+			panic(err)
+		}
 		if *debug {
 			logger = zap.Must(zap.NewDevelopment())
 		} else {
@@ -165,19 +604,74 @@ func CreateServer(args []string, funcs ...Func) (srv *server.Server) {
 		return
 	}
 
+	if *validateOnly {
+		if err = validate(app, fs, os.Stdout); err != nil {
+			logger.Error("validate", zap.Error(err))
+			exit(1)
+		}
+		return
+	}
+
 	if *goMaxProcess > 0 {
 		logger.Debug("GOMAXPROCS", zap.Int("count", *goMaxProcess))
 		runtime.GOMAXPROCS(*goMaxProcess)
 	}
 
-	return server.New(app,
+	var serverOptions = []server.Option{
 		server.WithAddress(*serverAddress),
 		server.WithPort(*port),
 		server.WithPathPrefix(*serverPathPrefix),
-		server.WithCORS(*serverCORS),
+	}
+	if *serverSocket != "" {
+		mode, _ := strconv.ParseUint(*serverSocketMode, 8, 32)
+		uid, gid := parseOwner(*serverSocketOwner)
+		serverOptions = append(serverOptions,
+			server.WithSocket(*serverSocket, os.FileMode(mode)),
+			server.WithSocketOwner(uid, gid))
+	}
+	if *serverSystemdSocket {
+		serverOptions = append(serverOptions, server.WithSystemdSocket(true))
+	}
+	if *serverGRPCAddress != "" {
+		serverOptions = append(serverOptions, server.WithGRPC(*serverGRPCAddress,
+			grpcserver.New(app, grpcserver.WithSecret(*serverGRPCSecret))))
+	}
+	if *serverCORS {
+		serverOptions = append(serverOptions, server.WithCORSOptions(cors.Options{
+			AllowedOrigins: splitCSV(*serverCORSAllowedOrigins),
+			AllowedMethods: splitCSV(*serverCORSAllowedMethods),
+			MaxAge:         int(serverCORSMaxAge.Seconds()),
+		}))
+	}
+	return server.New(app, append(serverOptions,
 		server.WithStripQueryString(*serverStripQueryString),
 		server.WithAccessLog(*serverAccessLog),
+		server.WithTLS(*serverTLSCertFile, *serverTLSKeyFile),
+		server.WithH2C(*serverH2C),
+		server.WithAdmin(*serverAdminAddress, *serverAdminSecret),
 		server.WithLogger(logger),
 		server.WithDebug(*debug),
-	)
+	)...)
+}
+
+// parseOwner parses uid:gid with -1 for missing or invalid id
+func parseOwner(s string) (uid, gid int) {
+	uid, gid = -1, -1
+	u, g, _ := strings.Cut(s, ":")
+	if v, err := strconv.Atoi(u); err == nil {
+		uid = v
+	}
+	if v, err := strconv.Atoi(g); err == nil {
+		gid = v
+	}
+	return
+}
+
+func splitCSV(s string) (res []string) {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return
 }
@@ -1,12 +1,29 @@
 package config
 
 import (
+	"flag"
 	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/auditlog"
+	"github.com/cshum/imagor/cache/diskcache"
+	"github.com/cshum/imagor/cache/memorycache"
+	"github.com/cshum/imagor/cache/rediscache"
+	"github.com/cshum/imagor/cache/tiercache"
 	"github.com/cshum/imagor/imagorpath"
+	"github.com/cshum/imagor/instrumented"
+	"github.com/cshum/imagor/invalidator/cloudflare"
+	"github.com/cshum/imagor/invalidator/fastly"
 	"github.com/cshum/imagor/loader/httploader"
+	"github.com/cshum/imagor/sentry"
 	"github.com/cshum/imagor/storage/filestorage"
+	"github.com/cshum/imagor/webhook"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -18,9 +35,9 @@ func TestDefault(t *testing.T) {
 	assert.False(t, app.Debug)
 	assert.False(t, app.Unsafe)
 	assert.Equal(t, time.Second*30, app.RequestTimeout)
-	assert.Equal(t, time.Second*20, app.LoadTimeout)
-	assert.Equal(t, time.Second*20, app.SaveTimeout)
-	assert.Equal(t, time.Second*20, app.ProcessTimeout)
+	assert.Empty(t, app.LoadTimeout)
+	assert.Empty(t, app.SaveTimeout)
+	assert.Empty(t, app.ProcessTimeout)
 	assert.Empty(t, app.BasePathRedirect)
 	assert.Empty(t, app.ProcessConcurrency)
 	assert.Empty(t, app.BaseParams)
@@ -29,6 +46,8 @@ func TestDefault(t *testing.T) {
 	assert.False(t, app.AutoAVIF)
 	assert.False(t, app.DisableErrorBody)
 	assert.False(t, app.DisableParamsEndpoint)
+	assert.Nil(t, app.Crypter)
+	assert.Nil(t, app.Cache)
 	assert.Equal(t, time.Hour*24*7, app.CacheHeaderTTL)
 	assert.Equal(t, time.Hour*24, app.CacheHeaderSWR)
 	assert.Empty(t, app.ResultStorages)
@@ -51,11 +70,41 @@ func TestBasic(t *testing.T) {
 		"-imagor-process-timeout", "19s",
 		"-imagor-process-concurrency", "199",
 		"-imagor-process-queue-size", "1999",
+		"-imagor-process-queue-timeout", "3s",
+		"-imagor-process-memory-limit", "2GB",
+		"-imagor-process-memory-limit-auto",
+		"-imagor-upload-secret", "s3cret",
+		"-imagor-upload-max-size", "1KB",
+		"-imagor-batch-concurrency", "4",
+		"-imagor-purge-secret", "purg3",
+		"-imagor-warm-secret", "warm3",
+		"-imagor-srcset-secret", "srcset4",
+		"-imagor-srcset-widths", "200, 400,x",
+		"-imagor-stats-secret", "stats5",
+		"-imagor-presets", "thumb=fit-in/100x100; cover = 1200x630/smart;invalid",
+		"-imagor-async-timeout", "10m",
+		"-imagor-async-job-ttl", "2h",
+		"-imagor-loader-circuit-breaker-threshold", "5",
+		"-imagor-loader-circuit-breaker-cooldown", "1m",
 		"-imagor-base-path-redirect", "https://www.google.com",
+		"-imagor-presign-redirect", "5m",
+		"-imagor-save-data-quality", "40",
+		"-imagor-max-auto-width", "1600",
+		"-imagor-allowed-referers", "example.com,*.example.com",
+		"-imagor-deny-empty-referer",
+		"-imagor-debug-dir", "/tmp/imagor-debug",
+		"-imagor-unsupported-source", "passthrough",
+		"-imagor-processor-routes", "image/svg+xml=svg.Rasterizer,image/*=vips.Processor",
 		"-imagor-base-params", "fitlers:watermark(example.jpg)",
 		"-imagor-cache-header-ttl", "169h",
 		"-imagor-cache-header-swr", "167h",
+		"-imagor-fallback-image", "placeholder.jpg",
+		"-imagor-fallback-status-code", "200",
+		"-imagor-path-prefix", "img",
+		"-imagor-encryption-key", "abcd",
 		"-http-loader-insecure-skip-verify-transport",
+		"-http-loader-forward-trace-headers",
+		"-http-loader-override-headers", `User-Agent=imagor,X-Values=a\,b`,
 	})
 	app := srv.App.(*imagor.Imagor)
 
@@ -71,19 +120,65 @@ func TestBasic(t *testing.T) {
 	assert.Equal(t, time.Second*19, app.ProcessTimeout)
 	assert.Equal(t, int64(199), app.ProcessConcurrency)
 	assert.Equal(t, int64(1999), app.ProcessQueueSize)
+	assert.Equal(t, time.Second*3, app.ProcessQueueTimeout)
+	assert.Equal(t, int64(2<<30), app.ProcessMemoryLimit)
+	assert.Equal(t, "s3cret", app.UploadSecret)
+	assert.Equal(t, int64(1024), app.UploadMaxSize)
+	assert.Equal(t, 4, app.BatchConcurrency)
+	assert.Equal(t, "purg3", app.PurgeSecret)
+	assert.Equal(t, "warm3", app.WarmSecret)
+	assert.Equal(t, "srcset4", app.SrcsetSecret)
+	assert.Equal(t, []int{200, 400}, app.SrcsetWidths)
+	assert.Equal(t, "stats5", app.StatsSecret)
+	assert.Equal(t, map[string]string{
+		"thumb": "fit-in/100x100", "cover": "1200x630/smart",
+	}, app.Presets)
+	assert.Equal(t, time.Minute*10, app.AsyncTimeout)
+	assert.Equal(t, time.Hour*2, app.AsyncJobTTL)
+	assert.Equal(t, 5, app.LoaderBreakerThreshold)
+	assert.Equal(t, time.Minute, app.LoaderBreakerCooldown)
 	assert.Equal(t, "https://www.google.com", app.BasePathRedirect)
+	assert.Equal(t, time.Minute*5, app.PresignRedirect)
+	assert.Equal(t, "/tmp/imagor-debug", app.DebugDir)
+	assert.Equal(t, []string{"example.com", "*.example.com"}, app.AllowedReferers)
+	assert.True(t, app.DenyEmptyReferer)
+	assert.Equal(t, 40, app.SaveDataQuality)
+	assert.Equal(t, 1600, app.MaxAutoWidth)
+	assert.Equal(t, imagor.UnsupportedSourcePassthrough, app.UnsupportedSource)
+	assert.Equal(t, map[string]string{
+		"image/svg+xml": "svg.Rasterizer", "image/*": "vips.Processor",
+	}, app.ProcessorRoutes)
 	assert.Equal(t, "fitlers:watermark(example.jpg)/", app.BaseParams)
 	assert.Equal(t, time.Hour*169, app.CacheHeaderTTL)
 	assert.Equal(t, time.Hour*167, app.CacheHeaderSWR)
+	assert.Equal(t, "placeholder.jpg", app.FallbackImage)
+	assert.Equal(t, 200, app.FallbackStatusCode)
+	assert.Equal(t, "/img/", app.PathPrefix)
+	assert.Equal(t, imagorpath.NewAESCrypter("abcd").Encrypt("bar"), app.Crypter.Encrypt("bar"))
 
 	httpLoader := app.Loaders[0].(*httploader.HTTPLoader)
 	assert.True(t, httpLoader.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+	assert.True(t, httpLoader.ForwardTraceHeaders)
+	assert.Equal(t, map[string]string{"User-Agent": "imagor", "X-Values": "a,b"}, httpLoader.OverrideHeaders)
 }
 
 func TestVersion(t *testing.T) {
 	assert.Empty(t, CreateServer([]string{"-version"}))
 }
 
+func TestServerGRPC(t *testing.T) {
+	srv := CreateServer(nil)
+	assert.Empty(t, srv.GRPCAddr)
+	assert.Nil(t, srv.GRPC)
+
+	srv = CreateServer([]string{
+		"-server-grpc-address", ":9000",
+		"-server-grpc-secret", "s3cret",
+	})
+	assert.Equal(t, ":9000", srv.GRPCAddr)
+	assert.IsType(t, &grpc.Server{}, srv.GRPC)
+}
+
 func TestSignerAlgorithm(t *testing.T) {
 	srv := CreateServer([]string{
 		"-imagor-signer-type", "sha256",
@@ -97,6 +192,64 @@ func TestSignerAlgorithm(t *testing.T) {
 	})
 	app = srv.App.(*imagor.Imagor)
 	assert.Equal(t, "Kmml5ejnmsn7M7TszYkeM2j5G3bpI7mp", app.Signer.Sign("bar"))
+
+	srv = CreateServer([]string{
+		"-imagor-signer-type", "sha512",
+		"-imagor-signer-truncate", "4",
+	})
+	app = srv.App.(*imagor.Imagor)
+	assert.Equal(t, "Kmml5ejn", app.Signer.Sign("bar"))
+
+	srv = CreateServer([]string{
+		"-imagor-signer-type", "md5",
+	})
+	app = srv.App.(*imagor.Imagor)
+	assert.Equal(t, imagorpath.NewDefaultSigner("").Sign("bar"), app.Signer.Sign("bar"))
+}
+
+func TestThumborCompat(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-secret", "1234",
+		"-imagor-signer-type", "thumbor",
+		"-imagor-thumbor-legacy-urls",
+	})
+	app := srv.App.(*imagor.Imagor)
+	assert.Equal(t, imagorpath.NewDefaultSigner("1234").Sign("bar"), app.Signer.Sign("bar"))
+	assert.True(t, imagorpath.Verify(app.Signer, "bar", strings.TrimRight(app.Signer.Sign("bar"), "=")))
+	assert.NotNil(t, app.LegacyCrypter)
+
+	srv = CreateServer([]string{"-imagor-secret", "1234"})
+	assert.Nil(t, srv.App.(*imagor.Imagor).LegacyCrypter)
+}
+
+func TestSecretRotation(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-secret", "new",
+		"-imagor-secret-key-id", "k2",
+		"-imagor-previous-secrets", "k1:old,legacy",
+	})
+	app := srv.App.(*imagor.Imagor)
+	assert.Equal(t, "k2_"+imagorpath.NewDefaultSigner("new").Sign("bar"), app.Signer.Sign("bar"))
+	assert.True(t, imagorpath.Verify(app.Signer, "bar", "k1_"+imagorpath.NewDefaultSigner("old").Sign("bar")))
+	assert.True(t, imagorpath.Verify(app.Signer, "bar", imagorpath.NewDefaultSigner("legacy").Sign("bar")))
+	assert.False(t, imagorpath.Verify(app.Signer, "bar", imagorpath.NewDefaultSigner("old").Sign("bar")))
+}
+
+func TestResponseHeaders(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-response-header", "X-Content-Type-Options: nosniff",
+		"-imagor-response-header", "content-security-policy: default-src 'none'; sandbox",
+	})
+	app := srv.App.(*imagor.Imagor)
+	assert.Equal(t, http.Header{
+		"X-Content-Type-Options":  {"nosniff"},
+		"Content-Security-Policy": {"default-src 'none'; sandbox"},
+	}, app.ResponseHeaders)
+
+	fs := flag.NewFlagSet("imagor", flag.ContinueOnError)
+	var h http.Header
+	fs.Var((*HeaderFlag)(&h), "header", "")
+	assert.Error(t, fs.Parse([]string{"-header", "invalid"}))
 }
 
 func TestCacheHeaderNoCache(t *testing.T) {
@@ -117,12 +270,28 @@ func TestFileLoader(t *testing.T) {
 
 		"-file-loader-base-dir", "./foo",
 		"-file-loader-path-prefix", "abcd",
+		"-file-loader-watch-interval", "10s",
+		"-imagor-watch-warm",
+		"-file-blacklist", `\.exe$,^private/`,
+		"-file-blacklist", `\.sh$`,
+		"-file-allowed-extensions", ".jpg,png",
+		"-file-reject-unsafe-chars",
+		"-file-max-path-length", "255",
+		"-file-deny-symlink-escape",
 	})
 	app := srv.App.(*imagor.Imagor)
 	fileLoader := app.Loaders[0].(*filestorage.FileStorage)
 	assert.Equal(t, "./foo", fileLoader.BaseDir)
 	assert.Equal(t, "/abcd/", fileLoader.PathPrefix)
 	assert.Equal(t, "!", fileLoader.SafeChars)
+	assert.Len(t, fileLoader.Blacklists, 4)
+	assert.Equal(t, `^private/`, fileLoader.Blacklists[2].String())
+	assert.Equal(t, []string{".jpg", ".png"}, fileLoader.AllowedExtensions)
+	assert.True(t, fileLoader.RejectUnsafeChars)
+	assert.Equal(t, 255, fileLoader.MaxPathLength)
+	assert.True(t, fileLoader.DenySymlinkEscape)
+	assert.Equal(t, time.Second*10, fileLoader.WatchInterval)
+	assert.True(t, app.WatchWarm)
 }
 
 func TestFileStorage(t *testing.T) {
@@ -131,9 +300,13 @@ func TestFileStorage(t *testing.T) {
 
 		"-file-storage-base-dir", "./foo",
 		"-file-storage-path-prefix", "abcd",
+		"-file-storage-dedup",
 
 		"-file-result-storage-base-dir", "./bar",
 		"-file-result-storage-path-prefix", "bcda",
+		"-file-result-storage-fsync",
+		"-file-result-storage-fsync-dir",
+		"-file-result-storage-fsync-async",
 	})
 	app := srv.App.(*imagor.Imagor)
 	assert.Equal(t, 1, len(app.Loaders))
@@ -141,11 +314,127 @@ func TestFileStorage(t *testing.T) {
 	assert.Equal(t, "./foo", storage.BaseDir)
 	assert.Equal(t, "/abcd/", storage.PathPrefix)
 	assert.Equal(t, "!", storage.SafeChars)
+	assert.False(t, storage.Fsync)
+	assert.True(t, storage.Dedup)
 
 	resultStorage := app.ResultStorages[0].(*filestorage.FileStorage)
 	assert.Equal(t, "./bar", resultStorage.BaseDir)
 	assert.Equal(t, "/bcda/", resultStorage.PathPrefix)
 	assert.Equal(t, "!", resultStorage.SafeChars)
+	assert.True(t, resultStorage.Fsync)
+	assert.True(t, resultStorage.FsyncDir)
+	assert.True(t, resultStorage.AsyncFsync)
+}
+
+func TestInstrument(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-instrument",
+		"-file-storage-base-dir", "./foo",
+	})
+	app := srv.App.(*imagor.Imagor)
+	loader := app.Loaders[0].(*instrumented.Loader)
+	assert.Equal(t, "httploader.HTTPLoader", loader.Name)
+	assert.IsType(t, &httploader.HTTPLoader{}, loader.Unwrap())
+	storage := app.Storages[0].(*instrumented.Storage)
+	assert.Equal(t, "filestorage.FileStorage", storage.Name)
+	assert.IsType(t, &filestorage.FileStorage{}, storage.Unwrap())
+}
+
+func TestTenants(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tenants.json")
+	require.NoError(t, os.WriteFile(file, []byte(`[
+		{"name":"brand","hosts":["img.brand.com"],"secret":"brand-secret","allowed_sources":["brand/*"],"storage_prefix":"/brand/","base_params":"filters:quality(80)"},
+		{"name":"shop","path_prefix":"/shop/"}
+	]`), 0600))
+	srv := CreateServer([]string{
+		"-imagor-secret", "1234",
+		"-imagor-tenants-file", file,
+	})
+	app := srv.App.(*imagor.Imagor)
+	require.Len(t, app.Tenants, 2)
+	assert.Equal(t, "brand", app.Tenants[0].Name)
+	assert.Equal(t, []string{"img.brand.com"}, app.Tenants[0].Hosts)
+	assert.Equal(t, "brand/", app.Tenants[0].StoragePrefix)
+	assert.Equal(t, "filters:quality(80)/", app.Tenants[0].BaseParams)
+	assert.Equal(t, imagorpath.NewDefaultSigner("brand-secret").Sign("foo"), app.Tenants[0].Signer.Sign("foo"))
+	assert.Equal(t, "/shop", app.Tenants[1].PathPrefix)
+	assert.Nil(t, app.Tenants[1].Signer)
+
+	require.NoError(t, os.WriteFile(file, []byte(`[{"name":"all"}]`), 0600))
+	assert.Panics(t, func() {
+		CreateServer([]string{"-imagor-tenants-file", file})
+	})
+}
+
+func TestParamsHook(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hook.expr")
+	require.NoError(t, os.WriteFile(file, []byte(`{height: min(params.height, 300)}`), 0600))
+	srv := CreateServer([]string{
+		"-imagor-params-hook", `{width: min(params.width, 200)}`,
+		"-imagor-params-hook-file", file,
+	})
+	app := srv.App.(*imagor.Imagor)
+	require.Len(t, app.ParamsHooks, 2)
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	p := imagorpath.Parse("unsafe/1000x1000/foo.jpg")
+	for _, hook := range app.ParamsHooks {
+		var err error
+		p, err = hook(r, p)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 200, p.Width)
+	assert.Equal(t, 300, p.Height)
+
+	assert.Panics(t, func() {
+		CreateServer([]string{"-imagor-params-hook", `{width: `})
+	})
+	assert.Panics(t, func() {
+		CreateServer([]string{"-imagor-params-hook-file", filepath.Join(t.TempDir(), "not-exists")})
+	})
+}
+
+func TestComponents(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "components.yml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+loaders:
+  - http
+  - type: file
+    options:
+      base_dir: `+dir+`
+      allowed_extensions: [.jpg, .png]
+result_storages:
+  - type: file
+    options:
+      base_dir: `+dir+`
+      expiration: 24h
+`), 0600))
+	srv := CreateServer([]string{"-http-loader-disable", "-imagor-components-file", file})
+	app := srv.App.(*imagor.Imagor)
+	require.Len(t, app.Loaders, 2)
+	assert.IsType(t, &httploader.HTTPLoader{}, app.Loaders[0])
+	fl := app.Loaders[1].(*filestorage.FileStorage)
+	assert.Equal(t, dir, fl.BaseDir)
+	assert.Equal(t, []string{".jpg", ".png"}, fl.AllowedExtensions)
+	require.Len(t, app.ResultStorages, 1)
+	assert.Equal(t, time.Hour*24, app.ResultStorages[0].(*filestorage.FileStorage).Expiration)
+
+	// components in addition to configured
+	srv = CreateServer([]string{"-imagor-components-file", file})
+	assert.Len(t, srv.App.(*imagor.Imagor).Loaders, 3)
+
+	for _, content := range []string{
+		"loaders: [foo]",
+		"processors: [http]",
+		"loaders: [{type: file}]",
+		"loaders: [{type: file, options: {base_dir: /tmp, expiration: abc}}]",
+		"foo: [http]",
+	} {
+		require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+		assert.Panics(t, func() {
+			CreateServer([]string{"-imagor-components-file", file})
+		}, content)
+	}
 }
 
 func TestPathStyle(t *testing.T) {
@@ -163,3 +452,265 @@ func TestPathStyle(t *testing.T) {
 	app = srv.App.(*imagor.Imagor)
 	assert.Equal(t, "abc.30fdbe2aa5086e0f0c50", app.ResultStoragePathStyle.HashResult(imagorpath.Parse("200x200/abc")))
 }
+
+func TestCORS(t *testing.T) {
+	srv := CreateServer([]string{
+		"-server-cors",
+		"-server-cors-allowed-origins", "https://foo.com, https://bar.com",
+		"-server-cors-max-age", "1h",
+	})
+	r := httptest.NewRequest(http.MethodOptions, "https://example.com/unsafe/foo.jpg", nil)
+	r.Header.Set("Origin", "https://bar.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, r)
+	assert.Equal(t, "https://bar.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+
+	r = httptest.NewRequest(http.MethodOptions, "https://example.com/unsafe/foo.jpg", nil)
+	r.Header.Set("Origin", "https://bar.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w = httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, r)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestServerSocket(t *testing.T) {
+	srv := CreateServer([]string{
+		"-server-socket", "/tmp/imagor.sock",
+		"-server-socket-mode", "0660",
+		"-server-socket-owner", "33:",
+	})
+	assert.Equal(t, "/tmp/imagor.sock", srv.Socket)
+	assert.Equal(t, os.FileMode(0660), srv.SocketMode)
+	assert.Equal(t, 33, srv.SocketUID)
+	assert.Equal(t, -1, srv.SocketGID)
+	assert.False(t, srv.SystemdSocket)
+
+	srv = CreateServer([]string{"-server-systemd-socket"})
+	assert.True(t, srv.SystemdSocket)
+}
+
+func TestUnsafeAllowedNetworks(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-unsafe",
+		"-imagor-unsafe-allowed-networks", "10.0.0.0/8,::1/128",
+		"-imagor-trusted-proxies", "172.16.0.0/12",
+	})
+	app := srv.App.(*imagor.Imagor)
+	assert.Len(t, app.UnsafeAllowedNetworks, 2)
+	assert.Equal(t, "10.0.0.0/8", app.UnsafeAllowedNetworks[0].String())
+	assert.Equal(t, "::1/128", app.UnsafeAllowedNetworks[1].String())
+	assert.Len(t, app.TrustedProxies, 1)
+	assert.Equal(t, "172.16.0.0/12", app.TrustedProxies[0].String())
+}
+
+func TestResultCache(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-cache-size", "1000000",
+		"-imagor-result-cache-ttl", "1h",
+		"-imagor-error-cache-ttl", "30s",
+	})
+	app := srv.App.(*imagor.Imagor)
+	assert.Equal(t, int64(1000000), app.Cache.(*memorycache.MemoryCache).MaxSize)
+	assert.Equal(t, time.Hour, app.ResultCacheTTL)
+	assert.Equal(t, time.Second*30, app.ErrorCacheTTL)
+}
+
+func TestCacheDisk(t *testing.T) {
+	dir := t.TempDir()
+	srv := CreateServer([]string{
+		"-imagor-cache-size", "1MB",
+		"-imagor-cache-disk-dir", dir,
+	})
+	app := srv.App.(*imagor.Imagor)
+	dc := app.Cache.(*memorycache.MemoryCache).Overflow.(*diskcache.DiskCache)
+	assert.Equal(t, dir, dc.Dir)
+	assert.Equal(t, int64(1<<30), dc.MaxSize)
+
+	srv = CreateServer([]string{
+		"-imagor-cache-size", "1MB",
+		"-imagor-cache-disk-dir", dir,
+		"-imagor-cache-disk-size", "10GB",
+	})
+	app = srv.App.(*imagor.Imagor)
+	dc = app.Cache.(*memorycache.MemoryCache).Overflow.(*diskcache.DiskCache)
+	assert.Equal(t, int64(10<<30), dc.MaxSize)
+}
+
+func TestSourceCache(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-source-cache-size", "512MB",
+		"-imagor-source-cache-ttl", "10m",
+	})
+	app := srv.App.(*imagor.Imagor)
+	assert.Equal(t, int64(512<<20), app.SourceCache.(*memorycache.MemoryCache).MaxSize)
+	assert.Equal(t, time.Minute*10, app.SourceCacheTTL)
+	assert.Nil(t, app.Cache)
+
+	srv = CreateServer([]string{})
+	assert.Nil(t, srv.App.(*imagor.Imagor).SourceCache)
+}
+
+func TestRedisCache(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-result-cache-ttl", "1h",
+		"-redis-cache-url", "redis://:s3cret@localhost:6380/2",
+		"-redis-cache-prefix", "img:",
+	})
+	app := srv.App.(*imagor.Imagor)
+	c := app.Cache.(*rediscache.RedisCache)
+	assert.Equal(t, "localhost:6380", c.Addr)
+	assert.Equal(t, "s3cret", c.Password)
+	assert.Equal(t, 2, c.DB)
+	assert.Equal(t, "img:", c.Prefix)
+
+	srv = CreateServer([]string{
+		"-imagor-cache-size", "1000000",
+		"-redis-cache-url", "redis://localhost",
+		"-redis-cache-local-ttl", "10s",
+	})
+	app = srv.App.(*imagor.Imagor)
+	tc := app.Cache.(*tiercache.TierCache)
+	assert.IsType(t, &memorycache.MemoryCache{}, tc.Local)
+	assert.IsType(t, &rediscache.RedisCache{}, tc.Remote)
+	assert.Equal(t, time.Second*10, tc.LocalTTL)
+
+	srv = CreateServer([]string{"-redis-cache-url", "invalid"})
+	assert.Nil(t, srv.App.(*imagor.Imagor).Cache)
+}
+
+func TestWebhook(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-webhook-url", "https://example.com/hook",
+		"-imagor-webhook-secret", "abc",
+		"-imagor-webhook-events", "processed, save_failed",
+	})
+	app := srv.App.(*imagor.Imagor)
+	require.Len(t, app.EventHandlers, 1)
+	hook := app.EventHandlers[0].(*webhook.Webhook)
+	assert.Equal(t, "https://example.com/hook", hook.URL)
+	assert.Equal(t, "abc", hook.Secret)
+	assert.Equal(t, []imagor.EventType{imagor.EventProcessed, imagor.EventSaveFailed}, hook.Events)
+
+	srv = CreateServer([]string{})
+	assert.Empty(t, srv.App.(*imagor.Imagor).EventHandlers)
+}
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	srv := CreateServer([]string{
+		"-imagor-audit-log", path,
+		"-imagor-audit-log-max-size", "1MB",
+		"-imagor-audit-log-max-backups", "3",
+	})
+	app := srv.App.(*imagor.Imagor)
+	require.Len(t, app.EventHandlers, 1)
+	l := app.EventHandlers[0].(*auditlog.AuditLog)
+	assert.Equal(t, path, l.Path)
+	assert.Equal(t, []imagor.EventType{imagor.EventProcessed}, l.Events)
+	assert.Equal(t, int64(1<<20), l.MaxSize)
+	assert.Equal(t, 3, l.MaxBackups)
+}
+
+func TestInvalidators(t *testing.T) {
+	srv := CreateServer([]string{
+		"-cloudflare-zone-id", "zone1",
+		"-cloudflare-api-token", "t0ken",
+		"-cloudflare-base-url", "https://img.example.com",
+		"-fastly-api-key", "k3y",
+		"-fastly-service-id", "svc1",
+		"-fastly-base-url", "https://img2.example.com",
+	})
+	app := srv.App.(*imagor.Imagor)
+	require.Len(t, app.Invalidators, 2)
+	cf := app.Invalidators[0].(*cloudflare.Cloudflare)
+	assert.Equal(t, "zone1", cf.ZoneID)
+	assert.Equal(t, "t0ken", cf.APIToken)
+	assert.Equal(t, "https://img.example.com", cf.BaseURL)
+	f := app.Invalidators[1].(*fastly.Fastly)
+	assert.Equal(t, "k3y", f.APIKey)
+	assert.Equal(t, "svc1", f.ServiceID)
+	assert.Equal(t, "https://img2.example.com", f.BaseURL)
+
+	srv = CreateServer([]string{})
+	assert.Empty(t, srv.App.(*imagor.Imagor).Invalidators)
+}
+
+func TestSentry(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-sentry-dsn", "https://abc@o1.ingest.sentry.io/123",
+		"-imagor-sentry-environment", "production",
+	})
+	app := srv.App.(*imagor.Imagor)
+	s := app.ErrorReporter.(*sentry.Sentry)
+	assert.Equal(t, "https://abc@o1.ingest.sentry.io/123", s.DSN)
+	assert.Equal(t, "production", s.Environment)
+
+	srv = CreateServer([]string{"-imagor-sentry-dsn", "invalid"})
+	assert.Nil(t, srv.App.(*imagor.Imagor).ErrorReporter)
+}
+
+func TestAPIKeys(t *testing.T) {
+	srv := CreateServer([]string{
+		"-imagor-api-keys", "backend=k3y1, worker = k3y2,invalid",
+		"-imagor-api-keys", `batch=k3y\,3`,
+		"-imagor-api-key-required",
+	})
+	app := srv.App.(*imagor.Imagor)
+	assert.Equal(t, map[string]string{"backend": "k3y1", "worker": "k3y2", "batch": "k3y,3"}, app.APIKeys)
+	assert.True(t, app.APIKeyRequired)
+}
+
+func TestSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secret"), []byte(" 1234\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "api_keys"), []byte("backend=k3y1\n"), 0600))
+	srv := CreateServer([]string{
+		"-imagor-secret", "abcd",
+		"-imagor-secret-file", filepath.Join(dir, "secret"),
+		"-imagor-api-keys-file", filepath.Join(dir, "api_keys"),
+	})
+	app := srv.App.(*imagor.Imagor)
+	assert.Equal(t, imagorpath.NewDefaultSigner("1234").Sign("foo"), app.Signer.Sign("foo"))
+	assert.Equal(t, map[string]string{"backend": "k3y1"}, app.APIKeys)
+
+	assert.Panics(t, func() {
+		CreateServer([]string{"-imagor-secret-file", filepath.Join(dir, "missing")})
+	})
+}
+
+func TestValidate(t *testing.T) {
+	var code = -1
+	exit = func(c int) { code = c }
+	defer func() { exit = os.Exit }()
+
+	assert.Nil(t, CreateServer([]string{
+		"-validate",
+		"-imagor-secret", "1234",
+		"-file-storage-base-dir", t.TempDir(),
+	}))
+	assert.Equal(t, -1, code)
+
+	assert.Nil(t, CreateServer([]string{
+		"-validate",
+		"-file-storage-base-dir", filepath.Join(t.TempDir(), "missing"),
+	}))
+	assert.Equal(t, 1, code)
+}
+
+func TestPrintConfig(t *testing.T) {
+	fs := flag.NewFlagSet("imagor", flag.ContinueOnError)
+	fs.String("imagor-secret", "", "")
+	fs.String("imagor-base-path-redirect", "", "")
+	fs.Int("port", 8000, "")
+	var headers http.Header
+	fs.Var((*HeaderFlag)(&headers), "imagor-response-header", "")
+	require.NoError(t, fs.Parse([]string{
+		"-imagor-secret", "1234",
+		"-imagor-response-header", "X-Foo: bar",
+	}))
+	var buf strings.Builder
+	printConfig(fs, &buf)
+	assert.Equal(t, "imagor-response-header=X-Foo: bar\nimagor-secret=******\nport=8000\n", buf.String())
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"github.com/peterbourgon/ff/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// mapSeparators separators of flags in format of name=value pairs,
+// that can be declared as map in config file
+var mapSeparators = map[string]string{
+	"imagor-presets": ";",
+}
+
+// newConfigFileParser returns config file parser by extension of the config flag value,
+// YAML for .yml and .yaml, TOML for .toml, or .env format otherwise.
+// YAML and TOML are nested by flag name segments, with unknown fields rejected
+func newConfigFileParser(fs *flag.FlagSet) ff.ConfigFileParser {
+	return func(r io.Reader, set func(name, value string) error) error {
+		var path string
+		if f := fs.Lookup("config"); f != nil {
+			path = f.Value.String()
+		}
+		var m map[string]interface{}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yml", ".yaml":
+			if err := yaml.NewDecoder(r).Decode(&m); err != nil && err != io.EOF {
+				return fmt.Errorf("config %s: %w", path, err)
+			}
+		case ".toml":
+			var err error
+			if m, err = parseTOML(r); err != nil {
+				return fmt.Errorf("config %s: %w", path, err)
+			}
+		default:
+			return ff.EnvParser(r, set)
+		}
+		if err := setConfigValues(fs, "", m, set); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+		return nil
+	}
+}
+
+// setConfigValues sets flags of nested config values, with keys joined by dash
+func setConfigValues(
+	fs *flag.FlagSet, prefix string, m map[string]interface{}, set func(name, value string) error,
+) error {
+	for _, key := range sortedKeys(m) {
+		name := strings.ReplaceAll(strings.ToLower(key), "_", "-")
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+		f := fs.Lookup(name)
+		switch val := m[key].(type) {
+		case map[string]interface{}:
+			if isHeaderFlag(f) {
+				for _, k := range sortedKeys(val) {
+					if err := set(name, k+": "+toString(val[k])); err != nil {
+						return err
+					}
+				}
+			} else if isMapFlag(f) {
+				for _, k := range sortedKeys(val) {
+					if err := set(name, escapeMapValue(k)+"="+escapeMapValue(toString(val[k]))); err != nil {
+						return err
+					}
+				}
+			} else if sep, ok := mapSeparators[name]; ok && f != nil {
+				var pairs []string
+				for _, k := range sortedKeys(val) {
+					pairs = append(pairs, k+"="+toString(val[k]))
+				}
+				if err := set(name, strings.Join(pairs, sep)); err != nil {
+					return err
+				}
+			} else if err := setConfigValues(fs, name, val, set); err != nil {
+				return err
+			}
+		case []interface{}:
+			if f == nil {
+				return fmt.Errorf("unknown config field %q", name)
+			}
+			var values []string
+			for _, v := range val {
+				if _, ok := v.(map[string]interface{}); ok {
+					return fmt.Errorf("unsupported array of tables %q", name)
+				}
+				values = append(values, toString(v))
+			}
+			if isRepeatableFlag(f) {
+				for _, v := range values {
+					if err := set(name, v); err != nil {
+						return err
+					}
+				}
+			} else if err := set(name, strings.Join(values, ",")); err != nil {
+				return err
+			}
+		default:
+			if f == nil {
+				return fmt.Errorf("unknown config field %q", name)
+			}
+			if err := set(name, toString(val)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isHeaderFlag(f *flag.Flag) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.Value.(*HeaderFlag)
+	return ok
+}
+
+func isMapFlag(f *flag.Flag) bool {
+	if f == nil {
+		return false
+	}
+	_, ok := f.Value.(*MapFlag)
+	return ok
+}
+
+// isRepeatableFlag returns if flag appends values on each set
+func isRepeatableFlag(f *flag.Flag) bool {
+	if f == nil {
+		return false
+	}
+	switch f.Value.(type) {
+	case *HeaderFlag, *RegexpSliceFlag, *MapFlag:
+		return true
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	var keys = make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseTOML parses TOML into nested map
+func parseTOML(r io.Reader) (map[string]interface{}, error) {
+	tree, err := toml.LoadReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return tree.ToMap(), nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/storage/filestorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func assertConfigFile(t *testing.T, app *imagor.Imagor) {
+	assert.True(t, app.Debug)
+	assert.True(t, app.Unsafe)
+	assert.Equal(t, time.Second*10, app.RequestTimeout)
+	assert.Equal(t, map[string]string{
+		"thumb": "fit-in/100x100",
+		"cover": "1200x630/smart",
+	}, app.Presets)
+	assert.Equal(t, map[string]string{"backend": "k3y,1"}, app.APIKeys)
+	assert.Equal(t, "nosniff", app.ResponseHeaders.Get("X-Content-Type-Options"))
+	assert.Equal(t, "default-src 'none'", app.ResponseHeaders.Get("Content-Security-Policy"))
+	assert.Empty(t, app.Loaders)
+	require.Len(t, app.Storages, 1)
+	assert.Equal(t, "./foo", app.Storages[0].(*filestorage.FileStorage).BaseDir)
+	assert.Equal(t, "/abcd/", app.Storages[0].(*filestorage.FileStorage).PathPrefix)
+}
+
+func TestConfigFileYAML(t *testing.T) {
+	path := writeConfigFile(t, "imagor.yml", `
+debug: true
+imagor:
+  unsafe: true
+  request_timeout: 10s
+  presets:
+    thumb: fit-in/100x100
+    cover: 1200x630/smart
+  api-keys:
+    backend: "k3y,1"
+  response-header:
+    X-Content-Type-Options: nosniff
+    Content-Security-Policy: default-src 'none'
+http-loader:
+  disable: true
+file:
+  storage:
+    base-dir: ./foo
+    path-prefix: abcd
+`)
+	srv := CreateServer([]string{"-config", path})
+	assertConfigFile(t, srv.App.(*imagor.Imagor))
+}
+
+func TestConfigFileTOML(t *testing.T) {
+	path := writeConfigFile(t, "imagor.toml", `
+debug = true # debug mode
+
+[imagor]
+unsafe = true
+request-timeout = "10s"
+response-header = [
+  "X-Content-Type-Options: nosniff",
+  "Content-Security-Policy: default-src 'none'",
+]
+
+[imagor.presets]
+thumb = "fit-in/100x100"
+cover = '1200x630/smart'
+
+[imagor.api-keys]
+backend = "k3y,1"
+
+[http-loader]
+disable = true
+
+[file]
+storage.base-dir = "./foo"
+storage.path-prefix = "abcd"
+`)
+	srv := CreateServer([]string{"-config", path})
+	assertConfigFile(t, srv.App.(*imagor.Imagor))
+}
+
+func TestConfigFileTOMLSyntax(t *testing.T) {
+	path := writeConfigFile(t, "imagor.toml", `
+debug = true
+
+[imagor]
+unsafe = true
+request-timeout = "10s"
+presets = { thumb = "fit-in/100x100", cover = '1200x630/smart' }
+
+[imagor.api-keys]
+backend = """
+k3y,1"""
+
+[imagor.response-header]
+X-Content-Type-Options = "nosniff"
+Content-Security-Policy = "default-src 'none'"
+
+[http-loader]
+disable = true
+
+[file.storage]
+base-dir = "./foo"
+path-prefix = "abcd"
+`)
+	srv := CreateServer([]string{"-config", path})
+	assertConfigFile(t, srv.App.(*imagor.Imagor))
+
+	for name, content := range map[string]string{
+		"unquoted":        "[imagor]\nprocess-queue-size = ten\n",
+		"array of tables": "[[imagor.presets]]\nthumb = \"fit-in/100x100\"\n",
+		"duplicated key":  "debug = true\ndebug = false\n",
+	} {
+		path := writeConfigFile(t, "imagor.toml", content)
+		assert.Panics(t, func() {
+			CreateServer([]string{"-config", path})
+		}, name)
+	}
+}
+
+func TestConfigFileUnknownField(t *testing.T) {
+	for name, content := range map[string]string{
+		"imagor.yml":  "imagor:\n  unsafe: true\n  unknown: 1\n",
+		"imagor.toml": "[imagor]\nunsafe = true\nunknown = 1\n",
+	} {
+		path := writeConfigFile(t, name, content)
+		func() {
+			defer func() {
+				assert.Contains(t, fmt.Sprint(recover()), `unknown config field "imagor-unknown"`, name)
+			}()
+			CreateServer([]string{"-config", path})
+		}()
+	}
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"flag"
+	"github.com/cshum/imagor"
+	"github.com/peterbourgon/ff/v3"
+	"go.uber.org/zap"
+	"io"
+)
+
+// NewImagorFromEnv creates imagor with loaders, storages and processors of funcs
+// configured entirely by environment variables e.g. IMAGOR_SECRET, FILE_LOADER_BASE_DIR,
+// for embedding imagor in container deployments without command-line arguments
+func NewImagorFromEnv(funcs ...Func) (app *imagor.Imagor, err error) {
+	var (
+		fs    = flag.NewFlagSet("imagor", flag.ContinueOnError)
+		debug = fs.Bool("debug", false, "Debug mode")
+	)
+	fs.SetOutput(io.Discard)
+	app = NewImagor(fs, func() (*zap.Logger, bool) {
+		var readSecretFiles = withSecretFiles(fs)
+		if err = ff.Parse(fs, nil, ff.WithEnvVars()); err == nil {
+			err = readSecretFiles()
+		}
+		var logger *zap.Logger
+		if *debug {
+			logger = zap.Must(zap.NewDevelopment())
+		} else {
+			logger = zap.Must(zap.NewProduction())
+		}
+		return logger, *debug
+	}, funcs...)
+	if err != nil {
+		return nil, err
+	}
+	return app, nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/cshum/imagor/storage/filestorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewImagorFromEnv(t *testing.T) {
+	t.Setenv("IMAGOR_SECRET", "1234")
+	t.Setenv("IMAGOR_UNSAFE", "1")
+	t.Setenv("IMAGOR_REQUEST_TIMEOUT", "10s")
+	t.Setenv("IMAGOR_LOAD_TIMEOUT", "5s")
+	t.Setenv("IMAGOR_CACHE_SIZE", "1000")
+	t.Setenv("HTTP_LOADER_DISABLE", "1")
+	t.Setenv("FILE_LOADER_BASE_DIR", "./foo")
+	t.Setenv("FILE_RESULT_STORAGE_BASE_DIR", "./bar")
+	t.Setenv("FILE_RESULT_STORAGE_PATH_PREFIX", "abcd")
+
+	app, err := NewImagorFromEnv()
+	require.NoError(t, err)
+	assert.False(t, app.Debug)
+	assert.True(t, app.Unsafe)
+	assert.Equal(t, imagorpath.NewDefaultSigner("1234").Sign("foo"), app.Signer.Sign("foo"))
+	assert.Equal(t, time.Second*10, app.RequestTimeout)
+	assert.Equal(t, time.Second*5, app.LoadTimeout)
+	assert.NotNil(t, app.Cache)
+	require.Len(t, app.Loaders, 1)
+	assert.Equal(t, "./foo", app.Loaders[0].(*filestorage.FileStorage).BaseDir)
+	require.Len(t, app.ResultStorages, 1)
+	resultStorage := app.ResultStorages[0].(*filestorage.FileStorage)
+	assert.Equal(t, "./bar", resultStorage.BaseDir)
+	assert.Equal(t, "/abcd/", resultStorage.PathPrefix)
+
+	t.Setenv("IMAGOR_REQUEST_TIMEOUT", "abc")
+	_, err = NewImagorFromEnv()
+	assert.Error(t, err)
+
+	secretFile := filepath.Join(t.TempDir(), "imagor_secret")
+	require.NoError(t, os.WriteFile(secretFile, []byte("s3cret\n"), 0600))
+	t.Setenv("IMAGOR_REQUEST_TIMEOUT", "10s")
+	t.Setenv("IMAGOR_SECRET_FILE", secretFile)
+	app, err = NewImagorFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, imagorpath.NewDefaultSigner("s3cret").Sign("foo"), app.Signer.Sign("foo"))
+
+	t.Setenv("IMAGOR_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = NewImagorFromEnv()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
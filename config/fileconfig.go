@@ -5,6 +5,8 @@ import (
 	"github.com/cshum/imagor"
 	"github.com/cshum/imagor/storage/filestorage"
 	"go.uber.org/zap"
+	"regexp"
+	"strings"
 )
 
 func withFileSystem(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Option {
@@ -15,6 +17,8 @@ func withFileSystem(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 			"Base directory for File Loader. Enable File Loader only if this value present")
 		fileLoaderPathPrefix = fs.String("file-loader-path-prefix", "",
 			"Base path prefix for File Loader")
+		fileLoaderWatchInterval = fs.Duration("file-loader-watch-interval", 0,
+			"File Loader polls base directory for changed files at interval e.g. 10s, evicting cached results of changed images. 0 means disabled")
 
 		fileStorageBaseDir = fs.String("file-storage-base-dir", "",
 			"Base directory for File Storage. Enable File Storage only if this value present")
@@ -26,6 +30,14 @@ func withFileSystem(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 			"File Storage write permission")
 		fileStorageExpiration = fs.Duration("file-storage-expiration", 0,
 			"File Storage expiration duration e.g. 24h. Default no expiration")
+		fileStorageFsync = fs.Bool("file-storage-fsync", false,
+			"File Storage fsync saved file to disk before save completes")
+		fileStorageFsyncDir = fs.Bool("file-storage-fsync-dir", false,
+			"File Storage fsync parent directory of saved file to disk before save completes")
+		fileStorageFsyncAsync = fs.Bool("file-storage-fsync-async", false,
+			"File Storage fsync in background after save completes instead of write-through, with pending fsync waited on shutdown")
+		fileStorageDedup = fs.Bool("file-storage-dedup", false,
+			"File Storage saves identical images once, with image files hard linked to content by hash")
 
 		fileResultStorageBaseDir = fs.String("file-result-storage-base-dir", "",
 			"Base directory for File Result Storage. Enable File Result Storage only if this value present")
@@ -37,9 +49,31 @@ func withFileSystem(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 			"File Storage write permission")
 		fileResultStorageExpiration = fs.Duration("file-result-storage-expiration", 0,
 			"File Result Storage expiration duration e.g. 24h. Default no expiration")
+		fileResultStorageFsync = fs.Bool("file-result-storage-fsync", false,
+			"File Result Storage fsync saved file to disk before save completes")
+		fileResultStorageFsyncDir = fs.Bool("file-result-storage-fsync-dir", false,
+			"File Result Storage fsync parent directory of saved file to disk before save completes")
+		fileResultStorageFsyncAsync = fs.Bool("file-result-storage-fsync-async", false,
+			"File Result Storage fsync in background after save completes instead of write-through, with pending fsync waited on shutdown")
 
-		_, _ = cb()
+		fileAllowedExtensions = fs.String("file-allowed-extensions", "",
+			"File Loader and Storage allow only image keys of file extensions by csv e.g. .jpg,.png,.webp. Default allow all")
+		fileRejectUnsafeChars = fs.Bool("file-reject-unsafe-chars", false,
+			"File Loader and Storage reject image keys with control characters or backslash instead of escaping them")
+		fileMaxPathLength = fs.Int("file-max-path-length", 0,
+			"File Loader and Storage reject image keys longer than the max length after escape. 0 means no limit")
+		fileDenySymlinkEscape = fs.Bool("file-deny-symlink-escape", false,
+			"File Loader and Storage reject image keys resolving outside of base directory by symlinks")
+
+		fileBlacklists []*regexp.Regexp
 	)
+	fs.Var((*RegexpSliceFlag)(&fileBlacklists), "file-blacklist",
+		"File Loader and Storage reject image keys matching the regular expressions by csv in addition to dot files e.g. \\.exe$,^private/. Can be repeated")
+	_, _ = cb()
+	var allowedExtensions []string
+	if *fileAllowedExtensions != "" {
+		allowedExtensions = strings.Split(*fileAllowedExtensions, ",")
+	}
 	return func(o *imagor.Imagor) {
 		if *fileStorageBaseDir != "" {
 			// activate File Storage only if base dir config presents
@@ -51,6 +85,15 @@ func withFileSystem(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 					filestorage.WithWritePermission(*fileStorageWritePermission),
 					filestorage.WithSafeChars(*fileSafeChars),
 					filestorage.WithExpiration(*fileStorageExpiration),
+					filestorage.WithFsync(*fileStorageFsync),
+					filestorage.WithFsyncDir(*fileStorageFsyncDir),
+					filestorage.WithAsyncFsync(*fileStorageFsyncAsync),
+					filestorage.WithDedup(*fileStorageDedup),
+					filestorage.WithBlacklist(fileBlacklists...),
+					filestorage.WithAllowedExtensions(allowedExtensions...),
+					filestorage.WithRejectUnsafeChars(*fileRejectUnsafeChars),
+					filestorage.WithMaxPathLength(*fileMaxPathLength),
+					filestorage.WithDenySymlinkEscape(*fileDenySymlinkEscape),
 				),
 			)
 		}
@@ -60,7 +103,13 @@ func withFileSystem(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 				filestorage.New(
 					*fileLoaderBaseDir,
 					filestorage.WithPathPrefix(*fileLoaderPathPrefix),
+					filestorage.WithWatchInterval(*fileLoaderWatchInterval),
 					filestorage.WithSafeChars(*fileSafeChars),
+					filestorage.WithBlacklist(fileBlacklists...),
+					filestorage.WithAllowedExtensions(allowedExtensions...),
+					filestorage.WithRejectUnsafeChars(*fileRejectUnsafeChars),
+					filestorage.WithMaxPathLength(*fileMaxPathLength),
+					filestorage.WithDenySymlinkEscape(*fileDenySymlinkEscape),
 				),
 			)
 		}
@@ -74,6 +123,13 @@ func withFileSystem(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 					filestorage.WithWritePermission(*fileResultStorageWritePermission),
 					filestorage.WithSafeChars(*fileSafeChars),
 					filestorage.WithExpiration(*fileResultStorageExpiration),
+					filestorage.WithFsync(*fileResultStorageFsync),
+					filestorage.WithFsyncDir(*fileResultStorageFsyncDir),
+					filestorage.WithAsyncFsync(*fileResultStorageFsyncAsync),
+					filestorage.WithBlacklist(fileBlacklists...),
+					filestorage.WithRejectUnsafeChars(*fileRejectUnsafeChars),
+					filestorage.WithMaxPathLength(*fileMaxPathLength),
+					filestorage.WithDenySymlinkEscape(*fileDenySymlinkEscape),
 				),
 			)
 		}
@@ -1,7 +1,13 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -32,3 +38,188 @@ func (s *CIDRSliceFlag) Set(value string) error {
 func (c *CIDRSliceFlag) Get() any {
 	return c
 }
+
+// RegexpSliceFlag is a flag type of regular expressions by csv, which can be repeated.
+// Comma within brackets, braces or parentheses, or escaped by backslash, is not a separator.
+type RegexpSliceFlag []*regexp.Regexp
+
+func (s *RegexpSliceFlag) String() string {
+	var ss []string
+	for _, v := range *s {
+		ss = append(ss, v.String())
+	}
+	return strings.Join(ss, ",")
+}
+
+func (s *RegexpSliceFlag) Set(value string) error {
+	for _, v := range splitRegexpCSV(value) {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return err
+		}
+		*s = append(*s, re)
+	}
+	return nil
+}
+
+func (s *RegexpSliceFlag) Get() any {
+	return s
+}
+
+// splitRegexpCSV splits regular expressions by comma outside of groups
+func splitRegexpCSV(value string) (res []string) {
+	var depth, start int
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				res = append(res, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(res, value[start:])
+}
+
+// MapFlag is a flag type of key=value pairs by csv e.g. key=value,key2=value2, which can be repeated.
+// Comma and backslash in keys and values can be escaped by backslash. Pairs without key are skipped.
+type MapFlag map[string]string
+
+func (m *MapFlag) String() string {
+	var keys = make([]string, 0, len(*m))
+	for key := range *m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var ss = make([]string, 0, len(keys))
+	for _, key := range keys {
+		ss = append(ss, escapeMapValue(key)+"="+escapeMapValue((*m)[key]))
+	}
+	return strings.Join(ss, ",")
+}
+
+func (m *MapFlag) Set(value string) error {
+	for _, pair := range splitEscapedCSV(value) {
+		key, v, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			continue
+		}
+		if *m == nil {
+			*m = MapFlag{}
+		}
+		(*m)[key] = strings.TrimSpace(v)
+	}
+	return nil
+}
+
+func (m *MapFlag) Get() any {
+	return m
+}
+
+func escapeMapValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`).Replace(s)
+}
+
+// splitEscapedCSV splits value by comma, unescaping comma and backslash escaped by backslash
+func splitEscapedCSV(value string) (res []string) {
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\' && i+1 < len(value) && (value[i+1] == ',' || value[i+1] == '\\'):
+			i++
+			sb.WriteByte(value[i])
+		case c == ',':
+			res = append(res, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return append(res, sb.String())
+}
+
+// HeaderFlag is a flag type of HTTP headers in format of Name: value, which can be repeated.
+type HeaderFlag http.Header
+
+func (h *HeaderFlag) String() string {
+	var ss []string
+	for key, values := range *h {
+		for _, v := range values {
+			ss = append(ss, key+": "+v)
+		}
+	}
+	return strings.Join(ss, "\n")
+}
+
+func (h *HeaderFlag) Set(value string) error {
+	key, v, ok := strings.Cut(value, ":")
+	if key = strings.TrimSpace(key); !ok || key == "" {
+		return fmt.Errorf("invalid header %q", value)
+	}
+	if *h == nil {
+		*h = HeaderFlag{}
+	}
+	http.Header(*h).Add(key, strings.TrimSpace(v))
+	return nil
+}
+
+func (h *HeaderFlag) Get() any {
+	return h
+}
+
+// sizeUnits byte size units in multiples of 1024
+var sizeUnits = map[string]float64{
+	"":  1,
+	"b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+}
+
+// SizeFlag is a flag type of byte size in bytes or with unit e.g. 64MB, 1.5GiB,
+// where KB, MB, GB, TB are in multiples of 1024 same as KiB, MiB, GiB, TiB.
+type SizeFlag int64
+
+func (s *SizeFlag) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+func (s *SizeFlag) Set(value string) error {
+	value = strings.TrimSpace(value)
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		i = len(value)
+	}
+	num, err := strconv.ParseFloat(value[:i], 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %q", value)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(value[i:]))]
+	if !ok {
+		return fmt.Errorf("invalid size unit %q", value)
+	}
+	size := num * unit
+	if size > math.MaxInt64 {
+		return fmt.Errorf("size %q out of range", value)
+	}
+	*s = SizeFlag(size)
+	return nil
+}
+
+func (s *SizeFlag) Get() any {
+	return s
+}
@@ -21,3 +21,65 @@ func TestCIDRSliceFlag(t *testing.T) {
 		assert.Error(t, f.Set(input))
 	})
 }
+
+func TestSizeFlag(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"1024", 1024},
+		{"512B", 512},
+		{"64KB", 64 << 10},
+		{"64MB", 64 << 20},
+		{"64mb", 64 << 20},
+		{"1.5GiB", 3 << 29},
+		{"2 G", 2 << 30},
+		{"1TB", 1 << 40},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var f SizeFlag
+			assert.NoError(t, f.Set(tt.input))
+			assert.Equal(t, tt.expected, int64(f))
+			assert.Equal(t, &f, f.Get())
+		})
+	}
+	t.Run("parse error", func(t *testing.T) {
+		var f SizeFlag
+		assert.Error(t, f.Set(""))
+		assert.Error(t, f.Set("MB"))
+		assert.Error(t, f.Set("64XB"))
+		assert.Error(t, f.Set("-1MB"))
+	})
+}
+
+func TestRegexpSliceFlag(t *testing.T) {
+	t.Run("set and get", func(t *testing.T) {
+		var f RegexpSliceFlag
+		assert.NoError(t, f.Set(`\.exe$,^[a-z]{1,3}/,a\,b`))
+		assert.NoError(t, f.Set("(foo|bar),"))
+		assert.Equal(t, `\.exe$,^[a-z]{1,3}/,a\,b,(foo|bar)`, f.String())
+		assert.Equal(t, &f, f.Get())
+		assert.True(t, f[1].MatchString("abc/d.jpg"))
+		assert.False(t, f[1].MatchString("abcd/d.jpg"))
+		assert.True(t, f[2].MatchString("a,b"))
+	})
+	t.Run("parse error", func(t *testing.T) {
+		var f RegexpSliceFlag
+		assert.Error(t, f.Set("abc,[a-z"))
+	})
+}
+
+func TestMapFlag(t *testing.T) {
+	var f MapFlag
+	assert.NoError(t, f.Set(`a=1, b = 2,invalid,=3`))
+	assert.NoError(t, f.Set(`c=x\,y\\z,a=4`))
+	assert.Equal(t, MapFlag{"a": "4", "b": "2", "c": `x,y\z`}, f)
+	assert.Equal(t, `a=4,b=2,c=x\,y\\z`, f.String())
+	assert.Equal(t, &f, f.Get())
+
+	var f2 MapFlag
+	assert.NoError(t, f2.Set(f.String()))
+	assert.Equal(t, f, f2)
+}
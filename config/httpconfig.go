@@ -17,10 +17,10 @@ func withHTTPLoader(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 			"Forward browser client request headers to HTTP Loader request")
 		httpLoaderForwardAllHeaders = fs.Bool("http-loader-forward-all-headers", false,
 			"Deprecated in flavour of -http-loader-forward-client-headers")
+		httpLoaderForwardTraceHeaders = fs.Bool("http-loader-forward-trace-headers", false,
+			"Forward traceparent, tracestate, X-Request-Id and X-Correlation-Id request headers to HTTP Loader request, for correlating origin access logs")
 		httpLoaderAllowedSources = fs.String("http-loader-allowed-sources", "",
 			"HTTP Loader allowed hosts whitelist to load images from if set. Accept csv wth glob pattern e.g. *.google.com,*.github.com.")
-		httpLoaderMaxAllowedSize = fs.Int("http-loader-max-allowed-size", 0,
-			"HTTP Loader maximum allowed size in bytes for loading images if set")
 		httpLoaderInsecureSkipVerifyTransport = fs.Bool("http-loader-insecure-skip-verify-transport", false,
 			"HTTP Loader to use HTTP transport with InsecureSkipVerify true")
 		httpLoaderDefaultScheme = fs.String("http-loader-default-scheme", "https",
@@ -37,10 +37,16 @@ func withHTTPLoader(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 			"HTTP Loader rejects connections to private network IP addresses.")
 		httpLoaderBlockLinkLocalNetworks = fs.Bool("http-loader-block-link-local-networks", false,
 			"HTTP Loader rejects connections to link local network IP addresses.")
-		httpLoaderBlockNetworks []*net.IPNet
-		httpLoaderDisable       = fs.Bool("http-loader-disable", false,
+		httpLoaderBlockNetworks   []*net.IPNet
+		httpLoaderMaxAllowedSize  int64
+		httpLoaderOverrideHeaders map[string]string
+		httpLoaderDisable         = fs.Bool("http-loader-disable", false,
 			"Disable HTTP Loader")
 	)
+	fs.Var((*SizeFlag)(&httpLoaderMaxAllowedSize), "http-loader-max-allowed-size",
+		"HTTP Loader maximum allowed size for loading images if set, in bytes or with unit e.g. 20MB")
+	fs.Var((*MapFlag)(&httpLoaderOverrideHeaders), "http-loader-override-headers",
+		"HTTP Loader override request headers in format of name=value by csv e.g. User-Agent=imagor,X-Api-Key=s3cret. Can be repeated")
 	fs.Var((*CIDRSliceFlag)(&httpLoaderBlockNetworks), "http-loader-block-networks",
 		"HTTP Loader rejects connections to link local network IP addresses. This options takes a comma separated list of networks in CIDR notation e.g. ::1/128,127.0.0.0/8.")
 	_, _ = cb()
@@ -53,8 +59,10 @@ func withHTTPLoader(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Opti
 						*httpLoaderForwardClientHeaders || *httpLoaderForwardAllHeaders),
 					httploader.WithAccept(*httpLoaderAccept),
 					httploader.WithForwardHeaders(*httpLoaderForwardHeaders),
+					httploader.WithForwardTraceHeaders(*httpLoaderForwardTraceHeaders),
+					httploader.WithOverrideHeaders(httpLoaderOverrideHeaders),
 					httploader.WithAllowedSources(*httpLoaderAllowedSources),
-					httploader.WithMaxAllowedSize(*httpLoaderMaxAllowedSize),
+					httploader.WithMaxAllowedSize(int(httpLoaderMaxAllowedSize)),
 					httploader.WithInsecureSkipVerifyTransport(*httpLoaderInsecureSkipVerifyTransport),
 					httploader.WithDefaultScheme(*httpLoaderDefaultScheme),
 					httploader.WithProxyTransport(*httpLoaderProxyURLs, *httpLoaderProxyAllowedSources),
@@ -0,0 +1,64 @@
+package otelconfig
+
+import (
+	"context"
+	"flag"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/tracer/oteltracer"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.uber.org/zap"
+	"net/url"
+)
+
+func WithOTel(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Option {
+	var (
+		otelTracing = fs.Bool("otel-tracing", false,
+			"Enable OpenTelemetry tracing of requests, loads, processing and saves, exported by OTLP over HTTP with W3C trace context propagation")
+		otelServiceName = fs.String("otel-service-name", "imagor",
+			"OpenTelemetry service name of exported spans")
+		otelExporterEndpoint = fs.String("otel-exporter-otlp-endpoint", "",
+			"OTLP HTTP endpoint of exported spans e.g. https://collector:4318, or host:port. Plain HTTP if of http:// scheme. Default localhost:4318")
+		otelExporterInsecure = fs.Bool("otel-exporter-otlp-insecure", false,
+			"Export spans to OTLP endpoint over plain HTTP instead of HTTPS")
+		otelSampleRatio = fs.Float64("otel-sample-ratio", 1,
+			"OpenTelemetry ratio of traces sampled from 0 to 1, unless sampled by parent trace context")
+
+		_, _ = cb()
+	)
+	return func(app *imagor.Imagor) {
+		if !*otelTracing {
+			return
+		}
+		var options []otlptracehttp.Option
+		var insecure = *otelExporterInsecure
+		if endpoint := *otelExporterEndpoint; endpoint != "" {
+			// endpoint of OTEL_EXPORTER_OTLP_ENDPOINT format is URL
+			if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+				endpoint = u.Host
+				insecure = insecure || u.Scheme == "http"
+			}
+			options = append(options, otlptracehttp.WithEndpoint(endpoint))
+		}
+		if insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+		// exporter connects on export, so that imagor starts regardless of collector availability
+		exporter, err := otlptracehttp.New(context.Background(), options...)
+		if err != nil {
+			panic(err)
+		}
+		provider := sdktrace.NewTracerProvider(
+			sdktrace.WithBatcher(exporter),
+			sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(*otelSampleRatio))),
+			sdktrace.WithResource(resource.NewWithAttributes(
+				semconv.SchemaURL,
+				semconv.ServiceNameKey.String(*otelServiceName),
+				semconv.ServiceVersionKey.String(imagor.Version),
+			)),
+		)
+		imagor.WithTracer(oteltracer.New(provider))(app)
+	}
+}
@@ -0,0 +1,29 @@
+package otelconfig
+
+import (
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/config"
+	"github.com/cshum/imagor/tracer/oteltracer"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"testing"
+)
+
+func TestOTelDisabled(t *testing.T) {
+	srv := config.CreateServer([]string{}, WithOTel)
+	app := srv.App.(*imagor.Imagor)
+	assert.Nil(t, app.Tracer)
+}
+
+func TestOTelTracing(t *testing.T) {
+	srv := config.CreateServer([]string{
+		"-otel-tracing",
+		"-otel-service-name", "foo",
+		"-otel-exporter-otlp-endpoint", "localhost:4318",
+		"-otel-exporter-otlp-insecure",
+		"-otel-sample-ratio", "0.5",
+	}, WithOTel)
+	app := srv.App.(*imagor.Imagor)
+	tracer := app.Tracer.(*oteltracer.Tracer)
+	assert.IsType(t, &sdktrace.TracerProvider{}, tracer.Provider)
+}
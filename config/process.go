@@ -0,0 +1,165 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// runProcess processes local files of paths, directories or globs by params with the configured processors,
+// writing results to output directory without starting the server
+func runProcess(args []string, w io.Writer, funcs ...Func) error {
+	fs := flag.NewFlagSet("imagor process", flag.ExitOnError)
+	var (
+		params = fs.String("process-params", "",
+			"imagor params or preset name applied to each file e.g. fit-in/200x200/filters:format(webp)")
+		outputDir = fs.String("process-output-dir", "",
+			"Output directory of processed files, named by the source file with extension of the result format")
+		workers = fs.Int("process-workers", runtime.NumCPU(),
+			"Number of files processed in parallel")
+	)
+	srv := createServer(fs, args, funcs...)
+	if srv == nil {
+		return nil
+	}
+	app := srv.App.(*imagor.Imagor)
+	if *workers < 1 {
+		*workers = 1
+	}
+	if fs.NArg() == 0 || *outputDir == "" {
+		return errors.New("usage: imagor process -process-output-dir <dir> [flags] <file, directory or glob>...")
+	}
+	files, err := expandFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	spec := *params
+	if preset, ok := app.Presets[spec]; ok {
+		spec = preset
+	}
+	spec = strings.Trim(spec, "/")
+	if spec != "" {
+		spec += "/"
+	}
+	// placeholder image, source is the file submitted
+	p := imagorpath.Parse(spec + "image")
+	if err = os.MkdirAll(*outputDir, 0755); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err = app.Startup(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		_ = app.Shutdown(ctx)
+	}()
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		sema   = make(chan struct{}, *workers)
+		failed int
+		first  error
+	)
+	for _, file := range files {
+		wg.Add(1)
+		sema <- struct{}{}
+		go func(file string) {
+			defer func() {
+				<-sema
+				wg.Done()
+			}()
+			out, err := processFile(ctx, app, file, p, *outputDir)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				if first == nil {
+					first = fmt.Errorf("%s: %w", file, err)
+				}
+				_, _ = fmt.Fprintf(w, "%s: %s\n", file, err)
+				return
+			}
+			_, _ = fmt.Fprintf(w, "%s -> %s\n", file, out)
+		}(file)
+	}
+	wg.Wait()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed, %w", failed, len(files), first)
+	}
+	return nil
+}
+
+// processFile processes file by params, writes result to output directory and returns its path
+func processFile(ctx context.Context, app *imagor.Imagor, file string, p imagorpath.Params, outputDir string) (string, error) {
+	res, err := app.Process(ctx, imagor.ProcessRequest{
+		Source: imagor.NewBlobFromFile(file),
+		Params: p,
+	})
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Base(file)
+	if ext := res.Blob.Extension(); ext != "" {
+		name = strings.TrimSuffix(name, filepath.Ext(name)) + ext
+	}
+	out := filepath.Join(outputDir, name)
+	if abs, _ := filepath.Abs(out); abs != "" {
+		if src, _ := filepath.Abs(file); src == abs {
+			return "", fmt.Errorf("output %s overwrites source", out)
+		}
+	}
+	reader, _, err := res.Blob.NewReader()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	f, err := os.Create(out)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(f, reader); err != nil {
+		_ = f.Close()
+		return "", err
+	}
+	return out, f.Close()
+}
+
+// expandFiles returns sorted regular files of paths, files of directories and matches of globs
+func expandFiles(patterns []string) (files []string, err error) {
+	var seen = map[string]bool{}
+	for _, pattern := range patterns {
+		if info, e := os.Stat(pattern); e == nil && info.IsDir() {
+			pattern = filepath.Join(pattern, "*")
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", pattern, err)
+		}
+		var n int
+		for _, match := range matches {
+			if info, e := os.Stat(match); e != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			n++
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("%s: no files matched", pattern)
+		}
+	}
+	sort.Strings(files)
+	return
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// jsonProcessor responds params as metadata JSON, fails on source of "fail"
+type jsonProcessor struct {
+	started int32
+}
+
+func (p *jsonProcessor) Startup(context.Context) error {
+	atomic.AddInt32(&p.started, 1)
+	return nil
+}
+
+func (p *jsonProcessor) Process(_ context.Context, blob *imagor.Blob, params imagorpath.Params, _ imagor.LoadFunc) (*imagor.Blob, error) {
+	buf, _ := blob.ReadAll()
+	if string(buf) == "fail" {
+		return nil, errors.New("cannot process")
+	}
+	return imagor.NewBlobFromJsonMarshal(map[string]string{"path": params.Path, "source": string(buf)}), nil
+}
+
+func (p *jsonProcessor) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestRunProcess(t *testing.T) {
+	processor := &jsonProcessor{}
+	withProcessor := func(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Option {
+		_, _ = cb()
+		return imagor.WithProcessors(processor)
+	}
+	src := t.TempDir()
+	out := filepath.Join(t.TempDir(), "out")
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a.jpg"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "b.png"), []byte("b"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "c.txt"), []byte("c"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(src, "dir"), 0755))
+
+	var w strings.Builder
+	ok, err := RunCommand([]string{
+		"process", "-imagor-presets", "thumb=fit-in/100x100", "-process-params", "thumb",
+		"-process-output-dir", out, "-process-workers", "2",
+		filepath.Join(src, "*.jpg"), filepath.Join(src, "*.png"), filepath.Join(src, "a.jpg"),
+	}, &w, withProcessor)
+	assert.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&processor.started))
+	buf, err := os.ReadFile(filepath.Join(out, "a.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path":"fit-in/100x100/image","source":"a"}`, string(buf))
+	buf, err = os.ReadFile(filepath.Join(out, "b.json"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path":"fit-in/100x100/image","source":"b"}`, string(buf))
+	assert.Len(t, strings.Split(strings.TrimSpace(w.String()), "\n"), 2)
+	assert.Contains(t, w.String(), filepath.Join(src, "a.jpg")+" -> "+filepath.Join(out, "a.json"))
+
+	t.Run("directory without processors", func(t *testing.T) {
+		var w strings.Builder
+		out := t.TempDir()
+		_, err := RunCommand([]string{"process", "-process-output-dir", out, src}, &w)
+		require.NoError(t, err)
+		buf, err := os.ReadFile(filepath.Join(out, "c.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "c", string(buf))
+		assert.Len(t, strings.Split(strings.TrimSpace(w.String()), "\n"), 3)
+	})
+	t.Run("errors", func(t *testing.T) {
+		_, err := RunCommand([]string{"process", filepath.Join(src, "*.jpg")}, &strings.Builder{})
+		assert.ErrorContains(t, err, "usage")
+		_, err = RunCommand([]string{"process", "-process-output-dir", out, filepath.Join(src, "*.gif")}, &strings.Builder{})
+		assert.ErrorContains(t, err, "no files matched")
+		_, err = RunCommand([]string{"process", "-process-output-dir", src, filepath.Join(src, "c.txt")}, &strings.Builder{})
+		assert.ErrorContains(t, err, "overwrites source")
+
+		require.NoError(t, os.WriteFile(filepath.Join(src, "fail.jpg"), []byte("fail"), 0644))
+		var w strings.Builder
+		_, err = RunCommand([]string{
+			"process", "-process-output-dir", out, filepath.Join(src, "*.jpg"),
+		}, &w, withProcessor)
+		assert.ErrorContains(t, err, "1 of 2 files failed")
+		assert.Contains(t, w.String(), "cannot process")
+	})
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretFlags flags of sensitive settings that can be read from file
+// by the <name>-file flag e.g. IMAGOR_SECRET_FILE for Docker and Kubernetes secrets
+var secretFlags = []string{
+	"imagor-secret",
+	"imagor-previous-secrets",
+	"imagor-encryption-key",
+	"imagor-api-keys",
+	"imagor-purge-secret",
+	"imagor-warm-secret",
+	"imagor-srcset-secret",
+	"imagor-stats-secret",
+	"imagor-upload-secret",
+	"imagor-webhook-secret",
+	"imagor-sentry-dsn",
+	"redis-cache-url",
+	"server-admin-secret",
+	"server-grpc-secret",
+	"cloudflare-api-token",
+	"fastly-api-key",
+	"http-loader-proxy-urls",
+	"aws-access-key-id",
+	"aws-secret-access-key",
+	"aws-loader-access-key-id",
+	"aws-loader-secret-access-key",
+	"aws-storage-access-key-id",
+	"aws-storage-secret-access-key",
+	"aws-result-storage-access-key-id",
+	"aws-result-storage-secret-access-key",
+}
+
+// withSecretFiles registers <name>-file flags of the secret flags defined in flagset,
+// returns func to be called after parse that sets secret flags by trimmed file contents
+func withSecretFiles(fs *flag.FlagSet) func() error {
+	var files = map[string]*string{}
+	for _, name := range secretFlags {
+		if fs.Lookup(name) != nil && fs.Lookup(name+"-file") == nil {
+			files[name] = fs.String(name+"-file", "",
+				fmt.Sprintf("Read %s from the given file e.g. Docker or Kubernetes secrets", name))
+		}
+	}
+	return func() error {
+		for _, name := range secretFlags {
+			file, ok := files[name]
+			if !ok || *file == "" {
+				continue
+			}
+			content, err := os.ReadFile(*file)
+			if err != nil {
+				return fmt.Errorf("%s-file: %w", name, err)
+			}
+			if err := fs.Set(name, strings.TrimSpace(string(content))); err != nil {
+				return fmt.Errorf("%s-file: %w", name, err)
+			}
+		}
+		return nil
+	}
+}
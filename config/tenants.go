@@ -0,0 +1,52 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"os"
+)
+
+// tenantConfig tenant entry of tenants file
+type tenantConfig struct {
+	Name           string   `json:"name"`
+	Hosts          []string `json:"hosts"`
+	PathPrefix     string   `json:"path_prefix"`
+	Secret         string   `json:"secret"`
+	AllowedSources []string `json:"allowed_sources"`
+	StoragePrefix  string   `json:"storage_prefix"`
+	BaseParams     string   `json:"base_params"`
+}
+
+// readTenants reads tenants from JSON file of tenant config array,
+// with tenant secret signed by signer of the configured signer type
+func readTenants(file string, newSigner func(secret string) imagorpath.Signer) ([]imagor.Tenant, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var configs []tenantConfig
+	if err = json.Unmarshal(buf, &configs); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	var tenants = make([]imagor.Tenant, 0, len(configs))
+	for i, c := range configs {
+		if len(c.Hosts) == 0 && c.PathPrefix == "" {
+			return nil, fmt.Errorf("%s: tenant %d %q requires hosts or path_prefix", file, i, c.Name)
+		}
+		tenant := imagor.Tenant{
+			Name:           c.Name,
+			Hosts:          c.Hosts,
+			PathPrefix:     c.PathPrefix,
+			AllowedSources: c.AllowedSources,
+			StoragePrefix:  c.StoragePrefix,
+			BaseParams:     c.BaseParams,
+		}
+		if c.Secret != "" {
+			tenant.Signer = newSigner(c.Secret)
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
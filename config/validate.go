@@ -0,0 +1,56 @@
+package config
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/cshum/imagor"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// validateTimeout timeout of startup and health checks of validate mode
+const validateTimeout = time.Minute
+
+// exit process exit, replaceable for testing
+var exit = os.Exit
+
+// validate runs startup and health checks of imagor pipeline against its dependencies,
+// and prints effective config to w with secret values masked
+func validate(app *imagor.Imagor, fs *flag.FlagSet, w io.Writer) (err error) {
+	printConfig(fs, w)
+	ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+	defer cancel()
+	if err = app.Startup(ctx); err != nil {
+		return fmt.Errorf("startup: %w", err)
+	}
+	defer func() {
+		if e := app.Shutdown(ctx); e != nil && err == nil {
+			err = fmt.Errorf("shutdown: %w", e)
+		}
+	}()
+	if err = app.Health(ctx); err != nil {
+		return fmt.Errorf("health: %w", err)
+	}
+	return
+}
+
+// printConfig prints non-empty flag values in format of name=value
+func printConfig(fs *flag.FlagSet, w io.Writer) {
+	var secrets = map[string]bool{}
+	for _, name := range secretFlags {
+		secrets[name] = true
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if value == "" || f.Name == "validate" {
+			return
+		}
+		if secrets[f.Name] {
+			value = "******"
+		}
+		_, _ = fmt.Fprintf(w, "%s=%s\n", f.Name, strings.ReplaceAll(value, "\n", `\n`))
+	})
+}
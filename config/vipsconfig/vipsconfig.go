@@ -1,10 +1,13 @@
 package vipsconfig
 
 import (
+	"context"
 	"flag"
 	"github.com/cshum/imagor"
 	"github.com/cshum/imagor/vips"
+	"github.com/cshum/imagor/wasmfilter"
 	"go.uber.org/zap"
+	"strings"
 )
 
 func WithVips(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Option {
@@ -20,11 +23,11 @@ func WithVips(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Option {
 		vipsConcurrency = fs.Int("vips-concurrency", 1,
 			"VIPS concurrency. Set -1 to be the number of CPU cores")
 		vipsMaxCacheFiles = fs.Int("vips-max-cache-files", 0,
-			"VIPS max cache files")
+			"VIPS max number of open files held by operation cache")
 		vipsMaxCacheSize = fs.Int("vips-max-cache-size", 0,
-			"VIPS max cache size")
+			"VIPS max number of operations held by operation cache")
 		vipsMaxCacheMem = fs.Int("vips-max-cache-mem", 0,
-			"VIPS max cache mem")
+			"VIPS max memory in bytes held by operation cache")
 		vipsMaxWidth = fs.Int("vips-max-width", 0,
 			"VIPS max image width")
 		vipsMaxHeight = fs.Int("vips-max-height", 0,
@@ -33,25 +36,55 @@ func WithVips(fs *flag.FlagSet, cb func() (*zap.Logger, bool)) imagor.Option {
 			"VIPS max image resolution")
 		vipsMozJPEG = fs.Bool("vips-mozjpeg", false,
 			"VIPS enable maximum compression with MozJPEG. Requires mozjpeg to be installed")
+		vipsReportLeaks = fs.Bool("vips-report-leaks", false,
+			"VIPS report leaked objects and memory on shutdown, for debugging")
+		vipsCacheTrace = fs.Bool("vips-cache-trace", false,
+			"VIPS log operation cache hits and misses, for debugging")
+		vipsDisableSIMD = fs.Bool("vips-disable-simd", false,
+			"VIPS disable SIMD vector paths of operations")
+		vipsTolerant = fs.Bool("vips-tolerant", false,
+			"VIPS decode truncated or corrupt images best-effort instead of failing, flagged as damaged in metadata")
+		vipsWasmFilterDir = fs.String("vips-wasm-filter-dir", "",
+			"VIPS directory of WebAssembly pixel filter modules *.wasm, applied by filters:wasm(name,args) of file name without extension")
 
 		logger, isDebug = cb()
 	)
-	return imagor.WithProcessors(
-		vips.NewProcessor(
-			vips.WithMaxAnimationFrames(*vipsMaxAnimationFrames),
-			vips.WithDisableBlur(*vipsDisableBlur),
-			vips.WithDisableFilters(*vipsDisableFilters),
-			vips.WithConcurrency(*vipsConcurrency),
-			vips.WithMaxCacheFiles(*vipsMaxCacheFiles),
-			vips.WithMaxCacheMem(*vipsMaxCacheMem),
-			vips.WithMaxCacheSize(*vipsMaxCacheSize),
-			vips.WithMaxFilterOps(*vipsMaxFilterOps),
-			vips.WithMaxWidth(*vipsMaxWidth),
-			vips.WithMaxHeight(*vipsMaxHeight),
-			vips.WithMaxResolution(*vipsMaxResolution),
-			vips.WithMozJPEG(*vipsMozJPEG),
-			vips.WithLogger(logger),
-			vips.WithDebug(isDebug),
-		),
-	)
+	var options = []vips.Option{
+		vips.WithMaxAnimationFrames(*vipsMaxAnimationFrames),
+		vips.WithDisableBlur(*vipsDisableBlur),
+		vips.WithDisableFilters(*vipsDisableFilters),
+		vips.WithConcurrency(*vipsConcurrency),
+		vips.WithMaxCacheFiles(*vipsMaxCacheFiles),
+		vips.WithMaxCacheMem(*vipsMaxCacheMem),
+		vips.WithMaxCacheSize(*vipsMaxCacheSize),
+		vips.WithMaxFilterOps(*vipsMaxFilterOps),
+		vips.WithMaxWidth(*vipsMaxWidth),
+		vips.WithMaxHeight(*vipsMaxHeight),
+		vips.WithMaxResolution(*vipsMaxResolution),
+		vips.WithMozJPEG(*vipsMozJPEG),
+		vips.WithReportLeaks(*vipsReportLeaks),
+		vips.WithCacheTrace(*vipsCacheTrace),
+		vips.WithDisableSIMD(*vipsDisableSIMD),
+		vips.WithTolerant(*vipsTolerant),
+		vips.WithLogger(logger),
+		vips.WithDebug(isDebug),
+	}
+	if *vipsWasmFilterDir != "" {
+		// modules compiled on processor startup, which fails on invalid module
+		rt := wasmfilter.New(*vipsWasmFilterDir)
+		options = append(options, vips.WithFilterPlugin("wasm", wasmFilter(rt), rt))
+	}
+	return imagor.WithProcessors(vips.NewProcessor(options...))
+}
+
+// wasmFilter filters:wasm(name,args) runs WebAssembly filter module of name over the pixel buffer
+func wasmFilter(rt *wasmfilter.Runtime) vips.FilterFunc {
+	return func(ctx context.Context, img *vips.Image, _ imagor.LoadFunc, args ...string) error {
+		if len(args) == 0 {
+			return nil
+		}
+		return img.FilterMemory(func(buf []byte, width, height, bands int) error {
+			return rt.Filter(ctx, args[0], strings.Join(args[1:], ","), buf, width, height, bands)
+		})
+	}
 }
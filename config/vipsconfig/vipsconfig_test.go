@@ -1,10 +1,14 @@
 package vipsconfig
 
 import (
+	"context"
 	"github.com/cshum/imagor"
 	"github.com/cshum/imagor/config"
 	"github.com/cshum/imagor/vips"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -12,9 +16,41 @@ func TestWithVips(t *testing.T) {
 	srv := config.CreateServer([]string{
 		"-vips-max-animation-frames", "167",
 		"-vips-disable-filters", "blur,watermark,rgb",
+		"-vips-max-cache-size", "50",
+		"-vips-report-leaks",
+		"-vips-disable-simd",
+		"-vips-tolerant",
 	}, WithVips)
 	app := srv.App.(*imagor.Imagor)
 	processor := app.Processors[0].(*vips.Processor)
 	assert.Equal(t, 167, processor.MaxAnimationFrames)
 	assert.Equal(t, []string{"blur", "watermark", "rgb"}, processor.DisableFilters)
+	assert.Equal(t, 50, processor.MaxCacheSize)
+	assert.True(t, processor.ReportLeaks)
+	assert.False(t, processor.CacheTrace)
+	assert.True(t, processor.DisableSIMD)
+	assert.True(t, processor.Tolerant)
+}
+
+func TestWithVipsWasmFilter(t *testing.T) {
+	srv := config.CreateServer(nil, WithVips)
+	processor := srv.App.(*imagor.Imagor).Processors[0].(*vips.Processor)
+	assert.Nil(t, processor.Filters["wasm"])
+	assert.Empty(t, processor.Plugins)
+
+	srv = config.CreateServer([]string{
+		"-vips-wasm-filter-dir", "../../wasmfilter/testdata",
+	}, WithVips)
+	processor = srv.App.(*imagor.Imagor).Processors[0].(*vips.Processor)
+	assert.NotNil(t, processor.Filters["wasm"])
+	require.Len(t, processor.Plugins, 1)
+	assert.NoError(t, processor.Startup(context.Background()))
+	assert.NoError(t, processor.Shutdown(context.Background()))
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo.wasm"), []byte("foo"), 0644))
+	srv = config.CreateServer([]string{"-vips-wasm-filter-dir", dir}, WithVips)
+	processor = srv.App.(*imagor.Imagor).Processors[0].(*vips.Processor)
+	assert.Error(t, processor.Startup(context.Background()))
+	assert.NoError(t, processor.Shutdown(context.Background()))
 }
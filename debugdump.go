@@ -0,0 +1,163 @@
+package imagor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/cshum/imagor/bufpool"
+	"github.com/cshum/imagor/imagorpath"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// debugIDHeader response header of debug dump ID, for locating dumped artifacts in debug directory
+const debugIDHeader = "X-Imagor-Debug-Id"
+
+// requestIDRegexp valid X-Request-Id header value to be used in debug dump ID
+var requestIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]{0,63}$`)
+
+type debugDumpKey struct{}
+
+// debugDump loaded source, processor stages and output of request dumped to debug directory,
+// for reproducing "this URL renders wrong" reports offline
+type debugDump struct {
+	mu     sync.Mutex
+	id     string
+	dir    string
+	seq    int
+	logger *zap.Logger
+}
+
+// withDebugDump returns request with debug dump context, started by Do if dump is applicable
+func withDebugDump(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), debugDumpKey{}, &debugDump{}))
+}
+
+// debugDumpFrom returns debug dump of context, nil if not enabled or not started
+func debugDumpFrom(ctx context.Context) *debugDump {
+	if d, _ := ctx.Value(debugDumpKey{}).(*debugDump); d != nil && d.started() {
+		return d
+	}
+	return nil
+}
+
+// startDebugDump starts debug dump of request in debug mode, or with debug() filter of signed URL.
+// Returns nil if not applicable
+func (app *Imagor) startDebugDump(r *http.Request, p imagorpath.Params, hasDebug bool) *debugDump {
+	d, _ := r.Context().Value(debugDumpKey{}).(*debugDump)
+	if d == nil || !(app.Debug || (hasDebug && !p.Unsafe)) {
+		return nil
+	}
+	var id = r.Header.Get("X-Request-Id")
+	if !requestIDRegexp.MatchString(id) {
+		id = newJobID()[:16]
+	}
+	id = time.Now().UTC().Format("20060102T150405") + "-" + id
+	dir := filepath.Join(app.DebugDir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		app.Logger.Warn("debug-dump", zap.String("dir", dir), zap.Error(err))
+		return nil
+	}
+	d.mu.Lock()
+	d.id = id
+	d.dir = dir
+	d.logger = app.Logger
+	d.mu.Unlock()
+	app.Logger.Info("debug-dump", zap.String("id", id), zap.String("dir", dir))
+	d.writeJSON("params.json", p)
+	return d
+}
+
+func (d *debugDump) started() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dir != ""
+}
+
+// ID returns debug dump ID, empty if not started
+func (d *debugDump) ID() string {
+	if d == nil {
+		return ""
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.id
+}
+
+// source dumps loaded source blob
+func (d *debugDump) source(blob *Blob) {
+	d.writeBlob("source", blob)
+}
+
+// stage dumps output blob of processor in order of stages
+func (d *debugDump) stage(processor Processor, blob *Blob) {
+	if d == nil || isBlobEmpty(blob) {
+		return
+	}
+	d.mu.Lock()
+	d.seq++
+	name := fmt.Sprintf("stage-%d-%s", d.seq, processorName(processor))
+	d.mu.Unlock()
+	d.writeBlob(name, blob)
+}
+
+// result dumps final output blob, or error of request
+func (d *debugDump) result(blob *Blob, err error) {
+	if d == nil {
+		return
+	}
+	if err != nil {
+		d.write("error.txt", func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, err.Error())
+			return err
+		})
+		return
+	}
+	d.writeBlob("output", blob)
+}
+
+func (d *debugDump) writeBlob(name string, blob *Blob) {
+	if d == nil || isBlobEmpty(blob) {
+		return
+	}
+	d.write(name+getExtension(blob.BlobType()), func(w io.Writer) error {
+		reader, _, err := blob.NewReader()
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = reader.Close()
+		}()
+		_, err = bufpool.Copy(w, reader)
+		return err
+	})
+}
+
+func (d *debugDump) writeJSON(name string, v interface{}) {
+	d.write(name, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	})
+}
+
+// write creates file of name under debug directory, logged if failed
+func (d *debugDump) write(name string, fn func(w io.Writer) error) {
+	path := filepath.Join(d.dir, name)
+	f, err := os.Create(path)
+	if err == nil {
+		err = fn(f)
+		if e := f.Close(); err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		d.logger.Warn("debug-dump", zap.String("path", path), zap.Error(err))
+	}
+}
@@ -0,0 +1,115 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWithDebugDir(t *testing.T) {
+	var signer = imagorpath.NewDefaultSigner("1234")
+	factory := func(dir string, debug bool) *Imagor {
+		return New(
+			WithUnsafe(true),
+			WithSigner(signer),
+			WithDebug(debug),
+			WithDebugDir(dir),
+			WithCache(newMapCache()),
+			WithResultCacheTTL(time.Minute),
+			WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+				if image == "broken.png" {
+					return nil, ErrNotFound
+				}
+				return NewBlobFromBytes([]byte("foo")), nil
+			})),
+			WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+				buf, err := blob.ReadAll()
+				if err != nil {
+					return nil, err
+				}
+				return NewBlobFromBytes(append(buf, "bar"...)), nil
+			})),
+		)
+	}
+	doGet := func(app *Imagor, path string, header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/"+path, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		app.ServeHTTP(w, r)
+		return w
+	}
+	readDump := func(t *testing.T, dir, id string) map[string]string {
+		entries, err := os.ReadDir(filepath.Join(dir, id))
+		require.NoError(t, err)
+		var files = map[string]string{}
+		for _, entry := range entries {
+			buf, err := os.ReadFile(filepath.Join(dir, id, entry.Name()))
+			require.NoError(t, err)
+			files[entry.Name()] = string(buf)
+		}
+		return files
+	}
+
+	t.Run("signed debug filter", func(t *testing.T) {
+		dir := t.TempDir()
+		app := factory(dir, false)
+		path := imagorpath.Generate(imagorpath.Params{
+			Image:   "foo.png",
+			Width:   100,
+			Filters: imagorpath.Filters{{Name: "debug"}},
+		}, signer)
+
+		w := doGet(app, imagorpath.GenerateUnsafe(imagorpath.Params{Image: "foo.png", Width: 100}), nil)
+		assert.Equal(t, 200, w.Code)
+		assert.Empty(t, w.Header().Get(debugIDHeader))
+
+		w = doGet(app, path, http.Header{"X-Request-Id": {"abc-123"}})
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "foobar", w.Body.String())
+		id := w.Header().Get(debugIDHeader)
+		assert.Regexp(t, `^\d{8}T\d{6}-abc-123$`, id)
+		files := readDump(t, dir, id)
+		assert.Equal(t, "foo", files["source"])
+		assert.Equal(t, "foobar", files["stage-1-imagor.processorFunc"])
+		assert.Equal(t, "foobar", files["output"])
+		assert.Contains(t, files["params.json"], `"image": "foo.png"`)
+		assert.NotContains(t, files["params.json"], "debug")
+
+		// bypass cached result, with invalid request ID replaced
+		w = doGet(app, path, http.Header{"X-Request-Id": {"../../etc"}})
+		assert.Equal(t, 200, w.Code)
+		id = w.Header().Get(debugIDHeader)
+		assert.Regexp(t, `^\d{8}T\d{6}-[0-9a-f]{16}$`, id)
+		assert.Equal(t, "foo", readDump(t, dir, id)["source"])
+
+		w = doGet(app, "unsafe/filters:debug()/foo.png", nil)
+		assert.Equal(t, 200, w.Code)
+		assert.Empty(t, w.Header().Get(debugIDHeader), "debug filter requires signed URL")
+	})
+
+	t.Run("debug mode", func(t *testing.T) {
+		dir := t.TempDir()
+		w := doGet(factory(dir, true), "unsafe/broken.png", nil)
+		assert.Equal(t, 404, w.Code)
+		id := w.Header().Get(debugIDHeader)
+		require.NotEmpty(t, id)
+		files := readDump(t, dir, id)
+		assert.Contains(t, files["error.txt"], ErrNotFound.Error())
+		assert.Empty(t, files["source"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		w := doGet(factory("", true), "unsafe/foo.png", nil)
+		assert.Equal(t, 200, w.Code)
+		assert.Empty(t, w.Header().Get(debugIDHeader))
+	})
+}
@@ -0,0 +1,97 @@
+package imagor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type diagnosticsKey struct{}
+
+// timingNames Server-Timing metrics in order of stages
+var timingNames = []string{"result", "load", "process", "save"}
+
+// diagnostics of request collected for diagnostic response headers
+type diagnostics struct {
+	mu        sync.Mutex
+	cache     string
+	processor string
+	timings   []timing
+}
+
+type timing struct {
+	name string
+	dur  time.Duration
+}
+
+// withDiagnostics returns request with diagnostics context
+func withDiagnostics(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), diagnosticsKey{}, &diagnostics{}))
+}
+
+// diagnosticsFrom returns diagnostics of context, nil if not enabled
+func diagnosticsFrom(ctx context.Context) *diagnostics {
+	d, _ := ctx.Value(diagnosticsKey{}).(*diagnostics)
+	return d
+}
+
+func (d *diagnostics) setCache(status string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.cache = status
+	d.mu.Unlock()
+}
+
+// setProcessor sets package qualified type name of processor e.g. vips.Processor
+func (d *diagnostics) setProcessor(processor Processor) {
+	if d == nil {
+		return
+	}
+	name := processorName(processor)
+	d.mu.Lock()
+	d.processor = name
+	d.mu.Unlock()
+}
+
+// track adds duration of stage since start
+func (d *diagnostics) track(name string, start time.Time) {
+	if d == nil {
+		return
+	}
+	dur := time.Since(start)
+	d.mu.Lock()
+	d.timings = append(d.timings, timing{name: name, dur: dur})
+	d.mu.Unlock()
+}
+
+// setHeaders sets X-Imagor-Cache, X-Imagor-Processor and Server-Timing response headers
+func (d *diagnostics) setHeaders(w http.ResponseWriter) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.cache != "" {
+		w.Header().Set("X-Imagor-Cache", d.cache)
+	}
+	if d.processor != "" {
+		w.Header().Set("X-Imagor-Processor", d.processor)
+	}
+	if len(d.timings) > 0 {
+		var metrics = make([]string, 0, len(d.timings))
+		for _, name := range timingNames {
+			for _, t := range d.timings {
+				if t.name == name {
+					metrics = append(metrics, fmt.Sprintf(
+						"%s;dur=%.1f", t.name, float64(t.dur.Microseconds())/1000))
+				}
+			}
+		}
+		w.Header().Set("Server-Timing", strings.Join(metrics, ", "))
+	}
+}
@@ -0,0 +1,53 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestWithDiagnosticHeaders(t *testing.T) {
+	factory := func(enable bool) *Imagor {
+		return New(
+			WithUnsafe(true),
+			WithDiagnosticHeaders(enable),
+			WithCache(newMapCache()),
+			WithResultCacheTTL(time.Minute),
+			WithStorages(newMapStore()),
+			WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+				return NewBlobFromBytes([]byte("foo")), nil
+			})),
+			WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+				return blob, nil
+			})),
+		)
+	}
+	doGet := func(app *Imagor) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.png", nil))
+		return w
+	}
+	app := factory(true)
+	w := doGet(app)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "MISS", w.Header().Get("X-Imagor-Cache"))
+	assert.Equal(t, "imagor.processorFunc", w.Header().Get("X-Imagor-Processor"))
+	assert.Regexp(t, regexp.MustCompile(`^load;dur=[0-9.]+, process;dur=[0-9.]+, save;dur=[0-9.]+$`),
+		w.Header().Get("Server-Timing"))
+
+	w = doGet(app)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "HIT", w.Header().Get("X-Imagor-Cache"))
+	assert.Empty(t, w.Header().Get("X-Imagor-Processor"))
+	assert.Empty(t, w.Header().Get("Server-Timing"))
+
+	w = doGet(factory(false))
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("X-Imagor-Cache"))
+	assert.Empty(t, w.Header().Get("Server-Timing"))
+}
@@ -0,0 +1,65 @@
+package imagor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// errorCacheKey cache key of load failure of image
+func errorCacheKey(image string) string {
+	return "error:" + image
+}
+
+// isErrorCacheable checks if load error is of not found or origin status failure,
+// excluding transient errors of timeout, cancellation, circuit breaker and
+// transport errors e.g. connection refused that are not imagor Error
+func isErrorCacheable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var e Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	if e == ErrLoaderUnavailable || e == ErrInternal || e.Timeout() {
+		return false
+	}
+	return e.Code == http.StatusNotFound || e.Code >= 500
+}
+
+// cachedLoadError returns load error of image cached within ErrorCacheTTL
+func (app *Imagor) cachedLoadError(ctx context.Context, image string) error {
+	if app.Cache == nil || app.ErrorCacheTTL <= 0 || image == "" {
+		return nil
+	}
+	blob, err := app.Cache.Get(ctx, errorCacheKey(image))
+	if err != nil || isBlobEmpty(blob) {
+		return nil
+	}
+	buf, err := blob.ReadAll()
+	if err != nil {
+		return nil
+	}
+	var res struct {
+		Code    int    `json:"status"`
+		Message string `json:"message"`
+	}
+	if err = json.Unmarshal(buf, &res); err != nil || res.Code == 0 {
+		return nil
+	}
+	if app.Debug {
+		app.Logger.Debug("error-cache-hit", zap.String("image", image))
+	}
+	return NewError(res.Message, res.Code)
+}
+
+// cacheLoadError caches load error of image for ErrorCacheTTL if cacheable
+func (app *Imagor) cacheLoadError(ctx context.Context, image string, err error) {
+	if app.Cache == nil || app.ErrorCacheTTL <= 0 || image == "" || !isErrorCacheable(err) {
+		return
+	}
+	app.setCache(DetachContext(ctx), errorCacheKey(image), NewBlobFromJsonMarshal(WrapError(err)), app.ErrorCacheTTL)
+}
@@ -0,0 +1,70 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithErrorCacheTTL(t *testing.T) {
+	var loads int
+	cache := newMapCache()
+	app := New(
+		WithUnsafe(true),
+		WithCache(cache),
+		WithErrorCacheTTL(time.Second*30),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			loads++
+			switch image {
+			case "missing":
+				return nil, ErrNotFound
+			case "origin-error":
+				return nil, NewError("bad gateway", http.StatusBadGateway)
+			case "timeout":
+				return nil, context.DeadlineExceeded
+			case "refused":
+				return nil, errors.New("connection refused")
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	doGet := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil))
+		return w
+	}
+	for _, tt := range []struct {
+		image string
+		code  int
+		loads int
+	}{
+		{"missing", 404, 1},
+		{"origin-error", 502, 1},
+		{"refused", 500, 2},
+		{"timeout", 408, 2},
+		{"foo", 200, 2},
+	} {
+		t.Run(tt.image, func(t *testing.T) {
+			loads = 0
+			w := doGet("/unsafe/" + tt.image)
+			assert.Equal(t, tt.code, w.Code)
+			w = doGet("/unsafe/fit-in/" + tt.image)
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, tt.loads, loads)
+		})
+	}
+	assert.Equal(t, time.Second*30, cache.TTL["error:missing"])
+	assert.NotContains(t, cache.Map, "error:timeout")
+	assert.NotContains(t, cache.Map, "error:refused")
+	assert.NotContains(t, cache.Map, "error:foo")
+
+	w := doGet("/unsafe/missing")
+	assert.Equal(t, jsonStr(ErrNotFound), w.Body.String())
+
+	assert.NoError(t, app.purge(httptest.NewRequest(http.MethodDelete, "/", nil), app.trustedParams("missing")))
+	assert.NotContains(t, cache.Map, "error:missing")
+}
@@ -2,6 +2,7 @@ package imagor
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/cshum/imagor/imagorpath"
@@ -16,12 +17,20 @@ var (
 	ErrInvalid               = NewError("invalid", http.StatusBadRequest)
 	ErrMethodNotAllowed      = NewError("method not allowed", http.StatusMethodNotAllowed)
 	ErrSignatureMismatch     = NewError("url signature mismatch", http.StatusForbidden)
+	ErrUnauthorized          = NewError("unauthorized", http.StatusUnauthorized)
+	ErrFeatureNotAllowed     = NewError("feature not allowed", http.StatusForbidden)
+	ErrSizeNotAllowed        = NewError("size not allowed", http.StatusForbidden)
+	ErrRefererNotAllowed     = NewError("referer not allowed", http.StatusForbidden)
+	ErrSourceNotAllowed      = NewError("source not allowed", http.StatusForbidden)
 	ErrTimeout               = NewError("timeout", http.StatusRequestTimeout)
 	ErrExpired               = NewError("expired", http.StatusGone)
 	ErrUnsupportedFormat     = NewError("unsupported format", http.StatusNotAcceptable)
+	ErrUnsupportedMediaType  = NewError("unsupported media type", http.StatusUnsupportedMediaType)
 	ErrMaxSizeExceeded       = NewError("maximum size exceeded", http.StatusBadRequest)
 	ErrMaxResolutionExceeded = NewError("maximum resolution exceeded", http.StatusUnprocessableEntity)
+	ErrInvalidImage          = NewError("invalid image", http.StatusUnprocessableEntity)
 	ErrTooManyRequests       = NewError("too many requests", http.StatusTooManyRequests)
+	ErrLoaderUnavailable     = NewError("loader unavailable", http.StatusServiceUnavailable)
 	ErrInternal              = NewError("internal error", http.StatusInternalServerError)
 )
 
@@ -37,12 +46,102 @@ func (p ErrForward) Error() string {
 	return fmt.Sprintf("%s forward %s", errPrefix, imagorpath.GeneratePath(p.Params))
 }
 
+// Stage imagor pipeline stage where an error originated
+type Stage string
+
+const (
+	StageLoad    Stage = "load"
+	StageProcess Stage = "process"
+	StageSave    Stage = "save"
+)
+
+// StageError wraps error with the pipeline stage it originated from.
+// Use errors.As to get the stage, and errors.Is or errors.As to match the underlying error
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Stage, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+func wrapStage(stage Stage, err error) error {
+	if err == nil {
+		return nil
+	}
+	var se *StageError
+	if errors.As(err, &se) {
+		return err
+	}
+	return &StageError{Stage: stage, Err: err}
+}
+
+// ErrorStage returns the pipeline stage the error originated from, empty if unknown
+func ErrorStage(err error) Stage {
+	var se *StageError
+	if errors.As(err, &se) {
+		return se.Stage
+	}
+	return ""
+}
+
 // Error imagor error convention
 type Error struct {
 	Message string `json:"message,omitempty"`
 	Code    int    `json:"status,omitempty"`
 }
 
+// errorCodes machine readable codes of imagor errors
+var errorCodes = map[Error]string{
+	ErrNotFound:              "NOT_FOUND",
+	ErrInvalid:               "INVALID",
+	ErrMethodNotAllowed:      "METHOD_NOT_ALLOWED",
+	ErrSignatureMismatch:     "SIGNATURE_MISMATCH",
+	ErrUnauthorized:          "UNAUTHORIZED",
+	ErrFeatureNotAllowed:     "FEATURE_NOT_ALLOWED",
+	ErrSizeNotAllowed:        "SIZE_NOT_ALLOWED",
+	ErrRefererNotAllowed:     "REFERER_NOT_ALLOWED",
+	ErrSourceNotAllowed:      "SOURCE_NOT_ALLOWED",
+	ErrTimeout:               "TIMEOUT",
+	ErrExpired:               "EXPIRED",
+	ErrUnsupportedFormat:     "UNSUPPORTED_FORMAT",
+	ErrUnsupportedMediaType:  "UNSUPPORTED_MEDIA_TYPE",
+	ErrMaxSizeExceeded:       "MAX_SIZE_EXCEEDED",
+	ErrMaxResolutionExceeded: "MAX_RESOLUTION_EXCEEDED",
+	ErrInvalidImage:          "INVALID_IMAGE",
+	ErrTooManyRequests:       "TOO_MANY_REQUESTS",
+	ErrLoaderUnavailable:     "LOADER_UNAVAILABLE",
+	ErrInternal:              "INTERNAL_SERVER_ERROR",
+}
+
+var errorCodeReplacer = strings.NewReplacer(" ", "_", "-", "_", "'", "")
+
+// ErrorCode returns machine readable code of the error e.g. NOT_FOUND,
+// derived from HTTP status text if not an imagor defined error
+func (e Error) ErrorCode() string {
+	if code, ok := errorCodes[e]; ok {
+		return code
+	}
+	if text := http.StatusText(e.Code); text != "" {
+		return strings.ToUpper(errorCodeReplacer.Replace(text))
+	}
+	return "ERROR"
+}
+
+// MarshalJSON marshals Error as JSON error envelope of status, code and message
+func (e Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code      int    `json:"status,omitempty"`
+		ErrorCode string `json:"code"`
+		Message   string `json:"message,omitempty"`
+	}{e.Code, e.ErrorCode(), e.Message})
+}
+
 type timeoutErr interface {
 	Timeout() bool
 }
@@ -70,17 +169,22 @@ func WrapError(err error) Error {
 	if err == nil {
 		return ErrInternal
 	}
-	if e, ok := err.(Error); ok {
+	var se *StageError
+	if errors.As(err, &se) {
+		err = se.Err
+	}
+	var e Error
+	if errors.As(err, &e) {
 		return e
 	}
-	if _, ok := err.(ErrForward); ok {
+	var forward ErrForward
+	if errors.As(err, &forward) {
 		// ErrForward till the end means no supported processor
 		return ErrUnsupportedFormat
 	}
-	if e, ok := err.(timeoutErr); ok {
-		if e.Timeout() {
-			return ErrTimeout
-		}
+	var te timeoutErr
+	if errors.As(err, &te) && te.Timeout() {
+		return ErrTimeout
 	}
 	if errors.Is(err, context.DeadlineExceeded) {
 		return ErrTimeout
@@ -3,10 +3,12 @@ package imagor
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/cshum/imagor/imagorpath"
 	"github.com/stretchr/testify/assert"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 )
@@ -44,4 +46,66 @@ func TestWrapError(t *testing.T) {
 	assert.Equal(t, "imagor: forward 167x169/foo", err.Error())
 	assert.Equal(t, ErrUnsupportedFormat, WrapError(err))
 
+	err = fmt.Errorf("wrapped: %w", ErrNotFound)
+	assert.Equal(t, ErrNotFound, WrapError(err))
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	err = fmt.Errorf("wrapped: %w", ErrForward{})
+	assert.Equal(t, ErrUnsupportedFormat, WrapError(err))
+}
+
+func TestErrorJSON(t *testing.T) {
+	assert.Equal(t, "NOT_FOUND", ErrNotFound.ErrorCode())
+	assert.Equal(t, "MAX_SIZE_EXCEEDED", ErrMaxSizeExceeded.ErrorCode())
+	assert.Equal(t, "BAD_GATEWAY", NewErrorFromStatusCode(502).ErrorCode())
+	assert.Equal(t, "IM_A_TEAPOT", NewError("foo", 418).ErrorCode())
+	assert.Equal(t, "ERROR", NewError("foo", 167).ErrorCode())
+	assert.Equal(t, `{"status":404,"code":"NOT_FOUND","message":"not found"}`, jsonStr(ErrNotFound))
+	assert.Equal(t, `{"status":500,"code":"INTERNAL_SERVER_ERROR","message":"boom"}`,
+		jsonStr(WrapError(errors.New("boom"))))
+
+	for _, tt := range []struct {
+		err  error
+		code int
+		body string
+	}{
+		{ErrSignatureMismatch, 403, `{"status":403,"code":"SIGNATURE_MISMATCH","message":"url signature mismatch"}`},
+		{errors.New("boom"), 500, `{"status":500,"code":"INTERNAL_SERVER_ERROR","message":"boom"}`},
+		{NewError("moved", 301), 500, `{"status":500,"code":"INTERNAL_SERVER_ERROR","message":"moved"}`},
+	} {
+		w := httptest.NewRecorder()
+		writeError(w, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+		assert.Equal(t, tt.code, w.Code)
+		assert.Equal(t, tt.body, w.Body.String())
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestStageError(t *testing.T) {
+	assert.Nil(t, wrapStage(StageLoad, nil))
+	assert.Equal(t, Stage(""), ErrorStage(ErrNotFound))
+	assert.Equal(t, Stage(""), ErrorStage(nil))
+
+	err := wrapStage(StageLoad, ErrNotFound)
+	assert.Equal(t, "load: imagor: 404 not found", err.Error())
+	assert.Equal(t, StageLoad, ErrorStage(err))
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.False(t, errors.Is(err, ErrInvalid))
+	assert.Equal(t, ErrNotFound, WrapError(err))
+
+	var e Error
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, 404, e.Code)
+
+	// should keep the originating stage
+	err = wrapStage(StageProcess, err)
+	assert.Equal(t, StageLoad, ErrorStage(err))
+
+	err = wrapStage(StageProcess, context.DeadlineExceeded)
+	assert.Equal(t, StageProcess, ErrorStage(err))
+	assert.Equal(t, ErrTimeout, WrapError(err))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	err = wrapStage(StageProcess, errors.New("boom"))
+	assert.Equal(t, NewError("boom", 500), WrapError(err))
 }
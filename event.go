@@ -0,0 +1,48 @@
+package imagor
+
+import (
+	"context"
+	"time"
+)
+
+// EventType imagor processing event type
+type EventType string
+
+const (
+	EventProcessed  EventType = "processed"
+	EventLoadFailed EventType = "load_failed"
+	EventSaveFailed EventType = "save_failed"
+)
+
+// Event imagor processing event
+type Event struct {
+	Type  EventType `json:"type"`
+	Path  string    `json:"path,omitempty"`
+	Image string    `json:"image,omitempty"`
+	Key   string    `json:"key,omitempty"`
+	Error string    `json:"error,omitempty"`
+	Time  time.Time `json:"time"`
+
+	// Size of processed result in bytes
+	Size int64 `json:"size,omitempty"`
+	// ClientIP of the request
+	ClientIP string `json:"client_ip,omitempty"`
+	// APIKey name of the request authorized by API key
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// EventHandler receives imagor processing events.
+// HandleEvent is called synchronously within request, implementations should not block
+type EventHandler interface {
+	HandleEvent(ctx context.Context, event Event)
+}
+
+func (app *Imagor) emit(ctx context.Context, event Event) {
+	if len(app.EventHandlers) == 0 {
+		return
+	}
+	event.Time = time.Now()
+	for _, handler := range app.EventHandlers {
+		handler.HandleEvent(ctx, event)
+	}
+}
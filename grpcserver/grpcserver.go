@@ -0,0 +1,195 @@
+package grpcserver
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative imagor.proto
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Service gRPC service of imagor Process, for internal services submitting image bytes directly
+type Service struct {
+	UnimplementedImagorServer
+
+	App       *imagor.Imagor
+	Secret    string
+	ChunkSize int
+}
+
+// New creates gRPC server of imagor Process service
+func New(app *imagor.Imagor, options ...Option) *grpc.Server {
+	s := &Service{
+		App:       app,
+		ChunkSize: 64 << 10,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(s.unaryAuth),
+		grpc.StreamInterceptor(s.streamAuth),
+	)
+	RegisterImagorServer(srv, s)
+	return srv
+}
+
+// Process implements ImagorServer
+func (s *Service) Process(ctx context.Context, req *ProcessRequest) (*ProcessResponse, error) {
+	res, err := s.App.Process(ctx, imagor.ProcessRequest{
+		Source: imagor.NewBlobFromBytes(req.Source),
+		Params: parseParams(req.Params),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	buf, err := res.Blob.ReadAll()
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &ProcessResponse{
+		ContentType: res.ContentType,
+		Processor:   res.Processor,
+		Image:       buf,
+	}, nil
+}
+
+// ProcessStream implements ImagorServer
+func (s *Service) ProcessStream(stream Imagor_ProcessStreamServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	// source chunks buffered, as processors may read the source blob more than once
+	var buf = bytes.NewBuffer(first.Source)
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if s.App.MaxSourceSize > 0 && int64(buf.Len()+len(req.Source)) > s.App.MaxSourceSize {
+			return statusError(imagor.ErrMaxSizeExceeded)
+		}
+		buf.Write(req.Source)
+	}
+	res, err := s.App.Process(stream.Context(), imagor.ProcessRequest{
+		Source: imagor.NewBlobFromBytes(buf.Bytes()),
+		Params: parseParams(first.Params),
+	})
+	if err != nil {
+		return statusError(err)
+	}
+	reader, _, err := res.Blob.NewReader()
+	if err != nil {
+		return statusError(err)
+	}
+	defer reader.Close()
+	var msg = &ProcessResponse{
+		ContentType: res.ContentType,
+		Processor:   res.Processor,
+	}
+	for sent := false; ; sent = true {
+		// new chunk each message, as sent message is not to be modified
+		var chunk = make([]byte, s.ChunkSize)
+		n, err := io.ReadFull(reader, chunk)
+		if n > 0 || !sent {
+			msg.Image = chunk[:n]
+			if e := stream.Send(msg); e != nil {
+				return e
+			}
+			msg = &ProcessResponse{}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return statusError(err)
+		}
+	}
+}
+
+// parseParams parses params of imagor URL path without image.
+// Process does not verify signature, so params are parsed as unsafe
+// that leading segment is not taken as hash
+func parseParams(path string) imagorpath.Params {
+	path = strings.TrimPrefix(strings.Trim(path, "/"), "unsafe/")
+	// trailing slash for empty image
+	p := imagorpath.Parse("unsafe/" + path + "/")
+	p.Unsafe = false
+	return p
+}
+
+// statusError converts imagor error to gRPC status error by HTTP status code
+func statusError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+	e := imagor.WrapError(err)
+	var code codes.Code
+	switch e.Code {
+	case http.StatusBadRequest, http.StatusNotAcceptable, http.StatusUnsupportedMediaType,
+		http.StatusUnprocessableEntity:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		code = codes.DeadlineExceeded
+	case http.StatusTooManyRequests:
+		code = codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		code = codes.Unavailable
+	default:
+		code = codes.Internal
+	}
+	return status.Error(code, e.Message)
+}
+
+// authorize checks bearer token of authorization metadata if secret is set
+func (s *Service) authorize(ctx context.Context) error {
+	if s.Secret == "" {
+		return nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		if token := strings.TrimPrefix(auth, "Bearer "); token != auth &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(s.Secret)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+func (s *Service) unaryAuth(
+	ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Service) streamAuth(
+	srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler,
+) error {
+	if err := s.authorize(stream.Context()); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
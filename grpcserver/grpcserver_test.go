@@ -0,0 +1,175 @@
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"testing"
+)
+
+type processorFunc func(ctx context.Context, blob *imagor.Blob, p imagorpath.Params, load imagor.LoadFunc) (*imagor.Blob, error)
+
+func (f processorFunc) Startup(_ context.Context) error {
+	return nil
+}
+
+func (f processorFunc) Process(ctx context.Context, blob *imagor.Blob, p imagorpath.Params, load imagor.LoadFunc) (*imagor.Blob, error) {
+	return f(ctx, blob, p, load)
+}
+
+func (f processorFunc) Shutdown(_ context.Context) error {
+	return nil
+}
+
+// newTestClient serves gRPC server of app with options in memory, returns client connected to it
+func newTestClient(t *testing.T, app *imagor.Imagor, options ...Option) ImagorClient {
+	ln := bufconn.Listen(1 << 20)
+	srv := New(app, options...)
+	go func() {
+		_ = srv.Serve(ln)
+	}()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return ln.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return NewImagorClient(conn)
+}
+
+// newTestApp processes source into params path followed by source bytes
+func newTestApp() *imagor.Imagor {
+	return imagor.New(
+		imagor.WithProcessors(processorFunc(func(ctx context.Context, blob *imagor.Blob, p imagorpath.Params, load imagor.LoadFunc) (*imagor.Blob, error) {
+			if p.Width > 1000 {
+				return nil, imagor.ErrMaxResolutionExceeded
+			}
+			buf, err := blob.ReadAll()
+			if err != nil {
+				return nil, err
+			}
+			return imagor.NewBlobFromBytes(append([]byte(p.Path+":"), buf...)), nil
+		})),
+	)
+}
+
+func TestProcess(t *testing.T) {
+	client := newTestClient(t, newTestApp())
+	ctx := context.Background()
+
+	res, err := client.Process(ctx, &ProcessRequest{
+		Params: "fit-in/200x200/filters:format(webp)",
+		Source: []byte("foo"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "fit-in/200x200/filters:format(webp)/:foo", string(res.Image))
+	assert.Equal(t, "grpcserver.processorFunc", res.Processor)
+	assert.NotEmpty(t, res.ContentType)
+
+	_, err = client.Process(ctx, &ProcessRequest{Params: "200x200"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Process(ctx, &ProcessRequest{Params: "2000x2000", Source: []byte("foo")})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, imagor.ErrMaxResolutionExceeded.Message, status.Convert(err).Message())
+}
+
+func TestProcessStream(t *testing.T) {
+	client := newTestClient(t, newTestApp(), WithChunkSize(100))
+	ctx := context.Background()
+	source := bytes.Repeat([]byte("0123456789"), 100)
+
+	stream, err := client.ProcessStream(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&ProcessRequest{Params: "100x100/", Source: source[:10]}))
+	for i := 10; i < len(source); i += 300 {
+		end := i + 300
+		if end > len(source) {
+			end = len(source)
+		}
+		require.NoError(t, stream.Send(&ProcessRequest{Source: source[i:end]}))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	var out []byte
+	var messages int
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if messages == 0 {
+			assert.NotEmpty(t, res.ContentType)
+			assert.Equal(t, "grpcserver.processorFunc", res.Processor)
+		} else {
+			assert.Empty(t, res.ContentType)
+		}
+		messages++
+		out = append(out, res.Image...)
+	}
+	assert.Equal(t, append([]byte("100x100/:"), source...), out)
+	assert.Equal(t, 11, messages)
+
+	stream, err = client.ProcessStream(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&ProcessRequest{Params: "2000x2000", Source: source}))
+	require.NoError(t, stream.CloseSend())
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestProcessStreamMaxSourceSize(t *testing.T) {
+	client := newTestClient(t, imagor.New(
+		imagor.WithProcessors(newTestApp().Processors...),
+		imagor.WithMaxSourceSize(100),
+	))
+	stream, err := client.ProcessStream(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&ProcessRequest{Params: "100x100", Source: make([]byte, 60)}))
+	require.NoError(t, stream.Send(&ProcessRequest{Source: make([]byte, 60)}))
+	require.NoError(t, stream.CloseSend())
+	_, err = stream.Recv()
+	assert.Equal(t, status.Code(statusError(imagor.ErrMaxSizeExceeded)), status.Code(err))
+	assert.Equal(t, imagor.ErrMaxSizeExceeded.Message, status.Convert(err).Message())
+}
+
+func TestSecret(t *testing.T) {
+	client := newTestClient(t, newTestApp(), WithSecret("s3cret"))
+	req := &ProcessRequest{Params: "100x100", Source: []byte("foo")}
+
+	_, err := client.Process(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.Process(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong"), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer s3cret")
+	_, err = client.Process(ctx, req)
+	assert.NoError(t, err)
+
+	stream, err := client.ProcessStream(context.Background())
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	stream, err = client.ProcessStream(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(req))
+	require.NoError(t, stream.CloseSend())
+	res, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "100x100/:foo", string(res.Image))
+}
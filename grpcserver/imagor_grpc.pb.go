@@ -0,0 +1,180 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: imagor.proto
+
+package grpcserver
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// ImagorClient is the client API for Imagor service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ImagorClient interface {
+	// Process processes source image by params
+	Process(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error)
+	// ProcessStream processes source image sent in chunks, by params of the first message,
+	// and responds processed image in chunks, for payloads larger than the message size limit
+	ProcessStream(ctx context.Context, opts ...grpc.CallOption) (Imagor_ProcessStreamClient, error)
+}
+
+type imagorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewImagorClient(cc grpc.ClientConnInterface) ImagorClient {
+	return &imagorClient{cc}
+}
+
+func (c *imagorClient) Process(ctx context.Context, in *ProcessRequest, opts ...grpc.CallOption) (*ProcessResponse, error) {
+	out := new(ProcessResponse)
+	err := c.cc.Invoke(ctx, "/imagor.v1.Imagor/Process", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *imagorClient) ProcessStream(ctx context.Context, opts ...grpc.CallOption) (Imagor_ProcessStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Imagor_ServiceDesc.Streams[0], "/imagor.v1.Imagor/ProcessStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &imagorProcessStreamClient{stream}
+	return x, nil
+}
+
+type Imagor_ProcessStreamClient interface {
+	Send(*ProcessRequest) error
+	Recv() (*ProcessResponse, error)
+	grpc.ClientStream
+}
+
+type imagorProcessStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *imagorProcessStreamClient) Send(m *ProcessRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *imagorProcessStreamClient) Recv() (*ProcessResponse, error) {
+	m := new(ProcessResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ImagorServer is the server API for Imagor service.
+// All implementations must embed UnimplementedImagorServer
+// for forward compatibility
+type ImagorServer interface {
+	// Process processes source image by params
+	Process(context.Context, *ProcessRequest) (*ProcessResponse, error)
+	// ProcessStream processes source image sent in chunks, by params of the first message,
+	// and responds processed image in chunks, for payloads larger than the message size limit
+	ProcessStream(Imagor_ProcessStreamServer) error
+	mustEmbedUnimplementedImagorServer()
+}
+
+// UnimplementedImagorServer must be embedded to have forward compatible implementations.
+type UnimplementedImagorServer struct {
+}
+
+func (UnimplementedImagorServer) Process(context.Context, *ProcessRequest) (*ProcessResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Process not implemented")
+}
+func (UnimplementedImagorServer) ProcessStream(Imagor_ProcessStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ProcessStream not implemented")
+}
+func (UnimplementedImagorServer) mustEmbedUnimplementedImagorServer() {}
+
+// UnsafeImagorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ImagorServer will
+// result in compilation errors.
+type UnsafeImagorServer interface {
+	mustEmbedUnimplementedImagorServer()
+}
+
+func RegisterImagorServer(s grpc.ServiceRegistrar, srv ImagorServer) {
+	s.RegisterService(&Imagor_ServiceDesc, srv)
+}
+
+func _Imagor_Process_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProcessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ImagorServer).Process(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/imagor.v1.Imagor/Process",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ImagorServer).Process(ctx, req.(*ProcessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Imagor_ProcessStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ImagorServer).ProcessStream(&imagorProcessStreamServer{stream})
+}
+
+type Imagor_ProcessStreamServer interface {
+	Send(*ProcessResponse) error
+	Recv() (*ProcessRequest, error)
+	grpc.ServerStream
+}
+
+type imagorProcessStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *imagorProcessStreamServer) Send(m *ProcessResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *imagorProcessStreamServer) Recv() (*ProcessRequest, error) {
+	m := new(ProcessRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Imagor_ServiceDesc is the grpc.ServiceDesc for Imagor service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Imagor_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "imagor.v1.Imagor",
+	HandlerType: (*ImagorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Process",
+			Handler:    _Imagor_Process_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ProcessStream",
+			Handler:       _Imagor_ProcessStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "imagor.proto",
+}
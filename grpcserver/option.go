@@ -0,0 +1,20 @@
+package grpcserver
+
+type Option func(s *Service)
+
+// WithSecret with secret for bearer token authorization metadata of requests,
+// no authorization if empty
+func WithSecret(secret string) Option {
+	return func(s *Service) {
+		s.Secret = secret
+	}
+}
+
+// WithChunkSize with size of image chunks responded by ProcessStream, default 64KB
+func WithChunkSize(size int) Option {
+	return func(s *Service) {
+		if size > 0 {
+			s.ChunkSize = size
+		}
+	}
+}
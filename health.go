@@ -0,0 +1,141 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+	"time"
+)
+
+// healthVars current health status of dependencies by name, "ok" or error message,
+// exposed by expvar debug endpoint /debug/vars
+var healthVars = expvar.NewMap("imagor_health")
+
+var healthMu sync.Mutex
+
+// healthCheck named HealthChecker of loader, storage, result storage or processor
+type healthCheck struct {
+	name    string
+	checker HealthChecker
+}
+
+// healthError aggregated errors of failed health checks
+type healthError []error
+
+func (e healthError) Error() string {
+	var msgs = make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Is checks if any of the health check errors matches target
+func (e healthError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// healthChecks returns loaders, storages, result storages and processors that implement HealthChecker
+func (app *Imagor) healthChecks() (checks []healthCheck) {
+	add := func(kind string, i int, v interface{}) {
+		if checker, ok := v.(HealthChecker); ok {
+			checks = append(checks, healthCheck{
+				name: fmt.Sprintf("%s[%d] %T", kind, i, unwrap(v)), checker: checker,
+			})
+		}
+	}
+	for i, loader := range app.Loaders {
+		add("loaders", i, loader)
+	}
+	for i, storage := range app.Storages {
+		add("storages", i, storage)
+	}
+	for i, storage := range app.ResultStorages {
+		add("result_storages", i, storage)
+	}
+	for i, processor := range app.Processors {
+		add("processors", i, processor)
+	}
+	return
+}
+
+// Health runs health checks of loaders, storages and processors that implement HealthChecker
+// concurrently, returning aggregated error of all failed checks
+func (app *Imagor) Health(ctx context.Context) error {
+	var (
+		checks = app.healthChecks()
+		errs   = make([]error, len(checks))
+		wg     sync.WaitGroup
+	)
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check healthCheck) {
+			defer wg.Done()
+			if err := check.checker.Health(ctx); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", check.name, err)
+			}
+		}(i, check)
+	}
+	wg.Wait()
+	var failed healthError
+	for i, check := range checks {
+		app.setHealthStatus(check.name, errs[i])
+		if errs[i] != nil {
+			failed = append(failed, errs[i])
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// setHealthStatus updates health status of dependency, logging on status changes
+func (app *Imagor) setHealthStatus(name string, err error) {
+	var status = "ok"
+	if err != nil {
+		status = err.Error()
+	}
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	var prev string
+	if v, ok := healthVars.Get(name).(*expvar.String); ok {
+		prev = v.Value()
+	}
+	if prev == status {
+		return
+	}
+	var v = new(expvar.String)
+	v.Set(status)
+	healthVars.Set(name, v)
+	if err != nil {
+		app.Logger.Warn("health", zap.String("name", name), zap.Error(err))
+	} else if prev != "" {
+		app.Logger.Info("health", zap.String("name", name), zap.String("status", status))
+	}
+}
+
+// runHealthChecks runs health checks periodically until context done
+func (app *Imagor) runHealthChecks(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, interval)
+			_ = app.Health(checkCtx)
+			cancel()
+		}
+	}
+}
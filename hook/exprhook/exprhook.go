@@ -0,0 +1,138 @@
+package exprhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ErrRejected params rejected by hook expression that evaluates to false
+var ErrRejected = imagor.NewError("params rejected", http.StatusForbidden)
+
+// Hook params hook of expr-lang expression, that is evaluated with params and request,
+// and rewrites params by the resulting map of params fields.
+// Params are unchanged if evaluated to nil or true, rejected if evaluated to false
+type Hook struct {
+	program *vm.Program
+}
+
+// env environment of hook expression
+type env struct {
+	Params  map[string]any `expr:"params"`
+	Request request        `expr:"request"`
+}
+
+// request attributes of hook expression
+type request struct {
+	Method string            `expr:"method"`
+	Host   string            `expr:"host"`
+	Path   string            `expr:"path"`
+	Header map[string]string `expr:"header"`
+	Tenant string            `expr:"tenant"`
+	APIKey string            `expr:"api_key"`
+}
+
+// New compiles hook expression e.g.
+//
+//	{width: min(params.width, 2000), height: min(params.height, 2000)}
+func New(code string) (*Hook, error) {
+	program, err := expr.Compile(code,
+		expr.Env(env{}),
+		expr.Function("filter", newFilter, new(func(string, string) map[string]any)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("exprhook: %w", err)
+	}
+	return &Hook{program: program}, nil
+}
+
+// Rewrite implements imagor.ParamsHookFunc
+func (h *Hook) Rewrite(r *http.Request, p imagorpath.Params) (imagorpath.Params, error) {
+	var header = make(map[string]string, len(r.Header))
+	for key := range r.Header {
+		header[key] = r.Header.Get(key)
+	}
+	var fields = paramsFields(p)
+	out, err := expr.Run(h.program, env{
+		Params: fields,
+		Request: request{
+			Method: r.Method,
+			Host:   r.Host,
+			Path:   r.URL.Path,
+			Header: header,
+			Tenant: imagor.TenantName(r.Context()),
+			APIKey: imagor.APIKeyName(r.Context()),
+		},
+	})
+	if err != nil {
+		return p, fmt.Errorf("exprhook: %w", err)
+	}
+	switch out := out.(type) {
+	case nil:
+		return p, nil
+	case bool:
+		if !out {
+			return p, ErrRejected
+		}
+		return p, nil
+	case map[string]any:
+		for key, value := range out {
+			if _, ok := fields[key]; !ok || key == "path" {
+				return p, fmt.Errorf("exprhook: unknown params field %q", key)
+			}
+			fields[key] = value
+		}
+		buf, err := json.Marshal(fields)
+		if err != nil {
+			return p, fmt.Errorf("exprhook: %w", err)
+		}
+		var rewritten imagorpath.Params
+		if err = json.Unmarshal(buf, &rewritten); err != nil {
+			return p, fmt.Errorf("exprhook: %w", err)
+		}
+		rewritten.Params = p.Params
+		return rewritten, nil
+	default:
+		return p, fmt.Errorf("exprhook: unexpected result of type %T", out)
+	}
+}
+
+// paramsFields returns all fields of params by JSON names including zero values,
+// so that expressions can refer to fields not set
+func paramsFields(p imagorpath.Params) map[string]any {
+	var fields = map[string]any{}
+	var v = reflect.ValueOf(p)
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		if filters, ok := v.Field(i).Interface().(imagorpath.Filters); ok {
+			var list = make([]any, 0, len(filters))
+			for _, f := range filters {
+				list = append(list, map[string]any{"name": f.Name, "args": f.Args})
+			}
+			fields[name] = list
+		} else {
+			fields[name] = v.Field(i).Interface()
+		}
+	}
+	return fields
+}
+
+// newFilter creates filter of name and args e.g. filter("watermark", "logo.png,0,0,0")
+func newFilter(params ...any) (any, error) {
+	name, ok := params[0].(string)
+	if !ok || name == "" {
+		return nil, errors.New("filter name required")
+	}
+	args, _ := params[1].(string)
+	return map[string]any{"name": name, "args": args}, nil
+}
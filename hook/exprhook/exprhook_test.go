@@ -0,0 +1,107 @@
+package exprhook
+
+import (
+	"context"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type loaderFunc func(r *http.Request, image string) (*imagor.Blob, error)
+
+func (f loaderFunc) Get(r *http.Request, image string) (*imagor.Blob, error) {
+	return f(r, image)
+}
+
+type processorFunc func(ctx context.Context, blob *imagor.Blob, p imagorpath.Params, load imagor.LoadFunc) (*imagor.Blob, error)
+
+func (f processorFunc) Startup(_ context.Context) error {
+	return nil
+}
+
+func (f processorFunc) Process(ctx context.Context, blob *imagor.Blob, p imagorpath.Params, load imagor.LoadFunc) (*imagor.Blob, error) {
+	return f(ctx, blob, p, load)
+}
+
+func (f processorFunc) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	hook, err := New(`
+params.image == "private.jpg" ? false : {
+	width: min(params.width, 500),
+	height: min(params.height, 500),
+	filters: request.header["X-Plan"] == "premium" ? params.filters :
+		concat(params.filters, [filter("watermark", "logo.png,0,0,0")])
+}`)
+	require.NoError(t, err)
+	app := imagor.New(
+		imagor.WithUnsafe(true),
+		imagor.WithParamsHook(hook.Rewrite),
+		imagor.WithLoaders(loaderFunc(func(r *http.Request, image string) (*imagor.Blob, error) {
+			return imagor.NewBlobFromBytes([]byte(image)), nil
+		})),
+		imagor.WithProcessors(processorFunc(func(ctx context.Context, blob *imagor.Blob, p imagorpath.Params, load imagor.LoadFunc) (*imagor.Blob, error) {
+			return imagor.NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	for _, test := range []struct {
+		path, plan, expected string
+		code                 int
+	}{
+		{"unsafe/2000x2000/filters:fill(white)/foo.jpg", "", "500x500/filters:fill(white):watermark(logo.png,0,0,0)/foo.jpg", 200},
+		{"unsafe/100x0/foo.jpg", "premium", "100x0/foo.jpg", 200},
+		{"unsafe/private.jpg", "premium", "", 403},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/"+test.path, nil)
+		if test.plan != "" {
+			r.Header.Set("X-Plan", test.plan)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		assert.Equal(t, test.code, w.Code, test.path)
+		if test.code == 200 {
+			assert.Equal(t, test.expected, w.Body.String(), test.path)
+		}
+	}
+}
+
+func TestHookResult(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/foo", nil)
+	p := imagorpath.Parse("fit-in/200x300/foo.jpg")
+	for _, test := range []struct {
+		code     string
+		expected string
+		err      string
+	}{
+		{code: `nil`, expected: "fit-in/200x300/foo.jpg"},
+		{code: `true`, expected: "fit-in/200x300/foo.jpg"},
+		{code: `false`, err: ErrRejected.Error()},
+		{code: `{fit_in: false, smart: request.host == "example.com"}`, expected: "200x300/smart/foo.jpg"},
+		{code: `{width: params.width / 3}`, err: "exprhook: json: cannot unmarshal number"},
+		{code: `{foo: 1}`, err: `exprhook: unknown params field "foo"`},
+		{code: `{path: "bar.jpg"}`, err: `exprhook: unknown params field "path"`},
+		{code: `"foo"`, err: "exprhook: unexpected result of type string"},
+		{code: `{filters: [filter("", "")]}`, err: "filter name required"},
+	} {
+		hook, err := New(test.code)
+		require.NoError(t, err, test.code)
+		res, err := hook.Rewrite(r, p)
+		if test.err != "" {
+			assert.ErrorContains(t, err, test.err, test.code)
+		} else {
+			require.NoError(t, err, test.code)
+			assert.Equal(t, test.expected, imagorpath.GeneratePath(res), test.code)
+		}
+	}
+
+	_, err := New(`params.foo(`)
+	assert.ErrorContains(t, err, "exprhook:")
+	_, err = New(`filter(1)`)
+	assert.Error(t, err)
+}
@@ -5,17 +5,22 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/cshum/imagor/bufpool"
 	"github.com/cshum/imagor/imagorpath"
 	"go.uber.org/zap"
 	"golang.org/x/sync/semaphore"
 	"golang.org/x/sync/singleflight"
 	"io"
+	"math"
+	"net"
 	"net/http"
+	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,6 +31,12 @@ type Loader interface {
 	Get(r *http.Request, key string) (*Blob, error)
 }
 
+// Stater optional interface for Loader to stat source image without loading,
+// for modified time check of stored results
+type Stater interface {
+	Stat(ctx context.Context, key string) (*Stat, error)
+}
+
 // Storage image storage interface
 type Storage interface {
 	Get(r *http.Request, key string) (*Blob, error)
@@ -34,6 +45,13 @@ type Storage interface {
 	Delete(ctx context.Context, key string) error
 }
 
+// Cache key value cache interface
+type Cache interface {
+	Get(ctx context.Context, key string) (*Blob, error)
+	Set(ctx context.Context, key string, blob *Blob, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
 // LoadFunc load function for Processor
 type LoadFunc func(string) (*Blob, error)
 
@@ -44,50 +62,155 @@ type Processor interface {
 	Shutdown(ctx context.Context) error
 }
 
+// HealthChecker optional interface for Loader, Storage and Processor
+// to report readiness, e.g. remote bucket reachable or directory writable
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
+// Starter optional interface for Loader and Storage to run on Imagor startup,
+// e.g. open connection pools or verify buckets
+type Starter interface {
+	Startup(ctx context.Context) error
+}
+
+// Shutdowner optional interface for Loader, Storage and Tracer to run on Imagor shutdown,
+// e.g. flush pending saves or exported spans, or close connection pools
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// paramsResult params endpoint response
+type paramsResult struct {
+	imagorpath.Params
+	SignatureValid bool `json:"signature_valid"`
+}
+
+// ErrorHandlerFunc handles the HTTP response of an imagor Error
+type ErrorHandlerFunc func(w http.ResponseWriter, r *http.Request, err Error)
+
 // Imagor image resize HTTP handler
 type Imagor struct {
 	Unsafe                 bool
+	UnsafeAllowedNetworks  []*net.IPNet
+	TrustedProxies         []*net.IPNet
 	Signer                 imagorpath.Signer
+	Crypter                imagorpath.Crypter
+	LegacyCrypter          imagorpath.Crypter
 	StoragePathStyle       imagorpath.StorageHasher
 	ResultStoragePathStyle imagorpath.ResultStorageHasher
 	BasePathRedirect       string
+	PathPrefix             string
+	FallbackImage          string
+	FallbackStatusCode     int
 	Loaders                []Loader
 	Storages               []Storage
 	ResultStorages         []Storage
 	Processors             []Processor
+	EventHandlers          []EventHandler
+	Tracer                 Tracer
+	ErrorReporter          ErrorReporter
+	Cache                  Cache
+	SourceCache            Cache
+	SourceCacheTTL         time.Duration
+	ResultCacheTTL         time.Duration
+	ErrorCacheTTL          time.Duration
 	RequestTimeout         time.Duration
 	LoadTimeout            time.Duration
 	SaveTimeout            time.Duration
 	ProcessTimeout         time.Duration
 	CacheHeaderTTL         time.Duration
 	CacheHeaderSWR         time.Duration
+	ResponseHeaders        http.Header
 	ProcessConcurrency     int64
 	ProcessQueueSize       int64
+	ProcessQueueTimeout    time.Duration
+	ProcessMemoryLimit     int64
+	LoaderBreakerThreshold int
+	LoaderBreakerCooldown  time.Duration
+	HealthCheckInterval    time.Duration
 	AutoWebP               bool
 	AutoAVIF               bool
+	DPRClientHints         bool
+	SaveDataQuality        int
+	MaxAutoWidth           int
+	DiagnosticHeaders      bool
 	ModifiedTimeCheck      bool
 	DisableErrorBody       bool
 	DisableParamsEndpoint  bool
+	UploadSecret           string
+	UploadMaxSize          int64
+	MaxSourceSize          int64
+	MaxSourcePixels        int64
+	ValidateSource         bool
+	SourcePassthroughTypes []string
+	UnsupportedSource      string
+	ProcessorRoutes        map[string]string
+	AllowedSizes           []string
+	AllowedFilters         []string
+	DeniedFilters          []string
+	DeniedFeatures         []string
+	AllowedReferers        []string
+	Tenants                []Tenant
+	DenyEmptyReferer       bool
+	RateLimit              float64
+	RateLimitBurst         int
+	RateLimitKeyFunc       func(r *http.Request) string
+	APIKeys                map[string]string
+	APIKeyRequired         bool
+	BatchConcurrency       int
+	PurgeSecret            string
+	PurgeSource            bool
+	Invalidators           []Invalidator
+	WarmSecret             string
+	Presets                map[string]string
+	JobQueues              []JobQueue
+	WorkerConcurrency      int
+	WatchWarm              bool
+	SrcsetSecret           string
+	SrcsetWidths           []int
+	StatsSecret            string
+	AsyncTimeout           time.Duration
+	AsyncJobTTL            time.Duration
+	AsyncMaxPending        int
+	PresignRedirect        time.Duration
+	DebugDir               string
+	ErrorHandlers          map[int]ErrorHandlerFunc
+	Compressors            map[string]CompressorFunc
+	ParamsHooks            []ParamsHookFunc
 	BaseParams             string
 	Logger                 *zap.Logger
 	Debug                  bool
 
-	g          singleflight.Group
-	sema       *semaphore.Weighted
-	queueSema  *semaphore.Weighted
-	baseParams imagorpath.Params
+	g                singleflight.Group
+	sema             *semaphore.Weighted
+	memSema          *semaphore.Weighted
+	queueSema        *semaphore.Weighted
+	breakers         []*circuitBreaker
+	jobs             jobStore
+	handler          http.Handler
+	middlewares      []func(http.Handler) http.Handler
+	baseParams       imagorpath.Params
+	stopHealthChecks context.CancelFunc
+	stopWatch        func()
+	stopWorkers      func(ctx context.Context)
+	watchIndex       *watchIndex
+	watchQueue       *watchQueue
+	stats            *stats
+	saves            sync.WaitGroup
+	pendingSaves     int64
 }
 
 // New create new Imagor
 func New(options ...Option) *Imagor {
 	app := &Imagor{
-		Logger:         zap.NewNop(),
-		RequestTimeout: time.Second * 30,
-		LoadTimeout:    time.Second * 20,
-		SaveTimeout:    time.Second * 20,
-		ProcessTimeout: time.Second * 20,
-		CacheHeaderTTL: time.Hour * 24 * 7,
-		CacheHeaderSWR: time.Hour * 24,
+		Logger:                zap.NewNop(),
+		RequestTimeout:        time.Second * 30,
+		CacheHeaderTTL:        time.Hour * 24 * 7,
+		CacheHeaderSWR:        time.Hour * 24,
+		LoaderBreakerCooldown: time.Second * 30,
+		AsyncJobTTL:           time.Hour,
+		AsyncMaxPending:       1000,
 	}
 	for _, option := range options {
 		option(app)
@@ -95,15 +218,41 @@ func New(options ...Option) *Imagor {
 	if app.ProcessConcurrency > 0 {
 		app.sema = semaphore.NewWeighted(app.ProcessConcurrency)
 	}
+	if app.StatsSecret != "" {
+		app.stats = newStats()
+	}
 	if app.ProcessQueueSize > 0 {
 		app.queueSema = semaphore.NewWeighted(app.ProcessQueueSize + app.ProcessConcurrency)
 	}
+	if app.ProcessMemoryLimit < 0 {
+		app.ProcessMemoryLimit = containerMemoryLimit() * 3 / 4
+	}
+	if app.ProcessMemoryLimit > 0 {
+		app.memSema = semaphore.NewWeighted(app.ProcessMemoryLimit)
+	}
+	if app.LoaderBreakerThreshold > 0 {
+		for range app.Loaders {
+			app.breakers = append(app.breakers, newCircuitBreaker(
+				app.LoaderBreakerThreshold, app.LoaderBreakerCooldown))
+		}
+	}
+	if app.RateLimit > 0 {
+		app.middlewares = append([]func(http.Handler) http.Handler{
+			app.rateLimitMiddleware,
+		}, app.middlewares...)
+	}
+	if len(app.middlewares) > 0 {
+		app.Use()
+	}
 	if app.Debug {
 		app.debugLog()
 	}
 	if app.Signer == nil {
 		app.Signer = imagorpath.NewDefaultSigner("")
 	}
+	if app.PathPrefix = strings.Trim(app.PathPrefix, "/"); app.PathPrefix != "" {
+		app.PathPrefix = "/" + app.PathPrefix + "/"
+	}
 	app.BaseParams = strings.TrimSpace(app.BaseParams)
 	if app.BaseParams != "" {
 		app.BaseParams = strings.TrimSuffix(app.BaseParams, "/") + "/"
@@ -111,33 +260,191 @@ func New(options ...Option) *Imagor {
 	return app
 }
 
-// Startup Imagor startup lifecycle
+// Startup Imagor startup lifecycle,
+// loaders and storages that implement Starter are started before processors
 func (app *Imagor) Startup(ctx context.Context) (err error) {
+	for _, c := range app.components() {
+		if starter, ok := c.(Starter); ok {
+			if err = starter.Startup(ctx); err != nil {
+				return fmt.Errorf("%T: %w", c, err)
+			}
+		}
+	}
 	for _, processor := range app.Processors {
 		if err = processor.Startup(ctx); err != nil {
 			return
 		}
 	}
+	if app.stopWatch == nil {
+		app.watchIndex = newWatchIndex()
+		if app.stopWatch = app.startWatch(); app.stopWatch == nil {
+			app.watchIndex = nil
+		}
+	}
+	if app.HealthCheckInterval > 0 && app.stopHealthChecks == nil {
+		var healthCtx context.Context
+		healthCtx, app.stopHealthChecks = context.WithCancel(context.Background())
+		go app.runHealthChecks(healthCtx, app.HealthCheckInterval)
+	}
+	if app.stopWorkers == nil {
+		app.stopWorkers = app.startWorkers()
+	}
 	return
 }
 
 // Shutdown Imagor shutdown lifecycle
 func (app *Imagor) Shutdown(ctx context.Context) (err error) {
+	if app.stopWorkers != nil {
+		// stop consuming jobs, waiting for jobs in progress
+		app.stopWorkers(ctx)
+		app.stopWorkers = nil
+	}
+	// drain storage writes of responded requests and async jobs before storages shut down
+	app.drainSaves(ctx)
+	if app.stopHealthChecks != nil {
+		app.stopHealthChecks()
+		app.stopHealthChecks = nil
+	}
+	if app.stopWatch != nil {
+		app.stopWatch()
+		app.stopWatch = nil
+		app.watchQueue = nil
+	}
 	for _, processor := range app.Processors {
 		if err = processor.Shutdown(ctx); err != nil {
 			return
 		}
 	}
+	// shutdown in reverse order of startup, continue on error
+	var components = app.components()
+	for i := len(components) - 1; i >= 0; i-- {
+		if shutdowner, ok := components[i].(Shutdowner); ok {
+			if e := shutdowner.Shutdown(ctx); e != nil && err == nil {
+				err = fmt.Errorf("%T: %w", components[i], e)
+			}
+		}
+	}
+	return
+}
+
+// components returns tracer, loaders, storages, result storages and event handlers in order,
+// with instance used for multiple roles e.g. file loader and storage returned once.
+// Tracer goes first so that it is shut down last, after spans of other shutdowns
+func (app *Imagor) components() (components []interface{}) {
+	var seen = map[interface{}]bool{}
+	var add = func(c interface{}) {
+		if c == nil {
+			return
+		}
+		if reflect.TypeOf(c).Comparable() {
+			if seen[c] {
+				return
+			}
+			seen[c] = true
+		}
+		components = append(components, c)
+	}
+	add(app.Tracer)
+	for _, loader := range app.Loaders {
+		add(loader)
+	}
+	for _, storage := range app.Storages {
+		add(storage)
+	}
+	for _, storage := range app.ResultStorages {
+		add(storage)
+	}
+	for _, handler := range app.EventHandlers {
+		add(handler)
+	}
 	return
 }
 
+// unwrap returns loader, storage or processor wrapped by decorator that implements Unwrap,
+// e.g. instrumented, or itself if not wrapped
+func unwrap(c interface{}) interface{} {
+	switch w := c.(type) {
+	case interface{ Unwrap() Loader }:
+		return unwrap(w.Unwrap())
+	case interface{ Unwrap() Processor }:
+		return unwrap(w.Unwrap())
+	}
+	return c
+}
+
+// Use appends middlewares wrapping imagor request handling, applied in the order of use.
+// Use is not concurrency safe and should be called before serving requests
+func (app *Imagor) Use(middlewares ...func(http.Handler) http.Handler) {
+	for _, middleware := range middlewares {
+		if middleware != nil {
+			app.middlewares = append(app.middlewares, middleware)
+		}
+	}
+	var handler http.Handler = http.HandlerFunc(app.serveHTTP)
+	for i := len(app.middlewares) - 1; i >= 0; i-- {
+		handler = app.middlewares[i](handler)
+	}
+	app.handler = handler
+}
+
 // ServeHTTP implements http.Handler for imagor operations
 func (app *Imagor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet && r.Method != http.MethodHead {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+	if app.Tracer != nil {
+		var span Span
+		r, span = app.traceRequest(r)
+		defer span.End(nil)
+	}
+	if app.stats != nil {
+		app.stats.serve(w, r, app.serveHandler)
+		return
+	}
+	app.serveHandler(w, r)
+}
+
+// serveHandler serves request through middlewares if used, with response compressed if enabled
+func (app *Imagor) serveHandler(w http.ResponseWriter, r *http.Request) {
+	if len(app.Compressors) > 0 {
+		cw := app.newCompressWriter(w, r)
+		defer cw.close()
+		w = cw
+	}
+	if app.handler != nil {
+		app.handler.ServeHTTP(w, r)
+		return
+	}
+	app.serveHTTP(w, r)
+}
+
+func (app *Imagor) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	isGet := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if !isGet && app.UploadSecret == "" && app.BatchConcurrency <= 0 &&
+		app.PurgeSecret == "" && app.WarmSecret == "" && app.SrcsetSecret == "" {
+		writeError(w, r, ErrMethodNotAllowed)
 		return
 	}
+	r = app.withAPIKeyName(r)
 	path := r.URL.EscapedPath()
+	if app.PathPrefix != "" {
+		if !strings.HasPrefix(path+"/", app.PathPrefix) {
+			writeError(w, r, ErrNotFound)
+			return
+		}
+		path = strings.TrimPrefix(path, strings.TrimSuffix(app.PathPrefix, "/"))
+	}
+	if !isGet {
+		app.serveEndpoint(w, r, path)
+		return
+	}
+	if len(app.Tenants) > 0 {
+		var tenant *Tenant
+		if tenant, path = app.matchTenant(r, path); tenant != nil {
+			r = withTenant(r, tenant)
+		}
+	}
+	if path == "/stats" && app.StatsSecret != "" {
+		app.serveStats(w, r)
+		return
+	}
 	if path == "/" || path == "" {
 		if app.BasePathRedirect == "" {
 			writeJSON(w, r, json.RawMessage(fmt.Sprintf(
@@ -148,26 +455,73 @@ func (app *Imagor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
-	p := imagorpath.Parse(path)
+	if id := strings.TrimPrefix(path, "/jobs/"); app.AsyncTimeout > 0 && id != path {
+		app.serveJob(w, r, id)
+		return
+	}
+	for key, values := range app.ResponseHeaders {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	p, err := app.parse(path)
+	if err != nil {
+		app.serveError(w, r, ErrSignatureMismatch)
+		return
+	}
 	if p.Params {
 		if !app.DisableParamsEndpoint {
-			writeJSONIndent(w, r, p)
+			writeJSONIndent(w, r, paramsResult{
+				Params:         p,
+				SignatureValid: app.isSignatureValid(r, p),
+			})
 		}
 		return
 	}
-	blob, err := checkBlob(app.Do(r, p))
+	if err = app.checkReferer(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if app.AsyncTimeout > 0 && !p.Meta && isAsyncRequest(r) {
+		app.setVaryHeaders(w, r, p)
+		app.serveAsync(w, r, path, p)
+		return
+	}
+	r = withVary(r)
+	if app.DiagnosticHeaders {
+		r = withDiagnostics(r)
+	}
+	if app.PresignRedirect > 0 {
+		r = withRedirect(r)
+	}
+	if app.DebugDir != "" {
+		r = withDebugDump(r)
+	}
+	res, err := app.DoResult(r, p)
+	blob := res.Blob
+	app.setVaryHeaders(w, r, p)
+	diagnosticsFrom(r.Context()).setHeaders(w)
+	if id := debugDumpFrom(r.Context()).ID(); id != "" {
+		w.Header().Set(debugIDHeader, id)
+	}
+	if rd := redirectFrom(r.Context()); rd != nil && rd.url != "" {
+		setCacheHeaders(w, r, 0, 0)
+		http.Redirect(w, r, rd.url, http.StatusTemporaryRedirect)
+		return
+	}
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			w.WriteHeader(499)
 			return
 		}
 		e := WrapError(err)
+		if e.Code == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", app.retryAfter())
+		}
 		if app.DisableErrorBody {
 			w.WriteHeader(e.Code)
 			return
 		}
-		if !isBlobEmpty(blob) && !p.Meta {
-			w.Header().Set("Content-Type", blob.ContentType())
+		if !isBlobEmpty(blob) && !res.Meta {
+			w.Header().Set("Content-Type", res.ContentType)
 			reader, size, _ := blob.NewReader()
 			if reader != nil {
 				w.WriteHeader(e.Code)
@@ -175,118 +529,239 @@ func (app *Imagor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 		}
-		w.WriteHeader(e.Code)
-		writeJSON(w, r, e)
+		if app.FallbackImage != "" && !p.Meta && isFallbackError(err, e) {
+			if fallback := app.loadFallback(r); fallback != nil {
+				reader, size, _ := fallback.NewReader()
+				if reader != nil {
+					w.Header().Set("Content-Type", fallback.ContentType())
+					setCacheHeaders(w, r, 0, 0)
+					if app.FallbackStatusCode > 0 {
+						w.WriteHeader(app.FallbackStatusCode)
+					} else {
+						w.WriteHeader(e.Code)
+					}
+					writeBody(w, r, reader, size)
+					return
+				}
+			}
+		}
+		app.serveError(w, r, e)
 		return
 	}
 	if isBlobEmpty(blob) {
 		return
 	}
-	w.Header().Set("Content-Type", blob.ContentType())
+	w.Header().Set("Content-Type", res.ContentType)
 	w.Header().Set("Content-Disposition", getContentDisposition(p, blob))
 	setCacheHeaders(w, r, app.CacheHeaderTTL, app.CacheHeaderSWR)
 	if checkStatNotModified(w, r, blob.Stat) {
 		w.WriteHeader(http.StatusNotModified)
 		return
 	}
+	if blob.FilePath() != "" && serveFile(w, r, blob) {
+		return
+	}
 	reader, size, _ := blob.NewReader()
 	writeBody(w, r, reader, size)
 	return
 }
 
-// Do executes Imagor operations
-func (app *Imagor) Do(r *http.Request, p imagorpath.Params) (blob *Blob, err error) {
+func (app *Imagor) isUnsafeAllowed(r *http.Request) bool {
+	if len(app.UnsafeAllowedNetworks) == 0 {
+		return true
+	}
+	return containsIP(app.UnsafeAllowedNetworks, ClientIP(r, app.TrustedProxies))
+}
+
+// serveEndpoint dispatches enabled endpoints of methods other than GET and HEAD
+func (app *Imagor) serveEndpoint(w http.ResponseWriter, r *http.Request, path string) {
+	switch {
+	case r.Method == http.MethodPut && app.UploadSecret != "":
+		app.serveUpload(w, r, path)
+	case r.Method == http.MethodPost && path == "/batch" && app.BatchConcurrency > 0:
+		app.serveBatch(w, r)
+	case r.Method == http.MethodPost && path == "/warm" && app.WarmSecret != "":
+		app.serveWarm(w, r)
+	case r.Method == http.MethodPost && path == "/srcset" && app.SrcsetSecret != "":
+		app.serveSrcset(w, r)
+	case r.Method == http.MethodDelete && app.PurgeSecret != "":
+		app.servePurge(w, r, path)
+	default:
+		writeError(w, r, ErrMethodNotAllowed)
+	}
+}
+
+// parse imagor path into params, decrypting encrypted path if Crypter enabled
+func (app *Imagor) parse(path string) (imagorpath.Params, error) {
+	if token := strings.TrimPrefix(path, "/enc/"); app.Crypter != nil && token != path {
+		return app.decrypt(token)
+	}
+	if app.LegacyCrypter != nil {
+		if plain, err := app.LegacyCrypter.Decrypt(path); err == nil {
+			return app.trustedParams(plain), nil
+		}
+	}
+	return imagorpath.Parse(path), nil
+}
+
+// decrypt parse Params from encrypted path token, signed with app Signer
+func (app *Imagor) decrypt(token string) (p imagorpath.Params, err error) {
+	var path string
+	if path, err = app.Crypter.Decrypt(token); err != nil {
+		if app.Debug {
+			app.Logger.Debug("decrypt", zap.String("token", token), zap.Error(err))
+		}
+		return
+	}
+	p = app.trustedParams(path)
+	return
+}
+
+// trustedParams parse path from trusted source into signed params
+func (app *Imagor) trustedParams(path string) imagorpath.Params {
+	p := imagorpath.Parse("unsafe/" + path)
+	p.Unsafe = false
+	p.Hash = app.Signer.Sign(p.Path)
+	return p
+}
+
+// serveError writes error response by error handler of its status code if any
+func (app *Imagor) serveError(w http.ResponseWriter, r *http.Request, e Error) {
+	if handler := app.errorHandler(e.Code); handler != nil {
+		handler(w, r, e)
+		return
+	}
+	writeError(w, r, e)
+}
+
+func (app *Imagor) errorHandler(code int) ErrorHandlerFunc {
+	if handler, ok := app.ErrorHandlers[code]; ok {
+		return handler
+	}
+	return app.ErrorHandlers[0]
+}
+
+// ServeBlob executes Imagor operations of params independent of http.Request,
+// for embedding imagor in workers, CLIs and queue consumers.
+// Params are trusted and signed with app Signer
+func (app *Imagor) ServeBlob(ctx context.Context, p imagorpath.Params) (*Blob, error) {
+	if p.Path == "" {
+		p.Path = imagorpath.GeneratePath(p)
+	}
+	p.Unsafe = false
+	p.Params = false
+	if app.Signer != nil {
+		p.Hash = app.Signer.Sign(p.Path)
+	}
+	if APIKeyName(ctx) == "" {
+		ctx = context.WithValue(ctx, apiKeyNameKey{}, "internal")
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	return checkBlob(app.Do(r, p))
+}
+
+// Do executes Imagor operations, returns blob of the Result.
+// Use DoResult for content type, cache status, validators and processor
+func (app *Imagor) Do(r *http.Request, p imagorpath.Params) (*Blob, error) {
+	res, err := app.DoResult(r, p)
+	return res.Blob, err
+}
+
+func (app *Imagor) do(r *http.Request, p imagorpath.Params) (blob *Blob, err error) {
 	var ctx = WithContext(r.Context())
 	var cancel func()
-	if app.RequestTimeout > 0 {
+	if app.RequestTimeout > 0 && !isAsyncJob(ctx) {
 		ctx, cancel = context.WithTimeout(ctx, app.RequestTimeout)
 		Defer(ctx, cancel)
-		r = r.WithContext(ctx)
 	}
-	if !(app.Unsafe && p.Unsafe) && app.Signer != nil && app.Signer.Sign(p.Path) != p.Hash {
-		err = ErrSignatureMismatch
-		if app.Debug {
-			app.Logger.Debug("sign-mismatch", zap.Any("params", p), zap.String("expected", app.Signer.Sign(p.Path)))
+	r = r.WithContext(ctx)
+	if err = app.authorize(r, p); err != nil {
+		if app.Debug && err == ErrSignatureMismatch {
+			app.Logger.Debug("sign-mismatch", zap.Any("params", p), zap.String("expected", app.signer(ctx).Sign(p.Path)))
 		}
 		return
 	}
-	var isPathChanged bool
-	if app.BaseParams != "" {
-		p = imagorpath.Apply(p, app.BaseParams)
-		isPathChanged = true
-	}
-	var hasFormat, hasPreview bool
-	var filters = p.Filters
-	p.Filters = nil
-	for _, f := range filters {
-		switch f.Name {
-		case "expire":
-			// expire(timestamp) filter
-			if ts, e := strconv.ParseInt(f.Args, 10, 64); e == nil {
-				r.Header.Set("Cache-Control", "no-cache")
-				if exp := time.UnixMilli(ts); !exp.IsZero() && time.Now().After(exp) {
-					err = ErrExpired
-					return
-				}
-			}
-		case "format":
-			hasFormat = true
-		case "preview":
-			hasPreview = true // disable result storage on preview() filter
-		}
-		// exclude utility filters from result path
-		switch f.Name {
-		case "expire", "attachment":
-			isPathChanged = true
-		default:
-			p.Filters = append(p.Filters, f)
+	if err = app.checkPolicy(p); err != nil {
+		if app.Debug {
+			app.Logger.Debug("not-allowed", zap.Any("params", p))
 		}
+		return
 	}
-	// auto WebP / AVIF
-	if !hasFormat && (app.AutoWebP || app.AutoAVIF) {
-		accept := r.Header.Get("Accept")
-		if app.AutoAVIF && strings.Contains(accept, "image/avif") {
-			p.Filters = append(p.Filters, imagorpath.Filter{
-				Name: "format",
-				Args: "avif",
-			})
-			isPathChanged = true
-		} else if app.AutoWebP && strings.Contains(accept, "image/webp") {
-			p.Filters = append(p.Filters, imagorpath.Filter{
-				Name: "format",
-				Args: "webp",
-			})
-			isPathChanged = true
+	var tenant = tenantFrom(ctx)
+	if err = tenant.checkSource(p.Image); err != nil {
+		if app.Debug {
+			app.Logger.Debug("not-allowed", zap.Any("params", p), zap.String("tenant", tenant.Name))
 		}
+		return
 	}
-	if isPathChanged {
-		p.Path = imagorpath.GeneratePath(p)
+	var flags resultFlags
+	if p, flags, err = app.resultParams(r, p); err != nil {
+		return
 	}
+	var hasPreview, hasDebug = flags.preview, flags.debug
 	var resultKey string
 	if !hasPreview {
-		if app.ResultStoragePathStyle != nil {
-			resultKey = app.ResultStoragePathStyle.HashResult(p)
-		} else {
-			resultKey = p.Path
+		resultKey = app.resultKey(ctx, p)
+	}
+	if rd := redirectFrom(ctx); rd != nil && isPassthrough(p) {
+		if rd.url = app.presign(ctx, p.Image); rd.url != "" {
+			return nil, nil
 		}
 	}
 	load := func(image string) (*Blob, error) {
+		// images loaded by filters are isolated by tenant the same way
+		if err := tenant.checkSource(image); err != nil {
+			return nil, err
+		}
 		blob, shouldSave, err := app.loadStorage(r, image)
 		if shouldSave {
-			var storageKey = image
-			if app.StoragePathStyle != nil {
-				storageKey = app.StoragePathStyle.Hash(image)
-			}
-			go app.save(ctx, app.Storages, storageKey, blob)
+			var storageKey = app.storageKey(ctx, image)
+			done := app.trackSave()
+			go func() {
+				defer done()
+				app.save(ctx, p, app.Storages, storageKey, blob)
+			}()
 		}
 		return blob, err
 	}
-	return app.suppress(ctx, p.Path, func(ctx context.Context, cb func(*Blob, error)) (*Blob, error) {
-		if resultKey != "" {
+	var cacheKey string
+	if !hasPreview && app.Cache != nil && app.ResultCacheTTL > 0 {
+		cacheKey = "result:" + tenant.prefixKey(p.Path)
+	}
+	var diag = diagnosticsFrom(ctx)
+	diag.setCache("MISS")
+	var suppressKey = tenant.prefixKey(p.Path)
+	var dump = app.startDebugDump(r, p, hasDebug)
+	if dump != nil {
+		// dumped request runs its own pipeline, bypassing cached results
+		suppressKey += "#debug:" + dump.ID()
+	}
+	return app.suppress(ctx, suppressKey, func(ctx context.Context, cb func(*Blob, error)) (*Blob, error) {
+		if cacheKey != "" && dump == nil {
+			if blob, err := checkBlob(app.Cache.Get(ctx, cacheKey)); err == nil && !isBlobEmpty(blob) {
+				if app.Debug {
+					app.Logger.Debug("result-cache-hit", zap.String("key", cacheKey))
+				}
+				diag.setCache("HIT")
+				app.stats.cacheHit()
+				return blob, nil
+			}
+		}
+		if resultKey != "" && dump == nil {
+			start := time.Now()
 			if blob := app.loadResult(r, resultKey, p.Image); blob != nil {
+				app.setCache(ctx, cacheKey, blob, app.ResultCacheTTL)
+				app.watchIndex.add(p.Image, tenant.prefixKey(p.Path), resultKey)
+				diag.setCache("HIT")
+				diag.track("result", start)
+				app.stats.storageHit()
 				return blob, nil
 			}
 		}
+		app.stats.miss()
 		if app.queueSema != nil {
 			if !app.queueSema.TryAcquire(1) {
 				err = ErrTooManyRequests
@@ -298,7 +773,7 @@ func (app *Imagor) Do(r *http.Request, p imagorpath.Params) (blob *Blob, err err
 			defer app.queueSema.Release(1)
 		}
 		if app.sema != nil {
-			if err = app.sema.Acquire(ctx, 1); err != nil {
+			if err = app.acquire(ctx); err != nil {
 				if app.Debug {
 					app.Logger.Debug("acquire", zap.Error(err))
 				}
@@ -307,83 +782,310 @@ func (app *Imagor) Do(r *http.Request, p imagorpath.Params) (blob *Blob, err err
 			defer app.sema.Release(1)
 		}
 		var shouldSave bool
-		if blob, shouldSave, err = app.loadStorage(r, p.Image); err != nil {
+		var start = time.Now()
+		blob, shouldSave, err = app.loadStorage(r, p.Image)
+		diag.track("load", start)
+		if err != nil {
 			if app.Debug {
 				app.Logger.Debug("load", zap.Any("params", p), zap.Error(err))
 			}
-			return blob, err
+			if !errors.Is(err, context.Canceled) {
+				app.emit(ctx, Event{
+					Type: EventLoadFailed, Path: p.Path, Image: p.Image, Error: err.Error(),
+				})
+				app.report(ctx, wrapStage(StageLoad, err), p)
+			}
+			dump.result(nil, wrapStage(StageLoad, err))
+			return blob, wrapStage(StageLoad, err)
+		}
+		dump.source(blob)
+		if err = app.validateSource(blob); err != nil {
+			if app.Debug {
+				app.Logger.Debug("validate", zap.Any("params", p), zap.Error(err))
+			}
+			dump.result(nil, wrapStage(StageLoad, err))
+			return nil, wrapStage(StageLoad, err)
 		}
 		var doneSave chan struct{}
 		if shouldSave {
 			doneSave = make(chan struct{})
-			var storageKey = p.Image
-			if app.StoragePathStyle != nil {
-				storageKey = app.StoragePathStyle.Hash(p.Image)
-			}
+			var storageKey = app.storageKey(ctx, p.Image)
+			done := app.trackSave()
 			go func(blob *Blob) {
-				app.save(ctx, app.Storages, storageKey, blob)
+				defer done()
+				start := time.Now()
+				app.save(ctx, p, app.Storages, storageKey, blob)
+				diag.track("save", start)
 				close(doneSave)
 			}(blob)
 		}
 		if isBlobEmpty(blob) {
 			return blob, err
 		}
-		var cancel func()
-		if app.ProcessTimeout > 0 {
-			ctx, cancel = context.WithTimeout(ctx, app.ProcessTimeout)
-			Defer(ctx, cancel)
-		}
-		var forwardP = p
-		for _, processor := range app.Processors {
-			b, e := checkBlob(processor.Process(ctx, blob, forwardP, load))
-			if !isBlobEmpty(b) {
-				blob = b // forward blob to next processor if exists
+		var releaseMemory func()
+		if releaseMemory, err = app.acquireMemory(ctx, blob); err != nil {
+			if app.Debug {
+				app.Logger.Debug("acquire-memory", zap.Error(err))
 			}
-			if e == nil {
-				blob = b
-				err = nil
-				if app.Debug {
-					app.Logger.Debug("processed", zap.Any("params", forwardP))
-				}
-				break
-			} else if forward, ok := e.(ErrForward); ok {
-				err = e
-				forwardP = forward.Params
-				if app.Debug {
-					app.Logger.Debug("forward", zap.Any("params", forwardP))
-				}
-			} else {
-				if ctx.Err() == nil {
-					err = e
-					app.Logger.Warn("process", zap.Any("params", p), zap.Error(err))
-				} else {
-					err = ctx.Err()
-				}
-				break
+			if shouldSave {
+				<-doneSave
 			}
+			return nil, err
 		}
+		var cancel func()
+		if timeout := app.stageTimeout(ctx, app.ProcessTimeout); timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			Defer(ctx, cancel)
+		}
+		var source = blob
+		start = time.Now()
+		blob, err = app.runProcessors(ctx, blob, p, load, dump)
+		diag.track("process", start)
+		releaseMemory()
+		var passthrough bool
+		blob, passthrough, err = app.unsupportedSource(source, blob, p, err)
+		dump.result(blob, err)
 		if shouldSave {
 			// make sure storage saved before response and result storage
 			<-doneSave
 		}
+		if err == nil && !passthrough {
+			app.setCache(ctx, cacheKey, blob, app.ResultCacheTTL)
+			app.watchIndex.add(p.Image, tenant.prefixKey(p.Path), resultKey)
+			var size int64
+			if !isBlobEmpty(blob) {
+				size = blob.Size()
+			}
+			app.stats.process()
+			app.emit(ctx, Event{
+				Type: EventProcessed, Path: p.Path, Image: p.Image, Key: resultKey,
+				Size: size, ClientIP: ClientIP(r, app.TrustedProxies), APIKey: APIKeyName(ctx),
+			})
+		}
+		// saves after response are tracked before responding, so that Shutdown drains them
+		defer app.trackSave()()
 		cb(blob, err)
 		ctx = DetachContext(ctx)
-		if err == nil && !isBlobEmpty(blob) && resultKey != "" &&
+		if err == nil && !passthrough && !isBlobEmpty(blob) && resultKey != "" &&
 			len(app.ResultStorages) > 0 {
-			app.save(ctx, app.ResultStorages, resultKey, blob)
+			app.save(ctx, p, app.ResultStorages, resultKey, blob)
 		}
 		if err != nil && shouldSave {
-			app.del(ctx, app.Storages, p.Image)
+			app.del(ctx, app.Storages, app.storageKey(ctx, p.Image))
 		}
 		return blob, err
 	})
 }
 
+// resultFlags utility filters of request params that apply to the result
+type resultFlags struct {
+	preview bool
+	debug   bool
+}
+
+// resultParams rewrites request params into params of the result, with base params, params hooks,
+// client hints, Save-Data quality and auto format applied, and utility filters excluded from the path.
+// Path is normalized as the canonical path of result keys, that purge derives the same keys
+func (app *Imagor) resultParams(
+	r *http.Request, p imagorpath.Params,
+) (_ imagorpath.Params, flags resultFlags, err error) {
+	if p, err = app.requestParams(r, p); err != nil {
+		return
+	}
+	setVaryParams(r.Context(), p)
+	return app.negotiateParams(r, p)
+}
+
+// requestParams applies base params, tenant base params and params hooks to request params
+func (app *Imagor) requestParams(r *http.Request, p imagorpath.Params) (imagorpath.Params, error) {
+	return app.applyParamsHooks(r, app.applyBaseParams(r, p))
+}
+
+// applyBaseParams applies base params and tenant base params to request params
+func (app *Imagor) applyBaseParams(r *http.Request, p imagorpath.Params) imagorpath.Params {
+	if app.BaseParams != "" {
+		p = imagorpath.Apply(p, app.BaseParams)
+	}
+	if tenant := tenantFrom(r.Context()); tenant != nil && tenant.BaseParams != "" {
+		p = imagorpath.Apply(p, tenant.BaseParams)
+	}
+	return p
+}
+
+// negotiateParams applies client hints, Save-Data quality and auto format negotiated by request headers,
+// and excludes utility filters from the path of params
+func (app *Imagor) negotiateParams(
+	r *http.Request, p imagorpath.Params,
+) (_ imagorpath.Params, flags resultFlags, err error) {
+	var hasAutoWidth bool
+	if app.MaxAutoWidth > 0 {
+		var sized = p
+		if p, hasAutoWidth = app.applyAutoWidth(r, p); hasAutoWidth {
+			// client hint width is bound by allowed sizes the same way as URL width
+			p = app.snapAllowedSize(p, sized)
+		}
+	}
+	if app.DPRClientHints && !hasAutoWidth {
+		// width client hint is already in device pixels
+		p = app.snapAllowedSize(applyDPR(r, p), p)
+	}
+	var hasFormat, hasQuality bool
+	var filters = p.Filters
+	p.Filters = nil
+	for _, f := range filters {
+		switch f.Name {
+		case "expire":
+			// expire(timestamp) filter
+			if ts, e := strconv.ParseInt(f.Args, 10, 64); e == nil {
+				r.Header.Set("Cache-Control", "no-cache")
+				if exp := time.UnixMilli(ts); !exp.IsZero() && time.Now().After(exp) {
+					err = ErrExpired
+					return
+				}
+			}
+		case "format":
+			hasFormat = true
+		case "quality":
+			hasQuality = true
+		case "preview":
+			flags.preview = true // disable result storage on preview() filter
+		case "debug":
+			flags.debug = true
+		}
+		// exclude utility filters from result path
+		if f.Name != "expire" && f.Name != "attachment" && f.Name != "debug" && f.Name != "auto_width" {
+			p.Filters = append(p.Filters, f)
+		}
+	}
+	// lighter output on Save-Data or slow connection
+	var saveData = app.SaveDataQuality > 0 && isSaveData(r)
+	if saveData && !hasQuality {
+		p.Filters = append(p.Filters, imagorpath.Filter{
+			Name: "quality",
+			Args: strconv.Itoa(app.SaveDataQuality),
+		})
+	}
+	// auto WebP / AVIF
+	if !hasFormat && (app.AutoWebP || app.AutoAVIF || saveData) {
+		accept := r.Header.Get("Accept")
+		if (app.AutoAVIF || saveData) && strings.Contains(accept, "image/avif") {
+			p.Filters = append(p.Filters, imagorpath.Filter{
+				Name: "format",
+				Args: "avif",
+			})
+		} else if (app.AutoWebP || saveData) && strings.Contains(accept, "image/webp") {
+			p.Filters = append(p.Filters, imagorpath.Filter{
+				Name: "format",
+				Args: "webp",
+			})
+		}
+	}
+	// canonical path for result keys, that trivially different URLs share the same result
+	p.Path = imagorpath.NormalizeParams(p).Path
+	return p, flags, nil
+}
+
+// runProcessors processes blob by params with processors routed by content type,
+// forwarding blob and params of ErrForward to the next processor
+func (app *Imagor) runProcessors(
+	ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc, dump *debugDump,
+) (*Blob, error) {
+	var err error
+	var diag = diagnosticsFrom(ctx)
+	var forwardP = p
+	var processors = app.routeProcessors(blob)
+	if len(processors) == 0 && len(app.Processors) > 0 {
+		// routed to processor not configured
+		err = wrapStage(StageProcess, ErrUnsupportedFormat)
+	}
+	for _, processor := range processors {
+		spanCtx, span := app.startSpan(ctx, "imagor.process")
+		span.SetAttribute("imagor.processor", getType(processor))
+		span.SetAttribute("imagor.params", forwardP.Path)
+		b, e := checkBlob(processor.Process(spanCtx, blob, forwardP, load))
+		if _, ok := e.(ErrForward); ok {
+			span.End(nil)
+		} else {
+			span.End(e)
+		}
+		if !isBlobEmpty(b) {
+			blob = b // forward blob to next processor if exists
+			dump.stage(processor, b)
+		}
+		if e == nil {
+			blob = b
+			err = nil
+			diag.setProcessor(processor)
+			if app.Debug {
+				app.Logger.Debug("processed", zap.Any("params", forwardP))
+			}
+			break
+		} else if forward, ok := e.(ErrForward); ok {
+			err = e
+			forwardP = forward.Params
+			if app.Debug {
+				app.Logger.Debug("forward", zap.Any("params", forwardP))
+			}
+		} else {
+			if ctx.Err() == nil {
+				err = wrapStage(StageProcess, e)
+				app.Logger.Warn("process", zap.Any("params", p), zap.Error(err))
+				app.report(ctx, err, p)
+			} else {
+				err = ctx.Err()
+			}
+			break
+		}
+	}
+	return blob, err
+}
+
+// isSignatureValid checks if request params is unsafe allowed or signature matched
+func (app *Imagor) isSignatureValid(r *http.Request, p imagorpath.Params) bool {
+	if app.Unsafe && p.Unsafe && app.isUnsafeAllowed(r) {
+		return true
+	}
+	signer := app.signer(r.Context())
+	return signer == nil || p.Verify(signer)
+}
+
+// acquire process semaphore, with ErrTooManyRequests if exceeded process queue timeout
+func (app *Imagor) acquire(ctx context.Context) error {
+	if app.ProcessQueueTimeout <= 0 {
+		return app.sema.Acquire(ctx, 1)
+	}
+	queueCtx, cancel := context.WithTimeout(ctx, app.ProcessQueueTimeout)
+	defer cancel()
+	if err := app.sema.Acquire(queueCtx, 1); err != nil {
+		if ctx.Err() == nil {
+			return ErrTooManyRequests
+		}
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (app *Imagor) retryAfter() string {
+	if secs := int64(math.Ceil(app.ProcessQueueTimeout.Seconds())); secs > 1 {
+		return strconv.FormatInt(secs, 10)
+	}
+	return "1"
+}
+
+// stageTimeout returns timeout of load, process or save stage,
+// defaults to RequestTimeout if not set, or async job timeout for async job
+func (app *Imagor) stageTimeout(ctx context.Context, timeout time.Duration) time.Duration {
+	if timeout > 0 || isAsyncJob(ctx) {
+		return timeout
+	}
+	return app.RequestTimeout
+}
+
 func (app *Imagor) requestWithLoadContext(r *http.Request) *http.Request {
 	var ctx = r.Context()
 	var cancel func()
-	if app.LoadTimeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, app.LoadTimeout)
+	if timeout := app.stageTimeout(ctx, app.LoadTimeout); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		Defer(ctx, cancel)
 		return r.WithContext(ctx)
 	}
@@ -396,7 +1098,8 @@ func (app *Imagor) loadResult(r *http.Request, resultKey, imageKey string) *Blob
 	blob, origin, err := fromStorages(r, app.ResultStorages, resultKey)
 	if err == nil && !isBlobEmpty(blob) {
 		if app.ModifiedTimeCheck && origin != nil && blob.Stat != nil {
-			if sourceStat, err2 := app.storageStat(ctx, imageKey); sourceStat != nil && err2 == nil {
+			if sourceStat, err2 := app.sourceStat(ctx, imageKey); sourceStat != nil && err2 == nil &&
+				!sourceStat.ModifiedTime.IsZero() {
 				if !blob.Stat.ModifiedTime.Before(sourceStat.ModifiedTime) {
 					return blob
 				}
@@ -408,6 +1111,36 @@ func (app *Imagor) loadResult(r *http.Request, resultKey, imageKey string) *Blob
 	return nil
 }
 
+// isFallbackError checks if error is a load or process failure that fallback image applies,
+// i.e. not found, origin or processing errors, but not rejections e.g. rate limit or policy denial
+func isFallbackError(err error, e Error) bool {
+	if e.Code == http.StatusNotFound {
+		return true
+	}
+	switch ErrorStage(err) {
+	case StageLoad, StageProcess:
+		return true
+	}
+	return false
+}
+
+func (app *Imagor) loadFallback(r *http.Request) *Blob {
+	var ctx = WithContext(r.Context())
+	if app.RequestTimeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, app.RequestTimeout)
+		Defer(ctx, cancel)
+	}
+	blob, _, err := app.loadStorage(r.WithContext(ctx), app.FallbackImage)
+	if blob, err = checkBlob(blob, err); err != nil || isBlobEmpty(blob) {
+		if app.Debug {
+			app.Logger.Debug("fallback", zap.String("image", app.FallbackImage), zap.Error(err))
+		}
+		return nil
+	}
+	return blob
+}
+
 func fromStorages(
 	r *http.Request, storages []Storage, key string,
 ) (blob *Blob, origin Storage, err error) {
@@ -427,12 +1160,25 @@ func fromStorages(
 }
 
 func (app *Imagor) loadStorage(r *http.Request, key string) (blob *Blob, shouldSave bool, err error) {
+	// source of tenant may differ by prefixed storages
+	var cacheKey = tenantFrom(r.Context()).prefixKey(key)
+	if err = app.cachedLoadError(r.Context(), cacheKey); err != nil {
+		return
+	}
+	if blob = app.loadSourceCache(r.Context(), cacheKey); blob != nil {
+		return
+	}
 	r = app.requestWithLoadContext(r)
 	var origin Storage
 	blob, origin, err = app.fromStoragesAndLoaders(r, app.Storages, app.Loaders, key)
 	if !isBlobEmpty(blob) && origin == nil && err == nil && len(app.Storages) > 0 {
 		shouldSave = true
 	}
+	if err != nil {
+		app.cacheLoadError(r.Context(), cacheKey, err)
+	} else {
+		app.setSourceCache(r.Context(), cacheKey, blob)
+	}
 	return
 }
 
@@ -443,18 +1189,35 @@ func (app *Imagor) fromStoragesAndLoaders(
 		err = ErrNotFound
 		return
 	}
-	var storageKey = image
-	if app.StoragePathStyle != nil {
-		storageKey = app.StoragePathStyle.Hash(image)
-	}
-	if storageKey != "" {
+	if storageKey := app.storageKey(r.Context(), image); storageKey != "" {
 		blob, origin, err = fromStorages(r, storages, storageKey)
 		if !isBlobEmpty(blob) && origin != nil && err == nil {
 			return
 		}
 	}
-	for _, loader := range loaders {
-		b, e := checkBlob(loader.Get(r, image))
+	for i, loader := range loaders {
+		var cb *circuitBreaker
+		if i < len(app.breakers) {
+			cb = app.breakers[i]
+			if !cb.Allow() {
+				err = ErrLoaderUnavailable
+				continue
+			}
+		}
+		var lr = r
+		var span Span = noopSpan{}
+		if app.Tracer != nil {
+			var ctx context.Context
+			ctx, span = app.Tracer.Start(r.Context(), "imagor.load")
+			span.SetAttribute("imagor.loader", getType(loader))
+			span.SetAttribute("imagor.image", image)
+			lr = r.WithContext(ctx)
+		}
+		b, e := checkBlob(loader.Get(lr, image))
+		span.End(e)
+		if cb != nil {
+			cb.Done(e)
+		}
 		if !isBlobEmpty(b) {
 			blob = b
 			if e == nil {
@@ -470,22 +1233,85 @@ func (app *Imagor) fromStoragesAndLoaders(
 	return
 }
 
-func (app *Imagor) storageStat(ctx context.Context, key string) (stat *Stat, err error) {
+// storageKey returns storage key of image by storage path style, with tenant storage prefix
+func (app *Imagor) storageKey(ctx context.Context, image string) string {
+	var key = image
+	if app.StoragePathStyle != nil {
+		key = app.StoragePathStyle.Hash(image)
+	}
+	return tenantFrom(ctx).prefixKey(key)
+}
+
+// resultKey returns result storage key of params by result storage path style, with tenant storage prefix
+func (app *Imagor) resultKey(ctx context.Context, p imagorpath.Params) string {
+	var key = p.Path
+	if app.ResultStoragePathStyle != nil {
+		key = app.ResultStoragePathStyle.HashResult(p)
+	}
+	return tenantFrom(ctx).prefixKey(key)
+}
+
+// sourceStat stat source image from storages, or loaders that implement Stater
+func (app *Imagor) sourceStat(ctx context.Context, image string) (stat *Stat, err error) {
+	var storageKey = app.storageKey(ctx, image)
 	for _, storage := range app.Storages {
-		if stat, err = storage.Stat(ctx, key); stat != nil && err == nil {
+		if stat, err = storage.Stat(ctx, storageKey); stat != nil && err == nil {
 			return
 		}
 	}
+	for _, loader := range app.Loaders {
+		if stater, ok := loader.(Stater); ok {
+			if stat, err = stater.Stat(ctx, image); stat != nil && err == nil {
+				return
+			}
+		}
+	}
 	return
 }
 
-func (app *Imagor) save(ctx context.Context, storages []Storage, key string, blob *Blob) {
+func (app *Imagor) setCache(ctx context.Context, key string, blob *Blob, ttl time.Duration) {
+	if key == "" || isBlobEmpty(blob) || blob.Size() > maxMemorySize {
+		return
+	}
+	if err := app.Cache.Set(ctx, key, blob, ttl); err != nil {
+		app.Logger.Warn("cache", zap.String("key", key), zap.Error(err))
+	} else if app.Debug {
+		app.Logger.Debug("cached", zap.String("key", key))
+	}
+}
+
+// trackSave tracks in-flight storage write or async job for Shutdown to drain, returns func to mark it done
+func (app *Imagor) trackSave() func() {
+	app.saves.Add(1)
+	atomic.AddInt64(&app.pendingSaves, 1)
+	return func() {
+		atomic.AddInt64(&app.pendingSaves, -1)
+		app.saves.Done()
+	}
+}
+
+// drainSaves waits for in-flight storage writes until done or context deadline
+func (app *Imagor) drainSaves(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		app.saves.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		app.Logger.Warn("shutdown-saves",
+			zap.Int64("pending", atomic.LoadInt64(&app.pendingSaves)), zap.Error(ctx.Err()))
+	}
+}
+
+func (app *Imagor) save(ctx context.Context, p imagorpath.Params, storages []Storage, key string, blob *Blob) {
 	if key == "" {
 		return
 	}
-	if app.SaveTimeout > 0 {
+	if timeout := app.stageTimeout(ctx, app.SaveTimeout); timeout > 0 {
 		var cancel func()
-		ctx, cancel = context.WithTimeout(ctx, app.SaveTimeout)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 	var wg sync.WaitGroup
@@ -493,8 +1319,15 @@ func (app *Imagor) save(ctx context.Context, storages []Storage, key string, blo
 		wg.Add(1)
 		go func(storage Storage) {
 			defer wg.Done()
-			if err := storage.Put(ctx, key, blob); err != nil {
-				app.Logger.Warn("save", zap.String("key", key), zap.Error(err))
+			ctx, span := app.startSpan(ctx, "imagor.save")
+			span.SetAttribute("imagor.storage", getType(storage))
+			span.SetAttribute("imagor.key", key)
+			err := storage.Put(ctx, key, blob)
+			span.End(err)
+			if err != nil {
+				app.Logger.Warn("save", zap.String("key", key), zap.Error(wrapStage(StageSave, err)))
+				app.emit(ctx, Event{Type: EventSaveFailed, Key: key, Error: err.Error()})
+				app.report(ctx, wrapStage(StageSave, err), p)
 			} else if app.Debug {
 				app.Logger.Debug("saved", zap.String("key", key))
 			}
@@ -506,9 +1339,9 @@ func (app *Imagor) save(ctx context.Context, storages []Storage, key string, blo
 
 func (app *Imagor) del(ctx context.Context, storages []Storage, key string) {
 	ctx = DetachContext(ctx)
-	if app.SaveTimeout > 0 {
+	if timeout := app.stageTimeout(ctx, app.SaveTimeout); timeout > 0 {
 		var cancel func()
-		ctx, cancel = context.WithTimeout(ctx, app.SaveTimeout)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 	var wg sync.WaitGroup
@@ -597,6 +1430,7 @@ func (app *Imagor) debugLog() {
 		zap.Duration("process_timeout", app.ProcessTimeout),
 		zap.Duration("save_timeout", app.SaveTimeout),
 		zap.Int64("process_concurrency", app.ProcessConcurrency),
+		zap.Int64("process_memory_limit", app.ProcessMemoryLimit),
 		zap.Duration("cache_header_ttl", app.CacheHeaderTTL),
 		zap.Strings("loaders", loaders),
 		zap.Strings("storages", storages),
@@ -610,11 +1444,7 @@ func checkStatNotModified(w http.ResponseWriter, r *http.Request, stat *Stat) bo
 		return false
 	}
 	var isETagMatch, isNotModified bool
-	var etag = stat.ETag
-	if etag == "" && stat.Size > 0 && !stat.ModifiedTime.IsZero() {
-		etag = fmt.Sprintf(
-			"%x-%x", int(stat.ModifiedTime.Unix()), int(stat.Size))
-	}
+	var etag = statETag(stat)
 	if etag != "" {
 		w.Header().Set("ETag", etag)
 		if inm := r.Header.Get("If-None-Match"); inm == etag {
@@ -622,7 +1452,7 @@ func checkStatNotModified(w http.ResponseWriter, r *http.Request, stat *Stat) bo
 		}
 	}
 	if mTime := stat.ModifiedTime; !mTime.IsZero() {
-		w.Header().Set("Last-Modified", mTime.Format(http.TimeFormat))
+		w.Header().Set("Last-Modified", mTime.UTC().Format(http.TimeFormat))
 		if ims := r.Header.Get("If-Modified-Since"); ims != "" {
 			if imsTime, err := time.Parse(http.TimeFormat, ims); err == nil {
 				isNotModified = mTime.Before(imsTime)
@@ -661,13 +1491,33 @@ func getCacheControl(ttl, swr time.Duration) string {
 }
 
 func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	writeJSONStatus(w, r, http.StatusOK, v)
+}
+
+// writeJSONStatus writes JSON with status code, after the JSON headers are set
+func writeJSONStatus(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
 	buf, _ := json.Marshal(v)
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(buf)
+	}
+}
+
+// writeError writes error as JSON error envelope with its status code
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := WrapError(err)
+	if e.Code < http.StatusBadRequest || e.Code > 599 {
+		e.Code = http.StatusInternalServerError
+	}
+	buf, _ := json.Marshal(e)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.WriteHeader(e.Code)
 	if r.Method != http.MethodHead {
 		_, _ = w.Write(buf)
 	}
-	return
 }
 
 func writeJSONIndent(w http.ResponseWriter, r *http.Request, v interface{}) {
@@ -685,21 +1535,41 @@ func writeBody(w http.ResponseWriter, r *http.Request, reader io.ReadCloser, siz
 		_ = reader.Close()
 	}()
 	if size > 0 {
-		// total size known, use io.Copy
+		// total size known, copy with pooled buffer
 		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
 		if r.Method != http.MethodHead {
-			_, _ = io.Copy(w, reader)
+			_, _ = bufpool.Copy(w, reader)
 		}
 	} else {
-		// total size unknown, read all
-		buf, _ := io.ReadAll(reader)
-		w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+		// total size unknown, read all into pooled buffer
+		buf := bufpool.Get()
+		defer bufpool.Put(buf)
+		_, _ = buf.ReadFrom(reader)
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
 		if r.Method != http.MethodHead {
-			_, _ = w.Write(buf)
+			_, _ = w.Write(buf.Bytes())
 		}
 	}
 }
 
+// serveFile serves untransformed blob of file storage hit from the opened file,
+// with Range requests and sendfile handled by http.ServeContent. Returns false if file cannot be opened
+func serveFile(w http.ResponseWriter, r *http.Request, blob *Blob) bool {
+	file, err := os.Open(blob.FilePath())
+	if err != nil {
+		return false
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	var modTime time.Time
+	if blob.Stat != nil {
+		modTime = blob.Stat.ModifiedTime
+	}
+	http.ServeContent(w, r, "", modTime, file)
+	return true
+}
+
 func getContentDisposition(p imagorpath.Params, blob *Blob) string {
 	for _, f := range p.Filters {
 		if f.Name == "attachment" {
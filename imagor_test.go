@@ -15,8 +15,12 @@ import (
 	"math/rand"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -86,7 +90,8 @@ func TestWithUnsafe(t *testing.T) {
 	app.ServeHTTP(w, httptest.NewRequest(
 		http.MethodPost, "https://example.com/unsafe/foo.jpg", nil))
 	assert.Equal(t, 405, w.Code)
-	assert.Equal(t, "", w.Body.String())
+	assert.Equal(t, `{"status":405,"code":"METHOD_NOT_ALLOWED","message":"method not allowed"}`, w.Body.String())
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 
 	w = httptest.NewRecorder()
 	app.ServeHTTP(w, httptest.NewRequest(
@@ -95,6 +100,53 @@ func TestWithUnsafe(t *testing.T) {
 	assert.Equal(t, w.Body.String(), jsonStr(ErrSignatureMismatch))
 }
 
+func TestWithUnsafeAllowedNetworks(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithUnsafeAllowedNetworks(mustParseCIDRs("10.0.0.0/8")...),
+		WithTrustedProxies(mustParseCIDRs("172.16.0.0/12")...),
+		WithSigner(imagorpath.NewDefaultSigner("1234")),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithLogger(zap.NewExample()))
+
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil)
+	r.RemoteAddr = "10.1.2.3:1234"
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil)
+	r.RemoteAddr = "1.2.3.4:1234"
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, jsonStr(ErrSignatureMismatch), w.Body.String())
+
+	r = httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil)
+	r.RemoteAddr = "172.16.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "10.1.2.3")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil)
+	r.RemoteAddr = "1.2.3.4:1234"
+	r.Header.Set("X-Forwarded-For", "10.1.2.3")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 403, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, "https://example.com/"+imagorpath.Generate(imagorpath.Params{
+		Image: "foo.jpg",
+	}, app.Signer), nil)
+	r.RemoteAddr = "1.2.3.4:1234"
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+}
+
 func TestWithContentDisposition(t *testing.T) {
 	logger := zap.NewExample()
 	app := New(
@@ -243,6 +295,102 @@ func TestWithCustomSigner(t *testing.T) {
 	assert.Equal(t, w.Body.String(), jsonStr(ErrSignatureMismatch))
 }
 
+func TestWithSignerTruncate(t *testing.T) {
+	for _, truncate := range []int{8, 16, 28} {
+		t.Run(fmt.Sprintf("truncate %d", truncate), func(t *testing.T) {
+			signer := imagorpath.NewHMACSigner(sha256.New, truncate, "1234")
+			app := New(
+				WithLogger(zap.NewExample()),
+				WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+					return NewBlobFromBytes([]byte("foo")), nil
+				})),
+				WithSigner(signer))
+			path := imagorpath.Generate(imagorpath.Params{
+				Image:  "foo.jpg",
+				Width:  167,
+				Height: 169,
+			}, signer)
+			assert.Equal(t, truncate, strings.Index(path, "/"))
+
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(
+				http.MethodGet, "https://example.com/"+path, nil))
+			assert.Equal(t, 200, w.Code)
+
+			w = httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(
+				http.MethodGet, "https://example.com/"+strings.Repeat("a", truncate)+path[truncate:], nil))
+			assert.Equal(t, 403, w.Code)
+		})
+	}
+}
+
+func TestWithCrypter(t *testing.T) {
+	crypter := imagorpath.NewAESCrypter("abcd")
+	app := New(
+		WithDebug(true),
+		WithLogger(zap.NewExample()),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithSigner(imagorpath.NewDefaultSigner("1234")),
+		WithCrypter(crypter))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/"+imagorpath.GenerateEncrypted(imagorpath.Params{
+			Image: "abcdefgh/foo.jpg",
+			Width: 100,
+		}, crypter), nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "abcdefgh/foo.jpg", w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/"+imagorpath.GenerateEncrypted(imagorpath.Params{
+			Image: "foo.jpg",
+		}, imagorpath.NewAESCrypter("dcba")), nil))
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, jsonStr(ErrSignatureMismatch), w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/"+imagorpath.Generate(imagorpath.Params{
+			Image: "bar.jpg",
+		}, app.Signer), nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "bar.jpg", w.Body.String())
+}
+
+func TestWithLegacyCrypter(t *testing.T) {
+	crypter := imagorpath.NewThumborLegacyCrypter("abcd")
+	app := New(
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithSigner(imagorpath.NewDefaultSigner("abcd")),
+		WithLegacyCrypter(crypter))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/"+crypter.Encrypt("300x200/smart/foo/bar.jpg"), nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foo/bar.jpg", w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/"+imagorpath.NewThumborLegacyCrypter("dcba").Encrypt("300x200/foo.jpg"), nil))
+	assert.Equal(t, 403, w.Code)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/"+imagorpath.Generate(imagorpath.Params{
+			Image: "bar.jpg", Width: 100,
+		}, app.Signer), nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "bar.jpg", w.Body.String())
+}
+
 func TestNewBlobFromPathNotFound(t *testing.T) {
 	loader := loaderFunc(func(r *http.Request, image string) (*Blob, error) {
 		return NewBlobFromFile("./non-exists-path"), nil
@@ -290,6 +438,210 @@ func TestWithDisableErrorBody(t *testing.T) {
 	assert.Empty(t, w.Body.String())
 }
 
+func TestHeadRequest(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithLogger(zap.NewExample()),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "foo.jpg" {
+				return NewBlobFromFile("testdata/gopher.png"), nil
+			}
+			return nil, ErrNotFound
+		})))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodHead, "https://example.com/unsafe/foo.jpg", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.NotEmpty(t, w.Header().Get("Content-Length"))
+	assert.NotEmpty(t, w.Header().Get("Cache-Control"))
+	assert.Empty(t, w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodHead, "https://example.com/unsafe/bar.jpg", nil))
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, strconv.Itoa(len(jsonStr(ErrNotFound))), w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.Bytes())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodHead, "https://example.com/", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Body.Bytes())
+}
+
+func TestWithFallbackImage(t *testing.T) {
+	loader := loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+		if image == "placeholder.png" {
+			return NewBlobFromFile("testdata/gopher.png"), nil
+		}
+		return nil, ErrNotFound
+	})
+	t.Run("error status code", func(t *testing.T) {
+		app := New(
+			WithUnsafe(true),
+			WithLogger(zap.NewExample()),
+			WithLoaders(loader),
+			WithFallbackImage("placeholder.png"))
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodGet, "https://example.com/unsafe/foo.jpg", nil))
+		assert.Equal(t, 404, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "private, no-cache, no-store, must-revalidate", w.Header().Get("Cache-Control"))
+		buf, _ := os.ReadFile("testdata/gopher.png")
+		assert.Equal(t, buf, w.Body.Bytes())
+
+		w = httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodGet, "https://example.com/unsafe/meta/foo.jpg", nil))
+		assert.Equal(t, 404, w.Code)
+		assert.Equal(t, jsonStr(ErrNotFound), w.Body.String())
+	})
+	t.Run("custom status code", func(t *testing.T) {
+		app := New(
+			WithUnsafe(true),
+			WithLogger(zap.NewExample()),
+			WithLoaders(loader),
+			WithFallbackImage("placeholder.png"),
+			WithFallbackStatusCode(200))
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodGet, "https://example.com/unsafe/foo.jpg", nil))
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+
+		w = httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodGet, "https://example.com/foo.jpg", nil))
+		assert.Equal(t, 403, w.Code)
+		assert.Equal(t, jsonStr(ErrSignatureMismatch), w.Body.String())
+	})
+	t.Run("rejected request", func(t *testing.T) {
+		app := New(
+			WithUnsafe(true),
+			WithLogger(zap.NewExample()),
+			WithLoaders(loader),
+			WithFallbackImage("placeholder.png"),
+			WithFallbackStatusCode(200))
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodGet, "https://example.com/unsafe/filters:expire(1)/foo.jpg", nil))
+		assert.Equal(t, 410, w.Code)
+		assert.Equal(t, jsonStr(ErrExpired), w.Body.String())
+	})
+	t.Run("fallback not found", func(t *testing.T) {
+		app := New(
+			WithUnsafe(true),
+			WithLogger(zap.NewExample()),
+			WithDebug(true),
+			WithLoaders(loader),
+			WithFallbackImage("missing.png"))
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodGet, "https://example.com/unsafe/foo.jpg", nil))
+		assert.Equal(t, 404, w.Code)
+		assert.Equal(t, jsonStr(ErrNotFound), w.Body.String())
+	})
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithLogger(zap.NewExample()),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "boom" {
+				return nil, errors.New("boom")
+			}
+			return nil, ErrNotFound
+		})),
+		WithErrorBody(http.StatusNotFound, "text/html", []byte("<h1>not found</h1>")),
+		WithErrorHandler(0, func(w http.ResponseWriter, r *http.Request, err Error) {
+			w.WriteHeader(err.Code)
+			_, _ = w.Write([]byte("oops " + strconv.Itoa(err.Code)))
+		}),
+	)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/foo.jpg", nil))
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, "text/html", w.Header().Get("Content-Type"))
+	assert.Equal(t, "<h1>not found</h1>", w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodHead, "https://example.com/unsafe/foo.jpg", nil))
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, "18", w.Header().Get("Content-Length"))
+	assert.Empty(t, w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/boom", nil))
+	assert.Equal(t, 500, w.Code)
+	assert.Equal(t, "oops 500", w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/foo.jpg", nil))
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, "oops 403", w.Body.String())
+
+	app = New(
+		WithCrypter(imagorpath.NewAESCrypter("abcd")),
+		WithErrorBody(http.StatusForbidden, "text/html", []byte("<h1>forbidden</h1>")),
+	)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/enc/invalid-token", nil))
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, "text/html", w.Header().Get("Content-Type"))
+	assert.Equal(t, "<h1>forbidden</h1>", w.Body.String())
+}
+
+func TestWithPathPrefix(t *testing.T) {
+	app := New(
+		WithLogger(zap.NewExample()),
+		WithPathPrefix("/img/"),
+		WithSigner(imagorpath.NewDefaultSigner("1234")),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})))
+	assert.Equal(t, "/img/", app.PathPrefix)
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/img/"+imagorpath.Generate(imagorpath.Params{
+			Image: "foo.jpg",
+			Width: 100,
+		}, app.Signer), nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foo.jpg", w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/"+imagorpath.Generate(imagorpath.Params{
+			Image: "foo.jpg",
+			Width: 100,
+		}, app.Signer), nil))
+	assert.Equal(t, 404, w.Code)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/imgfoo/bar", nil))
+	assert.Equal(t, 404, w.Code)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/img", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, fmt.Sprintf(`{"imagor":{"version":"%s"}}`, Version), w.Body.String())
+}
+
 func TestWithCacheHeaderTTL(t *testing.T) {
 	loader := loaderFunc(func(r *http.Request, image string) (blob *Blob, err error) {
 		return NewBlobFromBytes([]byte("ok")), nil
@@ -345,6 +697,40 @@ func TestWithCacheHeaderTTL(t *testing.T) {
 	})
 }
 
+func TestWithResponseHeader(t *testing.T) {
+	app := New(
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (blob *Blob, err error) {
+			if image == "missing" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte("ok")), nil
+		})),
+		WithResponseHeader("x-content-type-options", "nosniff"),
+		WithResponseHeader("X-Foo", "bar"),
+		WithResponseHeader("X-Foo", "baz"),
+		WithResponseHeader("", "ignored"),
+		WithUnsafe(true))
+	assert.Len(t, app.ResponseHeaders, 2)
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/foo.jpg", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, []string{"bar", "baz"}, w.Header().Values("X-Foo"))
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/missing", nil))
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/", nil))
+	assert.Empty(t, w.Header().Get("X-Content-Type-Options"))
+}
+
 func TestExpire(t *testing.T) {
 	loader := loaderFunc(func(r *http.Request, image string) (blob *Blob, err error) {
 		return NewBlobFromBytes([]byte("ok")), nil
@@ -425,7 +811,10 @@ func TestParams(t *testing.T) {
 	w := httptest.NewRecorder()
 	app.ServeHTTP(w, r)
 	assert.Equal(t, 200, w.Code)
-	buf, _ := json.MarshalIndent(imagorpath.Parse(r.URL.EscapedPath()), "", "  ")
+	buf, _ := json.MarshalIndent(paramsResult{
+		Params:         imagorpath.Parse(r.URL.EscapedPath()),
+		SignatureValid: true,
+	}, "", "  ")
 	assert.Equal(t, string(buf), w.Body.String())
 
 	r = httptest.NewRequest(
@@ -433,9 +822,19 @@ func TestParams(t *testing.T) {
 	w = httptest.NewRecorder()
 	app.ServeHTTP(w, r)
 	assert.Equal(t, 200, w.Code)
-	buf, _ = json.MarshalIndent(imagorpath.Parse(r.URL.EscapedPath()), "", "  ")
+	buf, _ = json.MarshalIndent(paramsResult{
+		Params: imagorpath.Parse(r.URL.EscapedPath()),
+	}, "", "  ")
 	assert.Equal(t, string(buf), w.Body.String())
 
+	r = httptest.NewRequest(
+		http.MethodGet, "https://example.com/params/_-19cQt1szHeUV0WyWFntvTImDI=/bar.jpg", nil)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"signature_valid": false`)
+	assert.NotContains(t, w.Body.String(), app.Signer.Sign("bar.jpg"))
+
 	app = New(
 		WithDebug(true),
 		WithLogger(zap.NewExample()),
@@ -712,25 +1111,106 @@ func TestWithLoadersStoragesProcessors(t *testing.T) {
 	}
 }
 
-func TestWithResultStorageNotModified(t *testing.T) {
-	resultStore := newMapStore()
+type mapCache struct {
+	l   sync.Mutex
+	Map map[string]*Blob
+	TTL map[string]time.Duration
+}
+
+func newMapCache() *mapCache {
+	return &mapCache{Map: map[string]*Blob{}, TTL: map[string]time.Duration{}}
+}
+
+func (c *mapCache) Get(_ context.Context, key string) (*Blob, error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if blob, ok := c.Map[key]; ok {
+		return blob, nil
+	}
+	return nil, ErrNotFound
+}
+
+func (c *mapCache) Set(_ context.Context, key string, blob *Blob, ttl time.Duration) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.Map[key] = blob
+	c.TTL[key] = ttl
+	return nil
+}
+
+func (c *mapCache) Delete(_ context.Context, key string) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+	delete(c.Map, key)
+	delete(c.TTL, key)
+	return nil
+}
+
+func TestWithResultCache(t *testing.T) {
+	cache := newMapCache()
+	var processCnt int
 	app := New(
 		WithDebug(true),
+		WithUnsafe(true),
 		WithLogger(zap.NewExample()),
-		WithResultStorages(resultStore),
+		WithCache(cache),
+		WithResultCacheTTL(time.Minute),
 		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "boom" {
+				return nil, ErrNotFound
+			}
 			return NewBlobFromBytes([]byte(image)), nil
 		})),
-		WithUnsafe(true),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			processCnt++
+			buf, _ := blob.ReadAll()
+			return NewBlobFromBytes([]byte(string(buf) + "-processed")), nil
+		})),
 	)
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(
+			http.MethodGet, "https://example.com/unsafe/fit-in/100x100/foo", nil))
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "foo-processed", w.Body.String())
+	}
+	assert.Equal(t, 1, processCnt)
+	assert.Contains(t, cache.Map, "result:fit-in/100x100/foo")
+	assert.Equal(t, time.Minute, cache.TTL["result:fit-in/100x100/foo"])
+
 	w := httptest.NewRecorder()
-	r := httptest.NewRequest(
-		http.MethodGet, "https://example.com/unsafe/foo", nil)
-	app.ServeHTTP(w, r)
-	time.Sleep(time.Millisecond * 10) // make sure storage reached
-	assert.Equal(t, 200, w.Code)
-	assert.Equal(t, "foo", w.Body.String())
-	assert.Empty(t, w.Header().Get("ETag"))
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/fit-in/100x100/boom", nil))
+	assert.Equal(t, 404, w.Code)
+	assert.NotContains(t, cache.Map, "result:fit-in/100x100/boom")
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/filters:preview()/foo", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.NotContains(t, cache.Map, "result:filters:preview()/foo")
+	assert.Equal(t, 2, processCnt)
+}
+
+func TestWithResultStorageNotModified(t *testing.T) {
+	resultStore := newMapStore()
+	app := New(
+		WithDebug(true),
+		WithLogger(zap.NewExample()),
+		WithResultStorages(resultStore),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithUnsafe(true),
+	)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/foo", nil)
+	app.ServeHTTP(w, r)
+	time.Sleep(time.Millisecond * 10) // make sure storage reached
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foo", w.Body.String())
+	assert.Empty(t, w.Header().Get("ETag"))
 
 	w = httptest.NewRecorder()
 	r = httptest.NewRequest(
@@ -1000,6 +1480,46 @@ func TestWithProcessQueueSize(t *testing.T) {
 	assert.Equal(t, n-size-conn, result[429])
 }
 
+func TestWithProcessQueueTimeout(t *testing.T) {
+	n := 5
+	app := New(
+		WithDebug(true),
+		WithUnsafe(true),
+		WithLogger(zap.NewExample()),
+		WithProcessConcurrency(1),
+		WithProcessQueueTimeout(time.Millisecond*15),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			time.Sleep(time.Millisecond * 50)
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	type res struct {
+		Code       int
+		RetryAfter string
+	}
+	cnt := make(chan res, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(
+				http.MethodGet, fmt.Sprintf("https://example.com/unsafe/%d", i), nil))
+			cnt <- res{w.Code, w.Header().Get("Retry-After")}
+		}(i)
+	}
+	result := map[int]int{}
+	for i := 0; i < n; i++ {
+		r := <-cnt
+		result[r.Code]++
+		if r.Code == 429 {
+			assert.Equal(t, "1", r.RetryAfter)
+		} else {
+			assert.Empty(t, r.RetryAfter)
+		}
+	}
+	assert.Equal(t, 1, result[200])
+	assert.Equal(t, 4, result[429])
+}
+
 func TestWithProcessConcurrency(t *testing.T) {
 	n := 5
 	app := New(
@@ -1130,7 +1650,7 @@ func TestBaseParams(t *testing.T) {
 		http.MethodGet, "https://example.com/unsafe/fit-in/200x0/filters:format(jpg)/abc.png", nil)
 	app.ServeHTTP(w, r)
 	assert.Equal(t, 200, w.Code)
-	assert.Equal(t, "fit-in/200x0/filters:format(jpg):watermark(example.jpg)/abc.png", w.Body.String())
+	assert.Equal(t, "fit-in/200x0/filters:watermark(example.jpg):format(jpg)/abc.png", w.Body.String())
 }
 
 func TestAutoWebP(t *testing.T) {
@@ -1167,6 +1687,7 @@ func TestAutoWebP(t *testing.T) {
 		app.ServeHTTP(w, r)
 		assert.Equal(t, 200, w.Code)
 		assert.Equal(t, w.Body.String(), "filters:format(webp)/abc.png")
+		assert.Equal(t, "Accept", w.Header().Get("Vary"))
 	})
 	t.Run("supported not image tag auto", func(t *testing.T) {
 		app := factory(true)
@@ -1197,6 +1718,7 @@ func TestAutoWebP(t *testing.T) {
 		app.ServeHTTP(w, r)
 		assert.Equal(t, 200, w.Code)
 		assert.Equal(t, w.Body.String(), "filters:format(jpg)/abc.png")
+		assert.Empty(t, w.Header().Get("Vary"))
 	})
 }
 
@@ -1409,3 +1931,731 @@ func TestSuppression(t *testing.T) {
 	}
 	assert.NotEqual(t, resMap["a"], resMap["b"])
 }
+
+func TestSuppressionProcess(t *testing.T) {
+	var loadCnt, processCnt int64
+	var l sync.Mutex
+	app := New(
+		WithUnsafe(true),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			l.Lock()
+			loadCnt++
+			l.Unlock()
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			l.Lock()
+			processCnt++
+			l.Unlock()
+			time.Sleep(time.Millisecond * 100)
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	n := 200
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, httptest.NewRequest(
+				http.MethodGet, "https://example.com/unsafe/fit-in/100x100/foo.jpg", nil))
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, "fit-in/100x100/foo.jpg", w.Body.String())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), loadCnt)
+	assert.Equal(t, int64(1), processCnt)
+}
+
+func TestDoErrorStage(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "missing" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return nil, ErrUnsupportedFormat
+		})),
+	)
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+
+	_, err := app.Do(r, imagorpath.Params{Unsafe: true, Image: "missing"})
+	assert.Equal(t, StageLoad, ErrorStage(err))
+	assert.True(t, errors.Is(err, ErrNotFound))
+
+	_, err = app.Do(r, imagorpath.Params{Unsafe: true, Image: "foo"})
+	assert.Equal(t, StageProcess, ErrorStage(err))
+	assert.True(t, errors.Is(err, ErrUnsupportedFormat))
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/missing", nil))
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, jsonStr(ErrNotFound), w.Body.String())
+}
+
+type healthStorage struct {
+	*mapStore
+	Err error
+}
+
+func (s *healthStorage) Health(ctx context.Context) error {
+	return s.Err
+}
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+	store := &healthStorage{mapStore: newMapStore()}
+	app := New(
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return nil, ErrNotFound
+		})),
+		WithStorages(store),
+	)
+	assert.NoError(t, app.Health(ctx))
+
+	store.Err = errors.New("unreachable")
+	err := app.Health(ctx)
+	assert.ErrorIs(t, err, store.Err)
+	assert.Contains(t, err.Error(), "healthStorage")
+	assert.Equal(t, `"storages[0] *imagor.healthStorage: unreachable"`,
+		healthVars.Get("storages[0] *imagor.healthStorage").String())
+
+	resultStore := &healthStorage{mapStore: newMapStore(), Err: errors.New("forbidden")}
+	app.ResultStorages = []Storage{resultStore}
+	err = app.Health(ctx)
+	assert.ErrorIs(t, err, store.Err)
+	assert.ErrorIs(t, err, resultStore.Err)
+	assert.Equal(t, "storages[0] *imagor.healthStorage: unreachable; "+
+		"result_storages[0] *imagor.healthStorage: forbidden", err.Error())
+
+	store.Err = nil
+	resultStore.Err = nil
+	assert.NoError(t, app.Health(ctx))
+	assert.Equal(t, `"ok"`, healthVars.Get("storages[0] *imagor.healthStorage").String())
+}
+
+func TestHealthCheckInterval(t *testing.T) {
+	ctx := context.Background()
+	loader := &healthLoader{Err: errors.New("bad credentials")}
+	app := New(WithLoaders(loader), WithHealthCheckInterval(time.Millisecond*10))
+	require.NoError(t, app.Startup(ctx))
+	assert.Eventually(t, func() bool {
+		return healthVars.Get("loaders[0] *imagor.healthLoader").String() == `"loaders[0] *imagor.healthLoader: bad credentials"`
+	}, time.Second, time.Millisecond*10)
+	require.NoError(t, app.Shutdown(ctx))
+	assert.Nil(t, app.stopHealthChecks)
+}
+
+type healthLoader struct {
+	mu  sync.Mutex
+	Err error
+}
+
+func (l *healthLoader) Get(r *http.Request, image string) (*Blob, error) {
+	return nil, ErrNotFound
+}
+
+func (l *healthLoader) Health(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.Err
+}
+
+type eventRecorder struct {
+	l      sync.Mutex
+	Events []Event
+}
+
+func (h *eventRecorder) HandleEvent(_ context.Context, event Event) {
+	h.l.Lock()
+	defer h.l.Unlock()
+	h.Events = append(h.Events, event)
+}
+
+func (h *eventRecorder) types() (types []EventType) {
+	h.l.Lock()
+	defer h.l.Unlock()
+	for _, e := range h.Events {
+		types = append(types, e.Type)
+	}
+	return
+}
+
+type failStore struct {
+	*mapStore
+}
+
+func (s failStore) Put(ctx context.Context, image string, blob *Blob) error {
+	return errors.New("disk full")
+}
+
+func TestWithEventHandlers(t *testing.T) {
+	events := &eventRecorder{}
+	app := New(
+		WithUnsafe(true),
+		WithEventHandlers(events),
+		WithResultStorages(failStore{newMapStore()}),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "missing" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/100x100/foo", nil))
+	assert.Equal(t, 200, w.Code)
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, []EventType{EventProcessed, EventSaveFailed}, events.types())
+	assert.Equal(t, "100x100/foo", events.Events[0].Path)
+	assert.Equal(t, "foo", events.Events[0].Image)
+	assert.Equal(t, "100x100/foo", events.Events[1].Key)
+	assert.Equal(t, "disk full", events.Events[1].Error)
+	assert.False(t, events.Events[0].Time.IsZero())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/missing", nil))
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, []EventType{EventProcessed, EventSaveFailed, EventLoadFailed}, events.types())
+	assert.Equal(t, "missing", events.Events[2].Image)
+}
+
+type errorRecorder struct {
+	l      sync.Mutex
+	Errors []string
+}
+
+func (h *errorRecorder) ReportError(_ context.Context, err error, p imagorpath.Params) {
+	h.l.Lock()
+	defer h.l.Unlock()
+	h.Errors = append(h.Errors, string(ErrorStage(err))+" "+p.Image+" "+err.Error())
+}
+
+func (h *errorRecorder) errors() []string {
+	h.l.Lock()
+	defer h.l.Unlock()
+	return append([]string{}, h.Errors...)
+}
+
+func TestWithErrorReporter(t *testing.T) {
+	reporter := &errorRecorder{}
+	app := New(
+		WithUnsafe(true),
+		WithErrorReporter(reporter),
+		WithResultStorages(failStore{newMapStore()}),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			switch image {
+			case "missing":
+				return nil, ErrNotFound
+			case "broken":
+				return nil, errors.New("connection reset")
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			if p.Image == "bad" {
+				return nil, ErrUnsupportedFormat
+			}
+			if p.Image == "panic" {
+				return nil, errors.New("vips error")
+			}
+			return blob, nil
+		})),
+	)
+	for _, path := range []string{"missing", "bad", "broken", "panic", "foo"} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+path, nil))
+	}
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, []string{
+		"load broken load: connection reset",
+		"process panic process: vips error",
+		"save foo save: disk full",
+	}, reporter.errors())
+}
+
+func TestWithSourceValidation(t *testing.T) {
+	buf, err := os.ReadFile("testdata/gopher.png")
+	require.NoError(t, err)
+	store := newMapStore()
+	app := New(
+		WithUnsafe(true),
+		WithStorages(store),
+		WithMaxSourceSize(int64(len(buf))),
+		WithSourceValidation(true, "image/svg+xml", "application/*"),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			switch image {
+			case "gopher.png":
+				return NewBlobFromBytes(buf), nil
+			case "large.png":
+				return NewBlobFromBytes(append(buf, 0)), nil
+			case "foo.svg":
+				blob := NewBlobFromBytes([]byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+				blob.SetContentType("image/svg+xml")
+				return blob, nil
+			case "foo.pdf":
+				return NewBlobFromBytes([]byte("%PDF-1.4 foo")), nil
+			}
+			return NewBlobFromBytes([]byte("not an image")), nil
+		})),
+	)
+	for _, tt := range []struct {
+		path string
+		code int
+	}{
+		{"gopher.png", 200},
+		{"large.png", 400},
+		{"foo.svg", 200},
+		{"foo.pdf", 200},
+		{"foo.txt", 422},
+	} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+tt.path, nil))
+		assert.Equal(t, tt.code, w.Code, tt.path)
+		if tt.code == 422 {
+			assert.Equal(t, jsonStr(ErrInvalidImage), w.Body.String())
+		}
+	}
+	assert.NotNil(t, store.Map["gopher.png"])
+	assert.Nil(t, store.Map["large.png"])
+	assert.Nil(t, store.Map["foo.txt"])
+}
+
+func TestServeBlob(t *testing.T) {
+	store := newMapStore()
+	app := New(
+		WithSigner(imagorpath.NewDefaultSigner("1234")),
+		WithResultStorages(store),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "missing" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			assert.Equal(t, "internal", APIKeyName(ctx))
+			buf, _ := blob.ReadAll()
+			return NewBlobFromBytes([]byte(fmt.Sprintf("%s:%d", buf, p.Width))), nil
+		})),
+	)
+	blob, err := app.ServeBlob(context.Background(), imagorpath.Params{Image: "foo", Width: 100})
+	require.NoError(t, err)
+	buf, err := blob.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "foo:100", string(buf))
+	assert.Eventually(t, func() bool {
+		_, err := store.Get(&http.Request{}, "100x0/foo")
+		return err == nil
+	}, time.Second, time.Millisecond*10)
+
+	_, err = app.ServeBlob(context.Background(), imagorpath.Params{Image: "missing", Unsafe: true})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStageTimeout(t *testing.T) {
+	ctx := context.Background()
+	app := New(WithRequestTimeout(time.Second), WithLoadTimeout(time.Millisecond))
+	assert.Equal(t, time.Millisecond, app.stageTimeout(ctx, app.LoadTimeout))
+	assert.Equal(t, time.Second, app.stageTimeout(ctx, app.ProcessTimeout))
+	assert.Equal(t, time.Second, app.stageTimeout(ctx, app.SaveTimeout))
+
+	ctx = context.WithValue(ctx, asyncJobKey{}, true)
+	assert.Equal(t, time.Millisecond, app.stageTimeout(ctx, app.LoadTimeout))
+	assert.Empty(t, app.stageTimeout(ctx, app.ProcessTimeout))
+}
+
+type statLoader struct {
+	loaderFunc
+	ModTime time.Time
+}
+
+func (l *statLoader) Stat(ctx context.Context, image string) (*Stat, error) {
+	return &Stat{ModifiedTime: l.ModTime}, nil
+}
+
+func TestModifiedTimeCheckLoaderStat(t *testing.T) {
+	var processCnt int
+	resultStore := newMapStore()
+	loader := &statLoader{loaderFunc: func(r *http.Request, image string) (*Blob, error) {
+		return NewBlobFromBytes([]byte(image)), nil
+	}}
+	app := New(
+		WithLoaders(loader),
+		WithResultStorages(resultStore),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			processCnt++
+			return blob, nil
+		})),
+		WithUnsafe(true),
+		WithModifiedTimeCheck(true),
+	)
+	doGet := func() {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo", nil))
+		time.Sleep(time.Millisecond * 10) // make sure storage reached
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "foo", w.Body.String())
+	}
+	loader.ModTime = clock
+	doGet()
+	doGet()
+	assert.Equal(t, 1, processCnt, "should serve fresh stored result")
+
+	loader.ModTime = clock.Add(time.Hour)
+	doGet()
+	assert.Equal(t, 2, processCnt, "should reprocess stale stored result")
+}
+
+type lifecycleStorage struct {
+	*mapStore
+	Name        string
+	Events      *[]string
+	ShutdownErr error
+}
+
+func (s *lifecycleStorage) Startup(_ context.Context) error {
+	*s.Events = append(*s.Events, "startup "+s.Name)
+	return nil
+}
+
+func (s *lifecycleStorage) Shutdown(_ context.Context) error {
+	*s.Events = append(*s.Events, "shutdown "+s.Name)
+	return s.ShutdownErr
+}
+
+func TestLoaderStorageLifecycle(t *testing.T) {
+	ctx := context.Background()
+	var events []string
+	store := &lifecycleStorage{mapStore: newMapStore(), Name: "store", Events: &events}
+	resultStore := &lifecycleStorage{
+		mapStore: newMapStore(), Name: "result", Events: &events, ShutdownErr: errors.New("flush failed")}
+	app := New(
+		WithLoaders(store, loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return nil, ErrNotFound
+		})),
+		WithStorages(store),
+		WithResultStorages(resultStore),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return blob, nil
+		})),
+	)
+	require.NoError(t, app.Startup(ctx))
+	assert.Equal(t, []string{"startup store", "startup result"}, events)
+
+	err := app.Shutdown(ctx)
+	assert.ErrorIs(t, err, resultStore.ShutdownErr)
+	assert.Contains(t, err.Error(), "lifecycleStorage")
+	assert.Equal(t, []string{
+		"startup store", "startup result", "shutdown result", "shutdown store",
+	}, events)
+}
+
+type watchLoader struct {
+	loaderFunc
+	changed func(image string)
+}
+
+func (l *watchLoader) Watch(_ context.Context, changed func(image string)) bool {
+	l.changed = changed
+	return true
+}
+
+func TestWatchEvictSource(t *testing.T) {
+	ctx := context.Background()
+	var cnt int
+	loader := &watchLoader{loaderFunc: func(r *http.Request, image string) (*Blob, error) {
+		if image == "missing" {
+			return nil, ErrNotFound
+		}
+		cnt++
+		return NewBlobFromBytes([]byte(image + strconv.Itoa(cnt))), nil
+	}}
+	store := newMapStore()
+	resultStore := newMapStore()
+	app := New(
+		WithLoaders(loader),
+		WithStorages(store),
+		WithResultStorages(resultStore),
+		WithCache(newMapCache()),
+		WithResultCacheTTL(time.Hour),
+		WithErrorCacheTTL(time.Hour),
+		WithUnsafe(true),
+	)
+	require.NoError(t, app.Startup(ctx))
+	defer func() {
+		require.NoError(t, app.Shutdown(ctx))
+		assert.Nil(t, app.stopWatch)
+	}()
+	require.NotNil(t, loader.changed)
+
+	get := func(path string) string {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+path, nil))
+		time.Sleep(time.Millisecond * 10) // make sure storage reached
+		return w.Body.String()
+	}
+	assert.Equal(t, "foo1", get("foo"))
+	assert.Equal(t, "foo1", get("filters:fill(red)/foo"))
+	assert.Equal(t, "foo1", get("foo"))
+	get("missing")
+	assert.Len(t, app.watchIndex.images["foo"], 2)
+
+	loader.changed("foo")
+	loader.changed("missing")
+	assert.Empty(t, app.watchIndex.images["foo"])
+	assert.Equal(t, "foo2", get("foo"))
+	assert.Equal(t, "foo2", get("filters:fill(red)/foo"))
+}
+
+type presignLoader struct {
+	loaderFunc
+}
+
+func (l *presignLoader) Presign(_ context.Context, image string, expires time.Duration) (string, error) {
+	if image == "missing" {
+		return "", ErrNotFound
+	}
+	return "https://bucket.example.com/" + image + "?expires=" + strconv.Itoa(int(expires.Seconds())), nil
+}
+
+func TestPresignRedirect(t *testing.T) {
+	loader := &presignLoader{loaderFunc: func(r *http.Request, image string) (*Blob, error) {
+		return NewBlobFromBytes([]byte(image)), nil
+	}}
+	app := New(
+		WithLoaders(loader),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			buf, _ := blob.ReadAll()
+			return NewBlobFromBytes([]byte(string(buf) + " processed")), nil
+		})),
+		WithPresignRedirect(time.Minute*5),
+		WithUnsafe(true),
+	)
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/foo/bar.mp4", nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	assert.Equal(t, "https://bucket.example.com/foo/bar.mp4?expires=300", w.Header().Get("Location"))
+	assert.Equal(t, "private, no-cache, no-store, must-revalidate", w.Header().Get("Cache-Control"))
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/fit-in/100x100/foo/bar.jpg", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foo/bar.jpg processed", w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/missing", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "missing processed", w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/foo/bar.mp4", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	blob, err := app.ServeBlob(context.Background(), imagorpath.Params{Image: "foo/bar.mp4"})
+	require.NoError(t, err)
+	buf, _ := blob.ReadAll()
+	assert.Equal(t, "foo/bar.mp4 processed", string(buf))
+}
+
+func TestWithUnsupportedSource(t *testing.T) {
+	newApp := func(policy string) (*Imagor, *mapStore) {
+		resultStore := newMapStore()
+		return New(
+			WithUnsafe(true),
+			WithUnsupportedSource(policy),
+			WithResultStorages(resultStore),
+			WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+				if image == "font.woff" {
+					blob := NewBlobFromBytes([]byte("wOFF font"))
+					blob.SetContentType("font/woff")
+					return blob, nil
+				}
+				return NewBlobFromBytes([]byte("%PDF-1.4 foo")), nil
+			})),
+			WithProcessors(
+				processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+					return NewBlobFromBytes([]byte("forwarded")), ErrForward{p}
+				}),
+				processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+					if p.Image == "invalid.pdf" {
+						return nil, ErrInvalidImage
+					}
+					return nil, ErrUnsupportedFormat
+				}),
+			),
+		), resultStore
+	}
+
+	app, resultStore := newApp(UnsupportedSourcePassthrough)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/fit-in/100x100/foo.pdf", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, "%PDF-1.4 foo", w.Body.String())
+	assert.Empty(t, resultStore.Map)
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/font.woff", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "font/woff", w.Header().Get("Content-Type"))
+	assert.Equal(t, "wOFF font", w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/meta/foo.pdf", nil))
+	assert.Equal(t, 415, w.Code)
+	assert.Equal(t, jsonStr(ErrUnsupportedMediaType), w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/invalid.pdf", nil))
+	assert.Equal(t, 422, w.Code)
+
+	app, _ = newApp(UnsupportedSourceReject)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/foo.pdf", nil))
+	assert.Equal(t, 415, w.Code)
+	assert.Equal(t, jsonStr(ErrUnsupportedMediaType), w.Body.String())
+
+	app, _ = newApp("")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/foo.pdf", nil))
+	assert.Equal(t, 406, w.Code)
+}
+
+type svgProcessor struct {
+	processorFunc
+}
+
+func TestWithProcessorRoutes(t *testing.T) {
+	var tried []string
+	app := New(
+		WithUnsafe(true),
+		WithProcessorRoutes(map[string]string{
+			"image/svg+xml": "imagor.svgProcessor",
+			"image/*":       "imagor.processorFunc",
+			"video/mp4":     "vips.Processor",
+		}),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			blob := NewBlobFromBytes([]byte(image))
+			switch image {
+			case "foo.svg":
+				blob.SetContentType("image/svg+xml")
+			case "foo.jpg":
+				blob.SetContentType("image/jpeg")
+			case "foo.mp4":
+				blob.SetContentType("video/mp4")
+			}
+			return blob, nil
+		})),
+		WithProcessors(
+			processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+				tried = append(tried, "raster")
+				return NewBlobFromBytes([]byte("raster " + p.Image)), nil
+			}),
+			&svgProcessor{processorFunc: func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+				tried = append(tried, "svg")
+				return NewBlobFromBytes([]byte("svg " + p.Image)), nil
+			}},
+		),
+	)
+	for _, tt := range []struct {
+		image string
+		code  int
+		body  string
+		tried []string
+	}{
+		{"foo.svg", 200, "svg foo.svg", []string{"svg"}},
+		{"foo.jpg", 200, "raster foo.jpg", []string{"raster"}},
+		{"foo.txt", 200, "raster foo.txt", []string{"raster"}},
+		{"foo.mp4", 406, "foo.mp4", nil},
+	} {
+		tried = nil
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/"+tt.image, nil))
+		assert.Equal(t, tt.code, w.Code, tt.image)
+		assert.Equal(t, tt.body, w.Body.String(), tt.image)
+		assert.Equal(t, tt.tried, tried, tt.image)
+	}
+}
+
+func TestServeFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "foo.txt")
+	require.NoError(t, os.WriteFile(file, []byte("0123456789"), 0644))
+	app := New(
+		WithUnsafe(true),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "missing.txt" {
+				return NewBlobFromFile(filepath.Join(filepath.Dir(file), image)), nil
+			}
+			return NewBlobFromFile(file), nil
+		})),
+	)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.txt", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "0123456789", w.Body.String())
+	assert.Equal(t, "10", w.Header().Get("Content-Length"))
+	assert.Equal(t, "bytes", w.Header().Get("Accept-Ranges"))
+	assert.NotEmpty(t, w.Header().Get("Last-Modified"))
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.txt", nil)
+	r.Header.Set("Range", "bytes=2-5")
+	app.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "2345", w.Body.String())
+	assert.Equal(t, "bytes 2-5/10", w.Header().Get("Content-Range"))
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodHead, "https://example.com/unsafe/foo.txt", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, "10", w.Header().Get("Content-Length"))
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/missing.txt", nil))
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestShutdownDrainSaves(t *testing.T) {
+	var saved int64
+	newApp := func(delay time.Duration) *Imagor {
+		return New(
+			WithUnsafe(true),
+			WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+				return NewBlobFromBytes([]byte("foo")), nil
+			})),
+			WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+				return blob, nil
+			})),
+			WithResultStorages(saverFunc(func(ctx context.Context, image string, blob *Blob) error {
+				time.Sleep(delay)
+				atomic.AddInt64(&saved, 1)
+				return nil
+			})),
+		)
+	}
+	app := newApp(time.Millisecond * 50)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, int64(0), atomic.LoadInt64(&saved), "saved after response")
+	require.NoError(t, app.Shutdown(context.Background()))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&saved), "drained on shutdown")
+
+	// shutdown deadline exceeded
+	app = newApp(time.Millisecond * 200)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/bar.jpg", nil))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	start := time.Now()
+	require.NoError(t, app.Shutdown(ctx))
+	assert.Less(t, time.Since(start), time.Millisecond*150)
+	assert.Equal(t, int64(1), atomic.LoadInt64(&saved))
+}
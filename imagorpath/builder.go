@@ -0,0 +1,156 @@
+package imagorpath
+
+import "strings"
+
+// Builder fluent builder of Params for generating imagor endpoint e.g.
+// Resize(300, 200).Smart().Filter("quality", "80").Generate(image, signer).
+// Builder is immutable, each method returns a new Builder
+type Builder struct {
+	p Params
+}
+
+// NewBuilder creates Builder with Params as base
+func NewBuilder(p Params) Builder {
+	return Builder{p: p}.clone()
+}
+
+// Resize creates Builder with resize dimensions, 0 for auto and negative for flip
+func Resize(width, height int) Builder {
+	return Builder{}.Resize(width, height)
+}
+
+// FitIn creates Builder with fit-in dimensions
+func FitIn(width, height int) Builder {
+	return Builder{}.FitIn(width, height)
+}
+
+func (b Builder) clone() Builder {
+	if b.p.Filters != nil {
+		b.p.Filters = append(Filters{}, b.p.Filters...)
+	}
+	return b
+}
+
+// Resize sets resize dimensions, 0 for auto and negative for flip
+func (b Builder) Resize(width, height int) Builder {
+	b.p.Width = width
+	b.p.Height = height
+	return b
+}
+
+// FitIn sets fit-in dimensions
+func (b Builder) FitIn(width, height int) Builder {
+	b.p.FitIn = true
+	return b.Resize(width, height)
+}
+
+// Stretch sets stretch without preserving aspect ratio
+func (b Builder) Stretch() Builder {
+	b.p.Stretch = true
+	return b
+}
+
+// Smart sets smart crop by focal point detection
+func (b Builder) Smart() Builder {
+	b.p.Smart = true
+	return b
+}
+
+// Meta sets meta endpoint responding image metadata
+func (b Builder) Meta() Builder {
+	b.p.Meta = true
+	return b
+}
+
+// Trim sets trim by color of top-left pixel with tolerance
+func (b Builder) Trim(tolerance int) Builder {
+	b.p.Trim = true
+	b.p.TrimBy = TrimByTopLeft
+	b.p.TrimTolerance = tolerance
+	return b
+}
+
+// TrimBottomRight sets trim by color of bottom-right pixel with tolerance
+func (b Builder) TrimBottomRight(tolerance int) Builder {
+	b = b.Trim(tolerance)
+	b.p.TrimBy = TrimByBottomRight
+	return b
+}
+
+// Crop sets manual crop of top-left and bottom-right points,
+// in pixels or ratio between 0 and 1
+func (b Builder) Crop(left, top, right, bottom float64) Builder {
+	b.p.CropLeft = left
+	b.p.CropTop = top
+	b.p.CropRight = right
+	b.p.CropBottom = bottom
+	return b
+}
+
+// Padding sets padding of fit-in or resized image
+func (b Builder) Padding(left, top, right, bottom int) Builder {
+	b.p.PaddingLeft = left
+	b.p.PaddingTop = top
+	b.p.PaddingRight = right
+	b.p.PaddingBottom = bottom
+	return b
+}
+
+// HFlip sets horizontal flip
+func (b Builder) HFlip() Builder {
+	b.p.HFlip = true
+	return b
+}
+
+// VFlip sets vertical flip
+func (b Builder) VFlip() Builder {
+	b.p.VFlip = true
+	return b
+}
+
+// HAlign sets horizontal alignment of crop: left or right
+func (b Builder) HAlign(align string) Builder {
+	b.p.HAlign = align
+	return b
+}
+
+// VAlign sets vertical alignment of crop: top or bottom
+func (b Builder) VAlign(align string) Builder {
+	b.p.VAlign = align
+	return b
+}
+
+// Filter appends filter with args joined by comma
+func (b Builder) Filter(name string, args ...string) Builder {
+	b = b.clone()
+	b.p.Filters = append(b.p.Filters, Filter{Name: name, Args: strings.Join(args, ",")})
+	return b
+}
+
+// Params returns Params of image with Path generated
+func (b Builder) Params(image string) Params {
+	p := b.clone().p
+	p.Image = image
+	p.Path = GeneratePath(p)
+	return p
+}
+
+// GeneratePath generates imagor path of image
+func (b Builder) GeneratePath(image string) string {
+	return b.Params(image).Path
+}
+
+// Generate generates imagor endpoint of image with signature by signer, unsafe if signer is nil
+func (b Builder) Generate(image string, signer Signer) string {
+	return Generate(b.Params(image), signer)
+}
+
+// GenerateUnsafe generates unsafe imagor endpoint of image
+func (b Builder) GenerateUnsafe(image string) string {
+	return GenerateUnsafe(b.Params(image))
+}
+
+// GenerateEncrypted generates encrypted imagor endpoint of image with crypter
+func (b Builder) GenerateEncrypted(image string, crypter Crypter) string {
+	return GenerateEncrypted(b.Params(image), crypter)
+}
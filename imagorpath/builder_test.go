@@ -0,0 +1,52 @@
+package imagorpath
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	signer := NewDefaultSigner("mysecret")
+	image := "raw.githubusercontent.com/cshum/imagor/master/testdata/gopher.png"
+
+	assert.Equal(t,
+		"fit-in/500x400/0x20/filters:fill(white)/"+image,
+		FitIn(500, 400).Padding(0, 20, 0, 20).Filter("fill", "white").GeneratePath(image))
+	assert.Equal(t,
+		"OyGJyvfYJw8xNkYDmXU-4NPA2U0=/fit-in/500x400/0x20/filters:fill(white)/"+image,
+		FitIn(500, 400).Padding(0, 20, 0, 20).Filter("fill", "white").Generate(image, signer))
+	assert.Equal(t,
+		"unsafe/meta/trim:bottom-right:10/10x11:12x13/stretch/-300x200/left/top/smart/filters:quality(80):watermark(a.png,10,10,0)/"+image,
+		Resize(300, 200).Meta().TrimBottomRight(10).Crop(10, 11, 12, 13).Stretch().HFlip().
+			HAlign(HAlignLeft).VAlign(VAlignTop).Smart().
+			Filter("quality", "80").Filter("watermark", "a.png", "10", "10", "0").
+			GenerateUnsafe(image))
+	assert.Equal(t, "unsafe/300x-200/"+image, Resize(300, 200).VFlip().Generate(image, nil))
+
+	crypter := NewAESCrypter("1234")
+	path, err := crypter.Decrypt(Resize(100, 100).GenerateEncrypted(image, crypter)[4:])
+	assert.NoError(t, err)
+	assert.Equal(t, "100x100/"+image, path)
+}
+
+func TestBuilderRoundTrip(t *testing.T) {
+	b := Resize(300, 200).Smart().Filter("quality", "80").Filter("format", "webp")
+	p := b.Params("foo/bar.jpg")
+	assert.Equal(t, p, Parse(p.Path))
+
+	signed := b.Generate("foo/bar.jpg", NewDefaultSigner("1234"))
+	parsed := Parse(signed)
+	assert.True(t, Verify(NewDefaultSigner("1234"), parsed.Path, parsed.Hash))
+	parsed.Hash = ""
+	assert.Equal(t, p, parsed)
+	assert.Equal(t, p, NewBuilder(parsed).Params("foo/bar.jpg"))
+}
+
+func TestBuilderImmutable(t *testing.T) {
+	base := Resize(100, 100).Filter("quality", "80")
+	a := base.Filter("format", "webp")
+	b := base.Filter("format", "png")
+	assert.Equal(t, "100x100/filters:quality(80)/a.jpg", base.GeneratePath("a.jpg"))
+	assert.Equal(t, "100x100/filters:quality(80):format(webp)/a.jpg", a.GeneratePath("a.jpg"))
+	assert.Equal(t, "100x100/filters:quality(80):format(png)/a.jpg", b.GeneratePath("a.jpg"))
+}
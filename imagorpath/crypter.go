@@ -0,0 +1,68 @@
+package imagorpath
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// ErrDecrypt error on invalid or tampered encrypted path
+var ErrDecrypt = errors.New("imagorpath: decrypt failed")
+
+// Crypter imagor URL path encrypter and decrypter
+type Crypter interface {
+	Encrypt(path string) string
+	Decrypt(token string) (string, error)
+}
+
+// NewAESCrypter AES-GCM crypter with keys derived from secret.
+// Nonce is derived from the path so that the same path always
+// results the same token, which keeps encrypted URLs cacheable
+func NewAESCrypter(secret string) Crypter {
+	block, _ := aes.NewCipher(deriveKey(secret, "imagor-aes-gcm"))
+	aead, _ := cipher.NewGCM(block)
+	return &aesCrypter{
+		aead:     aead,
+		nonceKey: deriveKey(secret, "imagor-aes-nonce"),
+	}
+}
+
+// deriveKey derives 32 bytes key from secret for the purpose,
+// so that cipher and nonce keys are independent of each other
+func deriveKey(secret, purpose string) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+type aesCrypter struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+func (c *aesCrypter) Encrypt(path string) string {
+	h := hmac.New(sha256.New, c.nonceKey)
+	h.Write([]byte(path))
+	nonce := h.Sum(nil)[:c.aead.NonceSize()]
+	return base64.RawURLEncoding.EncodeToString(
+		c.aead.Seal(nonce, nonce, []byte(path), nil))
+}
+
+func (c *aesCrypter) Decrypt(token string) (string, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	size := c.aead.NonceSize()
+	if len(buf) < size {
+		return "", ErrDecrypt
+	}
+	plain, err := c.aead.Open(nil, buf[:size], buf[size:], nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
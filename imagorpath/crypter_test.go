@@ -0,0 +1,47 @@
+package imagorpath
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestAESCrypter(t *testing.T) {
+	crypter := NewAESCrypter("1234")
+	path := "fit-in/200x300/filters:fill(white)/https://example.com/foo.jpg?bar=baz"
+	token := crypter.Encrypt(path)
+	assert.NotContains(t, token, "example.com")
+	assert.NotContains(t, token, "/")
+	assert.Equal(t, token, crypter.Encrypt(path), "encrypt should be deterministic")
+	assert.NotEqual(t, token, crypter.Encrypt(path+"1"))
+
+	res, err := crypter.Decrypt(token)
+	assert.NoError(t, err)
+	assert.Equal(t, path, res)
+
+	_, err = NewAESCrypter("4321").Decrypt(token)
+	assert.Equal(t, ErrDecrypt, err)
+
+	_, err = crypter.Decrypt(strings.ToUpper(token))
+	assert.Equal(t, ErrDecrypt, err)
+
+	_, err = crypter.Decrypt("abc")
+	assert.Equal(t, ErrDecrypt, err)
+
+	_, err = crypter.Decrypt("!@#$")
+	assert.Equal(t, ErrDecrypt, err)
+}
+
+func TestGenerateEncrypted(t *testing.T) {
+	crypter := NewAESCrypter("1234")
+	p := Params{
+		Image:  "foo.jpg",
+		Width:  167,
+		Height: 169,
+	}
+	path := GenerateEncrypted(p, crypter)
+	assert.True(t, strings.HasPrefix(path, "enc/"))
+	res, err := crypter.Decrypt(strings.TrimPrefix(path, "enc/"))
+	assert.NoError(t, err)
+	assert.Equal(t, "167x169/foo.jpg", res)
+}
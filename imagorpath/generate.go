@@ -83,17 +83,7 @@ func GeneratePath(p Params) string {
 		}
 		parts = append(parts, "filters:"+strings.Join(filters, ":"))
 	}
-	if strings.Contains(p.Image, "?") ||
-		strings.HasPrefix(p.Image, "trim/") ||
-		strings.HasPrefix(p.Image, "meta/") ||
-		strings.HasPrefix(p.Image, "fit-in/") ||
-		strings.HasPrefix(p.Image, "stretch/") ||
-		strings.HasPrefix(p.Image, "top/") ||
-		strings.HasPrefix(p.Image, "left/") ||
-		strings.HasPrefix(p.Image, "right/") ||
-		strings.HasPrefix(p.Image, "bottom/") ||
-		strings.HasPrefix(p.Image, "center/") ||
-		strings.HasPrefix(p.Image, "smart/") {
+	if strings.ContainsAny(p.Image, "?#%") || isParamsLike(p.Image) {
 		p.Image = url.QueryEscape(p.Image)
 	}
 	parts = append(parts, p.Image)
@@ -114,3 +104,15 @@ func Generate(p Params, signer Signer) string {
 		return "unsafe/" + imgPath
 	}
 }
+
+// GenerateEncrypted generate encrypted imagor endpoint by Params struct with crypter
+func GenerateEncrypted(p Params, crypter Crypter) string {
+	return "enc/" + crypter.Encrypt(GeneratePath(p))
+}
+
+// isParamsLike checks if image starts with segments that would be parsed as params
+// e.g. fit-in/, 10x10/ or filters:, which needs to be escaped for round-trip
+func isParamsLike(image string) bool {
+	match := paramsRegex.FindStringSubmatch(image)
+	return len(match) > 0 && match[len(match)-1] != strings.TrimLeft(image, "/")
+}
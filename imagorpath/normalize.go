@@ -2,6 +2,7 @@ package imagorpath
 
 import (
 	"path"
+	"sort"
 	"strings"
 )
 
@@ -126,3 +127,40 @@ func Normalize(image string, safeChars SafeChars) string {
 		return escape(image, safeChars.ShouldEscape)
 	}
 }
+
+// settingFilters are filters of output settings that take effect regardless of position,
+// where the last one wins if repeated
+var settingFilters = map[string]bool{
+	"format":         true,
+	"quality":        true,
+	"max_bytes":      true,
+	"strip_exif":     true,
+	"strip_icc":      true,
+	"strip_metadata": true,
+}
+
+// NormalizeParams returns Params with canonical Path of identical output,
+// for result cache and storage keys of trivially different URLs.
+// Setting filters are deduplicated and sorted after other filters, which keep their order
+func NormalizeParams(p Params) Params {
+	var filters Filters
+	var settings = map[string]Filter{}
+	for _, f := range p.Filters {
+		if settingFilters[f.Name] {
+			settings[f.Name] = f
+		} else {
+			filters = append(filters, f)
+		}
+	}
+	var names = make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		filters = append(filters, settings[name])
+	}
+	p.Filters = filters
+	p.Path = GeneratePath(p)
+	return p
+}
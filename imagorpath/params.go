@@ -1,5 +1,10 @@
 package imagorpath
 
+import (
+	"net/url"
+	"strings"
+)
+
 const (
 	TrimByTopLeft     = "top-left"
 	TrimByBottomRight = "bottom-right"
@@ -46,3 +51,50 @@ type Filter struct {
 	Name string `json:"name,omitempty"`
 	Args string `json:"args,omitempty"`
 }
+
+// SplitArgs returns raw args split by comma, excluding comma within parentheses
+// of nested imagor path e.g. watermark(fit-in/100x100/filters:fill(red,1)/a.png,0,0)
+func (f Filter) SplitArgs() (args []string) {
+	if f.Args == "" {
+		return nil
+	}
+	var depth, start int
+	for i := 0; i < len(f.Args); i++ {
+		switch f.Args[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, f.Args[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(args, f.Args[start:])
+}
+
+// UnescapedArgs returns args split by SplitArgs with each arg URL unescaped,
+// or raw arg if it is not a valid escape
+func (f Filter) UnescapedArgs() []string {
+	args := f.SplitArgs()
+	for i, arg := range args {
+		if u, err := url.PathUnescape(arg); err == nil {
+			args[i] = u
+		}
+	}
+	return args
+}
+
+// EscapeFilterArg escapes filter arg that may contain characters of path and filter syntax
+// such as / : ( ) , e.g. source URL of watermark, to be unescaped by UnescapedArgs.
+// Path escaping is used so that literal + of args is preserved
+func EscapeFilterArg(arg string) string {
+	return filterArgEscaper.Replace(url.PathEscape(arg))
+}
+
+// filterArgEscaper escapes characters that url.PathEscape leaves in path segment
+var filterArgEscaper = strings.NewReplacer(":", "%3A", "+", "%2B")
@@ -4,6 +4,8 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
@@ -371,7 +373,176 @@ func TestNormalize(t *testing.T) {
 	assert.Equal(t, "a+", Normalize("a ", nil))
 }
 
+func TestNormalizeParams(t *testing.T) {
+	expected := "fit-in/100x0/filters:fill(white):watermark(a.png):format(webp):quality(80)/foo%3Fbar.png"
+	for _, path := range []string{
+		"fit-in/100x0/filters:fill(white):watermark(a.png):format(webp):quality(80)/foo%3Fbar.png",
+		"fit-in/100x0/filters:quality(80):fill(white):format(webp):watermark(a.png)/foo%3Fbar.png",
+		"fit-in/100x0/center/middle/filters:quality(90):fill(white):QUALITY(80):watermark(a.png):format(webp)/foo%3Fbar.png",
+		"fit-in/100x0/filters:format(jpeg):fill(white):watermark(a.png):format(webp):quality(80)/foo%3Fbar.png",
+	} {
+		assert.Equal(t, expected, NormalizeParams(Parse(path)).Path, path)
+	}
+	for _, path := range []string{
+		"trim:top-left/fit-in/100x0/center/middle/filters:fill(white)/foo.png",
+		"trim/0x0:0x0/fit-in/100x0/filters:fill(white)/foo.png",
+	} {
+		assert.Equal(t, "trim/fit-in/100x0/filters:fill(white)/foo.png", NormalizeParams(Parse(path)).Path, path)
+	}
+	assert.NotEqual(t, expected, NormalizeParams(Parse(
+		"fit-in/100x0/filters:watermark(a.png):fill(white):format(webp):quality(80)/foo%3Fbar.png")).Path,
+		"should keep order of non setting filters")
+}
+
 func TestHMACSigner(t *testing.T) {
 	signer := NewHMACSigner(sha256.New, 28, "abcd")
 	assert.Equal(t, signer.Sign("assfasf"), "zb6uWXQxwJDOe_zOgxkuj96Etrsz")
+
+	for _, truncate := range []int{1, 7, 8} {
+		signer = NewHMACSigner(sha256.New, truncate, "abcd")
+		path := Generate(Params{Image: "foo.jpg", Width: 100}, signer)
+		assert.Equal(t, MinSignerTruncate, strings.Index(path, "/"))
+		p := Parse(path)
+		assert.Len(t, p.Hash, MinSignerTruncate)
+		assert.True(t, p.Verify(signer))
+	}
+}
+
+func TestSignerAlgorithm(t *testing.T) {
+	alg, ok := SignerAlgorithm("SHA256")
+	assert.True(t, ok)
+	assert.Equal(t, "zb6uWXQxwJDOe_zOgxkuj96Etrsz", NewHMACSigner(alg, 28, "abcd").Sign("assfasf"))
+
+	alg, ok = SignerAlgorithm("sha1")
+	assert.True(t, ok)
+	assert.Equal(t, NewDefaultSigner("abcd").Sign("assfasf"), NewHMACSigner(alg, 0, "abcd").Sign("assfasf"))
+
+	_, ok = SignerAlgorithm("md5")
+	assert.False(t, ok)
+}
+
+func TestMultiSigner(t *testing.T) {
+	path := "fit-in/100x100/foobar.jpg"
+	current := NewKeyIDSigner("k2", NewDefaultSigner("new"))
+	previous := NewKeyIDSigner("k1", NewDefaultSigner("old"))
+	legacy := NewDefaultSigner("legacy")
+	signer := NewMultiSigner(current, previous, legacy)
+
+	assert.Equal(t, "k2_"+NewDefaultSigner("new").Sign(path), signer.Sign(path))
+	assert.True(t, Verify(signer, path, signer.Sign(path)))
+	assert.True(t, Verify(signer, path, previous.Sign(path)))
+	assert.True(t, Verify(signer, path, legacy.Sign(path)))
+	assert.False(t, Verify(signer, path, NewDefaultSigner("new").Sign(path)))
+	assert.False(t, Verify(signer, path, "k1_"+legacy.Sign(path)))
+	assert.False(t, Verify(signer, path, NewDefaultSigner("other").Sign(path)))
+	assert.False(t, Verify(signer, "fit-in/200x200/foobar.jpg", signer.Sign(path)))
+
+	p := Parse(Generate(Params{Image: "foobar.jpg", FitIn: true, Width: 100, Height: 100}, signer))
+	assert.True(t, Verify(signer, p.Path, p.Hash))
+	assert.True(t, p.Verify(signer))
+	assert.False(t, p.Verify(NewDefaultSigner("other")))
+	assert.False(t, Parse("unsafe/"+path).Verify(signer))
+	assert.Equal(t, "", NewMultiSigner().Sign(path))
+}
+
+func TestParseGenerateRoundTrip(t *testing.T) {
+	images := []string{
+		"a.jpg", "foo/bar.png", "https://example.com/a.jpg?x=1", "a b.jpg",
+		"fit-in/a.jpg", "top/a.jpg", "middle/a.jpg", "smart/a.jpg", "meta/a.jpg", "trim/a.jpg",
+		"10x10/a.jpg", "-100x/a.jpg", "filters:x/a.jpg", "filters:fill(red)/a.jpg",
+	}
+	filters := []Filters{
+		nil,
+		{{Name: "quality", Args: "80"}},
+		{{Name: "fill", Args: "white"}, {Name: "watermark", Args: "a.png,10,10,0"}},
+		{{Name: "grayscale"}, {Name: "watermark", Args: "fit-in/100x100/filters:fill(red):quality(80)/b.png,1,1"}},
+	}
+	var count int
+	for i, image := range images {
+		for j, f := range filters {
+			for k := 0; k < 16; k++ {
+				p := Params{
+					Image:   image,
+					Meta:    k&1 != 0,
+					FitIn:   k&2 != 0,
+					Smart:   k&4 != 0,
+					Width:   100 * (k % 3),
+					Height:  100 * ((i + j) % 3),
+					HFlip:   k&8 != 0,
+					Filters: f,
+				}
+				if (i+k)%2 == 0 {
+					p.Trim, p.TrimBy, p.TrimTolerance = true, TrimByBottomRight, k
+				}
+				if (j+k)%3 == 0 {
+					p.CropLeft, p.CropTop, p.CropRight, p.CropBottom = 10, 20, 30, 40
+				}
+				if (i+j+k)%3 == 0 {
+					p.PaddingLeft, p.PaddingTop, p.PaddingRight, p.PaddingBottom = 1, 2, 3, 4
+				}
+				if (i+k)%4 == 0 {
+					p.HAlign, p.VAlign = HAlignRight, VAlignTop
+				}
+				p.Path = GeneratePath(p)
+				parsed := Parse(Generate(p, NewDefaultSigner("1234")))
+				assert.True(t, Verify(NewDefaultSigner("1234"), parsed.Path, parsed.Hash), p.Path)
+				parsed.Hash = ""
+				assert.Equal(t, p, parsed, p.Path)
+				count++
+			}
+		}
+	}
+	assert.Equal(t, len(images)*len(filters)*16, count)
+}
+
+func TestParseNestedFilters(t *testing.T) {
+	p := Parse("unsafe/fit-in/200x200/filters:watermark(fit-in/100x100/filters:fill(red):quality(80)/b.png,0,0):format(webp)/a.jpg")
+	assert.Equal(t, "a.jpg", p.Image)
+	assert.Equal(t, Filters{
+		{Name: "watermark", Args: "fit-in/100x100/filters:fill(red):quality(80)/b.png,0,0"},
+		{Name: "format", Args: "webp"},
+	}, p.Filters)
+
+	// unbalanced parentheses fallback to lazy match
+	p = Parse("unsafe/filters:label(a(b,1,1)/a.jpg")
+	assert.Equal(t, "a.jpg", p.Image)
+	assert.Len(t, p.Filters, 1)
+}
+
+func TestFilterArgs(t *testing.T) {
+	assert.Nil(t, Filter{Name: "grayscale"}.SplitArgs())
+	f := Filter{Name: "watermark", Args: "fit-in/100x100/filters:fill(red,1):quality(80)/b.png,repeat,bottom,10"}
+	assert.Equal(t, []string{"fit-in/100x100/filters:fill(red,1):quality(80)/b.png", "repeat", "bottom", "10"}, f.SplitArgs())
+
+	src := "https://example.com/a(1) b+c.png?w=1,2"
+	p := Parse(GenerateUnsafe(Params{
+		Image:   "a.jpg",
+		Filters: Filters{{Name: "watermark", Args: EscapeFilterArg(src) + ",10,10"}},
+	}))
+	assert.Equal(t, "a.jpg", p.Image)
+	require.Len(t, p.Filters, 1)
+	assert.Equal(t, []string{src, "10", "10"}, p.Filters[0].UnescapedArgs())
+	assert.Equal(t, []string{"a%zz", "b c", "a+b"}, Filter{Args: "a%zz,b%20c,a+b"}.UnescapedArgs())
+}
+
+func TestParseFullURLImage(t *testing.T) {
+	src := "https://example.com/a b/c.jpg?w=1&h=2"
+	for _, path := range []string{
+		"unsafe/fit-in/100x100/" + url.QueryEscape(src),
+		"unsafe/fit-in/100x100/" + url.QueryEscape(url.QueryEscape(src)),
+		"unsafe/fit-in/100x100/HTTPS%253A%252F%252Fexample.com%252Fa%2520b%252Fc.jpg%253Fw%253D1%2526h%253D2",
+	} {
+		p := Parse(path)
+		assert.Equal(t, 100, p.Width, path)
+		assert.Equal(t, src, strings.Replace(p.Image, "HTTPS", "https", 1), path)
+	}
+	assert.Equal(t, "https://example.com/a.jpg", Parse("unsafe/https:/example.com/a.jpg").Image)
+	assert.Equal(t, "http://example.com/a.jpg", Parse("unsafe/http:/example.com/a.jpg").Image)
+
+	for _, image := range []string{src, "https://example.com/100%25.jpg", "https://example.com/a.jpg#b"} {
+		path := Generate(Params{Image: image, Width: 10}, NewDefaultSigner("1234"))
+		p := Parse(path)
+		assert.Equal(t, image, p.Image, path)
+		assert.Equal(t, path, Generate(p, NewDefaultSigner("1234")))
+	}
 }
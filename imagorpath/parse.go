@@ -66,7 +66,7 @@ func Apply(p Params, path string) Params {
 	index += 1
 	if match[index+1] == "unsafe/" {
 		p.Unsafe = true
-	} else if len(match[index+2]) > 8 {
+	} else if len(match[index+2]) >= MinSignerTruncate {
 		p.Hash = match[index+2]
 	}
 	index += 3
@@ -136,20 +136,69 @@ func Apply(p Params, path string) Params {
 		p.Smart = true
 	}
 	index += 1
+	var image = match[index+2]
 	if match[index] != "" {
-		p.Filters = append(p.Filters, parseFilters(match[index+1])...)
+		filters := match[index+1]
+		if f, img, ok := splitNestedFilters(match[index] + image); ok {
+			filters, image = f, img
+		}
+		p.Filters = append(p.Filters, parseFilters(filters)...)
 	}
-	index += 2
-	if str := match[index]; str != "" {
+	if str := image; str != "" {
 		p.Image = str
 		if u, err := url.QueryUnescape(str); err == nil {
 			p.Image = u
 		}
+		p.Image = canonicalImage(p.Image)
 	}
 	return p
 }
 
+var (
+	encodedURLRegex   = regexp.MustCompile("(?i)^https?%3A%2F%2F")
+	collapsedURLRegex = regexp.MustCompile("^(?i)(https?):/+([^/])")
+)
+
+// canonicalImage returns canonical image of full source URL, so that image
+// and its storage keys are stable across URL encodings of different URL generators.
+// Double encoded URL is unescaped again,
+// and scheme slashes collapsed by proxies e.g. https:/example.com are restored
+func canonicalImage(image string) string {
+	if encodedURLRegex.MatchString(image) {
+		if u, err := url.QueryUnescape(image); err == nil {
+			image = u
+		}
+	}
+	return collapsedURLRegex.ReplaceAllString(image, "$1://$2")
+}
+
+// splitNestedFilters splits filters and image of path starting with filters segment,
+// by balanced parentheses that filter args may contain nested imagor path with filters
+// e.g. filters:watermark(fit-in/100x100/filters:fill(red)/image.png,0,0)/image.jpg
+func splitNestedFilters(path string) (filters, image string, ok bool) {
+	const prefix = "filters:"
+	var depth int
+	for i := len(prefix); i < len(path); i++ {
+		switch path[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return "", "", false
+			}
+		case '/':
+			if depth == 0 && path[i-1] == ')' {
+				return path[len(prefix):i], path[i+1:], true
+			}
+		}
+	}
+	return "", "", false
+}
+
 func parseFilters(filters string) (results []Filter) {
+	if nested := splitFilters(filters); nested != nil {
+		return nested
+	}
 	splits := strings.Split(filters, "):")
 	for _, seg := range splits {
 		seg = strings.TrimSuffix(seg, ")") + ")"
@@ -162,3 +211,35 @@ func parseFilters(filters string) (results []Filter) {
 	}
 	return
 }
+
+// splitFilters parses filters by balanced parentheses, nil if not balanced
+func splitFilters(filters string) (results []Filter) {
+	var depth, start, open int
+	for i := 0; i < len(filters); i++ {
+		switch filters[i] {
+		case '(':
+			if depth == 0 {
+				open = i
+			}
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return nil
+			} else if depth == 0 {
+				if open == start || (i+1 < len(filters) && filters[i+1] != ':') {
+					return nil
+				}
+				results = append(results, Filter{
+					Name: strings.ToLower(filters[start:open]),
+					Args: filters[open+1 : i],
+				})
+				start = i + 2
+				i++
+			}
+		}
+	}
+	if depth != 0 || start < len(filters) {
+		return nil
+	}
+	return results
+}
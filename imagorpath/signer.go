@@ -3,10 +3,27 @@ package imagorpath
 import (
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/base64"
 	"hash"
+	"strings"
 )
 
+// SignerAlgorithms HMAC hash algorithms supported for URL signature by name
+var SignerAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// SignerAlgorithm returns HMAC hash algorithm by name, ok false if not supported
+func SignerAlgorithm(name string) (alg func() hash.Hash, ok bool) {
+	alg, ok = SignerAlgorithms[strings.ToLower(strings.TrimSpace(name))]
+	return
+}
+
 // Signer imagor URL signature signer
 type Signer interface {
 	Sign(path string) string
@@ -17,8 +34,15 @@ func NewDefaultSigner(secret string) Signer {
 	return NewHMACSigner(sha1.New, 0, secret)
 }
 
-// NewHMACSigner custom HMAC alg signer with secret and string length based truncate
+// MinSignerTruncate minimum signature length of truncate, as shorter hash is not parsed as signature
+const MinSignerTruncate = 8
+
+// NewHMACSigner custom HMAC alg signer with secret and string length based truncate,
+// truncate below MinSignerTruncate is raised to MinSignerTruncate
 func NewHMACSigner(alg func() hash.Hash, truncate int, secret string) *hmacSigner {
+	if truncate > 0 && truncate < MinSignerTruncate {
+		truncate = MinSignerTruncate
+	}
 	return &hmacSigner{
 		alg:      alg,
 		truncate: truncate,
@@ -41,3 +65,65 @@ func (s *hmacSigner) Sign(path string) string {
 	}
 	return sig
 }
+
+// Verifier optional interface for Signer to verify signature other than its own Sign result
+type Verifier interface {
+	Verify(path, hash string) bool
+}
+
+// Verify checks if hash is a valid signature of path by signer
+func Verify(signer Signer, path, hash string) bool {
+	if v, ok := signer.(Verifier); ok {
+		return v.Verify(path, hash)
+	}
+	return subtle.ConstantTimeCompare([]byte(signer.Sign(path)), []byte(hash)) == 1
+}
+
+// Verify checks if hash of params is a valid signature of its path by signer, in constant time
+func (p Params) Verify(signer Signer) bool {
+	return p.Hash != "" && Verify(signer, p.Path, p.Hash)
+}
+
+// NewMultiSigner signer for secret rotation, that signs with the first signer
+// and verifies signature against any of the signers
+func NewMultiSigner(signers ...Signer) Signer {
+	return multiSigner(signers)
+}
+
+type multiSigner []Signer
+
+func (s multiSigner) Sign(path string) string {
+	if len(s) == 0 {
+		return ""
+	}
+	return s[0].Sign(path)
+}
+
+func (s multiSigner) Verify(path, hash string) bool {
+	for _, signer := range s {
+		if Verify(signer, path, hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewKeyIDSigner signer that embeds key ID into the signature in format of <id>_<signature>,
+// so that secret of the signature is identified without trying every secret
+func NewKeyIDSigner(id string, signer Signer) Signer {
+	return &keyIDSigner{prefix: id + "_", signer: signer}
+}
+
+type keyIDSigner struct {
+	prefix string
+	signer Signer
+}
+
+func (s *keyIDSigner) Sign(path string) string {
+	return s.prefix + s.signer.Sign(path)
+}
+
+func (s *keyIDSigner) Verify(path, hash string) bool {
+	return strings.HasPrefix(hash, s.prefix) &&
+		Verify(s.signer, path, strings.TrimPrefix(hash, s.prefix))
+}
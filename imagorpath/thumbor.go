@@ -0,0 +1,94 @@
+package imagorpath
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+// NewThumborSigner thumbor HMAC method signer using SHA1 with secret,
+// that also accepts signature of URL-safe base64 without padding
+func NewThumborSigner(secret string) Signer {
+	return &thumborSigner{hmacSigner: NewHMACSigner(sha1.New, 0, secret)}
+}
+
+type thumborSigner struct {
+	*hmacSigner
+}
+
+func (s *thumborSigner) Verify(path, hash string) bool {
+	sig := strings.TrimRight(s.Sign(path), "=")
+	return hmac.Equal([]byte(sig), []byte(strings.TrimRight(hash, "=")))
+}
+
+// NewThumborLegacyCrypter crypter of thumbor legacy encrypted URL, i.e. ALLOW_OLD_URLS of thumbor.
+// Path is encrypted as <token>/<image> where token is AES-ECB encrypted operations
+// with MD5 of the image, using security key
+func NewThumborLegacyCrypter(securityKey string) Crypter {
+	if securityKey == "" {
+		return nil
+	}
+	key := []byte(strings.Repeat(securityKey, 16)[:16])
+	block, _ := aes.NewCipher(key)
+	return &thumborLegacyCrypter{block: block}
+}
+
+type thumborLegacyCrypter struct {
+	block cipher.Block
+}
+
+func (c *thumborLegacyCrypter) Encrypt(path string) string {
+	p := Parse("unsafe/" + path)
+	image := GeneratePath(Params{Image: p.Image})
+	p.Image = md5Hex(p.Image)
+	plain := []byte(GeneratePath(p))
+	size := c.block.BlockSize()
+	for pad := size - len(plain)%size; pad > 0; pad-- {
+		plain = append(plain, '{')
+	}
+	buf := make([]byte, len(plain))
+	for i := 0; i < len(plain); i += size {
+		c.block.Encrypt(buf[i:i+size], plain[i:i+size])
+	}
+	return base64.URLEncoding.EncodeToString(buf) + "/" + image
+}
+
+func (c *thumborLegacyCrypter) Decrypt(token string) (string, error) {
+	enc, image, ok := strings.Cut(strings.TrimPrefix(token, "/"), "/")
+	if !ok || image == "" {
+		return "", ErrDecrypt
+	}
+	buf, err := base64.URLEncoding.DecodeString(enc)
+	if err != nil {
+		if buf, err = base64.RawURLEncoding.DecodeString(enc); err != nil {
+			return "", ErrDecrypt
+		}
+	}
+	size := c.block.BlockSize()
+	if len(buf) == 0 || len(buf)%size != 0 {
+		return "", ErrDecrypt
+	}
+	for i := 0; i < len(buf); i += size {
+		c.block.Decrypt(buf[i:i+size], buf[i:i+size])
+	}
+	plain := strings.TrimRight(string(buf), "{")
+	hash := md5Hex(image)
+	if u, err := url.QueryUnescape(image); err == nil && !strings.HasSuffix(plain, hash) {
+		hash = md5Hex(u)
+	}
+	if !strings.HasSuffix(plain, hash) {
+		return "", ErrDecrypt
+	}
+	return strings.TrimSuffix(plain, hash) + image, nil
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,58 @@
+package imagorpath
+
+import (
+	"crypto/sha1"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestThumborSigner(t *testing.T) {
+	path := "500x500/top/raw.githubusercontent.com/cshum/imagor/master/testdata/gopher.png"
+	signer := NewThumborSigner("mysecret")
+	sig := signer.Sign(path)
+	assert.Equal(t, "cST4Ko5_FqwT3BDn-Wf4gO3RFSk=", sig)
+	assert.Equal(t, NewHMACSigner(sha1.New, 0, "mysecret").Sign(path), sig)
+	assert.True(t, Verify(signer, path, sig))
+	assert.True(t, Verify(signer, path, strings.TrimRight(sig, "=")))
+	assert.False(t, Verify(signer, path, sig[:20]))
+	assert.False(t, Verify(signer, path+"1", sig))
+	assert.False(t, Verify(NewDefaultSigner("mysecret"), path, strings.TrimRight(sig, "=")))
+}
+
+func TestThumborLegacyCrypter(t *testing.T) {
+	assert.Nil(t, NewThumborLegacyCrypter(""))
+	crypter := NewThumborLegacyCrypter("my-security-key")
+	for _, path := range []string{
+		"300x200/smart/some/image.jpg",
+		"fit-in/200x300/filters:fill(white)/example.com/foo.jpg",
+		"meta/foo.jpg",
+		"1234567890abcd.jpg",
+		"fit-in/" + "https%3A%2F%2Fexample.com%2Ffoo.jpg%3Fbar%3Dbaz",
+	} {
+		t.Run(path, func(t *testing.T) {
+			token := crypter.Encrypt(path)
+			enc, image, ok := strings.Cut(token, "/")
+			assert.True(t, ok)
+			assert.NotContains(t, enc, "image")
+			assert.Equal(t, GeneratePath(Params{Image: Parse("unsafe/" + path).Image}), image)
+
+			res, err := crypter.Decrypt(token)
+			assert.NoError(t, err)
+			assert.Equal(t, Parse("unsafe/"+path).Path, Parse("unsafe/"+res).Path)
+
+			_, err = crypter.Decrypt("/" + strings.TrimRight(enc, "=") + "/" + image)
+			assert.NoError(t, err)
+
+			_, err = crypter.Decrypt(enc + "/other.jpg")
+			assert.Equal(t, ErrDecrypt, err)
+			_, err = NewThumborLegacyCrypter("other-key").Decrypt(token)
+			assert.Equal(t, ErrDecrypt, err)
+		})
+	}
+	for _, token := range []string{"", "abc", "abc/foo.jpg", "!@#$/foo.jpg", "abcdefgh/foo.jpg",
+		"cST4Ko5_FqwT3BDn-Wf4gO3RFSk=/foo.jpg"} {
+		_, err := crypter.Decrypt(token)
+		assert.Equal(t, ErrDecrypt, err, token)
+	}
+}
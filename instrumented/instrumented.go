@@ -0,0 +1,246 @@
+package instrumented
+
+import (
+	"context"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"net/http"
+	"reflect"
+	"time"
+)
+
+// Loader instrumented imagor.Loader, records latency, errors and payload bytes of each operation by name.
+// Optional interfaces of the wrapped loader are forwarded
+type Loader struct {
+	Name string
+
+	loader imagor.Loader
+}
+
+// NewLoader wraps loader with metrics recorded by name, type name of loader if empty
+func NewLoader(name string, loader imagor.Loader) *Loader {
+	return &Loader{Name: nameOf(name, loader), loader: loader}
+}
+
+// Unwrap returns the wrapped loader
+func (l *Loader) Unwrap() imagor.Loader {
+	return l.loader
+}
+
+// Get implements imagor.Loader
+func (l *Loader) Get(r *http.Request, key string) (*imagor.Blob, error) {
+	start := time.Now()
+	blob, err := l.loader.Get(r, key)
+	var size int64
+	if err == nil && blob != nil {
+		size = blob.Size()
+	}
+	metrics(l.Name, "get").observe(start, size, err)
+	return blob, err
+}
+
+// Stat implements imagor.Stater, ErrInvalid if not supported by the wrapped loader
+func (l *Loader) Stat(ctx context.Context, key string) (*imagor.Stat, error) {
+	stater, ok := l.loader.(imagor.Stater)
+	if !ok {
+		return nil, imagor.ErrInvalid
+	}
+	start := time.Now()
+	stat, err := stater.Stat(ctx, key)
+	metrics(l.Name, "stat").observe(start, 0, err)
+	return stat, err
+}
+
+// Presign implements imagor.Presigner, ErrInvalid if not supported
+func (l *Loader) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	presigner, ok := l.loader.(imagor.Presigner)
+	if !ok {
+		return "", imagor.ErrInvalid
+	}
+	start := time.Now()
+	url, err := presigner.Presign(ctx, key, expires)
+	metrics(l.Name, "presign").observe(start, 0, err)
+	return url, err
+}
+
+// Watch implements imagor.Watcher, false if not supported
+func (l *Loader) Watch(ctx context.Context, changed func(image string)) bool {
+	if watcher, ok := l.loader.(imagor.Watcher); ok {
+		return watcher.Watch(ctx, changed)
+	}
+	return false
+}
+
+// Startup implements imagor.Starter
+func (l *Loader) Startup(ctx context.Context) error {
+	if starter, ok := l.loader.(imagor.Starter); ok {
+		return starter.Startup(ctx)
+	}
+	return nil
+}
+
+// Shutdown implements imagor.Shutdowner
+func (l *Loader) Shutdown(ctx context.Context) error {
+	if shutdowner, ok := l.loader.(imagor.Shutdowner); ok {
+		return shutdowner.Shutdown(ctx)
+	}
+	return nil
+}
+
+// Health implements imagor.HealthChecker
+func (l *Loader) Health(ctx context.Context) error {
+	if checker, ok := l.loader.(imagor.HealthChecker); ok {
+		return checker.Health(ctx)
+	}
+	return nil
+}
+
+// Storage instrumented imagor.Storage, records latency, errors and payload bytes of each operation by name
+type Storage struct {
+	*Loader
+
+	storage imagor.Storage
+}
+
+// NewStorage wraps storage with metrics recorded by name, type name of storage if empty
+func NewStorage(name string, storage imagor.Storage) *Storage {
+	return &Storage{Loader: NewLoader(name, storage), storage: storage}
+}
+
+// Put implements imagor.Storage
+func (s *Storage) Put(ctx context.Context, key string, blob *imagor.Blob) error {
+	start := time.Now()
+	err := s.storage.Put(ctx, key, blob)
+	var size int64
+	if blob != nil {
+		size = blob.Size()
+	}
+	metrics(s.Name, "put").observe(start, size, err)
+	return err
+}
+
+// Delete implements imagor.Storage
+func (s *Storage) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.storage.Delete(ctx, key)
+	metrics(s.Name, "delete").observe(start, 0, err)
+	return err
+}
+
+// DeletePrefix implements imagor.PrefixDeleter, skipped if not supported
+func (s *Storage) DeletePrefix(ctx context.Context, prefix string) error {
+	deleter, ok := s.storage.(imagor.PrefixDeleter)
+	if !ok {
+		return nil
+	}
+	start := time.Now()
+	err := deleter.DeletePrefix(ctx, prefix)
+	metrics(s.Name, "delete_prefix").observe(start, 0, err)
+	return err
+}
+
+// Processor instrumented imagor.Processor, records latency, errors and output bytes by name
+type Processor struct {
+	Name string
+
+	processor imagor.Processor
+}
+
+// NewProcessor wraps processor with metrics recorded by name, type name of processor if empty
+func NewProcessor(name string, processor imagor.Processor) *Processor {
+	return &Processor{Name: nameOf(name, processor), processor: processor}
+}
+
+// Unwrap returns the wrapped processor
+func (p *Processor) Unwrap() imagor.Processor {
+	return p.processor
+}
+
+// Startup implements imagor.Processor
+func (p *Processor) Startup(ctx context.Context) error {
+	return p.processor.Startup(ctx)
+}
+
+// Process implements imagor.Processor
+func (p *Processor) Process(
+	ctx context.Context, blob *imagor.Blob, params imagorpath.Params, load imagor.LoadFunc,
+) (*imagor.Blob, error) {
+	start := time.Now()
+	out, err := p.processor.Process(ctx, blob, params, load)
+	var size int64
+	if err == nil && out != nil {
+		size = out.Size()
+	}
+	metrics(p.Name, "process").observe(start, size, err)
+	return out, err
+}
+
+// Shutdown implements imagor.Processor
+func (p *Processor) Shutdown(ctx context.Context) error {
+	return p.processor.Shutdown(ctx)
+}
+
+// Health implements imagor.HealthChecker
+func (p *Processor) Health(ctx context.Context) error {
+	if checker, ok := p.processor.(imagor.HealthChecker); ok {
+		return checker.Health(ctx)
+	}
+	return nil
+}
+
+// Wrap instruments loaders, storages, result storages and processors of imagor app by type name.
+// Instance used for multiple roles e.g. file loader and storage shares the same wrapper
+func Wrap(app *imagor.Imagor) {
+	var storages = map[interface{}]*Storage{}
+	// lookup of wrapper by instance, skipped for non-comparable types that cannot be map keys
+	lookup := func(v interface{}) (*Storage, bool) {
+		if !reflect.TypeOf(v).Comparable() {
+			return nil, false
+		}
+		s, ok := storages[v]
+		return s, ok
+	}
+	for _, list := range [][]imagor.Storage{app.Storages, app.ResultStorages} {
+		for i, storage := range list {
+			if _, ok := storage.(*Storage); ok {
+				continue
+			}
+			s, ok := lookup(storage)
+			if !ok {
+				s = NewStorage("", storage)
+				if reflect.TypeOf(storage).Comparable() {
+					storages[storage] = s
+				}
+			}
+			list[i] = s
+		}
+	}
+	for i, loader := range app.Loaders {
+		switch loader.(type) {
+		case *Loader, *Storage:
+			continue
+		}
+		if s, ok := lookup(loader); ok {
+			app.Loaders[i] = s
+		} else {
+			app.Loaders[i] = NewLoader("", loader)
+		}
+	}
+	for i, processor := range app.Processors {
+		if _, ok := processor.(*Processor); !ok {
+			app.Processors[i] = NewProcessor("", processor)
+		}
+	}
+}
+
+// nameOf returns name, or package qualified type name of component if empty
+func nameOf(name string, component interface{}) string {
+	if name != "" {
+		return name
+	}
+	t := reflect.TypeOf(component)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
@@ -0,0 +1,108 @@
+package instrumented
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/cshum/imagor/storage/filestorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type loaderFunc func(r *http.Request, image string) (*imagor.Blob, error)
+
+func (f loaderFunc) Get(r *http.Request, image string) (*imagor.Blob, error) {
+	return f(r, image)
+}
+
+type processor struct{}
+
+func (processor) Startup(context.Context) error {
+	return nil
+}
+
+func (processor) Process(
+	_ context.Context, blob *imagor.Blob, _ imagorpath.Params, _ imagor.LoadFunc,
+) (*imagor.Blob, error) {
+	buf, err := blob.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	return imagor.NewBlobFromBytes(append(buf, buf...)), nil
+}
+
+func (processor) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestWrap(t *testing.T) {
+	storage := filestorage.New(t.TempDir())
+	app := imagor.New(
+		imagor.WithUnsafe(true),
+		imagor.WithLoaders(
+			NewLoader("missing", loaderFunc(func(r *http.Request, image string) (*imagor.Blob, error) {
+				return nil, imagor.ErrNotFound
+			})),
+			loaderFunc(func(r *http.Request, image string) (*imagor.Blob, error) {
+				if image == "broken" {
+					return nil, errors.New("broken")
+				}
+				return imagor.NewBlobFromBytes([]byte("foo")), nil
+			}),
+			storage,
+		),
+		imagor.WithStorages(storage),
+		imagor.WithProcessors(processor{}),
+	)
+	Wrap(app)
+	require.Len(t, app.Loaders, 3)
+	assert.Equal(t, "missing", app.Loaders[0].(*Loader).Name)
+	assert.Equal(t, "instrumented.loaderFunc", app.Loaders[1].(*Loader).Name)
+	assert.Same(t, app.Storages[0], app.Loaders[2], "storage used as loader should share wrapper")
+	assert.Equal(t, "filestorage.FileStorage", app.Storages[0].(*Storage).Name)
+	assert.Equal(t, "instrumented.processor", app.Processors[0].(*Processor).Name)
+
+	require.NoError(t, app.Startup(context.Background()))
+	t.Cleanup(func() {
+		assert.NoError(t, app.Shutdown(context.Background()))
+	})
+	for _, image := range []string{"foo", "bar", "broken"} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/"+image, nil))
+		if image == "broken" {
+			assert.NotEqual(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "foofoo", w.Body.String())
+		}
+	}
+
+	missing := Lookup("missing", "get")
+	require.NotNil(t, missing)
+	assert.Equal(t, int64(3), missing.Calls())
+	assert.Equal(t, int64(3), missing.NotFound())
+	assert.Equal(t, int64(0), missing.Errors())
+
+	loader := Lookup("instrumented.loaderFunc", "get")
+	require.NotNil(t, loader)
+	assert.Equal(t, int64(3), loader.Calls())
+	assert.Equal(t, int64(1), loader.Errors())
+	assert.Equal(t, int64(6), loader.Bytes())
+
+	process := Lookup("instrumented.processor", "process")
+	require.NotNil(t, process)
+	assert.Equal(t, int64(2), process.Calls())
+	assert.Equal(t, int64(12), process.Bytes())
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("imagor_instrumented").String()), &m))
+	assert.Contains(t, m, "filestorage.FileStorage")
+	assert.Contains(t, m["instrumented.processor"], "process")
+	assert.Nil(t, Lookup("instrumented.processor", "get"))
+}
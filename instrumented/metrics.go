@@ -0,0 +1,122 @@
+package instrumented
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"github.com/cshum/imagor"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// vars metrics of instrumented components by name and operation,
+// exposed by expvar debug endpoint /debug/vars
+var vars = expvar.NewMap("imagor_instrumented")
+
+var varsMu sync.Mutex
+
+// Metrics calls, errors, payload bytes and latency of a component operation
+type Metrics struct {
+	calls      int64
+	errors     int64
+	notFound   int64
+	bytes      int64
+	latency    int64
+	maxLatency int64
+}
+
+// metrics returns metrics of component name and operation, created if not exists
+func metrics(name, op string) *Metrics {
+	varsMu.Lock()
+	defer varsMu.Unlock()
+	ops, ok := vars.Get(name).(*expvar.Map)
+	if !ok {
+		ops = new(expvar.Map).Init()
+		vars.Set(name, ops)
+	}
+	m, ok := ops.Get(op).(*Metrics)
+	if !ok {
+		m = &Metrics{}
+		ops.Set(op, m)
+	}
+	return m
+}
+
+// observe records a call since start with payload size and error.
+// Not found is counted separately from errors, as expected outcome of chained loaders
+func (m *Metrics) observe(start time.Time, size int64, err error) {
+	elapsed := int64(time.Since(start))
+	atomic.AddInt64(&m.calls, 1)
+	atomic.AddInt64(&m.latency, elapsed)
+	for {
+		mx := atomic.LoadInt64(&m.maxLatency)
+		if elapsed <= mx || atomic.CompareAndSwapInt64(&m.maxLatency, mx, elapsed) {
+			break
+		}
+	}
+	if err != nil {
+		if errors.Is(err, imagor.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			atomic.AddInt64(&m.notFound, 1)
+		} else if !errors.Is(err, context.Canceled) {
+			atomic.AddInt64(&m.errors, 1)
+		}
+	}
+	if size > 0 {
+		atomic.AddInt64(&m.bytes, size)
+	}
+}
+
+// Calls number of calls
+func (m *Metrics) Calls() int64 {
+	return atomic.LoadInt64(&m.calls)
+}
+
+// Errors number of calls failed, excluding not found and canceled
+func (m *Metrics) Errors() int64 {
+	return atomic.LoadInt64(&m.errors)
+}
+
+// NotFound number of calls resulted in not found
+func (m *Metrics) NotFound() int64 {
+	return atomic.LoadInt64(&m.notFound)
+}
+
+// Bytes total payload bytes loaded, saved or processed
+func (m *Metrics) Bytes() int64 {
+	return atomic.LoadInt64(&m.bytes)
+}
+
+// Latency total and max latency of calls
+func (m *Metrics) Latency() (total, max time.Duration) {
+	return time.Duration(atomic.LoadInt64(&m.latency)), time.Duration(atomic.LoadInt64(&m.maxLatency))
+}
+
+// String implements expvar.Var, metrics in JSON with latency in milliseconds
+func (m *Metrics) String() string {
+	var (
+		calls      = m.Calls()
+		total, max = m.Latency()
+		avg        float64
+	)
+	if calls > 0 {
+		avg = total.Seconds() * 1000 / float64(calls)
+	}
+	return fmt.Sprintf(
+		`{"calls": %d, "errors": %d, "not_found": %d, "bytes": %d, "latency_avg_ms": %.3f, "latency_max_ms": %.3f}`,
+		calls, m.Errors(), m.NotFound(), m.Bytes(), avg, max.Seconds()*1000)
+}
+
+// Lookup returns metrics of component name and operation, nil if not recorded
+func Lookup(name, op string) *Metrics {
+	varsMu.Lock()
+	defer varsMu.Unlock()
+	ops, ok := vars.Get(name).(*expvar.Map)
+	if !ok {
+		return nil
+	}
+	m, _ := ops.Get(op).(*Metrics)
+	return m
+}
@@ -0,0 +1,91 @@
+package cloudflare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Cloudflare purges Cloudflare zone cache of the URLs on purge, implements imagor.Invalidator
+type Cloudflare struct {
+	ZoneID   string
+	APIToken string
+	BaseURL  string
+	Endpoint string
+	Client   *http.Client
+}
+
+// New creates Cloudflare invalidator of zone, with API token and the public base URL e.g. https://img.example.com
+func New(zoneID, apiToken, baseURL string, options ...Option) *Cloudflare {
+	c := &Cloudflare{
+		ZoneID:   zoneID,
+		APIToken: apiToken,
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Endpoint: "https://api.cloudflare.com/client/v4",
+		Client:   http.DefaultClient,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+type purgeRequest struct {
+	Files    []string `json:"files,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+type purgeResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// Invalidate purges files of the paths, or prefixes for paths ending with *
+func (c *Cloudflare) Invalidate(ctx context.Context, paths []string) error {
+	var req purgeRequest
+	// prefix purge is without scheme
+	host := c.BaseURL
+	if _, h, ok := strings.Cut(host, "://"); ok {
+		host = h
+	}
+	for _, path := range paths {
+		if prefix := strings.TrimSuffix(path, "*"); prefix != path {
+			req.Prefixes = append(req.Prefixes, host+prefix)
+		} else {
+			req.Files = append(req.Files, c.BaseURL+path)
+		}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		c.Endpoint+"/zones/"+c.ZoneID+"/purge_cache", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer "+c.APIToken)
+	resp, err := c.Client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	var res purgeResponse
+	_ = json.NewDecoder(resp.Body).Decode(&res)
+	if resp.StatusCode >= 300 || !res.Success {
+		if len(res.Errors) > 0 {
+			return fmt.Errorf("cloudflare: %d %s", res.Errors[0].Code, res.Errors[0].Message)
+		}
+		return fmt.Errorf("cloudflare: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
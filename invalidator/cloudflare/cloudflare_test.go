@@ -0,0 +1,42 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCloudflare(t *testing.T) {
+	var received []purgeRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/zones/zone1/purge_cache", r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"success":false,"errors":[{"code":10000,"message":"Authentication error"}]}`))
+			return
+		}
+		var req purgeRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		received = append(received, req)
+		_, _ = w.Write([]byte(`{"success":true,"errors":[]}`))
+	}))
+	defer ts.Close()
+
+	c := New("zone1", "t0ken", "https://img.example.com/", WithEndpoint(ts.URL), WithClient(ts.Client()))
+	ctx := context.Background()
+	require.NoError(t, c.Invalidate(ctx, []string{"/unsafe/foo.jpg", "/abc/foo.jpg"}))
+	require.NoError(t, c.Invalidate(ctx, []string{"/unsafe/200x200/*"}))
+	assert.Equal(t, []purgeRequest{
+		{Files: []string{"https://img.example.com/unsafe/foo.jpg", "https://img.example.com/abc/foo.jpg"}},
+		{Prefixes: []string{"img.example.com/unsafe/200x200/"}},
+	}, received)
+
+	err := New("zone1", "wrong", "https://img.example.com", WithEndpoint(ts.URL)).
+		Invalidate(ctx, []string{"/unsafe/foo.jpg"})
+	assert.EqualError(t, err, "cloudflare: 10000 Authentication error")
+}
@@ -0,0 +1,21 @@
+package cloudflare
+
+import "net/http"
+
+type Option func(c *Cloudflare)
+
+func WithEndpoint(endpoint string) Option {
+	return func(c *Cloudflare) {
+		if endpoint != "" {
+			c.Endpoint = endpoint
+		}
+	}
+}
+
+func WithClient(client *http.Client) Option {
+	return func(c *Cloudflare) {
+		if client != nil {
+			c.Client = client
+		}
+	}
+}
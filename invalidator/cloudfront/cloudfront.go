@@ -0,0 +1,40 @@
+package cloudfront
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/cloudfront/cloudfrontiface"
+	"strconv"
+	"time"
+)
+
+// CloudFront creates CloudFront invalidation of the paths on purge, implements imagor.Invalidator
+type CloudFront struct {
+	CloudFront     cloudfrontiface.CloudFrontAPI
+	DistributionID string
+}
+
+// New creates CloudFront invalidator of distribution
+func New(sess *session.Session, distributionID string) *CloudFront {
+	return &CloudFront{
+		CloudFront:     cloudfront.New(sess),
+		DistributionID: distributionID,
+	}
+}
+
+// Invalidate creates invalidation of the paths, where paths ending with * are wildcard paths
+func (c *CloudFront) Invalidate(ctx context.Context, paths []string) error {
+	_, err := c.CloudFront.CreateInvalidationWithContext(ctx, &cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(c.DistributionID),
+		InvalidationBatch: &cloudfront.InvalidationBatch{
+			CallerReference: aws.String(strconv.FormatInt(time.Now().UnixNano(), 10)),
+			Paths: &cloudfront.Paths{
+				Items:    aws.StringSlice(paths),
+				Quantity: aws.Int64(int64(len(paths))),
+			},
+		},
+	})
+	return err
+}
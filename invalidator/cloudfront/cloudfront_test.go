@@ -0,0 +1,48 @@
+package cloudfront
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+	"github.com/aws/aws-sdk-go/service/cloudfront/cloudfrontiface"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type fakeCloudFront struct {
+	cloudfrontiface.CloudFrontAPI
+	inputs []*cloudfront.CreateInvalidationInput
+	err    error
+}
+
+func (f *fakeCloudFront) CreateInvalidationWithContext(
+	_ aws.Context, input *cloudfront.CreateInvalidationInput, _ ...request.Option,
+) (*cloudfront.CreateInvalidationOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.inputs = append(f.inputs, input)
+	return &cloudfront.CreateInvalidationOutput{}, nil
+}
+
+func TestCloudFront(t *testing.T) {
+	c := New(session.Must(session.NewSession(&aws.Config{Region: aws.String("us-east-1")})), "DIST1")
+	fake := &fakeCloudFront{}
+	c.CloudFront = fake
+
+	ctx := context.Background()
+	require.NoError(t, c.Invalidate(ctx, []string{"/unsafe/foo.jpg", "/unsafe/200x200/*"}))
+	require.Len(t, fake.inputs, 1)
+	input := fake.inputs[0]
+	assert.Equal(t, "DIST1", aws.StringValue(input.DistributionId))
+	assert.Equal(t, []string{"/unsafe/foo.jpg", "/unsafe/200x200/*"}, aws.StringValueSlice(input.InvalidationBatch.Paths.Items))
+	assert.Equal(t, int64(2), aws.Int64Value(input.InvalidationBatch.Paths.Quantity))
+	assert.NotEmpty(t, aws.StringValue(input.InvalidationBatch.CallerReference))
+
+	fake.err = errors.New("access denied")
+	assert.Error(t, c.Invalidate(ctx, []string{"/unsafe/foo.jpg"}))
+}
@@ -0,0 +1,76 @@
+package fastly
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrPrefixNotSupported error on prefix invalidation without service ID,
+// as Fastly does not support wildcard purge of URLs
+var ErrPrefixNotSupported = errors.New("fastly: prefix purge requires service id")
+
+// Fastly purges Fastly cache of the URLs on purge, implements imagor.Invalidator
+type Fastly struct {
+	APIKey    string
+	ServiceID string
+	BaseURL   string
+	Endpoint  string
+	Client    *http.Client
+}
+
+// New creates Fastly invalidator with API key and the public base URL e.g. https://img.example.com
+func New(apiKey, baseURL string, options ...Option) *Fastly {
+	f := &Fastly{
+		APIKey:   apiKey,
+		BaseURL:  strings.TrimSuffix(baseURL, "/"),
+		Endpoint: "https://api.fastly.com",
+		Client:   http.DefaultClient,
+	}
+	for _, option := range options {
+		option(f)
+	}
+	return f
+}
+
+// Invalidate purges URLs of the paths. Paths ending with * purge all of the service
+func (f *Fastly) Invalidate(ctx context.Context, paths []string) error {
+	host := f.BaseURL
+	if _, h, ok := strings.Cut(host, "://"); ok {
+		host = h
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "*") {
+			if f.ServiceID == "" {
+				return ErrPrefixNotSupported
+			}
+			return f.purge(ctx, "/service/"+f.ServiceID+"/purge_all")
+		}
+	}
+	for _, path := range paths {
+		if err := f.purge(ctx, "/purge/"+host+path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *Fastly) purge(ctx context.Context, path string) error {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, f.Endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Fastly-Key", f.APIKey)
+	r.Header.Set("Accept", "application/json")
+	resp, err := f.Client.Do(r)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("fastly: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package fastly
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFastly(t *testing.T) {
+	var received []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		if r.Header.Get("Fastly-Key") != "k3y" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		received = append(received, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	f := New("k3y", "https://img.example.com/", WithEndpoint(ts.URL), WithClient(ts.Client()))
+	ctx := context.Background()
+	require.NoError(t, f.Invalidate(ctx, []string{"/unsafe/foo.jpg", "/abc/foo.jpg"}))
+	assert.Equal(t, ErrPrefixNotSupported, f.Invalidate(ctx, []string{"/unsafe/200x200/*"}))
+
+	f = New("k3y", "https://img.example.com", WithServiceID("svc1"), WithEndpoint(ts.URL))
+	require.NoError(t, f.Invalidate(ctx, []string{"/unsafe/200x200/*"}))
+	assert.Equal(t, []string{
+		"/purge/img.example.com/unsafe/foo.jpg",
+		"/purge/img.example.com/abc/foo.jpg",
+		"/service/svc1/purge_all",
+	}, received)
+
+	err := New("wrong", "https://img.example.com", WithEndpoint(ts.URL)).
+		Invalidate(ctx, []string{"/unsafe/foo.jpg"})
+	assert.EqualError(t, err, "fastly: unexpected status 401")
+}
@@ -0,0 +1,28 @@
+package fastly
+
+import "net/http"
+
+type Option func(f *Fastly)
+
+// WithServiceID with Fastly service ID, which is purged all for prefix invalidation
+func WithServiceID(serviceID string) Option {
+	return func(f *Fastly) {
+		f.ServiceID = serviceID
+	}
+}
+
+func WithEndpoint(endpoint string) Option {
+	return func(f *Fastly) {
+		if endpoint != "" {
+			f.Endpoint = endpoint
+		}
+	}
+}
+
+func WithClient(client *http.Client) Option {
+	return func(f *Fastly) {
+		if client != nil {
+			f.Client = client
+		}
+	}
+}
@@ -2,6 +2,7 @@ package httploader
 
 import (
 	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -15,6 +16,9 @@ import (
 	"github.com/cshum/imagor"
 )
 
+// traceHeaders trace context and request ID headers forwarded by ForwardTraceHeaders
+var traceHeaders = []string{"Traceparent", "Tracestate", "X-Request-Id", "X-Correlation-Id"}
+
 type HTTPLoader struct {
 	// The Transport used to request images, default http.DefaultTransport.
 	Transport http.RoundTripper
@@ -22,6 +26,10 @@ type HTTPLoader struct {
 	// ForwardHeaders copy request headers to image request headers
 	ForwardHeaders []string
 
+	// ForwardTraceHeaders copy trace context and request ID headers to image request headers,
+	// for correlation of origin access logs with imagor requests
+	ForwardTraceHeaders bool
+
 	// OverrideHeaders override image request headers
 	OverrideHeaders map[string]string
 
@@ -85,31 +93,44 @@ func New(options ...Option) *HTTPLoader {
 	return h
 }
 
-func (h *HTTPLoader) Get(r *http.Request, image string) (*imagor.Blob, error) {
+// resolve image URL with default scheme, validated against allowed sources
+func (h *HTTPLoader) resolve(image string) (string, error) {
 	if image == "" {
-		return nil, imagor.ErrInvalid
+		return "", imagor.ErrInvalid
 	}
 	u, err := url.Parse(image)
 	if err != nil {
-		return nil, imagor.ErrInvalid
+		return "", imagor.ErrInvalid
 	}
 	if u.Host == "" || u.Scheme == "" {
 		if h.DefaultScheme != "" {
 			image = h.DefaultScheme + "://" + image
 			if u, err = url.Parse(image); err != nil {
-				return nil, imagor.ErrInvalid
+				return "", imagor.ErrInvalid
 			}
 		} else {
-			return nil, imagor.ErrInvalid
+			return "", imagor.ErrInvalid
 		}
 	}
 	if !isURLAllowed(u, h.AllowedSources) {
-		return nil, imagor.ErrInvalid
+		return "", imagor.ErrInvalid
 	}
-	client := &http.Client{
+	return image, nil
+}
+
+func (h *HTTPLoader) client() *http.Client {
+	return &http.Client{
 		Transport:     h.Transport,
 		CheckRedirect: h.checkRedirect,
 	}
+}
+
+func (h *HTTPLoader) Get(r *http.Request, image string) (*imagor.Blob, error) {
+	image, err := h.resolve(image)
+	if err != nil {
+		return nil, err
+	}
+	client := h.client()
 	if h.MaxAllowedSize > 0 {
 		req, err := h.newRequest(r, http.MethodHead, image)
 		if err != nil {
@@ -166,6 +187,39 @@ func (h *HTTPLoader) Get(r *http.Request, image string) (*imagor.Blob, error) {
 	}), nil
 }
 
+// Stat image attributes by HEAD request of Last-Modified, ETag and Content-Length,
+// implements imagor.Stater
+func (h *HTTPLoader) Stat(ctx context.Context, image string) (*imagor.Stat, error) {
+	image, err := h.resolve(image)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodHead, image, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := h.newRequest(r, http.MethodHead, image)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, imagor.NewErrorFromStatusCode(resp.StatusCode)
+	}
+	stat := &imagor.Stat{
+		ETag: resp.Header.Get("ETag"),
+		Size: resp.ContentLength,
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		stat.ModifiedTime = t
+	}
+	return stat, nil
+}
+
 func (h *HTTPLoader) newRequest(r *http.Request, method, url string) (*http.Request, error) {
 	req, err := http.NewRequestWithContext(r.Context(), method, url, nil)
 	if err != nil {
@@ -185,9 +239,17 @@ func (h *HTTPLoader) newRequest(r *http.Request, method, url string) (*http.Requ
 			req.Header.Set(header, r.Header.Get(header))
 		}
 	}
+	if h.ForwardTraceHeaders {
+		for _, header := range traceHeaders {
+			if value := r.Header.Get(header); value != "" {
+				req.Header.Set(header, value)
+			}
+		}
+	}
 	for key, value := range h.OverrideHeaders {
 		req.Header.Set(key, value)
 	}
+	imagor.InjectTrace(r.Context(), req.Header)
 	return req, nil
 }
 
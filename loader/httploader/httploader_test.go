@@ -3,6 +3,7 @@ package httploader
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"io"
 	"math/rand"
 	"net"
@@ -10,6 +11,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/cshum/imagor"
 	"github.com/stretchr/testify/assert"
@@ -444,6 +446,34 @@ func TestWithForwardClientHeaders(t *testing.T) {
 	})
 }
 
+func TestWithForwardTraceHeaders(t *testing.T) {
+	loader := New(
+		WithTransport(roundTripFunc(func(r *http.Request) (w *http.Response, err error) {
+			assert.Equal(t, "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", r.Header.Get("traceparent"))
+			assert.Equal(t, "abc-123", r.Header.Get("X-Request-Id"))
+			assert.Empty(t, r.Header.Get("Tracestate"))
+			assert.Empty(t, r.Header.Get("X-Imagor-Foo"))
+			res := &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     map[string][]string{},
+				Body:       io.NopCloser(strings.NewReader("ok")),
+			}
+			res.Header.Set("Content-Type", "image/jpeg")
+			return res, nil
+		})),
+		WithForwardTraceHeaders(true),
+	)
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/imagor", nil)
+	r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+	r.Header.Set("x-request-id", "abc-123")
+	r.Header.Set("X-Imagor-Foo", "Bar")
+	b, err := loader.Get(r, "https://foo.bar/baz")
+	require.NoError(t, err)
+	buf, err := b.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", string(buf))
+}
+
 func TestWithOverrideHeaders(t *testing.T) {
 	doTests(t, New(
 		WithTransport(roundTripFunc(func(r *http.Request) (w *http.Response, err error) {
@@ -665,3 +695,34 @@ func TestWithInvalidHost(t *testing.T) {
 	assert.Empty(t, b)
 	assert.Equal(t, 404, err.(imagor.Error).Code)
 }
+
+func TestStat(t *testing.T) {
+	modTime := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	loader := New(
+		WithAllowedSources("foo.bar"),
+		WithTransport(roundTripFunc(func(r *http.Request) (w *http.Response, err error) {
+			assert.Equal(t, http.MethodHead, r.Method)
+			if r.URL.Path == "/missing.jpg" {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+			resp := &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        map[string][]string{},
+				ContentLength: 167,
+				Body:          io.NopCloser(strings.NewReader("")),
+			}
+			resp.Header.Set("Last-Modified", modTime.Format(http.TimeFormat))
+			resp.Header.Set("ETag", `"abc"`)
+			return resp, nil
+		})),
+	)
+	stat, err := loader.Stat(context.Background(), "foo.bar/image.jpg")
+	require.NoError(t, err)
+	assert.Equal(t, &imagor.Stat{ModifiedTime: modTime, ETag: `"abc"`, Size: 167}, stat)
+
+	_, err = loader.Stat(context.Background(), "foo.bar/missing.jpg")
+	assert.Equal(t, 404, imagor.WrapError(err).Code)
+
+	_, err = loader.Stat(context.Background(), "example.com/image.jpg")
+	assert.Equal(t, imagor.ErrInvalid, err)
+}
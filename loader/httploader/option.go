@@ -61,12 +61,28 @@ func WithForwardClientHeaders(enabled bool) Option {
 	}
 }
 
+// WithForwardTraceHeaders forwards traceparent, tracestate, X-Request-Id and X-Correlation-Id
+// request headers to image request. traceparent is replaced by span of imagor tracer if enabled
+func WithForwardTraceHeaders(enabled bool) Option {
+	return func(h *HTTPLoader) {
+		h.ForwardTraceHeaders = enabled
+	}
+}
+
 func WithOverrideHeader(name, value string) Option {
 	return func(h *HTTPLoader) {
 		h.OverrideHeaders[name] = value
 	}
 }
 
+func WithOverrideHeaders(headers map[string]string) Option {
+	return func(h *HTTPLoader) {
+		for name, value := range headers {
+			h.OverrideHeaders[name] = value
+		}
+	}
+}
+
 func WithAllowedSources(hosts ...string) Option {
 	return func(h *HTTPLoader) {
 		for _, raw := range hosts {
@@ -0,0 +1,32 @@
+package httploader
+
+import (
+	"github.com/cshum/imagor"
+)
+
+func init() {
+	imagor.RegisterComponent("http", newComponent)
+}
+
+// newComponent creates HTTPLoader of component options
+func newComponent(o imagor.ComponentOptions) (any, error) {
+	maxAllowedSize, err := o.Int("max_allowed_size")
+	if err != nil {
+		return nil, err
+	}
+	blockPrivateNetworks, err := o.Bool("block_private_networks")
+	if err != nil {
+		return nil, err
+	}
+	return New(
+		WithAllowedSources(o.Strings("allowed_sources")...),
+		WithForwardHeaders(o.Strings("forward_headers")...),
+		WithMaxAllowedSize(maxAllowedSize),
+		WithUserAgent(o.String("user_agent")),
+		WithAccept(o.String("accept")),
+		WithDefaultScheme(o.String("default_scheme")),
+		WithBlockLoopbackNetworks(blockPrivateNetworks),
+		WithBlockLinkLocalNetworks(blockPrivateNetworks),
+		WithBlockPrivateNetworks(blockPrivateNetworks),
+	), nil
+}
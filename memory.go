@@ -0,0 +1,71 @@
+package imagor
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cgroupMemoryFiles memory limit files of cgroup v2 and v1 of the container
+var cgroupMemoryFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// containerMemoryLimit returns memory limit of the container by cgroup, 0 if not limited
+func containerMemoryLimit() int64 {
+	for _, file := range cgroupMemoryFiles {
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		// cgroup v2 "max", or cgroup v1 page aligned max int64 if not limited
+		limit, err := strconv.ParseInt(strings.TrimSpace(string(buf)), 10, 64)
+		if err != nil || limit <= 0 || limit >= 1<<62 {
+			return 0
+		}
+		return limit
+	}
+	return 0
+}
+
+// estimateMemory returns estimated working set bytes of processing source blob,
+// by decoded RGBA pixels of all frames, or blob size if dimensions unknown
+func estimateMemory(blob *Blob) int64 {
+	if pixels, ok := sourcePixels(blob); ok {
+		return pixels * 4
+	}
+	return blob.Size()
+}
+
+// acquireMemory acquires estimated working set of processing source blob from memory budget,
+// queued until other processing releases, or ErrTooManyRequests on process queue timeout.
+// Estimate larger than the budget is capped to the budget, processed alone
+func (app *Imagor) acquireMemory(ctx context.Context, blob *Blob) (release func(), err error) {
+	if app.memSema == nil {
+		return func() {}, nil
+	}
+	n := estimateMemory(blob)
+	if n <= 0 {
+		return func() {}, nil
+	}
+	if n > app.ProcessMemoryLimit {
+		n = app.ProcessMemoryLimit
+	}
+	if app.ProcessQueueTimeout > 0 {
+		queueCtx, cancel := context.WithTimeout(ctx, app.ProcessQueueTimeout)
+		defer cancel()
+		if err = app.memSema.Acquire(queueCtx, n); err != nil {
+			if ctx.Err() == nil {
+				return nil, ErrTooManyRequests
+			}
+			return nil, ctx.Err()
+		}
+	} else if err = app.memSema.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+	return func() {
+		app.memSema.Release(n)
+	}, nil
+}
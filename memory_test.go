@@ -0,0 +1,93 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestContainerMemoryLimit(t *testing.T) {
+	defer func(files []string) {
+		cgroupMemoryFiles = files
+	}(cgroupMemoryFiles)
+	dir := t.TempDir()
+	v2 := filepath.Join(dir, "memory.max")
+	v1 := filepath.Join(dir, "memory.limit_in_bytes")
+	cgroupMemoryFiles = []string{v2, v1}
+
+	assert.Equal(t, int64(0), containerMemoryLimit())
+
+	require.NoError(t, os.WriteFile(v1, []byte("9223372036854771712\n"), 0644))
+	assert.Equal(t, int64(0), containerMemoryLimit())
+	require.NoError(t, os.WriteFile(v1, []byte("536870912\n"), 0644))
+	assert.Equal(t, int64(536870912), containerMemoryLimit())
+
+	require.NoError(t, os.WriteFile(v2, []byte("max\n"), 0644))
+	assert.Equal(t, int64(0), containerMemoryLimit())
+	require.NoError(t, os.WriteFile(v2, []byte("1073741824\n"), 0644))
+	assert.Equal(t, int64(1073741824), containerMemoryLimit())
+
+	app := New(WithProcessMemoryLimit(-1))
+	assert.Equal(t, int64(805306368), app.ProcessMemoryLimit)
+	assert.NotNil(t, app.memSema)
+}
+
+func TestProcessMemoryLimit(t *testing.T) {
+	buf, err := os.ReadFile("testdata/gopher.png")
+	require.NoError(t, err)
+	estimate := estimateMemory(NewBlobFromBytes(buf))
+	require.Greater(t, estimate, int64(0))
+	assert.Equal(t, int64(3), estimateMemory(NewBlobFromBytes([]byte("foo"))))
+
+	started := make(chan struct{}, 2)
+	resume := make(chan struct{})
+	app := New(
+		WithUnsafe(true),
+		WithProcessMemoryLimit(estimate*3/2),
+		WithProcessQueueTimeout(time.Millisecond*50),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "small.txt" {
+				return NewBlobFromBytes([]byte("foo")), nil
+			}
+			return NewBlobFromBytes(buf), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			started <- struct{}{}
+			if p.Image == "hold.png" {
+				<-resume
+			}
+			return NewBlobFromBytes([]byte("ok")), nil
+		})),
+	)
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/hold.png", nil))
+		done <- w.Code
+	}()
+	<-started
+
+	// exceeds budget while hold.png processing
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/gopher.png", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// fits in the remaining budget
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/small.txt", nil))
+	assert.Equal(t, 200, w.Code)
+	<-started
+
+	close(resume)
+	assert.Equal(t, 200, <-done)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/gopher.png", nil))
+	assert.Equal(t, 200, w.Code)
+}
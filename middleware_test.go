@@ -0,0 +1,62 @@
+package imagor
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUse(t *testing.T) {
+	var order []string
+	header := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				w.Header().Add("X-Middleware", name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	auth := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer 1234" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	app := New(
+		WithUnsafe(true),
+		WithMiddleware(header("a")),
+		WithMiddleware(nil),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	app.Use(header("b"), auth)
+
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo", nil))
+	assert.Equal(t, 401, w.Code)
+	assert.Equal(t, []string{"a", "b"}, w.Header().Values("X-Middleware"))
+
+	order = nil
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo", nil)
+	r.Header.Set("Authorization", "Bearer 1234")
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foo", w.Body.String())
+	assert.Equal(t, []string{"a", "b"}, order)
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	r.Header.Set("Authorization", "Bearer 1234")
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, fmt.Sprintf(`{"imagor":{"version":"%s"}}`, Version), w.Body.String())
+	assert.Equal(t, []string{"a", "b"}, w.Header().Values("X-Middleware"))
+}
@@ -3,6 +3,10 @@ package imagor
 import (
 	"github.com/cshum/imagor/imagorpath"
 	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -50,6 +54,254 @@ func WithProcessors(processors ...Processor) Option {
 	}
 }
 
+// WithTracer with Tracer for tracing imagor operations as spans
+func WithTracer(tracer Tracer) Option {
+	return func(app *Imagor) {
+		if tracer != nil {
+			app.Tracer = tracer
+		}
+	}
+}
+
+// WithErrorReporter with ErrorReporter for reporting internal errors
+func WithErrorReporter(reporter ErrorReporter) Option {
+	return func(app *Imagor) {
+		if reporter != nil {
+			app.ErrorReporter = reporter
+		}
+	}
+}
+
+// WithAllowedSizes restricts image URL dimensions to the sizes in WIDTHxHEIGHT if set,
+// e.g. 100x100, 200x0 where 0 means auto
+func WithAllowedSizes(sizes ...string) Option {
+	return func(app *Imagor) {
+		for _, size := range sizes {
+			if size = strings.TrimSpace(strings.ToLower(size)); size != "" {
+				app.AllowedSizes = append(app.AllowedSizes, size)
+			}
+		}
+	}
+}
+
+// WithAllowedFilters restricts image URL to the filters if set
+func WithAllowedFilters(filters ...string) Option {
+	return func(app *Imagor) {
+		app.AllowedFilters = append(app.AllowedFilters, filters...)
+	}
+}
+
+// WithDeniedFilters rejects image URL with any of the filters
+func WithDeniedFilters(filters ...string) Option {
+	return func(app *Imagor) {
+		app.DeniedFilters = append(app.DeniedFilters, filters...)
+	}
+}
+
+// WithDeniedFeatures rejects image URL with any of the features:
+// meta, trim, crop, fit-in, stretch, padding, flip, smart
+func WithDeniedFeatures(features ...string) Option {
+	return func(app *Imagor) {
+		app.DeniedFeatures = append(app.DeniedFeatures, features...)
+	}
+}
+
+// WithAllowedReferers restricts image requests to Referer or Origin of the hosts if set,
+// accepts glob pattern e.g. *.example.com for hotlink protection
+func WithAllowedReferers(hosts ...string) Option {
+	return func(app *Imagor) {
+		for _, host := range hosts {
+			if host = strings.TrimSpace(strings.ToLower(host)); host != "" {
+				app.AllowedReferers = append(app.AllowedReferers, host)
+			}
+		}
+	}
+}
+
+// WithTenant appends tenant configuration of image requests matched by Host header or path prefix,
+// matched in the order of tenants
+func WithTenant(tenant Tenant) Option {
+	return func(app *Imagor) {
+		var hosts []string
+		for _, host := range tenant.Hosts {
+			if host = strings.TrimSpace(strings.ToLower(host)); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+		tenant.Hosts = hosts
+		if tenant.PathPrefix = strings.Trim(tenant.PathPrefix, "/"); tenant.PathPrefix != "" {
+			tenant.PathPrefix = "/" + tenant.PathPrefix
+		}
+		if tenant.StoragePrefix = strings.Trim(tenant.StoragePrefix, "/"); tenant.StoragePrefix != "" {
+			tenant.StoragePrefix += "/"
+		}
+		if tenant.BaseParams = strings.TrimSpace(tenant.BaseParams); tenant.BaseParams != "" {
+			tenant.BaseParams = strings.TrimSuffix(tenant.BaseParams, "/") + "/"
+		}
+		app.Tenants = append(app.Tenants, tenant)
+	}
+}
+
+// WithDenyEmptyReferer rejects image requests without Referer and Origin if allowed referers are set
+func WithDenyEmptyReferer(deny bool) Option {
+	return func(app *Imagor) {
+		app.DenyEmptyReferer = deny
+	}
+}
+
+// WithMaxSourceSize with maximum size in bytes of loaded source image
+func WithMaxSourceSize(size int64) Option {
+	return func(app *Imagor) {
+		if size > 0 {
+			app.MaxSourceSize = size
+		}
+	}
+}
+
+// WithMaxSourcePixels with maximum pixel count width × height × frames of source image,
+// checked by reading image headers before decode.
+// Source of which dimensions cannot be read from headers e.g. SVG is rejected
+func WithMaxSourcePixels(pixels int64) Option {
+	return func(app *Imagor) {
+		if pixels > 0 {
+			app.MaxSourcePixels = pixels
+		}
+	}
+}
+
+// WithSourceValidation validates loaded source is an image before processing,
+// or of the passthrough content types e.g. image/svg+xml, application/pdf or image/*
+func WithSourceValidation(enabled bool, passthroughTypes ...string) Option {
+	return func(app *Imagor) {
+		app.ValidateSource = enabled
+		app.SourcePassthroughTypes = append(app.SourcePassthroughTypes, passthroughTypes...)
+	}
+}
+
+// WithUnsupportedSource sets policy of source not supported by any processor e.g. PDF without PDF support,
+// videos or fonts. UnsupportedSourcePassthrough responds source untouched with its content type,
+// UnsupportedSourceReject responds 415. By default responds 406 with the source as body
+func WithUnsupportedSource(policy string) Option {
+	return func(app *Imagor) {
+		if policy == UnsupportedSourcePassthrough || policy == UnsupportedSourceReject {
+			app.UnsupportedSource = policy
+		}
+	}
+}
+
+// WithProcessorRoutes routes sources by content type to processor by package qualified type name,
+// e.g. image/svg+xml to svg.Rasterizer and image/* to vips.Processor, instead of trying processors in order.
+// Content type pattern can be exact, wildcard e.g. image/*, or * for any.
+// Source routed to processor not configured responds ErrUnsupportedFormat
+func WithProcessorRoutes(routes map[string]string) Option {
+	return func(app *Imagor) {
+		for contentType, name := range routes {
+			if contentType = strings.TrimSpace(contentType); contentType != "" && name != "" {
+				if app.ProcessorRoutes == nil {
+					app.ProcessorRoutes = map[string]string{}
+				}
+				app.ProcessorRoutes[contentType] = strings.TrimSpace(name)
+			}
+		}
+	}
+}
+
+// WithRateLimit limits requests per second per client with burst size, responds 429 if exceeded.
+// Clients are keyed by client IP unless WithRateLimitKeyFunc is set
+func WithRateLimit(rate float64, burst int) Option {
+	return func(app *Imagor) {
+		if rate > 0 {
+			app.RateLimit = rate
+			app.RateLimitBurst = burst
+		}
+	}
+}
+
+// WithRateLimitKeyFunc with func returning rate limit key of request e.g. API key,
+// fallback to client IP if empty
+func WithRateLimitKeyFunc(fn func(r *http.Request) string) Option {
+	return func(app *Imagor) {
+		app.RateLimitKeyFunc = fn
+	}
+}
+
+// WithAPIKey with named API key, accepted by Authorization bearer token or api_key query
+// in place of URL signature
+func WithAPIKey(name, key string) Option {
+	return func(app *Imagor) {
+		if name == "" || key == "" {
+			return
+		}
+		if app.APIKeys == nil {
+			app.APIKeys = map[string]string{}
+		}
+		app.APIKeys[name] = key
+	}
+}
+
+// WithAPIKeyRequired requires API key on top of URL signature
+func WithAPIKeyRequired(required bool) Option {
+	return func(app *Imagor) {
+		app.APIKeyRequired = required
+	}
+}
+
+// WithMiddleware wraps imagor request handling with middleware, see Imagor.Use
+func WithMiddleware(middleware func(http.Handler) http.Handler) Option {
+	return func(app *Imagor) {
+		if middleware != nil {
+			app.middlewares = append(app.middlewares, middleware)
+		}
+	}
+}
+
+func WithEventHandlers(handlers ...EventHandler) Option {
+	return func(app *Imagor) {
+		for _, handler := range handlers {
+			if handler != nil {
+				app.EventHandlers = append(app.EventHandlers, handler)
+			}
+		}
+	}
+}
+
+func WithCache(cache Cache) Option {
+	return func(app *Imagor) {
+		if cache != nil {
+			app.Cache = cache
+		}
+	}
+}
+
+// WithSourceCache caches loaded source images for ttl, no expiry if 0,
+// so that different sizes and crops of hot source images do not each reload the original
+func WithSourceCache(cache Cache, ttl time.Duration) Option {
+	return func(app *Imagor) {
+		if cache != nil {
+			app.SourceCache = cache
+			app.SourceCacheTTL = ttl
+		}
+	}
+}
+
+func WithResultCacheTTL(ttl time.Duration) Option {
+	return func(app *Imagor) {
+		if ttl > 0 {
+			app.ResultCacheTTL = ttl
+		}
+	}
+}
+
+// WithErrorCacheTTL with TTL of caching source image load failures of not found or origin errors,
+// so that repeated requests of missing images do not hit the origin
+func WithErrorCacheTTL(ttl time.Duration) Option {
+	return func(app *Imagor) {
+		if ttl > 0 {
+			app.ErrorCacheTTL = ttl
+		}
+	}
+}
+
 func WithRequestTimeout(timeout time.Duration) Option {
 	return func(app *Imagor) {
 		if timeout > 0 {
@@ -74,6 +326,19 @@ func WithCacheHeaderSWR(swr time.Duration) Option {
 	}
 }
 
+// WithResponseHeader with static header added to image responses e.g. X-Content-Type-Options: nosniff
+func WithResponseHeader(key, value string) Option {
+	return func(app *Imagor) {
+		if key == "" {
+			return
+		}
+		if app.ResponseHeaders == nil {
+			app.ResponseHeaders = http.Header{}
+		}
+		app.ResponseHeaders.Add(key, value)
+	}
+}
+
 func WithCacheHeaderNoCache(nocache bool) Option {
 	return func(app *Imagor) {
 		if nocache {
@@ -122,12 +387,208 @@ func WithProcessQueueSize(size int64) Option {
 	}
 }
 
+func WithProcessQueueTimeout(timeout time.Duration) Option {
+	return func(app *Imagor) {
+		if timeout > 0 {
+			app.ProcessQueueTimeout = timeout
+		}
+	}
+}
+
+// WithProcessMemoryLimit limits estimated working set of concurrent processing in bytes,
+// by decoded pixels of source images. Requests exceeding the budget are queued
+// until others complete, or responds 429 on process queue timeout.
+// Negative value detects the limit as 75% of the container memory limit by cgroup
+func WithProcessMemoryLimit(limit int64) Option {
+	return func(app *Imagor) {
+		if limit != 0 {
+			app.ProcessMemoryLimit = limit
+		}
+	}
+}
+
+// WithLoaderCircuitBreaker trips loader after threshold of consecutive failures,
+// skipping it until cool-down elapsed so a dead origin fails fast
+func WithLoaderCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(app *Imagor) {
+		if threshold > 0 {
+			app.LoaderBreakerThreshold = threshold
+		}
+		if cooldown > 0 {
+			app.LoaderBreakerCooldown = cooldown
+		}
+	}
+}
+
+// WithHealthCheckInterval runs health checks of loaders, storages and processors periodically in background,
+// so that status of dependencies is logged and exposed by expvar before requests fail
+func WithHealthCheckInterval(interval time.Duration) Option {
+	return func(app *Imagor) {
+		if interval > 0 {
+			app.HealthCheckInterval = interval
+		}
+	}
+}
+
+// WithUpload enables PUT /upload/<key> endpoint authorized by bearer token of the secret
+func WithUpload(secret string, maxSize int64) Option {
+	return func(app *Imagor) {
+		app.UploadSecret = secret
+		if maxSize > 0 {
+			app.UploadMaxSize = maxSize
+		}
+	}
+}
+
+// WithBatchConcurrency enables POST /batch endpoint processing imagor paths with the concurrency
+func WithBatchConcurrency(concurrency int) Option {
+	return func(app *Imagor) {
+		if concurrency > 0 {
+			app.BatchConcurrency = concurrency
+		}
+	}
+}
+
+// WithPurge enables DELETE /purge/<path> endpoint authorized by bearer token of the secret
+func WithPurge(secret string) Option {
+	return func(app *Imagor) {
+		app.PurgeSecret = secret
+	}
+}
+
+// WithPurgeSource enables purge to delete source images from storages, in addition to results and caches.
+// Not to be enabled if storages are the system of record of originals e.g. uploads
+func WithPurgeSource(enabled bool) Option {
+	return func(app *Imagor) {
+		app.PurgeSource = enabled
+	}
+}
+
+// WithInvalidators with CDN invalidators triggered on purge
+func WithInvalidators(invalidators ...Invalidator) Option {
+	return func(app *Imagor) {
+		for _, invalidator := range invalidators {
+			if invalidator != nil {
+				app.Invalidators = append(app.Invalidators, invalidator)
+			}
+		}
+	}
+}
+
+// WithJobQueues consumes jobs of the queues by workers on startup,
+// generating sizes of source images into result storages
+func WithJobQueues(queues ...JobQueue) Option {
+	return func(app *Imagor) {
+		for _, queue := range queues {
+			if queue != nil {
+				app.JobQueues = append(app.JobQueues, queue)
+			}
+		}
+	}
+}
+
+// WithWorkerConcurrency number of workers consuming jobs of each queue, 1 by default
+func WithWorkerConcurrency(concurrency int) Option {
+	return func(app *Imagor) {
+		if concurrency > 0 {
+			app.WorkerConcurrency = concurrency
+		}
+	}
+}
+
+// WithWatchWarm generates all presets of images added or modified on loaders being watched
+// into result storages by queue workers, e.g. thumbnails of files dropped onto a watched directory
+func WithWatchWarm(enabled bool) Option {
+	return func(app *Imagor) {
+		app.WatchWarm = enabled
+	}
+}
+
+// WithAsyncTimeout enables async processing by async query or Imagor-Async header, with timeout of each job
+func WithAsyncTimeout(timeout time.Duration) Option {
+	return func(app *Imagor) {
+		if timeout > 0 {
+			app.AsyncTimeout = timeout
+		}
+	}
+}
+
+// WithAsyncJobTTL duration of finished async job status being kept
+func WithAsyncJobTTL(ttl time.Duration) Option {
+	return func(app *Imagor) {
+		if ttl > 0 {
+			app.AsyncJobTTL = ttl
+		}
+	}
+}
+
+// WithAsyncMaxPending maximum number of pending async jobs, responds 429 if exceeded
+func WithAsyncMaxPending(n int) Option {
+	return func(app *Imagor) {
+		if n > 0 {
+			app.AsyncMaxPending = n
+		}
+	}
+}
+
+// WithWarm enables POST /warm endpoint authorized by bearer token of the secret
+func WithWarm(secret string) Option {
+	return func(app *Imagor) {
+		app.WarmSecret = secret
+	}
+}
+
+// WithSrcset enables POST /srcset endpoint authorized by bearer token of the secret,
+// with default widths of srcset URLs
+func WithSrcset(secret string, widths ...int) Option {
+	return func(app *Imagor) {
+		app.SrcsetSecret = secret
+		for _, width := range widths {
+			if width > 0 {
+				app.SrcsetWidths = append(app.SrcsetWidths, width)
+			}
+		}
+	}
+}
+
+// WithStats enables GET /stats runtime statistics endpoint authorized by bearer token of the secret
+func WithStats(secret string) Option {
+	return func(app *Imagor) {
+		app.StatsSecret = secret
+	}
+}
+
+// WithPreset named imagor params used as size of Warm
+func WithPreset(name, params string) Option {
+	return func(app *Imagor) {
+		if name == "" {
+			return
+		}
+		if app.Presets == nil {
+			app.Presets = map[string]string{}
+		}
+		app.Presets[name] = params
+	}
+}
+
 func WithUnsafe(unsafe bool) Option {
 	return func(app *Imagor) {
 		app.Unsafe = unsafe
 	}
 }
 
+func WithUnsafeAllowedNetworks(networks ...*net.IPNet) Option {
+	return func(app *Imagor) {
+		app.UnsafeAllowedNetworks = append(app.UnsafeAllowedNetworks, networks...)
+	}
+}
+
+func WithTrustedProxies(networks ...*net.IPNet) Option {
+	return func(app *Imagor) {
+		app.TrustedProxies = append(app.TrustedProxies, networks...)
+	}
+}
+
 func WithAutoWebP(enable bool) Option {
 	return func(app *Imagor) {
 		app.AutoWebP = enable
@@ -140,18 +601,81 @@ func WithAutoAVIF(enable bool) Option {
 	}
 }
 
+// WithSaveDataQuality with default quality of output for clients with Save-Data: on
+// or ECT client hint of slow connection, also negotiating AVIF or WebP output by Accept header
+func WithSaveDataQuality(quality int) Option {
+	return func(app *Imagor) {
+		if quality > 0 && quality <= 100 {
+			app.SaveDataQuality = quality
+		}
+	}
+}
+
+// WithMaxAutoWidth enables auto_width() filter resizing width by Sec-CH-Width or Width client hint,
+// capped by the maximum width
+func WithMaxAutoWidth(width int) Option {
+	return func(app *Imagor) {
+		if width > 0 {
+			app.MaxAutoWidth = width
+		}
+	}
+}
+
+// WithDPRClientHints with dimensions scaled by Sec-CH-DPR or DPR client hint
+func WithDPRClientHints(enable bool) Option {
+	return func(app *Imagor) {
+		app.DPRClientHints = enable
+	}
+}
+
+// WithDiagnosticHeaders with X-Imagor-Cache, X-Imagor-Processor and Server-Timing response headers
+func WithDiagnosticHeaders(enable bool) Option {
+	return func(app *Imagor) {
+		app.DiagnosticHeaders = enable
+	}
+}
+
 func WithBasePathRedirect(url string) Option {
 	return func(app *Imagor) {
 		app.BasePathRedirect = url
 	}
 }
 
+func WithFallbackImage(image string) Option {
+	return func(app *Imagor) {
+		app.FallbackImage = image
+	}
+}
+
+func WithFallbackStatusCode(code int) Option {
+	return func(app *Imagor) {
+		if code > 0 {
+			app.FallbackStatusCode = code
+		}
+	}
+}
+
+func WithPathPrefix(prefix string) Option {
+	return func(app *Imagor) {
+		app.PathPrefix = prefix
+	}
+}
+
 func WithBaseParams(params string) Option {
 	return func(app *Imagor) {
 		app.BaseParams = params
 	}
 }
 
+// WithParamsHook register hook that rewrites params server-side before processing, applied in order after base params
+func WithParamsHook(hook ParamsHookFunc) Option {
+	return func(app *Imagor) {
+		if hook != nil {
+			app.ParamsHooks = append(app.ParamsHooks, hook)
+		}
+	}
+}
+
 func WithModifiedTimeCheck(enabled bool) Option {
 	return func(app *Imagor) {
 		app.ModifiedTimeCheck = enabled
@@ -170,6 +694,55 @@ func WithDisableParamsEndpoint(disabled bool) Option {
 	}
 }
 
+// WithErrorHandler register error response handler by HTTP status code. Status code 0 handles all errors without a specific handler
+func WithErrorHandler(code int, handler ErrorHandlerFunc) Option {
+	return func(app *Imagor) {
+		if handler != nil {
+			if app.ErrorHandlers == nil {
+				app.ErrorHandlers = map[int]ErrorHandlerFunc{}
+			}
+			app.ErrorHandlers[code] = handler
+		}
+	}
+}
+
+// WithCompression enables compression of JSON, SVG and error responses
+// by built-in content encodings negotiated via Accept-Encoding: gzip, deflate
+func WithCompression(encodings ...string) Option {
+	return func(app *Imagor) {
+		for _, encoding := range encodings {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if compressor, ok := builtinCompressors[encoding]; ok {
+				WithCompressor(encoding, compressor)(app)
+			}
+		}
+	}
+}
+
+// WithCompressor register compressor of content encoding e.g. br for compression of JSON, SVG and error responses
+func WithCompressor(encoding string, compressor CompressorFunc) Option {
+	return func(app *Imagor) {
+		if encoding != "" && compressor != nil {
+			if app.Compressors == nil {
+				app.Compressors = map[string]CompressorFunc{}
+			}
+			app.Compressors[strings.ToLower(encoding)] = compressor
+		}
+	}
+}
+
+// WithErrorBody register static error response body by HTTP status code. Status code 0 handles all errors without a specific handler
+func WithErrorBody(code int, contentType string, body []byte) Option {
+	return WithErrorHandler(code, func(w http.ResponseWriter, r *http.Request, err Error) {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(err.Code)
+		if r.Method != http.MethodHead {
+			_, _ = w.Write(body)
+		}
+	})
+}
+
 func WithDebug(debug bool) Option {
 	return func(app *Imagor) {
 		app.Debug = debug
@@ -192,6 +765,25 @@ func WithStoragePathStyle(hasher imagorpath.StorageHasher) Option {
 	}
 }
 
+// WithPresignRedirect redirects passthrough requests without transformation to presigned URL
+// of source image expiring in duration, by loaders that support it e.g. S3 and Google Cloud Storage,
+// instead of streaming through imagor
+func WithPresignRedirect(expires time.Duration) Option {
+	return func(app *Imagor) {
+		if expires > 0 {
+			app.PresignRedirect = expires
+		}
+	}
+}
+
+// WithDebugDir dumps loaded source, processor stages and output of requests
+// in debug mode or with debug() filter of signed URL, to directory by request ID
+func WithDebugDir(dir string) Option {
+	return func(app *Imagor) {
+		app.DebugDir = dir
+	}
+}
+
 func WithSigner(signer imagorpath.Signer) Option {
 	return func(app *Imagor) {
 		if signer != nil {
@@ -199,3 +791,21 @@ func WithSigner(signer imagorpath.Signer) Option {
 		}
 	}
 }
+
+func WithCrypter(crypter imagorpath.Crypter) Option {
+	return func(app *Imagor) {
+		if crypter != nil {
+			app.Crypter = crypter
+		}
+	}
+}
+
+// WithLegacyCrypter with crypter of legacy encrypted URL e.g. thumbor ALLOW_OLD_URLS,
+// which is served at root path in place of signed URL
+func WithLegacyCrypter(crypter imagorpath.Crypter) Option {
+	return func(app *Imagor) {
+		if crypter != nil {
+			app.LegacyCrypter = crypter
+		}
+	}
+}
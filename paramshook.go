@@ -0,0 +1,25 @@
+package imagor
+
+import (
+	"github.com/cshum/imagor/imagorpath"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ParamsHookFunc inspects params and request attributes e.g. Host, headers, TenantName and APIKeyName,
+// and returns params rewritten server-side before processing e.g. clamped sizes or injected watermark,
+// or error to reject the request. Hooks run after signature and policy checks, so rewritten params are trusted
+type ParamsHookFunc func(r *http.Request, p imagorpath.Params) (imagorpath.Params, error)
+
+// applyParamsHooks rewrites params by params hooks in order, stops at the first error
+func (app *Imagor) applyParamsHooks(r *http.Request, p imagorpath.Params) (_ imagorpath.Params, err error) {
+	for _, hook := range app.ParamsHooks {
+		if p, err = hook(r, p); err != nil {
+			if app.Debug {
+				app.Logger.Debug("params-hook", zap.Any("params", p), zap.Error(err))
+			}
+			return
+		}
+	}
+	return p, nil
+}
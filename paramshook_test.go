@@ -0,0 +1,62 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithParamsHook(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithAllowedSizes("100x100", "2000x2000"),
+		WithParamsHook(func(r *http.Request, p imagorpath.Params) (imagorpath.Params, error) {
+			if p.Image == "private.jpg" {
+				return p, ErrSourceNotAllowed
+			}
+			// clamp size
+			if p.Width > 500 {
+				p.Width = 500
+			}
+			if p.Height > 500 {
+				p.Height = 500
+			}
+			return p, nil
+		}),
+		WithParamsHook(nil),
+		WithParamsHook(func(r *http.Request, p imagorpath.Params) (imagorpath.Params, error) {
+			if r.Header.Get("X-Plan") != "premium" {
+				p.Filters = append(p.Filters, imagorpath.Filter{Name: "watermark", Args: "logo.png,0,0,0"})
+			}
+			return p, nil
+		}),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	assert.Len(t, app.ParamsHooks, 2)
+	for _, test := range []struct {
+		path, plan, expected string
+		code                 int
+	}{
+		{"unsafe/2000x2000/foo.jpg", "", "500x500/filters:watermark(logo.png,0,0,0)/foo.jpg", 200},
+		{"unsafe/100x100/foo.jpg", "premium", "100x100/foo.jpg", 200},
+		{"unsafe/300x300/foo.jpg", "premium", "", 403},
+		{"unsafe/private.jpg", "premium", "", 403},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/"+test.path, nil)
+		r.Header.Set("X-Plan", test.plan)
+		app.ServeHTTP(w, r)
+		assert.Equal(t, test.code, w.Code, test.path)
+		if test.code == 200 {
+			assert.Equal(t, test.expected, w.Body.String(), test.path)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package imagor
+
+import (
+	"fmt"
+	"github.com/cshum/imagor/imagorpath"
+	"math"
+)
+
+// URL features that can be denied by WithDeniedFeatures
+const (
+	FeatureMeta    = "meta"
+	FeatureTrim    = "trim"
+	FeatureCrop    = "crop"
+	FeatureFitIn   = "fit-in"
+	FeatureStretch = "stretch"
+	FeaturePadding = "padding"
+	FeatureFlip    = "flip"
+	FeatureSmart   = "smart"
+)
+
+// paramsFeatures returns URL features used by params
+func paramsFeatures(p imagorpath.Params) (features []string) {
+	if p.Meta {
+		features = append(features, FeatureMeta)
+	}
+	if p.Trim {
+		features = append(features, FeatureTrim)
+	}
+	if p.CropLeft > 0 || p.CropTop > 0 || p.CropRight > 0 || p.CropBottom > 0 {
+		features = append(features, FeatureCrop)
+	}
+	if p.FitIn {
+		features = append(features, FeatureFitIn)
+	}
+	if p.Stretch {
+		features = append(features, FeatureStretch)
+	}
+	if p.PaddingLeft > 0 || p.PaddingTop > 0 || p.PaddingRight > 0 || p.PaddingBottom > 0 {
+		features = append(features, FeaturePadding)
+	}
+	if p.HFlip || p.VFlip {
+		features = append(features, FeatureFlip)
+	}
+	if p.Smart {
+		features = append(features, FeatureSmart)
+	}
+	return
+}
+
+// checkPolicy checks params against allowed sizes, allowed and denied filters and denied URL features
+func (app *Imagor) checkPolicy(p imagorpath.Params) error {
+	if len(app.AllowedSizes) > 0 && (p.Width > 0 || p.Height > 0) &&
+		!contains(app.AllowedSizes, fmt.Sprintf("%dx%d", p.Width, p.Height)) {
+		return ErrSizeNotAllowed
+	}
+	if len(app.DeniedFeatures) > 0 {
+		for _, feature := range paramsFeatures(p) {
+			if contains(app.DeniedFeatures, feature) {
+				return ErrFeatureNotAllowed
+			}
+		}
+	}
+	if len(app.AllowedFilters) == 0 && len(app.DeniedFilters) == 0 {
+		return nil
+	}
+	for _, f := range p.Filters {
+		if contains(app.DeniedFilters, f.Name) ||
+			(len(app.AllowedFilters) > 0 && !contains(app.AllowedFilters, f.Name)) {
+			return ErrFeatureNotAllowed
+		}
+	}
+	return nil
+}
+
+// snapAllowedSize snaps dimensions of params rewritten by client hints e.g. auto width or DPR to allowed sizes,
+// the smallest allowed size of the same aspect ratio covering the rewritten size, or the largest one.
+// Returns dimensions of orig params that passed checkPolicy if no allowed size matches
+func (app *Imagor) snapAllowedSize(p, orig imagorpath.Params) imagorpath.Params {
+	if len(app.AllowedSizes) == 0 || (p.Width == orig.Width && p.Height == orig.Height) {
+		return p
+	}
+	// compare by width, or height if width is auto
+	var dimension = func(w, h int) int {
+		if w > 0 {
+			return w
+		}
+		return h
+	}
+	var target = dimension(p.Width, p.Height)
+	var snapped, largest *[2]int
+	for _, size := range app.AllowedSizes {
+		var s [2]int
+		if _, err := fmt.Sscanf(size, "%dx%d", &s[0], &s[1]); err != nil ||
+			!sameAspect(s[0], s[1], p.Width, p.Height) {
+			continue
+		}
+		var d = dimension(s[0], s[1])
+		if d >= target && (snapped == nil || d < dimension(snapped[0], snapped[1])) {
+			snapped = &s
+		}
+		if largest == nil || d > dimension(largest[0], largest[1]) {
+			largest = &s
+		}
+	}
+	if snapped == nil {
+		snapped = largest
+	}
+	if snapped == nil {
+		p.Width, p.Height = orig.Width, orig.Height
+	} else {
+		p.Width, p.Height = snapped[0], snapped[1]
+	}
+	return p
+}
+
+// sameAspect checks if size w x h of allowed sizes is of the same aspect ratio as width x height,
+// within rounding of scaled dimensions
+func sameAspect(w, h, width, height int) bool {
+	if (w == 0) != (width == 0) || (h == 0) != (height == 0) {
+		return false
+	}
+	if w == 0 || h == 0 {
+		return true
+	}
+	return math.Abs(float64(w)/float64(h)-float64(width)/float64(height)) <= 0.01*float64(width)/float64(height)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package imagor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithDeniedFeatures(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithDeniedFilters("label", "liquid_rescale"),
+		WithDeniedFeatures(FeatureMeta, FeatureSmart, FeatureCrop),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	for path, code := range map[string]int{
+		"unsafe/fit-in/100x100/filters:format(webp)/foo":  200,
+		"unsafe/filters:label(foo,1,1,10,red)/foo":        403,
+		"unsafe/filters:quality(80):liquid_rescale()/foo": 403,
+		"unsafe/meta/foo":          403,
+		"unsafe/100x100/smart/foo": 403,
+		"unsafe/10x10:100x100/foo": 403,
+		"unsafe/100x100/foo":       200,
+	} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/"+path, nil))
+		assert.Equal(t, code, w.Code, path)
+		if code == 403 {
+			assert.Equal(t, jsonStr(ErrFeatureNotAllowed), w.Body.String())
+		}
+	}
+}
+
+func TestWithAllowedFilters(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithAllowedFilters("format", "quality"),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	for path, code := range map[string]int{
+		"unsafe/filters:format(webp):quality(80)/foo": 200,
+		"unsafe/filters:format(webp):blur(5)/foo":     403,
+		"unsafe/100x100/foo":                          200,
+	} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/"+path, nil))
+		assert.Equal(t, code, w.Code, path)
+	}
+}
+
+func TestWithAllowedSizes(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithAllowedSizes("100x100", " 200X0 ", ""),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	assert.Equal(t, []string{"100x100", "200x0"}, app.AllowedSizes)
+	for path, code := range map[string]int{
+		"unsafe/100x100/foo":         200,
+		"unsafe/fit-in/-100x100/foo": 200,
+		"unsafe/200x0/foo":           200,
+		"unsafe/200x/foo":            200,
+		"unsafe/foo":                 200,
+		"unsafe/101x100/foo":         403,
+		"unsafe/0x200/foo":           403,
+	} {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/"+path, nil))
+		assert.Equal(t, code, w.Code, path)
+		if code == 403 {
+			assert.Equal(t, jsonStr(ErrSizeNotAllowed), w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"github.com/cshum/imagor/imagorpath"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// Presigner optional interface for Loader to presign short-lived URL of source image,
+// e.g. S3 and Google Cloud Storage, for redirect of passthrough requests
+type Presigner interface {
+	Presign(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+type redirectKey struct{}
+
+// redirect presigned URL of passthrough request
+type redirect struct {
+	url string
+}
+
+// withRedirect returns request with redirect context, allowing presigned redirect of passthrough
+func withRedirect(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), redirectKey{}, &redirect{}))
+}
+
+// redirectFrom returns redirect of context, nil if not enabled
+func redirectFrom(ctx context.Context) *redirect {
+	rd, _ := ctx.Value(redirectKey{}).(*redirect)
+	return rd
+}
+
+// isPassthrough checks if params has no transformation of image
+func isPassthrough(p imagorpath.Params) bool {
+	return !p.Meta && p.Path == imagorpath.NormalizeParams(imagorpath.Params{Image: p.Image}).Path
+}
+
+// presign returns presigned URL of image by the first loader that implements Presigner
+// and has the image, empty if none
+func (app *Imagor) presign(ctx context.Context, image string) string {
+	for _, loader := range app.Loaders {
+		presigner, ok := loader.(Presigner)
+		if !ok {
+			continue
+		}
+		url, err := presigner.Presign(ctx, image, app.PresignRedirect)
+		if err == nil {
+			if app.Debug {
+				app.Logger.Debug("presigned", zap.String("image", image))
+			}
+			return url
+		}
+		if app.Debug {
+			app.Logger.Debug("presign", zap.String("image", image), zap.Error(err))
+		}
+		if !errors.Is(err, ErrInvalid) && !isNotFound(err) {
+			return ""
+		}
+	}
+	return ""
+}
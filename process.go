@@ -0,0 +1,107 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// ProcessRequest source image submitted directly for processing by params,
+// without loaders, storages or signed URL
+type ProcessRequest struct {
+	// Source image, NewBlobFromBytes or NewBlob of reader for streaming large payloads
+	Source *Blob
+	// Params operations applied to Source. Image of params is ignored,
+	// images loaded by filters e.g. watermark are loaded by storages and loaders as usual
+	Params imagorpath.Params
+}
+
+// Process processes source image of request by params, returns Result of processed image.
+// Transport independent core for internal services submitting bytes directly e.g. RPC, queue consumers.
+// Params are trusted. Policies, source limits and concurrency limits apply,
+// results are neither cached nor saved to result storages.
+// Result is never nil
+func (app *Imagor) Process(ctx context.Context, req ProcessRequest) (*Result, error) {
+	var p = req.Params
+	var res = &Result{Meta: p.Meta}
+	if isBlobEmpty(req.Source) {
+		return res, ErrInvalid
+	}
+	if APIKeyName(ctx) == "" {
+		ctx = context.WithValue(ctx, apiKeyNameKey{}, "internal")
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return res, err
+	}
+	r = withDiagnostics(r)
+	ctx = WithContext(r.Context())
+	if app.RequestTimeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, app.RequestTimeout)
+		Defer(ctx, cancel)
+	}
+	r = r.WithContext(ctx)
+	blob, err := checkBlob(app.process(r, req.Source, p))
+	res.Blob = blob
+	diag := diagnosticsFrom(ctx)
+	diag.mu.Lock()
+	res.Processor = diag.processor
+	diag.mu.Unlock()
+	if !isBlobEmpty(blob) {
+		res.ContentType = blob.ContentType()
+	}
+	return res, err
+}
+
+func (app *Imagor) process(r *http.Request, source *Blob, p imagorpath.Params) (blob *Blob, err error) {
+	var ctx = r.Context()
+	if err = app.checkPolicy(p); err != nil {
+		return
+	}
+	if app.BaseParams != "" {
+		p = imagorpath.Apply(p, app.BaseParams)
+	}
+	if p, err = app.applyParamsHooks(r, p); err != nil {
+		return
+	}
+	p.Path = imagorpath.NormalizeParams(p).Path
+	if err = app.validateSource(source); err != nil {
+		return nil, wrapStage(StageLoad, err)
+	}
+	if app.queueSema != nil {
+		if !app.queueSema.TryAcquire(1) {
+			return nil, ErrTooManyRequests
+		}
+		defer app.queueSema.Release(1)
+	}
+	if app.sema != nil {
+		if err = app.acquire(ctx); err != nil {
+			return
+		}
+		defer app.sema.Release(1)
+	}
+	releaseMemory, err := app.acquireMemory(ctx, source)
+	if err != nil {
+		return
+	}
+	defer releaseMemory()
+	if timeout := app.stageTimeout(ctx, app.ProcessTimeout); timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		Defer(ctx, cancel)
+	}
+	load := func(image string) (*Blob, error) {
+		blob, _, err := app.loadStorage(r, image)
+		return blob, err
+	}
+	var start = time.Now()
+	blob, err = app.runProcessors(ctx, source, p, load, nil)
+	diagnosticsFrom(ctx).track("process", start)
+	if blob, _, err = app.unsupportedSource(source, blob, p, err); err == nil && app.Debug {
+		app.Logger.Debug("process", zap.Any("params", p))
+	}
+	return
+}
@@ -0,0 +1,84 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestProcess(t *testing.T) {
+	var loaded []string
+	app := New(
+		WithCache(newMapCache()),
+		WithResultCacheTTL(time.Minute),
+		WithAllowedSizes("100x100"),
+		WithMaxSourceSize(10),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			loaded = append(loaded, image)
+			return NewBlobFromBytes([]byte("watermark")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			buf, _ := blob.ReadAll()
+			if len(p.Filters) > 0 {
+				if _, err := load(p.Filters[0].Args); err != nil {
+					return nil, err
+				}
+			}
+			out := NewBlobFromBytes([]byte(p.Path + ":" + string(buf)))
+			out.SetContentType("image/webp")
+			return out, nil
+		})),
+	)
+	ctx := context.Background()
+
+	res, err := app.Process(ctx, ProcessRequest{
+		Source: NewBlobFromBytes([]byte("foo")),
+		Params: imagorpath.Params{Width: 100, Height: 100},
+	})
+	require.NoError(t, err)
+	buf, _ := res.Blob.ReadAll()
+	assert.Equal(t, "100x100/:foo", string(buf))
+	assert.Equal(t, "image/webp", res.ContentType)
+	assert.Equal(t, "imagor.processorFunc", res.Processor)
+	assert.Empty(t, res.Cache)
+
+	res, err = app.Process(ctx, ProcessRequest{
+		Source: NewBlobFromBytes([]byte("bar")),
+		Params: imagorpath.Params{Width: 100, Height: 100},
+	})
+	require.NoError(t, err)
+	buf, _ = res.Blob.ReadAll()
+	assert.Equal(t, "100x100/:bar", string(buf), "results not cached")
+	assert.Empty(t, loaded, "source not loaded")
+
+	_, err = app.Process(ctx, ProcessRequest{
+		Source: NewBlobFromBytes([]byte("foo")),
+		Params: imagorpath.Params{
+			Width: 100, Height: 100,
+			Filters: imagorpath.Filters{{Name: "watermark", Args: "logo.png"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logo.png"}, loaded, "filter images loaded by loaders")
+
+	_, err = app.Process(ctx, ProcessRequest{
+		Source: NewBlobFromBytes([]byte("foo")),
+		Params: imagorpath.Params{Width: 200, Height: 200},
+	})
+	assert.Equal(t, ErrSizeNotAllowed, err)
+
+	_, err = app.Process(ctx, ProcessRequest{
+		Source: NewBlobFromBytes([]byte("source exceeds max size")),
+		Params: imagorpath.Params{Width: 100, Height: 100},
+	})
+	assert.ErrorIs(t, err, ErrMaxSizeExceeded)
+
+	res, err = app.Process(ctx, ProcessRequest{})
+	assert.Equal(t, ErrInvalid, err)
+	require.NotNil(t, res)
+	assert.Nil(t, res.Blob)
+}
@@ -0,0 +1,298 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"github.com/cshum/imagor/imagorpath"
+	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PrefixDeleter optional interface for Cache and Storage to delete all keys with prefix
+type PrefixDeleter interface {
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Invalidator invalidates CDN cache of the public URL paths on purge.
+// Path ending with * is a prefix invalidation
+type Invalidator interface {
+	Invalidate(ctx context.Context, paths []string) error
+}
+
+// servePurge handles DELETE /purge/<imagor path> and DELETE /purge/<prefix>*
+func (app *Imagor) servePurge(w http.ResponseWriter, r *http.Request, path string) {
+	key := strings.TrimPrefix(path, "/purge/")
+	if key == path {
+		writeError(w, r, ErrMethodNotAllowed)
+		return
+	}
+	var err error
+	if !isBearerAuthorized(r, app.PurgeSecret) {
+		err = ErrUnauthorized
+	} else if key == "" || key == "*" {
+		err = ErrInvalid
+	} else if prefix := strings.TrimSuffix(key, "*"); prefix != key {
+		err = app.purgePrefix(r.Context(), prefix)
+	} else {
+		if len(app.Tenants) > 0 {
+			// results of tenant are keyed by its storage prefix and base params
+			var tenant *Tenant
+			if tenant, key = app.matchTenant(r, "/"+key); tenant != nil {
+				r = withTenant(r, tenant)
+			}
+			key = strings.TrimPrefix(key, "/")
+		}
+		err = app.purge(r, imagorpath.Parse(key))
+	}
+	if err == nil {
+		err = app.invalidate(r.Context(), key)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// invalidate CDN cache of the public URL paths of purge key with invalidators
+func (app *Imagor) invalidate(ctx context.Context, key string) error {
+	if len(app.Invalidators) == 0 {
+		return nil
+	}
+	paths := app.publicPaths(key)
+	if len(paths) == 0 {
+		return nil
+	}
+	for _, invalidator := range app.Invalidators {
+		if err := invalidator.Invalidate(ctx, paths); err != nil {
+			app.Logger.Warn("invalidate", zap.Strings("paths", paths), zap.Error(err))
+			return err
+		}
+	}
+	if app.Debug {
+		app.Logger.Debug("invalidated", zap.Strings("paths", paths))
+	}
+	return nil
+}
+
+// publicPaths returns URL paths that the purge key is served at,
+// as unsafe or signed URL unless key is already one of them
+func (app *Imagor) publicPaths(key string) []string {
+	prefix := strings.TrimSuffix(key, "*")
+	if p := imagorpath.Parse(prefix); p.Unsafe || p.Hash != "" {
+		return []string{app.location(key)}
+	}
+	var paths []string
+	if app.Unsafe {
+		paths = append(paths, app.location("unsafe/"+key))
+	}
+	// signed URLs of prefix cannot be matched by prefix as signature goes first
+	if app.Signer != nil && prefix == key {
+		paths = append(paths, app.location(app.Signer.Sign(key)+"/"+key))
+	}
+	return paths
+}
+
+// purge evicts results of the params from cache and result storages, of every variant negotiated by request headers,
+// and the source image from source cache, and from storages if PurgeSource enabled.
+// Result keys are derived by the same params pipeline as serving the request
+func (app *Imagor) purge(r *http.Request, p imagorpath.Params) error {
+	if p.Image == "" {
+		return ErrInvalid
+	}
+	var ctx = r.Context()
+	var tenant = tenantFrom(ctx)
+	var errs purgeErrors
+	if rp, err := app.requestParams(r, p); err == nil {
+		for _, v := range app.purgeVariants(r, rp) {
+			if app.Cache != nil {
+				errs.add(app.Cache.Delete(ctx, "result:"+tenant.prefixKey(v.Path)))
+			}
+			errs.add(app.deleteAll(ctx, app.ResultStorages, app.resultKey(ctx, v)))
+		}
+	}
+	errs.add(app.deleteSourceCaches(ctx, tenant.prefixKey(p.Image)))
+	if app.PurgeSource {
+		errs.add(app.deleteAll(ctx, app.Storages, app.storageKey(ctx, p.Image)))
+	}
+	return errs.err()
+}
+
+// deleteSourceCaches evicts source image from source cache and its load failure from error cache
+func (app *Imagor) deleteSourceCaches(ctx context.Context, image string) error {
+	var errs purgeErrors
+	if app.Cache != nil {
+		errs.add(app.Cache.Delete(ctx, errorCacheKey(image)))
+	}
+	errs.add(app.deleteSourceCache(ctx, image))
+	return errs.err()
+}
+
+// purgeVariants returns distinct result params of request params negotiated by purge headers.
+// Headers of a dimension that do not change the result are skipped,
+// so that variants are enumerated only by dimensions that apply to the params
+func (app *Imagor) purgeVariants(r *http.Request, p imagorpath.Params) (variants []imagorpath.Params) {
+	negotiate := func(header http.Header) (imagorpath.Params, bool) {
+		vr := r.Clone(r.Context())
+		vr.Header = header.Clone()
+		v, flags, err := app.negotiateParams(vr, p)
+		return v, err == nil && !flags.preview
+	}
+	var base, _ = negotiate(http.Header{})
+	var headers = []http.Header{{}}
+	for _, dimension := range app.purgeHeaders() {
+		var changes = []http.Header{{}}
+		var seen = map[string]bool{base.Path: true}
+		for _, header := range dimension {
+			if v, ok := negotiate(header); ok && !seen[v.Path] {
+				seen[v.Path] = true
+				changes = append(changes, header)
+			}
+		}
+		var product []http.Header
+		for _, header := range headers {
+			for _, change := range changes {
+				merged := header.Clone()
+				for key, values := range change {
+					merged[key] = values
+				}
+				product = append(product, merged)
+			}
+		}
+		headers = product
+	}
+	var purged = map[string]bool{}
+	for _, header := range headers {
+		if v, ok := negotiate(header); ok && !purged[v.Path] {
+			purged[v.Path] = true
+			variants = append(variants, v)
+		}
+	}
+	return
+}
+
+// purgeHeaders returns request headers of each dimension that results may be negotiated by,
+// i.e. Accept of auto format with Save-Data, and DPR or width client hints of allowed sizes
+func (app *Imagor) purgeHeaders() (dimensions [][]http.Header) {
+	var accepts = []string{""}
+	if app.AutoWebP || app.SaveDataQuality > 0 {
+		accepts = append(accepts, "image/webp")
+	}
+	if app.AutoAVIF || app.SaveDataQuality > 0 {
+		accepts = append(accepts, "image/avif")
+	}
+	var saveData = []string{""}
+	if app.SaveDataQuality > 0 {
+		saveData = append(saveData, "on")
+	}
+	var negotiation []http.Header
+	for _, accept := range accepts {
+		for _, sd := range saveData {
+			if accept == "" && sd == "" {
+				continue
+			}
+			header := http.Header{}
+			if accept != "" {
+				header.Set("Accept", accept)
+			}
+			if sd != "" {
+				header.Set("Save-Data", sd)
+			}
+			negotiation = append(negotiation, header)
+		}
+	}
+	var hints []http.Header
+	if app.DPRClientHints {
+		for dpr := 11; dpr <= maxDPR*10; dpr++ {
+			hints = append(hints, http.Header{
+				"Sec-Ch-Dpr": {strconv.FormatFloat(float64(dpr)/10, 'f', 1, 64)},
+			})
+		}
+	}
+	if app.MaxAutoWidth > 0 {
+		// hinted widths are bound by allowed sizes, otherwise not enumerable
+		for _, size := range app.AllowedSizes {
+			if w, _, _ := strings.Cut(size, "x"); w != "" && w != "0" {
+				hints = append(hints, http.Header{"Sec-Ch-Width": {w}})
+			}
+		}
+	}
+	for _, dimension := range [][]http.Header{negotiation, hints} {
+		if len(dimension) > 0 {
+			dimensions = append(dimensions, dimension)
+		}
+	}
+	return
+}
+
+// purgePrefix evicts keys with prefix from cache and storages that implement PrefixDeleter
+func (app *Imagor) purgePrefix(ctx context.Context, prefix string) error {
+	var errs purgeErrors
+	if d, ok := app.Cache.(PrefixDeleter); ok {
+		errs.add(d.DeletePrefix(ctx, "result:"+prefix))
+	}
+	if d, ok := app.SourceCache.(PrefixDeleter); ok {
+		errs.add(d.DeletePrefix(ctx, sourceCacheKey(prefix)))
+	}
+	var storages = app.ResultStorages
+	if app.PurgeSource {
+		storages = append(append([]Storage{}, app.Storages...), app.ResultStorages...)
+	}
+	for _, storage := range storages {
+		if d, ok := storage.(PrefixDeleter); ok {
+			errs.add(d.DeletePrefix(ctx, prefix))
+		}
+	}
+	return errs.err()
+}
+
+func (app *Imagor) deleteAll(ctx context.Context, storages []Storage, key string) error {
+	var errs purgeErrors
+	var wg sync.WaitGroup
+	for _, storage := range storages {
+		wg.Add(1)
+		go func(storage Storage) {
+			defer wg.Done()
+			err := storage.Delete(ctx, key)
+			if err != nil && !isNotFound(err) {
+				app.Logger.Warn("purge", zap.String("key", key), zap.Error(err))
+			} else if err == nil && app.Debug {
+				app.Logger.Debug("purged", zap.String("key", key))
+			}
+			errs.add(err)
+		}(storage)
+	}
+	wg.Wait()
+	return errs.err()
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, os.ErrNotExist)
+}
+
+// purgeErrors collects the first non not found error
+type purgeErrors struct {
+	mu    sync.Mutex
+	first error
+}
+
+func (e *purgeErrors) add(err error) {
+	if err == nil || isNotFound(err) {
+		return
+	}
+	e.mu.Lock()
+	if e.first == nil {
+		e.first = err
+	}
+	e.mu.Unlock()
+}
+
+func (e *purgeErrors) err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.first
+}
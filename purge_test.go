@@ -0,0 +1,258 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type prefixMapCache struct {
+	*mapCache
+}
+
+func (c *prefixMapCache) DeletePrefix(_ context.Context, prefix string) error {
+	c.l.Lock()
+	defer c.l.Unlock()
+	for key := range c.Map {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.Map, key)
+		}
+	}
+	return nil
+}
+
+func TestWithPurge(t *testing.T) {
+	cache := &prefixMapCache{newMapCache()}
+	store := newMapStore()
+	resultStore := newMapStore()
+	app := New(
+		WithUnsafe(true),
+		WithAutoWebP(true),
+		WithPurge("purg3"),
+		WithCache(cache),
+		WithResultCacheTTL(time.Minute),
+		WithStorages(store),
+		WithResultStorages(resultStore),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			buf, _ := blob.ReadAll()
+			return NewBlobFromBytes([]byte(string(buf) + "-processed")), nil
+		})),
+	)
+	doGet := func(path, accept string) {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		assert.Equal(t, 200, w.Code)
+		time.Sleep(time.Millisecond * 10)
+	}
+	doPurge := func(path, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodDelete, "https://example.com"+path, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	doGet("/unsafe/fit-in/100x100/foo/a.jpg", "")
+	doGet("/unsafe/fit-in/100x100/foo/a.jpg", "image/webp")
+	doGet("/unsafe/200x200/foo/b.jpg", "")
+	assert.Contains(t, cache.Map, "result:fit-in/100x100/foo/a.jpg")
+	assert.Contains(t, cache.Map, "result:fit-in/100x100/filters:format(webp)/foo/a.jpg")
+	assert.Contains(t, resultStore.Map, "fit-in/100x100/foo/a.jpg")
+	assert.Contains(t, resultStore.Map, "fit-in/100x100/filters:format(webp)/foo/a.jpg")
+	assert.Contains(t, store.Map, "foo/a.jpg")
+
+	w := doPurge("/purge/fit-in/100x100/foo/a.jpg", "wrong")
+	assert.Equal(t, 401, w.Code)
+	assert.Equal(t, jsonStr(ErrUnauthorized), w.Body.String())
+
+	w = doPurge("/purge/*", "purg3")
+	assert.Equal(t, 400, w.Code)
+
+	w = doPurge("/unsafe/foo/a.jpg", "purg3")
+	assert.Equal(t, 405, w.Code)
+
+	w = doPurge("/purge/fit-in/100x100/foo/a.jpg", "purg3")
+	assert.Equal(t, 204, w.Code)
+	assert.NotContains(t, cache.Map, "result:fit-in/100x100/foo/a.jpg")
+	assert.NotContains(t, cache.Map, "result:fit-in/100x100/filters:format(webp)/foo/a.jpg")
+	assert.NotContains(t, resultStore.Map, "fit-in/100x100/foo/a.jpg")
+	assert.NotContains(t, resultStore.Map, "fit-in/100x100/filters:format(webp)/foo/a.jpg")
+	assert.Contains(t, store.Map, "foo/a.jpg", "source kept unless purge source enabled")
+	assert.Contains(t, cache.Map, "result:200x200/foo/b.jpg")
+
+	// purge again not found is fine
+	w = doPurge("/purge/fit-in/100x100/foo/a.jpg", "purg3")
+	assert.Equal(t, 204, w.Code)
+
+	w = doPurge("/purge/200x200/*", "purg3")
+	assert.Equal(t, 204, w.Code)
+	assert.NotContains(t, cache.Map, "result:200x200/foo/b.jpg")
+
+	app = New(WithCache(cache))
+	w = doPurge("/purge/200x200/foo/b.jpg", "purg3")
+	assert.Equal(t, 405, w.Code)
+}
+
+func TestWithPurgeResultParams(t *testing.T) {
+	cache := newMapCache()
+	store := newMapStore()
+	resultStore := newMapStore()
+	app := New(
+		WithUnsafe(true),
+		WithBaseParams("filters:fill(white)"),
+		WithDPRClientHints(true),
+		WithSaveDataQuality(40),
+		WithPurge("purg3"),
+		WithPurgeSource(true),
+		WithTenant(Tenant{Name: "brand", Hosts: []string{"img.brand.com"}, StoragePrefix: "brand"}),
+		WithCache(cache),
+		WithResultCacheTTL(time.Minute),
+		WithStorages(store),
+		WithResultStorages(resultStore),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	doGet := func(header http.Header) {
+		r := httptest.NewRequest(http.MethodGet, "https://img.brand.com/unsafe/100x50/foo/a.jpg", nil)
+		r.Header = header
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		assert.Equal(t, 200, w.Code)
+	}
+	doGet(http.Header{})
+	doGet(http.Header{"Sec-Ch-Dpr": {"2"}})
+	doGet(http.Header{"Save-Data": {"on"}, "Accept": {"image/webp"}})
+	var keys = []string{
+		"brand/100x50/filters:fill(white)/foo/a.jpg",
+		"brand/200x100/filters:fill(white)/foo/a.jpg",
+		"brand/100x50/filters:fill(white):format(webp):quality(40)/foo/a.jpg",
+	}
+	for _, key := range keys {
+		assert.Contains(t, cache.Map, "result:"+key)
+		assert.Contains(t, resultStore.Map, key)
+	}
+	assert.Contains(t, store.Map, "brand/foo/a.jpg")
+
+	r := httptest.NewRequest(http.MethodDelete, "https://img.brand.com/purge/100x50/foo/a.jpg", nil)
+	r.Header.Set("Authorization", "Bearer purg3")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 204, w.Code)
+	for _, key := range keys {
+		assert.NotContains(t, cache.Map, "result:"+key)
+		assert.NotContains(t, resultStore.Map, key)
+	}
+	assert.NotContains(t, store.Map, "brand/foo/a.jpg")
+}
+
+func TestPurgeVariants(t *testing.T) {
+	var hooks int
+	cache := newMapCache()
+	app := New(
+		WithUnsafe(true),
+		WithAutoWebP(true),
+		WithAutoAVIF(true),
+		WithDPRClientHints(true),
+		WithSaveDataQuality(40),
+		WithMaxAutoWidth(2000),
+		WithAllowedSizes("100x50", "200x100", "400x200", "320x0", "640x0"),
+		WithPurge("purg3"),
+		WithCache(cache),
+		WithParamsHook(func(r *http.Request, p imagorpath.Params) (imagorpath.Params, error) {
+			hooks++
+			return p, nil
+		}),
+	)
+	doPurge := func(path string) {
+		r := httptest.NewRequest(http.MethodDelete, "https://example.com/purge/"+path, nil)
+		r.Header.Set("Authorization", "Bearer purg3")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		assert.Equal(t, 204, w.Code)
+	}
+	for _, tt := range []struct {
+		path     string
+		variants int
+	}{
+		// dpr snapped to allowed sizes x auto format and save data
+		{"100x50/foo/a.jpg", 3 * 6},
+		{"100x50/filters:format(png)/foo/a.jpg", 3 * 2},
+		{"100x50/filters:format(png):quality(80)/foo/a.jpg", 3},
+		{"filters:format(png):quality(80)/foo/a.jpg", 1},
+		// width hint snapped to allowed sizes x auto format and save data
+		{"filters:auto_width()/foo/a.jpg", 3 * 6},
+	} {
+		t.Run(tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodDelete, "https://example.com/", nil)
+			variants := app.purgeVariants(r, imagorpath.Parse(tt.path))
+			assert.Len(t, variants, tt.variants)
+
+			hooks = 0
+			doPurge(tt.path)
+			assert.Equal(t, 1, hooks)
+		})
+	}
+}
+
+type invalidatorFunc func(ctx context.Context, paths []string) error
+
+func (f invalidatorFunc) Invalidate(ctx context.Context, paths []string) error {
+	return f(ctx, paths)
+}
+
+func TestWithInvalidators(t *testing.T) {
+	var invalidated [][]string
+	var fail bool
+	signer := imagorpath.NewDefaultSigner("1234")
+	app := New(
+		WithUnsafe(true),
+		WithSigner(signer),
+		WithPathPrefix("/img/"),
+		WithPurge("purg3"),
+		WithStorages(newMapStore()),
+		WithInvalidators(nil, invalidatorFunc(func(ctx context.Context, paths []string) error {
+			if fail {
+				return errors.New("cdn error")
+			}
+			invalidated = append(invalidated, paths)
+			return nil
+		})),
+	)
+	doPurge := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodDelete, "https://example.com/img"+path, nil)
+		r.Header.Set("Authorization", "Bearer purg3")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	assert.Equal(t, 204, doPurge("/purge/fit-in/100x100/foo/a.jpg").Code)
+	assert.Equal(t, 204, doPurge("/purge/unsafe/200x200/foo/b.jpg").Code)
+	assert.Equal(t, 204, doPurge("/purge/200x200/*").Code)
+	assert.Equal(t, [][]string{
+		{
+			"/img/unsafe/fit-in/100x100/foo/a.jpg",
+			"/img/" + signer.Sign("fit-in/100x100/foo/a.jpg") + "/fit-in/100x100/foo/a.jpg",
+		},
+		{"/img/unsafe/200x200/foo/b.jpg"},
+		{"/img/unsafe/200x200/*"},
+	}, invalidated)
+
+	fail = true
+	w := doPurge("/purge/fit-in/100x100/foo/a.jpg")
+	assert.Equal(t, 500, w.Code)
+	assert.Len(t, invalidated, 3)
+}
@@ -0,0 +1,121 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WarmJob job of queue worker, generating sizes of the source image into result storages
+type WarmJob struct {
+	// Image source image key
+	Image string `json:"image"`
+	// Sizes preset names or imagor params of derivatives, all presets if empty
+	Sizes []string `json:"sizes,omitempty"`
+}
+
+// JobQueue source of jobs consumed by queue workers, e.g. SQS, Kafka, NATS
+type JobQueue interface {
+	// Receive waits for the next job until available or ctx done.
+	// done is called with the processing error once processed,
+	// for acknowledging the message or leaving it for redelivery
+	Receive(ctx context.Context) (job WarmJob, done func(err error), err error)
+}
+
+// queueRetryInterval interval before receiving again on queue error
+var queueRetryInterval = time.Second
+
+// startWorkers starts queue workers consuming jobs of JobQueues and watch,
+// returns func that stops workers and waits for jobs in progress until ctx done
+func (app *Imagor) startWorkers() func(ctx context.Context) {
+	var queues = app.JobQueues
+	if app.watchQueue != nil {
+		queues = append(queues[:len(queues):len(queues)], app.watchQueue)
+	}
+	if len(queues) == 0 {
+		return nil
+	}
+	var concurrency = app.WorkerConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, queue := range queues {
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func(queue JobQueue) {
+				defer wg.Done()
+				app.runWorker(ctx, queue)
+			}(queue)
+		}
+	}
+	return func(ctx context.Context) {
+		cancel()
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			app.Logger.Warn("shutdown-workers", zap.Error(ctx.Err()))
+		}
+	}
+}
+
+func (app *Imagor) runWorker(ctx context.Context, queue JobQueue) {
+	for ctx.Err() == nil {
+		job, done, err := queue.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			app.Logger.Warn("queue-receive", zap.String("queue", fmt.Sprintf("%T", queue)), zap.Error(err))
+			select {
+			case <-ctx.Done():
+			case <-time.After(queueRetryInterval):
+			}
+			continue
+		}
+		// job in progress completes on shutdown, not leaving partial results
+		err = app.runJob(DetachContext(ctx), job)
+		if done != nil {
+			done(err)
+		}
+	}
+}
+
+// runJob generates sizes of job by Warm, returns error if any size failed
+func (app *Imagor) runJob(ctx context.Context, job WarmJob) error {
+	var sizes = job.Sizes
+	if len(sizes) == 0 {
+		for name := range app.Presets {
+			sizes = append(sizes, name)
+		}
+		sort.Strings(sizes)
+	}
+	if job.Image == "" || len(sizes) == 0 {
+		app.Logger.Warn("queue-job", zap.Any("job", job), zap.Error(ErrInvalid))
+		return ErrInvalid
+	}
+	var err error
+	for _, res := range app.Warm(ctx, job.Image, sizes) {
+		if res.Error != "" {
+			app.Logger.Warn("queue-job", zap.String("image", job.Image),
+				zap.String("path", res.Path), zap.String("error", res.Error))
+			if err == nil {
+				err = errors.New(res.Error)
+			}
+		}
+	}
+	if err == nil && app.Debug {
+		app.Logger.Debug("queue-job", zap.String("image", job.Image), zap.Strings("sizes", sizes))
+	}
+	return err
+}
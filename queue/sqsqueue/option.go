@@ -0,0 +1,37 @@
+package sqsqueue
+
+import "strings"
+
+type Option func(q *SQSQueue)
+
+func WithBaseDir(baseDir string) Option {
+	return func(q *SQSQueue) {
+		if baseDir != "" {
+			baseDir = "/" + strings.Trim(baseDir, "/")
+			if baseDir != "/" {
+				baseDir += "/"
+			}
+			q.BaseDir = baseDir
+		}
+	}
+}
+
+func WithPathPrefix(prefix string) Option {
+	return func(q *SQSQueue) {
+		if prefix != "" {
+			prefix = "/" + strings.Trim(prefix, "/")
+			if prefix != "/" {
+				prefix += "/"
+			}
+			q.PathPrefix = prefix
+		}
+	}
+}
+
+func WithWaitTime(seconds int64) Option {
+	return func(q *SQSQueue) {
+		if seconds >= 0 && seconds <= 20 {
+			q.WaitTime = seconds
+		}
+	}
+}
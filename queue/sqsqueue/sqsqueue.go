@@ -0,0 +1,162 @@
+package sqsqueue
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/cshum/imagor"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// SQSQueue receives warm jobs of SQS queue, implements imagor.JobQueue.
+// Message body is either a job of image and sizes as JSON,
+// or S3 event notification of created objects, that generates all presets of each object.
+// Message is deleted once all its jobs succeeded, otherwise redelivered after visibility timeout
+type SQSQueue struct {
+	SQS      sqsiface.SQSAPI
+	QueueURL string
+	// BaseDir and PathPrefix map S3 object keys of event notifications to image keys,
+	// the same way as S3 Loader
+	BaseDir    string
+	PathPrefix string
+	// WaitTime long polling seconds of receive
+	WaitTime int64
+
+	mu      sync.Mutex
+	pending []pendingJob
+}
+
+type pendingJob struct {
+	job imagor.WarmJob
+	msg *message
+}
+
+// message of received jobs, deleted when all jobs done without error
+type message struct {
+	receipt   string
+	remaining int
+	failed    bool
+}
+
+type s3Event struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+}
+
+// New creates SQSQueue of queue URL
+func New(sess *session.Session, queueURL string, options ...Option) *SQSQueue {
+	q := &SQSQueue{
+		SQS:        sqs.New(sess),
+		QueueURL:   queueURL,
+		BaseDir:    "/",
+		PathPrefix: "/",
+		WaitTime:   20,
+	}
+	for _, option := range options {
+		option(q)
+	}
+	return q
+}
+
+// Receive receives next job, long polling SQS queue if no pending jobs of received messages
+func (q *SQSQueue) Receive(ctx context.Context) (imagor.WarmJob, func(err error), error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.pending) == 0 {
+		out, err := q.SQS.ReceiveMessageWithContext(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(q.QueueURL),
+			MaxNumberOfMessages: aws.Int64(10),
+			WaitTimeSeconds:     aws.Int64(q.WaitTime),
+		})
+		if err != nil {
+			return imagor.WarmJob{}, nil, err
+		}
+		for _, m := range out.Messages {
+			jobs := q.parse(aws.StringValue(m.Body))
+			if len(jobs) == 0 {
+				// nothing to process e.g. s3:TestEvent or invalid message
+				_ = q.delete(ctx, aws.StringValue(m.ReceiptHandle))
+				continue
+			}
+			msg := &message{receipt: aws.StringValue(m.ReceiptHandle), remaining: len(jobs)}
+			for _, job := range jobs {
+				q.pending = append(q.pending, pendingJob{job: job, msg: msg})
+			}
+		}
+	}
+	p := q.pending[0]
+	q.pending = q.pending[1:]
+	return p.job, func(err error) {
+		q.done(p.msg, err)
+	}, nil
+}
+
+func (q *SQSQueue) done(msg *message, err error) {
+	q.mu.Lock()
+	msg.remaining--
+	if err != nil {
+		msg.failed = true
+	}
+	shouldDelete := msg.remaining == 0 && !msg.failed
+	q.mu.Unlock()
+	if shouldDelete {
+		_ = q.delete(context.Background(), msg.receipt)
+	}
+}
+
+func (q *SQSQueue) delete(ctx context.Context, receipt string) error {
+	_, err := q.SQS.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(q.QueueURL),
+		ReceiptHandle: aws.String(receipt),
+	})
+	return err
+}
+
+// parse returns jobs of message body
+func (q *SQSQueue) parse(body string) (jobs []imagor.WarmJob) {
+	var event s3Event
+	if err := json.Unmarshal([]byte(body), &event); err != nil {
+		return nil
+	}
+	if len(event.Records) == 0 {
+		var job imagor.WarmJob
+		if err := json.Unmarshal([]byte(body), &job); err != nil || job.Image == "" {
+			return nil
+		}
+		return []imagor.WarmJob{job}
+	}
+	for _, record := range event.Records {
+		if !strings.HasPrefix(record.EventName, "ObjectCreated:") {
+			continue
+		}
+		if image, ok := q.image(record.S3.Object.Key); ok {
+			jobs = append(jobs, imagor.WarmJob{Image: image})
+		}
+	}
+	return
+}
+
+// image returns image key of S3 object key of event notification
+func (q *SQSQueue) image(key string) (string, bool) {
+	// object keys of event notifications are URL encoded
+	key, err := url.QueryUnescape(key)
+	if err != nil {
+		return "", false
+	}
+	key = "/" + strings.TrimPrefix(key, "/")
+	if !strings.HasPrefix(key, q.BaseDir) {
+		return "", false
+	}
+	return strings.TrimPrefix(q.PathPrefix+strings.TrimPrefix(key, q.BaseDir), "/"), true
+}
@@ -0,0 +1,99 @@
+package sqsqueue
+
+import (
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+	"github.com/cshum/imagor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type fakeSQS struct {
+	sqsiface.SQSAPI
+	batches [][]*sqs.Message
+	deleted []string
+	err     error
+}
+
+func (f *fakeSQS) ReceiveMessageWithContext(
+	_ aws.Context, input *sqs.ReceiveMessageInput, _ ...request.Option,
+) (*sqs.ReceiveMessageOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var out = &sqs.ReceiveMessageOutput{}
+	if len(f.batches) > 0 {
+		out.Messages = f.batches[0]
+		f.batches = f.batches[1:]
+	}
+	return out, nil
+}
+
+func (f *fakeSQS) DeleteMessageWithContext(
+	_ aws.Context, input *sqs.DeleteMessageInput, _ ...request.Option,
+) (*sqs.DeleteMessageOutput, error) {
+	f.deleted = append(f.deleted, aws.StringValue(input.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func newMessage(receipt, body string) *sqs.Message {
+	return &sqs.Message{ReceiptHandle: aws.String(receipt), Body: aws.String(body)}
+}
+
+func TestSQSQueue(t *testing.T) {
+	q := New(session.Must(session.NewSession(&aws.Config{Region: aws.String("us-east-1")})),
+		"https://sqs.us-east-1.amazonaws.com/123/jobs", WithBaseDir("uploads"), WithPathPrefix("s3"))
+	assert.Equal(t, "/uploads/", q.BaseDir)
+	assert.Equal(t, "/s3/", q.PathPrefix)
+	assert.Equal(t, int64(20), q.WaitTime)
+	fake := &fakeSQS{batches: [][]*sqs.Message{
+		{
+			newMessage("r1", `{"image":"foo.jpg","sizes":["thumb","50x50"]}`),
+			newMessage("r2", `{"Records":[`+
+				`{"eventName":"ObjectCreated:Put","s3":{"object":{"key":"uploads/my+photo%281%29.jpg"}}},`+
+				`{"eventName":"ObjectCreated:Copy","s3":{"object":{"key":"uploads/bar.png"}}},`+
+				`{"eventName":"ObjectRemoved:Delete","s3":{"object":{"key":"uploads/baz.png"}}},`+
+				`{"eventName":"ObjectCreated:Put","s3":{"object":{"key":"other/baz.png"}}}]}`),
+			newMessage("r3", `{"Event":"s3:TestEvent"}`),
+			newMessage("r4", `invalid`),
+		},
+		{},
+		{newMessage("r5", `{"image":"bar.jpg"}`)},
+	}}
+	q.SQS = fake
+	ctx := context.Background()
+
+	job, done, err := q.Receive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, imagor.WarmJob{Image: "foo.jpg", Sizes: []string{"thumb", "50x50"}}, job)
+	assert.Equal(t, []string{"r3", "r4"}, fake.deleted, "messages without jobs deleted")
+	done(nil)
+	assert.Equal(t, []string{"r3", "r4", "r1"}, fake.deleted)
+
+	job, done1, err := q.Receive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, imagor.WarmJob{Image: "s3/my photo(1).jpg"}, job)
+	job, done2, err := q.Receive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, imagor.WarmJob{Image: "s3/bar.png"}, job)
+	done1(nil)
+	assert.Len(t, fake.deleted, 3, "not deleted until all jobs of message done")
+	done2(nil)
+	assert.Equal(t, []string{"r3", "r4", "r1", "r2"}, fake.deleted)
+
+	job, done, err = q.Receive(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, imagor.WarmJob{Image: "bar.jpg"}, job)
+	done(imagor.ErrNotFound)
+	assert.Len(t, fake.deleted, 4, "failed job left for redelivery")
+
+	fake.err = errors.New("access denied")
+	_, _, err = q.Receive(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,142 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type chanQueue struct {
+	jobs    chan WarmJob
+	results chan error
+	errs    int32
+}
+
+func (q *chanQueue) Receive(ctx context.Context) (WarmJob, func(error), error) {
+	if atomic.AddInt32(&q.errs, -1) >= 0 {
+		return WarmJob{}, nil, errors.New("receive failed")
+	}
+	select {
+	case job := <-q.jobs:
+		return job, func(err error) { q.results <- err }, nil
+	case <-ctx.Done():
+		return WarmJob{}, nil, ctx.Err()
+	}
+}
+
+func TestJobQueues(t *testing.T) {
+	defer func(d time.Duration) { queueRetryInterval = d }(queueRetryInterval)
+	queueRetryInterval = time.Millisecond
+	var mu sync.Mutex
+	var processed []string
+	resultStore := newMapStore()
+	queue := &chanQueue{jobs: make(chan WarmJob), results: make(chan error), errs: 1}
+	app := New(
+		WithPreset("thumb", "fit-in/100x100"),
+		WithPreset("cover", "1200x630"),
+		WithJobQueues(queue, nil),
+		WithWorkerConcurrency(2),
+		WithResultStorages(resultStore),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "missing.jpg" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			mu.Lock()
+			processed = append(processed, p.Path)
+			mu.Unlock()
+			return NewBlobFromBytes([]byte("bar")), nil
+		})),
+	)
+	assert.Equal(t, 2, app.WorkerConcurrency)
+	require.Len(t, app.JobQueues, 1)
+	ctx := context.Background()
+	require.NoError(t, app.Startup(ctx))
+
+	queue.jobs <- WarmJob{Image: "gopher.png"}
+	assert.NoError(t, <-queue.results)
+	queue.jobs <- WarmJob{Image: "gopher.png", Sizes: []string{"50x50"}}
+	assert.NoError(t, <-queue.results)
+	queue.jobs <- WarmJob{Image: "missing.jpg", Sizes: []string{"thumb"}}
+	assert.Error(t, <-queue.results)
+	queue.jobs <- WarmJob{Sizes: []string{"thumb"}}
+	assert.Equal(t, ErrInvalid, <-queue.results)
+
+	mu.Lock()
+	sort.Strings(processed)
+	assert.Equal(t, []string{"1200x630/gopher.png", "50x50/gopher.png", "fit-in/100x100/gopher.png"}, processed)
+	mu.Unlock()
+
+	require.NoError(t, app.Shutdown(ctx))
+	assert.Nil(t, app.stopWorkers)
+	for _, key := range []string{"1200x630/gopher.png", "50x50/gopher.png", "fit-in/100x100/gopher.png"} {
+		_, err := resultStore.Get(&http.Request{}, key)
+		assert.NoError(t, err, key)
+	}
+}
+
+type statWatchLoader struct {
+	watchLoader
+}
+
+func (l *statWatchLoader) Stat(_ context.Context, image string) (*Stat, error) {
+	if image == "removed.jpg" {
+		return nil, ErrNotFound
+	}
+	return &Stat{}, nil
+}
+
+func TestWatchWarm(t *testing.T) {
+	var mu sync.Mutex
+	var processed []string
+	loader := &statWatchLoader{watchLoader{loaderFunc: func(r *http.Request, image string) (*Blob, error) {
+		return NewBlobFromBytes([]byte("foo")), nil
+	}}}
+	resultStore := newMapStore()
+	app := New(
+		WithPreset("thumb", "fit-in/100x100"),
+		WithPreset("cover", "1200x630"),
+		WithWatchWarm(true),
+		WithLoaders(loader),
+		WithResultStorages(resultStore),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			mu.Lock()
+			processed = append(processed, p.Path)
+			mu.Unlock()
+			return NewBlobFromBytes([]byte("bar")), nil
+		})),
+	)
+	assert.True(t, app.WatchWarm)
+	ctx := context.Background()
+	require.NoError(t, app.Startup(ctx))
+	require.NotNil(t, loader.changed)
+	require.NotNil(t, app.watchQueue)
+	assert.Empty(t, app.JobQueues)
+
+	loader.changed("removed.jpg")
+	loader.changed("gopher.png")
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(processed) == 2
+	}, time.Second, time.Millisecond)
+	require.NoError(t, app.Shutdown(ctx))
+	assert.Nil(t, app.watchQueue)
+
+	sort.Strings(processed)
+	assert.Equal(t, []string{"1200x630/gopher.png", "fit-in/100x100/gopher.png"}, processed)
+	for _, key := range processed {
+		_, err := resultStore.Get(&http.Request{}, key)
+		assert.NoError(t, err, key)
+	}
+}
@@ -0,0 +1,92 @@
+package imagor
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter token bucket rate limiter keyed by client
+type rateLimiter struct {
+	rate    float64 // tokens per second
+	burst   float64
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = int(math.Max(1, math.Ceil(rate)))
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// allow takes a token of key, returns duration until next token available if not allowed
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep evicts buckets refilled to full periodically, bounding memory by active clients
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitKey returns rate limit key of request, client IP if RateLimitKeyFunc not set
+func (app *Imagor) rateLimitKey(r *http.Request) string {
+	if app.RateLimitKeyFunc != nil {
+		if key := app.RateLimitKeyFunc(r); key != "" {
+			return key
+		}
+	}
+	if name := app.apiKeyName(r); name != "" {
+		return "key:" + name
+	}
+	return ClientIP(r, app.TrustedProxies)
+}
+
+// rateLimitMiddleware responds 429 with Retry-After if client exceeded rate limit
+func (app *Imagor) rateLimitMiddleware(next http.Handler) http.Handler {
+	limiter := newRateLimiter(app.RateLimit, app.RateLimitBurst)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := limiter.allow(app.rateLimitKey(r), time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, r, ErrTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
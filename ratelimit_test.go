@@ -0,0 +1,89 @@
+package imagor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(2, 3)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow("a", now)
+		assert.True(t, ok)
+	}
+	ok, wait := l.allow("a", now)
+	assert.False(t, ok)
+	assert.Equal(t, time.Millisecond*500, wait)
+
+	ok, _ = l.allow("b", now)
+	assert.True(t, ok)
+
+	ok, _ = l.allow("a", now.Add(time.Millisecond*500))
+	assert.True(t, ok)
+	ok, _ = l.allow("a", now.Add(time.Millisecond*500))
+	assert.False(t, ok)
+
+	// full buckets evicted
+	l.allow("c", now.Add(time.Minute*2))
+	assert.Len(t, l.buckets, 1)
+
+	assert.Equal(t, float64(1), newRateLimiter(0.5, 0).burst)
+	assert.Equal(t, float64(3), newRateLimiter(2.5, 0).burst)
+}
+
+func TestWithRateLimit(t *testing.T) {
+	_, trusted, _ := net.ParseCIDR("10.0.0.0/8")
+	app := New(
+		WithUnsafe(true),
+		WithRateLimit(0.001, 2),
+		WithTrustedProxies(trusted),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	do := func(remoteAddr, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo", nil)
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	assert.Equal(t, 200, do("1.1.1.1:1234", "").Code)
+	assert.Equal(t, 200, do("1.1.1.1:1234", "").Code)
+	w := do("1.1.1.1:1234", "")
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "1000", w.Header().Get("Retry-After"))
+	assert.Equal(t, jsonStr(ErrTooManyRequests), w.Body.String())
+
+	assert.Equal(t, 200, do("10.0.0.1:1234", "2.2.2.2").Code)
+	assert.Equal(t, 200, do("10.0.0.2:1234", "2.2.2.2").Code)
+	assert.Equal(t, 429, do("10.0.0.1:1234", "2.2.2.2").Code)
+	// spoofed header from untrusted remote is ignored
+	assert.Equal(t, 429, do("1.1.1.1:1234", "3.3.3.3").Code)
+
+	app = New(
+		WithRateLimit(0.001, 1),
+		WithRateLimitKeyFunc(func(r *http.Request) string {
+			return r.Header.Get("X-Api-Key")
+		}),
+	)
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	r.Header.Set("X-Api-Key", "foo")
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 429, w.Code)
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/", nil))
+	assert.Equal(t, 200, w.Code)
+}
@@ -0,0 +1,38 @@
+package imagor
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// checkReferer checks host of Referer, or Origin if absent, against AllowedReferers glob patterns
+// for hotlink protection. Request without Referer and Origin is allowed unless DenyEmptyReferer
+func (app *Imagor) checkReferer(r *http.Request) error {
+	if len(app.AllowedReferers) == 0 {
+		return nil
+	}
+	referer := r.Header.Get("Referer")
+	if referer == "" {
+		referer = r.Header.Get("Origin")
+	}
+	if referer == "" || referer == "null" {
+		// stripped by referrer policy, privacy settings or direct access
+		if app.DenyEmptyReferer {
+			return ErrRefererNotAllowed
+		}
+		return nil
+	}
+	u, err := url.Parse(referer)
+	if err != nil || u.Host == "" {
+		return ErrRefererNotAllowed
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range app.AllowedReferers {
+		if matched, e := path.Match(pattern, host); matched && e == nil {
+			return nil
+		}
+	}
+	return ErrRefererNotAllowed
+}
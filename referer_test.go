@@ -0,0 +1,60 @@
+package imagor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithAllowedReferers(t *testing.T) {
+	factory := func(denyEmpty bool, hosts ...string) *Imagor {
+		return New(
+			WithUnsafe(true),
+			WithAllowedReferers(hosts...),
+			WithDenyEmptyReferer(denyEmpty),
+			WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+				return NewBlobFromBytes([]byte("foo")), nil
+			})),
+		)
+	}
+	doGet := func(app *Imagor, header http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		app.ServeHTTP(w, r)
+		return w
+	}
+	app := factory(false, "example.com", " *.Example.com")
+	assert.Equal(t, []string{"example.com", "*.example.com"}, app.AllowedReferers)
+	for _, tt := range []struct {
+		name   string
+		header http.Header
+		code   int
+	}{
+		{"empty", nil, 200},
+		{"null origin", http.Header{"Origin": {"null"}}, 200},
+		{"allowed", http.Header{"Referer": {"https://example.com/gallery"}}, 200},
+		{"allowed subdomain", http.Header{"Referer": {"https://www.Example.com:8080/"}}, 200},
+		{"allowed origin", http.Header{"Origin": {"https://cdn.example.com"}}, 200},
+		{"denied", http.Header{"Referer": {"https://evil.com/example.com"}}, 403},
+		{"denied suffix", http.Header{"Referer": {"https://notexample.com/"}}, 403},
+		{"denied origin", http.Header{"Origin": {"https://evil.com"}}, 403},
+		{"invalid", http.Header{"Referer": {"example.com"}}, 403},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doGet(app, tt.header)
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == 403 {
+				assert.Equal(t, jsonStr(ErrRefererNotAllowed), w.Body.String())
+			}
+		})
+	}
+
+	w := doGet(factory(true, "example.com"), nil)
+	assert.Equal(t, 403, w.Code)
+	w = doGet(factory(true), nil)
+	assert.Equal(t, 200, w.Code, "no referer check if allowed referers not set")
+}
@@ -0,0 +1,114 @@
+package imagor
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ComponentFactory creates Loader, Storage or Processor from options of config entry
+type ComponentFactory func(options ComponentOptions) (any, error)
+
+// ComponentOptions options of component config entry by name
+type ComponentOptions map[string]string
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]ComponentFactory{}
+)
+
+// RegisterComponent registers component factory by name e.g. file, http, vips,
+// usually from init of the component package, so that components can be created from config by name.
+// Panics if name is registered twice
+func RegisterComponent(name string, factory ComponentFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("imagor: register component " + name + " with nil factory")
+	}
+	if _, ok := registry[name]; ok {
+		panic("imagor: register component " + name + " twice")
+	}
+	registry[name] = factory
+}
+
+// NewComponent creates component of registered name with options
+func NewComponent(name string, options ComponentOptions) (any, error) {
+	registryMu.RLock()
+	factory, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("imagor: unknown component %q, registered: %s",
+			name, strings.Join(Components(), ", "))
+	}
+	component, err := factory(options)
+	if err != nil {
+		return nil, fmt.Errorf("imagor: component %s: %w", name, err)
+	}
+	return component, nil
+}
+
+// Components returns sorted names of registered components
+func Components() (names []string) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// String returns option value, empty if not set
+func (o ComponentOptions) String(key string) string {
+	return o[key]
+}
+
+// Strings returns option value split by comma, nil if not set
+func (o ComponentOptions) Strings(key string) (values []string) {
+	for _, v := range strings.Split(o[key], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return
+}
+
+// Int returns option value as int, 0 if not set
+func (o ComponentOptions) Int(key string) (int, error) {
+	if o[key] == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(o[key])
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// Bool returns option value as bool, false if not set
+func (o ComponentOptions) Bool(key string) (bool, error) {
+	if o[key] == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(o[key])
+	if err != nil {
+		return false, fmt.Errorf("option %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// Duration returns option value as duration e.g. 10s, 0 if not set
+func (o ComponentOptions) Duration(key string) (time.Duration, error) {
+	if o[key] == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(o[key])
+	if err != nil {
+		return 0, fmt.Errorf("option %s: %w", key, err)
+	}
+	return d, nil
+}
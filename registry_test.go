@@ -0,0 +1,68 @@
+package imagor
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRegisterComponent(t *testing.T) {
+	RegisterComponent("test-loader", func(o ComponentOptions) (any, error) {
+		if o.String("fail") != "" {
+			return nil, errors.New("failed")
+		}
+		return loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(o.String("body"))), nil
+		}), nil
+	})
+	assert.Contains(t, Components(), "test-loader")
+	assert.Panics(t, func() {
+		RegisterComponent("test-loader", func(o ComponentOptions) (any, error) { return nil, nil })
+	})
+	assert.Panics(t, func() {
+		RegisterComponent("test-nil", nil)
+	})
+
+	component, err := NewComponent("test-loader", ComponentOptions{"body": "foo"})
+	require.NoError(t, err)
+	blob, err := component.(Loader).Get(nil, "a.jpg")
+	require.NoError(t, err)
+	buf, _ := blob.ReadAll()
+	assert.Equal(t, "foo", string(buf))
+
+	_, err = NewComponent("test-loader", ComponentOptions{"fail": "1"})
+	assert.EqualError(t, err, "imagor: component test-loader: failed")
+	_, err = NewComponent("test-unknown", nil)
+	assert.ErrorContains(t, err, `unknown component "test-unknown"`)
+}
+
+func TestComponentOptions(t *testing.T) {
+	o := ComponentOptions{
+		"str": "foo", "list": "a, b,,c", "int": "12", "bool": "true", "dur": "1m", "bad": "x",
+	}
+	assert.Equal(t, "foo", o.String("str"))
+	assert.Equal(t, []string{"a", "b", "c"}, o.Strings("list"))
+	assert.Nil(t, o.Strings("none"))
+	n, err := o.Int("int")
+	assert.NoError(t, err)
+	assert.Equal(t, 12, n)
+	b, err := o.Bool("bool")
+	assert.NoError(t, err)
+	assert.True(t, b)
+	d, err := o.Duration("dur")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, d)
+	n, err = o.Int("none")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = o.Int("bad")
+	assert.ErrorContains(t, err, "option bad")
+	_, err = o.Bool("bad")
+	assert.Error(t, err)
+	_, err = o.Duration("bad")
+	assert.Error(t, err)
+}
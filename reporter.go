@@ -0,0 +1,27 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"github.com/cshum/imagor/imagorpath"
+)
+
+// ErrorReporter reports imagor internal errors of load, process and save stages, e.g. to Sentry.
+// ReportError is called synchronously within request, implementations should not block
+type ErrorReporter interface {
+	ReportError(ctx context.Context, err error, p imagorpath.Params)
+}
+
+// isReportable excludes user errors such as not found, invalid params and timeout
+func isReportable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	return WrapError(err).Code >= 500
+}
+
+func (app *Imagor) report(ctx context.Context, err error, p imagorpath.Params) {
+	if app.ErrorReporter != nil && isReportable(err) {
+		app.ErrorReporter.ReportError(ctx, err, p)
+	}
+}
@@ -0,0 +1,59 @@
+package imagor
+
+import (
+	"fmt"
+	"github.com/cshum/imagor/imagorpath"
+	"net/http"
+	"time"
+)
+
+// Result of Imagor operations, with everything needed to set response headers
+type Result struct {
+	// Blob of processed image, or metadata JSON of meta endpoint.
+	// May be set on error as fallback body e.g. original source on processing failure
+	Blob *Blob
+	// ContentType of blob
+	ContentType string
+	// Meta whether blob is metadata JSON of meta endpoint
+	Meta bool
+	// Cache status HIT if served from result cache or result storage, MISS if processed
+	Cache string
+	// ETag validator of blob, empty if unknown
+	ETag string
+	// LastModified validator of blob, zero if unknown
+	LastModified time.Time
+	// Processor package qualified type name of processor that processed the image e.g. vips.Processor
+	Processor string
+}
+
+// DoResult executes Imagor operations, returns Result of blob with its content type,
+// cache status, validators and processor. Result is never nil
+func (app *Imagor) DoResult(r *http.Request, p imagorpath.Params) (*Result, error) {
+	var diag = diagnosticsFrom(r.Context())
+	if diag == nil {
+		r = withDiagnostics(r)
+		diag = diagnosticsFrom(r.Context())
+	}
+	blob, err := checkBlob(app.do(r, p))
+	var res = &Result{Blob: blob, Meta: p.Meta}
+	diag.mu.Lock()
+	res.Cache = diag.cache
+	res.Processor = diag.processor
+	diag.mu.Unlock()
+	if !isBlobEmpty(blob) {
+		res.ContentType = blob.ContentType()
+		if blob.Stat != nil {
+			res.ETag = statETag(blob.Stat)
+			res.LastModified = blob.Stat.ModifiedTime
+		}
+	}
+	return res, err
+}
+
+// statETag returns ETag of stat, or derived from modified time and size if not set
+func statETag(stat *Stat) string {
+	if stat.ETag == "" && stat.Size > 0 && !stat.ModifiedTime.IsZero() {
+		return fmt.Sprintf("%x-%x", int(stat.ModifiedTime.Unix()), int(stat.Size))
+	}
+	return stat.ETag
+}
@@ -0,0 +1,62 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDoResult(t *testing.T) {
+	modTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	app := New(
+		WithUnsafe(true),
+		WithCache(newMapCache()),
+		WithResultCacheTTL(time.Minute),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "missing.jpg" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			out := NewBlobFromBytes([]byte("bar"))
+			out.SetContentType("image/webp")
+			out.Stat = &Stat{ModifiedTime: modTime, Size: 3}
+			return out, nil
+		})),
+	)
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	p := imagorpath.Parse("unsafe/100x100/foo.jpg")
+
+	res, err := app.DoResult(r, p)
+	require.NoError(t, err)
+	buf, _ := res.Blob.ReadAll()
+	assert.Equal(t, "bar", string(buf))
+	assert.Equal(t, "image/webp", res.ContentType)
+	assert.Equal(t, "MISS", res.Cache)
+	assert.Equal(t, "imagor.processorFunc", res.Processor)
+	assert.Equal(t, modTime, res.LastModified)
+	assert.Equal(t, "65937d25-3", res.ETag)
+	assert.False(t, res.Meta)
+
+	res, err = app.DoResult(r, p)
+	require.NoError(t, err)
+	assert.Equal(t, "HIT", res.Cache)
+	assert.Empty(t, res.Processor)
+	assert.Equal(t, "image/webp", res.ContentType)
+
+	res, err = app.DoResult(r, imagorpath.Parse("unsafe/missing.jpg"))
+	assert.ErrorIs(t, err, ErrNotFound)
+	require.NotNil(t, res)
+	assert.Nil(t, res.Blob)
+
+	blob, err := app.Do(r, p)
+	require.NoError(t, err)
+	buf, _ = blob.ReadAll()
+	assert.Equal(t, "bar", string(buf))
+}
@@ -0,0 +1,46 @@
+package imagor
+
+import (
+	"reflect"
+	"strings"
+)
+
+// processorName returns package qualified type name of processor e.g. vips.Processor,
+// of the wrapped processor if decorated
+func processorName(processor Processor) string {
+	t := reflect.TypeOf(unwrap(processor))
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.String()
+}
+
+// routeProcessors returns processors that handle content type of source blob by ProcessorRoutes,
+// with exact content type taking precedence over wildcard e.g. image/*, then *.
+// Returns all processors if content type is not routed
+func (app *Imagor) routeProcessors(blob *Blob) []Processor {
+	if len(app.ProcessorRoutes) == 0 || isBlobEmpty(blob) {
+		return app.Processors
+	}
+	contentType, _, _ := strings.Cut(blob.ContentType(), ";")
+	contentType = strings.TrimSpace(contentType)
+	name, ok := app.ProcessorRoutes[contentType]
+	if !ok {
+		if major, _, found := strings.Cut(contentType, "/"); found {
+			name, ok = app.ProcessorRoutes[major+"/*"]
+		}
+	}
+	if !ok {
+		name, ok = app.ProcessorRoutes["*"]
+	}
+	if !ok {
+		return app.Processors
+	}
+	var processors []Processor
+	for _, processor := range app.Processors {
+		if processorName(processor) == name {
+			processors = append(processors, processor)
+		}
+	}
+	return processors
+}
@@ -0,0 +1,47 @@
+package sentry
+
+import (
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type Option func(s *Sentry)
+
+func WithEnvironment(env string) Option {
+	return func(s *Sentry) {
+		s.Environment = env
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Sentry) {
+		if timeout > 0 {
+			s.Timeout = timeout
+		}
+	}
+}
+
+func WithQueueSize(size int) Option {
+	return func(s *Sentry) {
+		if size > 0 {
+			s.QueueSize = size
+		}
+	}
+}
+
+func WithClient(client *http.Client) Option {
+	return func(s *Sentry) {
+		if client != nil {
+			s.Client = client
+		}
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(s *Sentry) {
+		if logger != nil {
+			s.Logger = logger
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sentry reports imagor errors to Sentry store API by DSN, implements imagor.ErrorReporter
+type Sentry struct {
+	DSN         string
+	Environment string
+	Timeout     time.Duration
+	QueueSize   int
+	Client      *http.Client
+	Logger      *zap.Logger
+
+	endpoint string
+	key      string
+	queue    chan *Event
+}
+
+// Event Sentry event payload
+type Event struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Release     string            `json:"release,omitempty"`
+	Environment string            `json:"environment,omitempty"`
+	ServerName  string            `json:"server_name,omitempty"`
+	Message     string            `json:"message"`
+	Exception   Exceptions        `json:"exception"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+// Exceptions Sentry exception interface
+type Exceptions struct {
+	Values []Exception `json:"values"`
+}
+
+// Exception Sentry exception
+type Exception struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// New creates Sentry reporter from DSN and starts the background sender
+func New(dsn string, options ...Option) (*Sentry, error) {
+	endpoint, key, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sentry{
+		DSN:       dsn,
+		Timeout:   time.Second * 10,
+		QueueSize: 100,
+		Client:    http.DefaultClient,
+		Logger:    zap.NewNop(),
+		endpoint:  endpoint,
+		key:       key,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.queue = make(chan *Event, s.QueueSize)
+	go s.run()
+	return s, nil
+}
+
+// parseDSN parses https://<key>@<host>[/<path>]/<project> into store endpoint and key
+func parseDSN(dsn string) (endpoint, key string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return
+	}
+	var idx = strings.LastIndex(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || u.Host == "" || idx < 0 || u.Path[idx+1:] == "" {
+		err = errors.New("sentry: invalid dsn")
+		return
+	}
+	key = u.User.Username()
+	endpoint = fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, u.Path[:idx], u.Path[idx+1:])
+	return
+}
+
+// ReportError enqueues error as Sentry event, dropping the event if queue is full
+func (s *Sentry) ReportError(_ context.Context, err error, p imagorpath.Params) {
+	select {
+	case s.queue <- s.newEvent(err, p):
+	default:
+		s.Logger.Warn("sentry-dropped", zap.Error(err))
+	}
+}
+
+// Close stops the background sender
+func (s *Sentry) Close() {
+	close(s.queue)
+}
+
+func (s *Sentry) newEvent(err error, p imagorpath.Params) *Event {
+	var cause = err
+	var se *imagor.StageError
+	if errors.As(err, &se) {
+		cause = se.Err
+	}
+	var id = make([]byte, 16)
+	_, _ = rand.Read(id)
+	hostname, _ := os.Hostname()
+	return &Event{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   time.Now().UTC(),
+		Level:       "error",
+		Platform:    "go",
+		Logger:      "imagor",
+		Release:     "imagor@" + imagor.Version,
+		Environment: s.Environment,
+		ServerName:  hostname,
+		Message:     err.Error(),
+		Exception: Exceptions{Values: []Exception{{
+			Type:  fmt.Sprintf("%T", cause),
+			Value: cause.Error(),
+		}}},
+		Tags: map[string]string{
+			"stage": string(imagor.ErrorStage(err)),
+		},
+		Extra: map[string]string{
+			"path":  p.Path,
+			"image": p.Image,
+		},
+	}
+}
+
+func (s *Sentry) run() {
+	for event := range s.queue {
+		if err := s.send(event); err != nil {
+			s.Logger.Warn("sentry", zap.Error(err))
+		}
+	}
+}
+
+func (s *Sentry) send(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf(
+		"Sentry sentry_version=7, sentry_client=imagor/%s, sentry_key=%s", imagor.Version, s.key))
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
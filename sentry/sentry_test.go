@@ -0,0 +1,73 @@
+package sentry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSentry(t *testing.T) {
+	type received struct {
+		Path  string
+		Auth  string
+		Event Event
+	}
+	ch := make(chan received, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		ch <- received{r.URL.Path, r.Header.Get("X-Sentry-Auth"), event}
+	}))
+	defer ts.Close()
+
+	dsn := "http://pubkey@" + ts.Listener.Addr().String() + "/prefix/42"
+	s, err := New(dsn, WithEnvironment("test"), WithTimeout(time.Second), WithQueueSize(10))
+	require.NoError(t, err)
+	defer s.Close()
+
+	s.ReportError(context.Background(),
+		imagor.NewError("boom", 500), imagorpath.Params{Path: "100x100/foo.jpg", Image: "foo.jpg"})
+	select {
+	case res := <-ch:
+		assert.Equal(t, "/prefix/api/42/store/", res.Path)
+		assert.Contains(t, res.Auth, "sentry_key=pubkey")
+		assert.Len(t, res.Event.EventID, 32)
+		assert.Equal(t, "error", res.Event.Level)
+		assert.Equal(t, "test", res.Event.Environment)
+		assert.Equal(t, "imagor@"+imagor.Version, res.Event.Release)
+		assert.Equal(t, "imagor: 500 boom", res.Event.Message)
+		assert.Equal(t, []Exception{{Type: "imagor.Error", Value: "imagor: 500 boom"}}, res.Event.Exception.Values)
+		assert.Equal(t, "100x100/foo.jpg", res.Event.Extra["path"])
+		assert.Equal(t, "foo.jpg", res.Event.Extra["image"])
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+}
+
+func TestStageError(t *testing.T) {
+	s := &Sentry{}
+	event := s.newEvent(&imagor.StageError{Stage: imagor.StageSave, Err: errors.New("disk full")}, imagorpath.Params{})
+	assert.Equal(t, "save: disk full", event.Message)
+	assert.Equal(t, []Exception{{Type: "*errors.errorString", Value: "disk full"}}, event.Exception.Values)
+	assert.Equal(t, "save", event.Tags["stage"])
+}
+
+func TestParseDSN(t *testing.T) {
+	endpoint, key, err := parseDSN("https://abc@o1.ingest.sentry.io/123")
+	require.NoError(t, err)
+	assert.Equal(t, "https://o1.ingest.sentry.io/api/123/store/", endpoint)
+	assert.Equal(t, "abc", key)
+
+	for _, dsn := range []string{"", "https://o1.ingest.sentry.io/123", "https://abc@o1.ingest.sentry.io/", "abc"} {
+		_, err = New(dsn)
+		assert.Error(t, err, dsn)
+	}
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"crypto/subtle"
+	"expvar"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+)
+
+// newAdminHandler creates pprof and expvar debug handler,
+// requires bearer token if secret is set
+func newAdminHandler(secret string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	if secret == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+			writeError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// startAdmin serves admin debug handler on separated address if set
+func (s *Server) startAdmin() *http.Server {
+	if s.AdminAddr == "" {
+		return nil
+	}
+	admin := &http.Server{
+		Addr:     s.AdminAddr,
+		Handler:  newAdminHandler(s.AdminSecret),
+		ErrorLog: s.ErrorLog,
+	}
+	go func() {
+		if err := admin.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			s.Logger.Error("admin-listen", zap.Error(err))
+		}
+	}()
+	s.Logger.Info("admin-listen", zap.String("addr", s.AdminAddr))
+	return admin
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"net"
+)
+
+// GRPCServer gRPC server served alongside HTTP, satisfied by *grpc.Server
+type GRPCServer interface {
+	Serve(ln net.Listener) error
+	GracefulStop()
+	Stop()
+}
+
+// startGRPC serves gRPC server on separated address if set
+func (s *Server) startGRPC() {
+	if s.GRPCAddr == "" || s.GRPC == nil {
+		return
+	}
+	ln, err := net.Listen("tcp", s.GRPCAddr)
+	if err != nil {
+		s.Logger.Fatal("grpc-listen", zap.Error(err))
+	}
+	go func() {
+		if err := s.GRPC.Serve(ln); err != nil {
+			s.Logger.Error("grpc-listen", zap.Error(err))
+		}
+	}()
+	s.Logger.Info("grpc-listen", zap.String("addr", ln.Addr().String()))
+}
+
+// stopGRPC gracefully stops gRPC server, forcibly stops on context done
+func (s *Server) stopGRPC(ctx context.Context) {
+	if s.GRPCAddr == "" || s.GRPC == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		s.GRPC.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.GRPC.Stop()
+		s.Logger.Error("grpc-shutdown", zap.Error(ctx.Err()))
+	}
+}
@@ -1,8 +1,10 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/cshum/imagor"
 	"go.uber.org/zap"
 	"log"
 	"net/http"
@@ -11,9 +13,15 @@ import (
 	"time"
 )
 
-type errResp struct {
-	Message string `json:"message,omitempty"`
-	Code    int    `json:"status,omitempty"`
+// writeError writes JSON error envelope of imagor Error from status code and message
+func writeError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	buf, _ := json.Marshal(imagor.NewError(message, code))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(buf)
+	}
 }
 
 func handleOk(w http.ResponseWriter, r *http.Request) {
@@ -21,6 +29,19 @@ func handleOk(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if checker, ok := s.App.(HealthChecker); ok {
+		ctx, cancel := context.WithTimeout(r.Context(), s.HealthTimeout)
+		defer cancel()
+		if err := checker.Health(ctx); err != nil {
+			s.Logger.Warn("health", zap.Error(err))
+			writeError(w, r, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+	}
+	handleOk(w, r)
+}
+
 func (s *Server) panicHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
@@ -30,11 +51,7 @@ func (s *Server) panicHandler(next http.Handler) http.Handler {
 					err = fmt.Errorf("%v", rvr)
 				}
 				s.Logger.Error("panic", zap.Error(err))
-				w.WriteHeader(http.StatusInternalServerError)
-				writeJSON(w, r, errResp{
-					Message: err.Error(),
-					Code:    http.StatusInternalServerError,
-				})
+				writeError(w, r, http.StatusInternalServerError, err.Error())
 			}
 		}()
 		next.ServeHTTP(w, r)
@@ -71,16 +88,6 @@ func stripQueryStringHandler(next http.Handler) http.Handler {
 	})
 }
 
-func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
-	buf, _ := json.Marshal(v)
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Content-Length", strconv.Itoa(len(buf)))
-	if r.Method != http.MethodHead {
-		_, _ = w.Write(buf)
-	}
-	return
-}
-
 type statusRecorder struct {
 	http.ResponseWriter
 	Status int
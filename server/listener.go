@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFdsStart first file descriptor passed by systemd socket activation
+const listenFdsStart = 3
+
+// listen creates listener from systemd socket, unix socket or tcp address
+func (s *Server) listen() (net.Listener, error) {
+	if s.SystemdSocket {
+		return systemdListener()
+	}
+	if s.Socket != "" {
+		return s.unixListener()
+	}
+	return net.Listen("tcp", s.Addr)
+}
+
+func (s *Server) unixListener() (net.Listener, error) {
+	// remove stale socket file from previous run
+	if fi, err := os.Lstat(s.Socket); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("socket: %s exists and is not a socket", s.Socket)
+		}
+		if err := os.Remove(s.Socket); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("unix", s.Socket)
+	if err != nil {
+		return nil, err
+	}
+	if s.SocketMode != 0 {
+		if err := os.Chmod(s.Socket, s.SocketMode); err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+	}
+	if s.SocketUID >= 0 || s.SocketGID >= 0 {
+		if err := os.Chown(s.Socket, s.SocketUID, s.SocketGID); err != nil {
+			_ = ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// systemdListener inherits the first socket passed by systemd LISTEN_FDS
+func systemdListener() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("systemd: LISTEN_PID not matching process")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, errors.New("systemd: LISTEN_FDS not set")
+	}
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+	f := os.NewFile(listenFdsStart, "LISTEN_FD_"+strconv.Itoa(listenFdsStart))
+	defer f.Close()
+	return net.FileListener(f)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/rs/cors"
 	"go.uber.org/zap"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -27,6 +28,62 @@ func WithPort(port int) Option {
 	}
 }
 
+// WithSocket listen on unix socket path instead of tcp address, with optional file mode
+func WithSocket(path string, mode os.FileMode) Option {
+	return func(s *Server) {
+		s.Socket = path
+		s.SocketMode = mode
+	}
+}
+
+// WithSocketOwner changes owner of the unix socket, -1 leaves the id unchanged
+func WithSocketOwner(uid, gid int) Option {
+	return func(s *Server) {
+		s.SocketUID = uid
+		s.SocketGID = gid
+	}
+}
+
+// WithSystemdSocket listen on socket inherited by systemd socket activation
+func WithSystemdSocket(enabled bool) Option {
+	return func(s *Server) {
+		s.SystemdSocket = enabled
+	}
+}
+
+// WithTLS serves HTTPS with HTTP/2 using certificate and key files
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.CertFile = certFile
+		s.KeyFile = keyFile
+	}
+}
+
+// WithH2C enable cleartext HTTP/2 (h2c) alongside HTTP/1.1
+func WithH2C(enabled bool) Option {
+	return func(s *Server) {
+		s.H2C = enabled
+	}
+}
+
+// WithAdmin serves pprof and expvar debug endpoints on separated admin address,
+// requires bearer token if secret is set
+func WithAdmin(addr, secret string) Option {
+	return func(s *Server) {
+		s.AdminAddr = addr
+		s.AdminSecret = secret
+	}
+}
+
+// WithGRPC serves gRPC server on separated address alongside HTTP,
+// gracefully stopped before app shutdown
+func WithGRPC(addr string, srv GRPCServer) Option {
+	return func(s *Server) {
+		s.GRPCAddr = addr
+		s.GRPC = srv
+	}
+}
+
 func WithLogger(logger *zap.Logger) Option {
 	return func(s *Server) {
 		if logger != nil {
@@ -57,6 +114,13 @@ func WithCORS(enabled bool) Option {
 	}
 }
 
+// WithCORSOptions enable CORS with custom cors.Options
+func WithCORSOptions(options cors.Options) Option {
+	return func(s *Server) {
+		s.Handler = cors.New(options).Handler(s.Handler)
+	}
+}
+
 func WithDebug(debug bool) Option {
 	return func(s *Server) {
 		s.Debug = debug
@@ -79,6 +143,14 @@ func WithShutdownTimeout(timeout time.Duration) Option {
 	}
 }
 
+func WithHealthTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		if timeout > 0 {
+			s.HealthTimeout = timeout
+		}
+	}
+}
+
 func WithStripQueryString(enabled bool) Option {
 	return func(s *Server) {
 		if enabled {
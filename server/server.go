@@ -3,7 +3,11 @@ package server
 import (
 	"context"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
 	"net/http"
+	"os"
 	"os/signal"
 	"strconv"
 	"syscall"
@@ -21,6 +25,11 @@ type Service interface {
 	Shutdown(ctx context.Context) error
 }
 
+// HealthChecker optional Service interface for readiness check
+type HealthChecker interface {
+	Health(ctx context.Context) error
+}
+
 // Server wraps the Service with additional http and app lifecycle handling
 type Server struct {
 	http.Server
@@ -29,9 +38,20 @@ type Server struct {
 	Port            int
 	CertFile        string
 	KeyFile         string
+	Socket          string
+	SocketMode      os.FileMode
+	SocketUID       int
+	SocketGID       int
+	SystemdSocket   bool
+	H2C             bool
+	AdminAddr       string
+	AdminSecret     string
+	GRPCAddr        string
+	GRPC            GRPCServer
 	PathPrefix      string
 	StartupTimeout  time.Duration
 	ShutdownTimeout time.Duration
+	HealthTimeout   time.Duration
 	Logger          *zap.Logger
 	Debug           bool
 }
@@ -41,13 +61,18 @@ func New(app Service, options ...Option) *Server {
 	s := &Server{}
 	s.App = app
 	s.Port = 8000
+	s.SocketUID = -1
+	s.SocketGID = -1
 	s.MaxHeaderBytes = 1 << 20
 	s.StartupTimeout = time.Second * 10
 	s.ShutdownTimeout = time.Second * 10
+	s.HealthTimeout = time.Second * 5
 	s.Logger = zap.NewNop()
 	s.Handler = pathHandler(http.MethodGet, map[string]http.HandlerFunc{
 		"/favicon.ico": handleOk,
 		"/healthcheck": handleOk,
+		"/livez":       handleOk,
+		"/readyz":      s.handleReady,
 	})(s.App)
 
 	for _, option := range options {
@@ -57,6 +82,10 @@ func New(app Service, options ...Option) *Server {
 		s.Handler = http.StripPrefix(s.PathPrefix, s.Handler)
 	}
 	s.Handler = s.panicHandler(s.Handler)
+	if s.H2C {
+		// serve cleartext HTTP/2 by prior knowledge or upgrade, HTTP/2 over TLS is enabled by default
+		s.Handler = h2c.NewHandler(s.Handler, &http2.Server{})
+	}
 	if s.Addr == "" {
 		s.Addr = s.Address + ":" + strconv.Itoa(s.Port)
 	}
@@ -74,14 +103,23 @@ func (s *Server) Run() {
 func (s *Server) RunContext(ctx context.Context) {
 	s.startup(ctx)
 
+	ln, err := s.listen()
+	if err != nil {
+		s.Logger.Fatal("listen", zap.Error(err))
+	}
 	go func() {
-		if err := s.listenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.serve(ln); err != nil && err != http.ErrServerClosed {
 			s.Logger.Fatal("listen", zap.Error(err))
 		}
 	}()
-	s.Logger.Info("listen", zap.String("addr", s.Addr))
+	s.Logger.Info("listen", zap.String("addr", ln.Addr().String()))
+	admin := s.startAdmin()
+	s.startGRPC()
 	<-ctx.Done()
 
+	if admin != nil {
+		_ = admin.Close()
+	}
 	s.shutdown(context.Background())
 }
 
@@ -100,14 +138,15 @@ func (s *Server) shutdown(ctx context.Context) {
 	if err := s.Shutdown(ctx); err != nil {
 		s.Logger.Error("server-shutdown", zap.Error(err))
 	}
+	s.stopGRPC(ctx)
 	if err := s.App.Shutdown(ctx); err != nil {
 		s.Logger.Error("app-shutdown", zap.Error(err))
 	}
 }
 
-func (s *Server) listenAndServe() error {
+func (s *Server) serve(ln net.Listener) error {
 	if s.CertFile != "" && s.KeyFile != "" {
-		return s.ListenAndServeTLS(s.CertFile, s.KeyFile)
+		return s.ServeTLS(ln, s.CertFile, s.KeyFile)
 	}
-	return s.ListenAndServe()
+	return s.Serve(ln)
 }
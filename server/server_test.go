@@ -2,15 +2,22 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"github.com/cshum/imagor"
 	"github.com/cshum/imagor/imagorpath"
+	"github.com/rs/cors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/net/http2"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -61,6 +68,51 @@ func TestServer_Run(t *testing.T) {
 	assert.Equal(t, 1, processor.ShutdownCnt)
 }
 
+type fakeGRPCServer struct {
+	served  chan net.Addr
+	block   chan struct{}
+	stopped bool
+	forced  bool
+}
+
+func (f *fakeGRPCServer) Serve(ln net.Listener) error {
+	f.served <- ln.Addr()
+	return nil
+}
+
+func (f *fakeGRPCServer) GracefulStop() {
+	<-f.block
+	f.stopped = true
+}
+
+func (f *fakeGRPCServer) Stop() {
+	f.forced = true
+}
+
+func TestServer_RunGRPC(t *testing.T) {
+	for _, graceful := range []bool{true, false} {
+		ctx, done := context.WithCancel(context.Background())
+		processor := &testProcessor{}
+		grpcServer := &fakeGRPCServer{served: make(chan net.Addr, 1), block: make(chan struct{})}
+		if graceful {
+			close(grpcServer.block)
+		}
+		s := New(imagor.New(imagor.WithProcessors(processor)),
+			WithAddr(":0"),
+			WithGRPC("localhost:0", grpcServer),
+			WithShutdownTimeout(time.Millisecond*10))
+		go func() {
+			addr := <-grpcServer.served
+			assert.NotEqual(t, "localhost:0", addr.String())
+			done()
+		}()
+		s.RunContext(ctx)
+		assert.Equal(t, graceful, grpcServer.stopped)
+		assert.Equal(t, !graceful, grpcServer.forced)
+		assert.Equal(t, 1, processor.ShutdownCnt)
+	}
+}
+
 func TestServer(t *testing.T) {
 	s := New(
 		imagor.New(
@@ -109,7 +161,7 @@ func TestServer(t *testing.T) {
 	assert.Equal(t, 500, w.Code)
 	assert.NotEmpty(t, w.Header().Get("Vary"))
 	assert.Equal(t, "Bar", w.Header().Get("X-Foo"))
-	assert.Equal(t, `{"message":"booooom","status":500}`, w.Body.String())
+	assert.Equal(t, `{"status":500,"code":"INTERNAL_SERVER_ERROR","message":"booooom"}`, w.Body.String())
 }
 
 func TestServerErrorLog(t *testing.T) {
@@ -152,7 +204,7 @@ func TestServerErrorLog(t *testing.T) {
 	assert.Equal(t, "Bar", w.Header.Get("X-Foo"))
 	resp, err := io.ReadAll(w.Body)
 	assert.NoError(t, err)
-	assert.Equal(t, `{"message":"booooom","status":500}`, string(resp))
+	assert.Equal(t, `{"status":500,"code":"INTERNAL_SERVER_ERROR","message":"booooom"}`, string(resp))
 
 	_, err = ts.Config.ErrorLog.Writer().Write([]byte("http: TLS handshake error from 172.16.0.3:42672: EOF"))
 	assert.NoError(t, err)
@@ -203,3 +255,162 @@ func TestWithPathPrefix(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	fmt.Println(w.Body.String())
 }
+
+func TestWithCORSOptions(t *testing.T) {
+	s := New(
+		imagor.New(
+			imagor.WithUnsafe(true),
+			imagor.WithLoaders(loaderFunc(func(r *http.Request, image string) (*imagor.Blob, error) {
+				return imagor.NewBlobFromBytes([]byte("foo")), nil
+			})),
+		),
+		WithCORSOptions(cors.Options{
+			AllowedOrigins: []string{"https://foo.com"},
+			AllowedMethods: []string{http.MethodGet},
+			MaxAge:         3600,
+		}),
+	)
+
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil)
+	r.Header.Set("Origin", "https://foo.com")
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "https://foo.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	r = httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil)
+	r.Header.Set("Origin", "https://bar.com")
+	w = httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	r = httptest.NewRequest(http.MethodOptions, "https://example.com/unsafe/foo.jpg", nil)
+	r.Header.Set("Origin", "https://foo.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	w = httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, r)
+	assert.Equal(t, "https://foo.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+}
+
+type healthLoader struct {
+	Err error
+}
+
+func (l *healthLoader) Get(r *http.Request, image string) (*imagor.Blob, error) {
+	return imagor.NewBlobFromBytes([]byte("foo")), nil
+}
+
+func (l *healthLoader) Health(ctx context.Context) error {
+	return l.Err
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	loader := &healthLoader{}
+	s := New(
+		imagor.New(imagor.WithLoaders(loader)),
+		WithHealthTimeout(time.Second),
+	)
+
+	w := httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/livez", nil))
+	assert.Equal(t, 200, w.Code)
+
+	w = httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/readyz", nil))
+	assert.Equal(t, 200, w.Code)
+
+	loader.Err = fmt.Errorf("bucket unreachable")
+	w = httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/readyz", nil))
+	assert.Equal(t, 503, w.Code)
+	assert.Contains(t, w.Body.String(), "bucket unreachable")
+
+	w = httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/livez", nil))
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestWithSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "imagor.sock")
+	s := New(imagor.New(), WithSocket(socket, 0660), WithSocketOwner(-1, -1))
+	ln, err := s.listen()
+	require.NoError(t, err)
+	fi, err := os.Stat(socket)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0660), fi.Mode().Perm())
+	go func() {
+		_ = s.serve(ln)
+	}()
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://imagor/healthcheck")
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	_ = resp.Body.Close()
+	require.NoError(t, s.Shutdown(context.Background()))
+
+	// non socket file is not removed
+	s = New(imagor.New(), WithSocket(socket, 0))
+	require.NoError(t, os.WriteFile(socket, nil, 0600))
+	_, err = s.listen()
+	assert.Error(t, err)
+
+	s = New(imagor.New(), WithSystemdSocket(true))
+	_, err = s.listen()
+	assert.Error(t, err)
+}
+
+func TestWithH2C(t *testing.T) {
+	s := New(imagor.New(), WithH2C(true), WithTLS("", ""))
+	ts := httptest.NewServer(s.Handler)
+	defer ts.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+	resp, err := client.Get(ts.URL + "/healthcheck")
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+	_ = resp.Body.Close()
+
+	resp, err = http.Get(ts.URL + "/healthcheck")
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, 1, resp.ProtoMajor)
+	_ = resp.Body.Close()
+}
+
+func TestWithAdmin(t *testing.T) {
+	s := New(imagor.New(), WithAdmin("localhost:0", "s3cret"))
+	assert.Equal(t, "localhost:0", s.AdminAddr)
+	h := newAdminHandler(s.AdminSecret)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/debug/vars", nil))
+	assert.Equal(t, 401, w.Code)
+
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/debug/vars", nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "memstats")
+
+	w = httptest.NewRecorder()
+	newAdminHandler("").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/debug/pprof/", nil))
+	assert.Equal(t, 200, w.Code)
+
+	// admin endpoints are not exposed on main handler
+	w = httptest.NewRecorder()
+	s.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/debug/vars", nil))
+	assert.NotEqual(t, 200, w.Code)
+}
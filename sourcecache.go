@@ -0,0 +1,49 @@
+package imagor
+
+import (
+	"context"
+	"go.uber.org/zap"
+)
+
+// sourceCacheKey cache key of loaded source image
+func sourceCacheKey(image string) string {
+	return "source:" + image
+}
+
+// loadSourceCache returns source image blob of SourceCache, nil if not cached
+func (app *Imagor) loadSourceCache(ctx context.Context, image string) *Blob {
+	if app.SourceCache == nil || image == "" {
+		return nil
+	}
+	blob, err := checkBlob(app.SourceCache.Get(ctx, sourceCacheKey(image)))
+	if err != nil || isBlobEmpty(blob) {
+		return nil
+	}
+	if app.Debug {
+		app.Logger.Debug("source-cache-hit", zap.String("image", image))
+	}
+	return blob
+}
+
+// setSourceCache caches loaded source image blob, so that different crops and sizes
+// of hot source images share the same download
+func (app *Imagor) setSourceCache(ctx context.Context, image string, blob *Blob) {
+	if app.SourceCache == nil || image == "" || isBlobEmpty(blob) || blob.Size() > maxMemorySize {
+		return
+	}
+	if err := app.SourceCache.Set(ctx, sourceCacheKey(image), blob, app.SourceCacheTTL); err != nil {
+		if err != ErrMaxSizeExceeded {
+			app.Logger.Warn("source-cache", zap.String("image", image), zap.Error(err))
+		}
+	} else if app.Debug {
+		app.Logger.Debug("source-cached", zap.String("image", image))
+	}
+}
+
+// deleteSourceCache evicts source image from SourceCache
+func (app *Imagor) deleteSourceCache(ctx context.Context, image string) error {
+	if app.SourceCache == nil {
+		return nil
+	}
+	return app.SourceCache.Delete(ctx, sourceCacheKey(image))
+}
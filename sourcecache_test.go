@@ -0,0 +1,57 @@
+package imagor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithSourceCache(t *testing.T) {
+	var loadCnt int64
+	cache := newMapCache()
+	app := New(
+		WithUnsafe(true),
+		WithPurge("purge3"),
+		WithSourceCache(cache, time.Minute),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			atomic.AddInt64(&loadCnt, 1)
+			if image == "missing.jpg" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+	)
+	doGet := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/"+path, nil))
+		return w
+	}
+	for _, path := range []string{
+		"unsafe/100x100/hero.jpg",
+		"unsafe/200x200/smart/hero.jpg",
+		"unsafe/10x10:90x90/hero.jpg",
+	} {
+		w := doGet(path)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, "foo", w.Body.String())
+	}
+	assert.Equal(t, int64(1), atomic.LoadInt64(&loadCnt), "source loaded once")
+	assert.Contains(t, cache.Map, "source:hero.jpg")
+	assert.Equal(t, time.Minute, cache.TTL["source:hero.jpg"])
+
+	assert.Equal(t, 404, doGet("unsafe/missing.jpg").Code)
+	assert.NotContains(t, cache.Map, "source:missing.jpg")
+
+	r := httptest.NewRequest(http.MethodDelete, "https://example.com/purge/100x100/hero.jpg", nil)
+	r.Header.Set("Authorization", "Bearer purge3")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 204, w.Code)
+	assert.NotContains(t, cache.Map, "source:hero.jpg")
+
+	assert.Equal(t, 200, doGet("unsafe/100x100/hero.jpg").Code)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&loadCnt), "reloaded after purge")
+}
@@ -0,0 +1,97 @@
+package imagor
+
+import (
+	"context"
+	"fmt"
+	"github.com/cshum/imagor/imagorpath"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// SrcsetURL signed URL of image resized to width
+type SrcsetURL struct {
+	Width int    `json:"width"`
+	URL   string `json:"url"`
+}
+
+// SrcsetResult signed URLs of image by widths, with srcset attribute value of HTML img
+type SrcsetResult struct {
+	Srcset string      `json:"srcset"`
+	URLs   []SrcsetURL `json:"urls"`
+}
+
+type srcsetRequest struct {
+	Image   string `json:"image"`
+	Params  string `json:"params"`
+	Widths  []int  `json:"widths"`
+	BaseURL string `json:"base_url"`
+}
+
+// Srcset generates signed URLs of image resized to each width, with params as preset name
+// or imagor params e.g. fit-in/filters:format(webp). Height of params is scaled proportionally.
+// URLs are prefixed with baseURL, or PathPrefix if empty
+func (app *Imagor) Srcset(_ context.Context, image, params string, widths []int, baseURL string) (*SrcsetResult, error) {
+	if len(widths) == 0 {
+		widths = app.SrcsetWidths
+	}
+	if image == "" || len(widths) == 0 || len(widths) > maxBatchSize {
+		return nil, ErrInvalid
+	}
+	if preset, ok := app.Presets[params]; ok {
+		params = preset
+	}
+	params = strings.Trim(params, "/")
+	if params != "" {
+		params += "/"
+	}
+	base := imagorpath.Parse("unsafe/" + params + strings.TrimLeft(image, "/"))
+	if base.Image == "" {
+		return nil, ErrInvalid
+	}
+	if baseURL == "" {
+		baseURL = app.PathPrefix
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	var res = &SrcsetResult{URLs: make([]SrcsetURL, 0, len(widths))}
+	var srcset = make([]string, 0, len(widths))
+	for _, width := range widths {
+		if width <= 0 {
+			return nil, ErrInvalid
+		}
+		p := base
+		if p.Width > 0 && p.Height > 0 {
+			p.Height = int(math.Round(float64(p.Height) * float64(width) / float64(p.Width)))
+		}
+		p.Width = width
+		p.Path = ""
+		u := baseURL + imagorpath.Generate(p, app.Signer)
+		res.URLs = append(res.URLs, SrcsetURL{Width: width, URL: u})
+		srcset = append(srcset, fmt.Sprintf("%s %dw", u, width))
+	}
+	res.Srcset = strings.Join(srcset, ", ")
+	return res, nil
+}
+
+// serveSrcset handles POST /srcset of image, params and widths,
+// responds JSON of signed URLs, or srcset attribute value if text/plain accepted
+func (app *Imagor) serveSrcset(w http.ResponseWriter, r *http.Request) {
+	var req srcsetRequest
+	var res *SrcsetResult
+	var err error
+	if !isBearerAuthorized(r, app.SrcsetSecret) {
+		err = ErrUnauthorized
+	} else if err = readJSONBody(r, &req); err == nil {
+		res, err = app.Srcset(r.Context(), req.Image, req.Params, req.Widths, req.BaseURL)
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/plain") {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(res.Srcset))
+		return
+	}
+	writeJSON(w, r, res)
+}
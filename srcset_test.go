@@ -0,0 +1,82 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSrcset(t *testing.T) {
+	signer := imagorpath.NewDefaultSigner("1234")
+	app := New(
+		WithSigner(signer),
+		WithSrcset("srcset3", 320, 640),
+		WithPreset("card", "400x300/smart/filters:format(webp)"),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	res, err := app.Srcset(context.Background(), "foo/bar.jpg", "card", []int{200, 800}, "https://img.example.com/")
+	require.NoError(t, err)
+	path200 := imagorpath.Generate(imagorpath.Params{
+		Image: "foo/bar.jpg", Width: 200, Height: 150, Smart: true,
+		Filters: imagorpath.Filters{{Name: "format", Args: "webp"}},
+	}, signer)
+	path800 := imagorpath.Generate(imagorpath.Params{
+		Image: "foo/bar.jpg", Width: 800, Height: 600, Smart: true,
+		Filters: imagorpath.Filters{{Name: "format", Args: "webp"}},
+	}, signer)
+	assert.Equal(t, []SrcsetURL{
+		{Width: 200, URL: "https://img.example.com/" + path200},
+		{Width: 800, URL: "https://img.example.com/" + path800},
+	}, res.URLs)
+	assert.Equal(t, "https://img.example.com/"+path200+" 200w, https://img.example.com/"+path800+" 800w", res.Srcset)
+
+	res, err = app.Srcset(context.Background(), "foo/bar.jpg", "fit-in", nil, "")
+	require.NoError(t, err)
+	require.Len(t, res.URLs, 2)
+	assert.Equal(t, "/"+imagorpath.Generate(imagorpath.Params{Image: "foo/bar.jpg", FitIn: true, Width: 320}, signer), res.URLs[0].URL)
+
+	_, err = app.Srcset(context.Background(), "foo/bar.jpg", "", []int{0}, "")
+	assert.Equal(t, ErrInvalid, err)
+
+	doPost := func(token, accept, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "https://example.com/srcset", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		r.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	w := doPost("wrong", "", `{"image":"foo/bar.jpg"}`)
+	assert.Equal(t, 401, w.Code)
+
+	w = doPost("srcset3", "", `{"params":"card"}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, jsonStr(ErrInvalid), w.Body.String())
+
+	w = doPost("srcset3", "", `{"image":"foo/bar.jpg","params":"card","widths":[200,800],"base_url":"https://img.example.com"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, jsonStr(SrcsetResult{
+		Srcset: "https://img.example.com/" + path200 + " 200w, https://img.example.com/" + path800 + " 800w",
+		URLs: []SrcsetURL{
+			{Width: 200, URL: "https://img.example.com/" + path200},
+			{Width: 800, URL: "https://img.example.com/" + path800},
+		},
+	}), w.Body.String())
+
+	w = doPost("srcset3", "text/plain", `{"image":"foo/bar.jpg","params":"card","widths":[200],"base_url":"https://img.example.com"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, "https://img.example.com/"+path200+" 200w", w.Body.String())
+
+	// signed URL served by imagor
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/"+res.URLs[1].URL, nil))
+	assert.Equal(t, 200, w.Code)
+}
@@ -0,0 +1,191 @@
+package imagor
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// stats runtime counters of imagor requests served, for GET /stats endpoint
+type stats struct {
+	started   time.Time
+	inFlight  int64
+	requests  int64
+	responses [6]int64 // by status class, index 0 for unknown
+	cacheHits int64
+	storeHits int64
+	misses    int64
+	processed int64
+	bytes     int64
+}
+
+// statsResult JSON response of GET /stats endpoint
+type statsResult struct {
+	Version    string                     `json:"version"`
+	Uptime     float64                    `json:"uptime_seconds"`
+	Requests   statsRequests              `json:"requests"`
+	Cache      statsCache                 `json:"cache"`
+	Processed  int64                      `json:"processed"`
+	Runtime    statsRuntime               `json:"runtime"`
+	Components map[string]json.RawMessage `json:"components,omitempty"`
+	Health     map[string]json.RawMessage `json:"health,omitempty"`
+}
+
+type statsRequests struct {
+	Total    int64            `json:"total"`
+	InFlight int64            `json:"in_flight"`
+	Status   map[string]int64 `json:"status"`
+	Bytes    int64            `json:"bytes"`
+}
+
+type statsCache struct {
+	ResultCacheHits   int64   `json:"result_cache_hits"`
+	ResultStorageHits int64   `json:"result_storage_hits"`
+	Misses            int64   `json:"misses"`
+	HitRatio          float64 `json:"hit_ratio"`
+}
+
+type statsRuntime struct {
+	Goroutines int    `json:"goroutines"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	HeapAlloc  uint64 `json:"heap_alloc"`
+	HeapInuse  uint64 `json:"heap_inuse"`
+	Sys        uint64 `json:"sys"`
+	NumGC      uint32 `json:"num_gc"`
+	PauseTotal uint64 `json:"gc_pause_total_ns"`
+}
+
+func newStats() *stats {
+	return &stats{started: time.Now()}
+}
+
+// statsWriter records status and bytes of response written
+type statsWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *statsWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statsWriter) Write(buf []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(buf)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// serve records request counters of the handler serving request
+func (s *stats) serve(w http.ResponseWriter, r *http.Request, next func(w http.ResponseWriter, r *http.Request)) {
+	atomic.AddInt64(&s.requests, 1)
+	atomic.AddInt64(&s.inFlight, 1)
+	defer atomic.AddInt64(&s.inFlight, -1)
+	sw := &statsWriter{ResponseWriter: w}
+	next(sw, r)
+	var class int
+	if sw.status >= 100 && sw.status < 600 {
+		class = sw.status / 100
+	}
+	atomic.AddInt64(&s.responses[class], 1)
+	atomic.AddInt64(&s.bytes, sw.bytes)
+}
+
+func (s *stats) cacheHit() {
+	if s != nil {
+		atomic.AddInt64(&s.cacheHits, 1)
+	}
+}
+
+func (s *stats) storageHit() {
+	if s != nil {
+		atomic.AddInt64(&s.storeHits, 1)
+	}
+}
+
+func (s *stats) miss() {
+	if s != nil {
+		atomic.AddInt64(&s.misses, 1)
+	}
+}
+
+func (s *stats) process() {
+	if s != nil {
+		atomic.AddInt64(&s.processed, 1)
+	}
+}
+
+// result returns snapshot of counters, with runtime memory stats and
+// instrumented components and health expvar if exists
+func (s *stats) result() statsResult {
+	var res = statsResult{
+		Version: Version,
+		Uptime:  time.Since(s.started).Seconds(),
+		Requests: statsRequests{
+			Total:    atomic.LoadInt64(&s.requests),
+			InFlight: atomic.LoadInt64(&s.inFlight),
+			Status:   map[string]int64{},
+			Bytes:    atomic.LoadInt64(&s.bytes),
+		},
+		Cache: statsCache{
+			ResultCacheHits:   atomic.LoadInt64(&s.cacheHits),
+			ResultStorageHits: atomic.LoadInt64(&s.storeHits),
+			Misses:            atomic.LoadInt64(&s.misses),
+		},
+		Processed: atomic.LoadInt64(&s.processed),
+	}
+	for class := 1; class < len(s.responses); class++ {
+		if n := atomic.LoadInt64(&s.responses[class]); n > 0 {
+			res.Requests.Status[string(rune('0'+class))+"xx"] = n
+		}
+	}
+	if hits := res.Cache.ResultCacheHits + res.Cache.ResultStorageHits; hits > 0 {
+		res.Cache.HitRatio = float64(hits) / float64(hits+res.Cache.Misses)
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	res.Runtime = statsRuntime{
+		Goroutines: runtime.NumGoroutine(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		HeapAlloc:  m.HeapAlloc,
+		HeapInuse:  m.HeapInuse,
+		Sys:        m.Sys,
+		NumGC:      m.NumGC,
+		PauseTotal: m.PauseTotalNs,
+	}
+	res.Components = expvarMap("imagor_instrumented")
+	res.Health = expvarMap("imagor_health")
+	return res
+}
+
+// expvarMap returns entries of expvar map by name, nil if not exists or empty
+func expvarMap(name string) (res map[string]json.RawMessage) {
+	if m, ok := expvar.Get(name).(*expvar.Map); ok {
+		m.Do(func(kv expvar.KeyValue) {
+			if res == nil {
+				res = map[string]json.RawMessage{}
+			}
+			res[kv.Key] = json.RawMessage(kv.Value.String())
+		})
+	}
+	return
+}
+
+// serveStats handles GET /stats of runtime statistics authorized by bearer token of stats secret
+func (app *Imagor) serveStats(w http.ResponseWriter, r *http.Request) {
+	if !isBearerAuthorized(r, app.StatsSecret) {
+		writeError(w, r, ErrUnauthorized)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	writeJSONIndent(w, r, app.stats.result())
+}
@@ -0,0 +1,61 @@
+package imagor
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithStats(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithStats("stats3"),
+		WithCache(newMapCache()),
+		WithResultCacheTTL(time.Minute),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "missing.jpg" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+	)
+	doGet := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/"+path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		app.ServeHTTP(w, r)
+		return w
+	}
+	for _, path := range []string{"unsafe/foo.jpg", "unsafe/foo.jpg", "unsafe/bar.jpg", "unsafe/missing.jpg"} {
+		doGet(path, "")
+	}
+	w := doGet("stats", "wrong")
+	assert.Equal(t, 401, w.Code)
+
+	w = doGet("stats", "stats3")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var res statsResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, Version, res.Version)
+	assert.Greater(t, res.Uptime, float64(0))
+	assert.Equal(t, int64(6), res.Requests.Total)
+	assert.Equal(t, int64(1), res.Requests.InFlight, "stats request itself")
+	assert.Equal(t, map[string]int64{"2xx": 3, "4xx": 2}, res.Requests.Status)
+	assert.Equal(t, int64(1), res.Cache.ResultCacheHits)
+	assert.Equal(t, int64(3), res.Cache.Misses)
+	assert.Equal(t, 0.25, res.Cache.HitRatio)
+	assert.Equal(t, int64(2), res.Processed)
+	assert.Greater(t, res.Runtime.Goroutines, 0)
+	assert.Greater(t, res.Runtime.HeapAlloc, uint64(0))
+
+	w = httptest.NewRecorder()
+	New(WithUnsafe(true)).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/stats", nil))
+	assert.NotEqual(t, 200, w.Code, "stats disabled")
+}
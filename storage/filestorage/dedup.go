@@ -0,0 +1,176 @@
+package filestorage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/cshum/imagor/bufpool"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// dedupDir directory under BaseDir of content files by hash,
+// excluded from image keys by the dot file blacklist
+const dedupDir = ".dedup"
+
+// savedDir directory under dedupDir of saved time marker files by image path hash.
+// Image files hard linked to the same content share its modified time,
+// so the saved time of each image file is kept by its own marker file
+const savedDir = "saved"
+
+var dedupSeq uint64
+
+// hashPath returns path of content file by content hash
+func (s *FileStorage) hashPath(hash string) string {
+	return filepath.Join(s.BaseDir, dedupDir, hash[:2], hash)
+}
+
+// savedPath returns path of saved time marker file of image file
+func (s *FileStorage) savedPath(image string) string {
+	sum := sha256.Sum256([]byte(image))
+	hash := hex.EncodeToString(sum[:])
+	return filepath.Join(s.BaseDir, dedupDir, savedDir, hash[:2], hash)
+}
+
+// touchSaved creates or refreshes saved time marker file of image file
+func (s *FileStorage) touchSaved(image string) error {
+	marker := s.savedPath(image)
+	if err := os.MkdirAll(filepath.Dir(marker), s.MkdirPermission); err != nil {
+		return err
+	}
+	if err := os.WriteFile(marker, nil, s.WritePermission); err != nil {
+		return err
+	}
+	now := time.Now()
+	return os.Chtimes(marker, now, now)
+}
+
+// modTime returns saved time of image file by its marker file if deduplicated,
+// or modified time of the file
+func (s *FileStorage) modTime(image string, info os.FileInfo) time.Time {
+	if s.Dedup {
+		if marker, err := os.Stat(s.savedPath(image)); err == nil {
+			return marker.ModTime()
+		}
+	}
+	return info.ModTime()
+}
+
+// putDedup saves content once by hash under dedupDir, with image file hard linked to it.
+// Link count of content file is the reference count of image files sharing it
+func (s *FileStorage) putDedup(image string, reader io.Reader) (err error) {
+	hash, tmp, err := s.writeHashed(reader)
+	if err != nil {
+		return
+	}
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+	s.dedupMu.Lock()
+	defer s.dedupMu.Unlock()
+	content := s.hashPath(hash)
+	if err = os.MkdirAll(filepath.Dir(content), s.MkdirPermission); err != nil {
+		return
+	}
+	contentInfo, err := os.Stat(content)
+	if os.IsNotExist(err) {
+		if err = os.Rename(tmp, content); err != nil {
+			return
+		}
+	} else if err != nil {
+		return
+	} else if info, e := os.Stat(image); e == nil && os.SameFile(info, contentInfo) {
+		if s.SaveErrIfExists {
+			return &os.LinkError{Op: "link", Old: content, New: image, Err: os.ErrExist}
+		}
+		return s.touchSaved(image)
+	}
+	if !s.SaveErrIfExists {
+		if err = s.release(image); err != nil && !os.IsNotExist(err) {
+			return
+		}
+	}
+	if err = os.Link(content, image); err != nil {
+		return
+	}
+	return s.touchSaved(image)
+}
+
+// writeHashed writes reader to temp file under dedupDir, returns content hash and temp file path
+func (s *FileStorage) writeHashed(reader io.Reader) (hash, tmp string, err error) {
+	dir := filepath.Join(s.BaseDir, dedupDir)
+	if err = os.MkdirAll(dir, s.MkdirPermission); err != nil {
+		return
+	}
+	tmp = filepath.Join(dir, fmt.Sprintf(".tmp-%d-%d", os.Getpid(), atomic.AddUint64(&dedupSeq, 1)))
+	w, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_EXCL, s.WritePermission)
+	if err != nil {
+		return
+	}
+	h := sha256.New()
+	if _, err = bufpool.Copy(io.MultiWriter(w, h), reader); err != nil {
+		_ = w.Close()
+		_ = os.Remove(tmp)
+		return
+	}
+	if err = w.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return
+	}
+	return hex.EncodeToString(h.Sum(nil)), tmp, nil
+}
+
+// remove removes image file, releasing its content file if no longer referenced
+func (s *FileStorage) remove(image string) error {
+	if !s.Dedup {
+		return os.Remove(image)
+	}
+	s.dedupMu.Lock()
+	defer s.dedupMu.Unlock()
+	return s.release(image)
+}
+
+// release removes image file, and its content file under dedupDir
+// if the image file is the last reference of it
+func (s *FileStorage) release(image string) error {
+	info, err := os.Stat(image)
+	if err != nil {
+		return err
+	}
+	var content string
+	if linkCount(info) == 2 {
+		if hash, err := hashFile(image); err == nil {
+			if contentInfo, err := os.Stat(s.hashPath(hash)); err == nil && os.SameFile(info, contentInfo) {
+				content = s.hashPath(hash)
+			}
+		}
+	}
+	if err = os.Remove(image); err != nil {
+		return err
+	}
+	if err = os.Remove(s.savedPath(image)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if content != "" {
+		return os.Remove(content)
+	}
+	return nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -2,30 +2,43 @@ package filestorage
 
 import (
 	"context"
+	"fmt"
 	"github.com/cshum/imagor"
+	"github.com/cshum/imagor/bufpool"
 	"github.com/cshum/imagor/imagorpath"
-	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
 var dotFileRegex = regexp.MustCompile("/\\.")
 
 type FileStorage struct {
-	BaseDir         string
-	PathPrefix      string
-	Blacklists      []*regexp.Regexp
-	MkdirPermission os.FileMode
-	WritePermission os.FileMode
-	SaveErrIfExists bool
-	SafeChars       string
-	Expiration      time.Duration
+	BaseDir           string
+	PathPrefix        string
+	Blacklists        []*regexp.Regexp
+	AllowedExtensions []string
+	RejectUnsafeChars bool
+	MaxPathLength     int
+	DenySymlinkEscape bool
+	MkdirPermission   os.FileMode
+	WritePermission   os.FileMode
+	SaveErrIfExists   bool
+	SafeChars         string
+	Expiration        time.Duration
+	Fsync             bool
+	FsyncDir          bool
+	AsyncFsync        bool
+	WatchInterval     time.Duration
+	Dedup             bool
 
 	safeChars imagorpath.SafeChars
+	syncing   sync.WaitGroup
+	dedupMu   sync.Mutex
 }
 
 func New(baseDir string, options ...Option) *FileStorage {
@@ -44,16 +57,71 @@ func New(baseDir string, options ...Option) *FileStorage {
 }
 
 func (s *FileStorage) Path(image string) (string, bool) {
+	if s.RejectUnsafeChars && hasUnsafeChars(image) {
+		return "", false
+	}
 	image = "/" + imagorpath.Normalize(image, s.safeChars)
+	if s.MaxPathLength > 0 && len(image) > s.MaxPathLength {
+		return "", false
+	}
 	for _, blacklist := range s.Blacklists {
 		if blacklist.MatchString(image) {
 			return "", false
 		}
 	}
+	if len(s.AllowedExtensions) > 0 && !isAllowedExtension(image, s.AllowedExtensions) {
+		return "", false
+	}
 	if !strings.HasPrefix(image, s.PathPrefix) {
 		return "", false
 	}
-	return filepath.Join(s.BaseDir, strings.TrimPrefix(image, s.PathPrefix)), true
+	image = filepath.Join(s.BaseDir, strings.TrimPrefix(image, s.PathPrefix))
+	if s.DenySymlinkEscape && !resolvesWithin(s.BaseDir, image) {
+		return "", false
+	}
+	return image, true
+}
+
+// resolvesWithin checks if path with symlinks evaluated stays within base dir
+func resolvesWithin(base, path string) bool {
+	base, err := evalExistingSymlinks(base)
+	if err != nil {
+		return false
+	}
+	if path, err = evalExistingSymlinks(path); err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(base, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// evalExistingSymlinks returns absolute path with symlinks evaluated,
+// for path not yet exists e.g. on save, symlinks of the nearest existing parent are evaluated.
+// Dangling symlink is an error as its target may be created outside
+func evalExistingSymlinks(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	var rest []string
+	for {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		if _, err := os.Lstat(path); err == nil {
+			return "", fmt.Errorf("dangling symlink %s", path)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path, nil
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
 }
 
 func (s *FileStorage) Get(_ *http.Request, image string) (*imagor.Blob, error) {
@@ -62,13 +130,21 @@ func (s *FileStorage) Get(_ *http.Request, image string) (*imagor.Blob, error) {
 		return nil, imagor.ErrInvalid
 	}
 	return imagor.NewBlobFromFile(image, func(stat os.FileInfo) error {
-		if s.Expiration > 0 && time.Now().Sub(stat.ModTime()) > s.Expiration {
-			return imagor.ErrExpired
-		}
-		return nil
+		return s.checkFileInfo(image, stat)
 	}), nil
 }
 
+// checkFileInfo checks if file is not a directory and not expired
+func (s *FileStorage) checkFileInfo(image string, stat os.FileInfo) error {
+	if stat.IsDir() {
+		return imagor.ErrNotFound
+	}
+	if s.Expiration > 0 && time.Now().Sub(s.modTime(image, stat)) > s.Expiration {
+		return imagor.ErrExpired
+	}
+	return nil
+}
+
 func (s *FileStorage) Put(_ context.Context, image string, blob *imagor.Blob) (err error) {
 	image, ok := s.Path(image)
 	if !ok {
@@ -84,6 +160,20 @@ func (s *FileStorage) Put(_ context.Context, image string, blob *imagor.Blob) (e
 	defer func() {
 		_ = reader.Close()
 	}()
+	if s.Dedup {
+		if err = s.putDedup(image, reader); err != nil {
+			return
+		}
+		if s.AsyncFsync && (s.Fsync || s.FsyncDir) {
+			s.syncing.Add(1)
+			go func() {
+				defer s.syncing.Done()
+				_ = s.sync(image, nil)
+			}()
+			return
+		}
+		return s.sync(image, nil)
+	}
 	flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
 	if s.SaveErrIfExists {
 		flag = os.O_RDWR | os.O_CREATE | os.O_EXCL
@@ -92,21 +182,108 @@ func (s *FileStorage) Put(_ context.Context, image string, blob *imagor.Blob) (e
 	if err != nil {
 		return
 	}
-	defer func() {
+	if _, err = bufpool.Copy(w, reader); err != nil {
 		_ = w.Close()
-	}()
-	if _, err = io.Copy(w, reader); err != nil {
 		return
 	}
+	if s.AsyncFsync && (s.Fsync || s.FsyncDir) {
+		if err = w.Close(); err != nil {
+			return
+		}
+		s.syncing.Add(1)
+		go func() {
+			defer s.syncing.Done()
+			_ = s.sync(image, nil)
+		}()
+		return
+	}
+	err = s.sync(image, w)
+	if e := w.Close(); err == nil {
+		err = e
+	}
+	return
+}
+
+// sync flushes saved file and its parent directory entry to disk per fsync options,
+// with file opened if not provided
+func (s *FileStorage) sync(image string, f *os.File) (err error) {
+	if s.Fsync {
+		if f == nil {
+			if f, err = os.Open(image); err != nil {
+				return
+			}
+			defer func() {
+				_ = f.Close()
+			}()
+		}
+		if err = f.Sync(); err != nil {
+			return
+		}
+	}
+	if s.FsyncDir {
+		dir, err := os.Open(filepath.Dir(image))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = dir.Close()
+		}()
+		return dir.Sync()
+	}
 	return
 }
 
+// Shutdown waits for pending async fsync of saved files, implements imagor.Shutdowner
+func (s *FileStorage) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.syncing.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (s *FileStorage) Delete(_ context.Context, image string) error {
 	image, ok := s.Path(image)
 	if !ok {
 		return imagor.ErrInvalid
 	}
-	return os.Remove(image)
+	return s.remove(image)
+}
+
+// DeletePrefix deletes all files with image path prefix, implements imagor.PrefixDeleter
+func (s *FileStorage) DeletePrefix(_ context.Context, prefix string) error {
+	target, ok := s.Path(prefix)
+	if !ok {
+		return imagor.ErrInvalid
+	}
+	if target == filepath.Clean(s.BaseDir) || strings.HasSuffix(prefix, "/") {
+		// match directory contents only
+		target += string(filepath.Separator)
+	}
+	dedup := filepath.Join(s.BaseDir, dedupDir)
+	err := filepath.Walk(filepath.Dir(target), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path == dedup {
+			// content and marker files are released by image files
+			return filepath.SkipDir
+		}
+		if !info.IsDir() && strings.HasPrefix(path, target) {
+			return s.remove(path)
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
 }
 
 func (s *FileStorage) Stat(_ context.Context, image string) (stat *imagor.Stat, err error) {
@@ -121,10 +298,46 @@ func (s *FileStorage) Stat(_ context.Context, image string) (stat *imagor.Stat,
 		}
 		return nil, err
 	}
+	if err = s.checkFileInfo(image, osStat); err != nil {
+		return nil, err
+	}
 	size := osStat.Size()
-	modTime := osStat.ModTime()
+	modTime := s.modTime(image, osStat)
 	return &imagor.Stat{
 		Size:         size,
 		ModifiedTime: modTime,
 	}, nil
 }
+
+// hasUnsafeChars checks if image contains control characters or backslash,
+// that are otherwise escaped by normalize
+func hasUnsafeChars(image string) bool {
+	for i := 0; i < len(image); i++ {
+		if c := image[i]; c < 0x20 || c == 0x7f || c == '\\' {
+			return true
+		}
+	}
+	return false
+}
+
+func isAllowedExtension(image string, extensions []string) bool {
+	ext := strings.ToLower(filepath.Ext(image))
+	for _, allowed := range extensions {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Health checks if BaseDir is an accessible directory
+func (s *FileStorage) Health(_ context.Context) error {
+	stat, err := os.Stat(s.BaseDir)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("%s is not a directory", s.BaseDir)
+	}
+	return nil
+}
@@ -7,7 +7,11 @@ import (
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -120,6 +124,130 @@ func TestFileStore_Path(t *testing.T) {
 	}
 }
 
+func TestFileStore_PathPolicy(t *testing.T) {
+	s := New("/home/imagor", WithPathPrefix("/uploads"), WithAllowedExtensions("jpg", ".PNG", " .webp", ""))
+	assert.Equal(t, []string{".jpg", ".png", ".webp"}, s.AllowedExtensions)
+	for image, ok := range map[string]bool{
+		"/uploads/a.jpg":     true,
+		"/uploads/b/a.JPG":   true,
+		"/uploads/a.png":     true,
+		"/uploads/a.webp":    true,
+		"/uploads/a.gif":     false,
+		"/uploads/a":         false,
+		"/uploads/a.jpg.exe": false,
+		"/private/a.jpg":     false,
+	} {
+		_, res := s.Path(image)
+		assert.Equal(t, ok, res, image)
+	}
+
+	s = New("/home/imagor")
+	res, ok := s.Path("/foo\\bar\x01.jpg")
+	assert.True(t, ok)
+	assert.Equal(t, "/home/imagor/foo%5Cbar%01.jpg", res)
+
+	s = New("/home/imagor", WithRejectUnsafeChars(true), WithMaxPathLength(20))
+	for image, ok := range map[string]bool{
+		"/foo/bar.jpg":          true,
+		"/foo\\bar.jpg":         false,
+		"/foo/bar\x00.jpg":      false,
+		"/foo/bar\x7f.jpg":      false,
+		"/foo/bar\t.jpg":        false,
+		"/foo/bar/baz/qux.jpg":  true,
+		"/foo/bar/baz/quux.jpg": false,
+		"/foo/{bar}{}.jpg":      false,
+	} {
+		_, res := s.Path(image)
+		assert.Equal(t, ok, res, image)
+	}
+}
+
+func TestFileStore_PathTraversal(t *testing.T) {
+	s := New("/home/imagor", WithPathPrefix("/foo"))
+	for _, image := range []string{
+		"/foo/../etc/passwd",
+		"/foo/../../etc/passwd",
+		"/foo/bar/../../etc/passwd",
+		"/foo/./../etc/passwd",
+		"/foo/..",
+		"/../foo/../etc/passwd",
+		"/etc/passwd",
+		"/foo/.git/config",
+		"/foo/bar/.env",
+		"/foo/bar/..hidden",
+		"/foo/..%2fetc/passwd",
+		"/foo/..\\..\\etc/passwd",
+		"/foo/....//etc/passwd",
+		"/foo/bar\x00/../../etc/passwd",
+	} {
+		res, ok := s.Path(image)
+		assert.False(t, ok, image)
+		assert.Empty(t, res, image)
+	}
+	for image, expected := range map[string]string{
+		"/foo/bar/../baz.jpg":         "/home/imagor/baz.jpg",
+		"/foo//bar.jpg":               "/home/imagor/bar.jpg",
+		"/foo/%2e%2e/%2e%2e/etc/pass": "/home/imagor/%252e%252e/%252e%252e/etc/pass",
+	} {
+		res, ok := s.Path(image)
+		assert.True(t, ok, image)
+		assert.Equal(t, expected, res, image)
+	}
+}
+
+func TestFileStorage_DenySymlinkEscape(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	base := filepath.Join(root, "base")
+	outside := filepath.Join(root, "outside")
+	require.NoError(t, os.MkdirAll(filepath.Join(base, "inner"), 0755))
+	require.NoError(t, os.MkdirAll(outside, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(outside, "secret.jpg"), []byte("secret"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(base, "inner", "a.jpg"), []byte("a"), 0644))
+	require.NoError(t, os.Symlink(outside, filepath.Join(base, "escape")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "secret.jpg"), filepath.Join(base, "secret.jpg")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "missing.jpg"), filepath.Join(base, "dangling.jpg")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "missing"), filepath.Join(base, "dangling")))
+	require.NoError(t, os.Symlink("inner", filepath.Join(base, "alias")))
+	require.NoError(t, os.Symlink("../outside", filepath.Join(base, "inner", "up")))
+	require.NoError(t, os.Symlink(base, filepath.Join(root, "base-link")))
+
+	// symlinks are followed without option
+	res, ok := New(base).Path("escape/secret.jpg")
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(base, "escape", "secret.jpg"), res)
+
+	for _, dir := range []string{base, filepath.Join(root, "base-link")} {
+		s := New(dir, WithDenySymlinkEscape(true))
+		for image, ok := range map[string]bool{
+			"inner/a.jpg":          true,
+			"alias/a.jpg":          true,
+			"new/dir/b.jpg":        true,
+			"alias/new/b.jpg":      true,
+			"escape/secret.jpg":    false,
+			"escape/new/b.jpg":     false,
+			"secret.jpg":           false,
+			"inner/up/secret.jpg":  false,
+			"alias/up/secret.jpg":  false,
+			"dangling.jpg":         false,
+			"dangling/b.jpg":       false,
+			"inner/../escape/x.jp": false,
+		} {
+			_, res := s.Path(image)
+			assert.Equal(t, ok, res, image)
+		}
+		_, err := s.Get(&http.Request{}, "escape/secret.jpg")
+		assert.ErrorIs(t, err, imagor.ErrInvalid)
+		assert.ErrorIs(t, s.Put(ctx, "escape/new.jpg", imagor.NewBlobFromBytes([]byte("x"))), imagor.ErrInvalid)
+		assert.ErrorIs(t, s.Put(ctx, "dangling.jpg", imagor.NewBlobFromBytes([]byte("x"))), imagor.ErrInvalid)
+		assert.NoError(t, s.Put(ctx, "alias/new.jpg", imagor.NewBlobFromBytes([]byte("x"))))
+	}
+	_, err := os.Stat(filepath.Join(outside, "new.jpg"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(outside, "missing.jpg"))
+	assert.True(t, os.IsNotExist(err))
+}
+
 func TestFileStorage_Load_Save(t *testing.T) {
 	ctx := imagor.WithContext(context.Background())
 	r := (&http.Request{}).WithContext(ctx)
@@ -214,3 +342,231 @@ func checkBlob(blob *imagor.Blob, err error) (*imagor.Blob, error) {
 	}
 	return blob, err
 }
+
+func TestFileStorage_Fsync(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	for name, opts := range map[string][]Option{
+		"write-through": {WithFsync(true), WithFsyncDir(true)},
+		"dir-only":      {WithFsyncDir(true)},
+		"async":         {WithFsync(true), WithFsyncDir(true), WithAsyncFsync(true)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := New(dir, opts...)
+			for i := 0; i < 10; i++ {
+				require.NoError(t, s.Put(ctx, name+"/"+strconv.Itoa(i), imagor.NewBlobFromBytes([]byte("foo"))))
+			}
+			require.NoError(t, s.Shutdown(ctx))
+			buf, err := os.ReadFile(filepath.Join(dir, name, "9"))
+			require.NoError(t, err)
+			assert.Equal(t, "foo", string(buf))
+		})
+	}
+	s := New(dir, WithFsync(true), WithAsyncFsync(true))
+	s.syncing.Add(1)
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, s.Shutdown(cctx), context.Canceled)
+	s.syncing.Done()
+	assert.NoError(t, s.Shutdown(ctx))
+}
+
+func TestFileStorage_FileInfo(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("foobar"), 0644))
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "a.txt"), modTime, modTime))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0755))
+
+	s := New(dir)
+	stat, err := s.Stat(ctx, "a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), stat.Size)
+	assert.True(t, modTime.Equal(stat.ModifiedTime))
+
+	_, err = s.Stat(ctx, "sub")
+	assert.ErrorIs(t, err, imagor.ErrNotFound)
+	_, err = checkBlob(s.Get(&http.Request{}, "sub"))
+	assert.ErrorIs(t, err, imagor.ErrNotFound)
+
+	_, err = New(dir, WithExpiration(time.Minute)).Stat(ctx, "a.txt")
+	assert.ErrorIs(t, err, imagor.ErrExpired)
+
+	app := imagor.New(imagor.WithLoaders(s), imagor.WithUnsafe(true))
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unsafe/a.txt", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "foobar", w.Body.String())
+	assert.Equal(t, "6", w.Header().Get("Content-Length"))
+	assert.Equal(t, modTime.UTC().Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+
+	r := httptest.NewRequest(http.MethodGet, "/unsafe/a.txt", nil)
+	r.Header.Set("If-Modified-Since", time.Now().UTC().Format(http.TimeFormat))
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 304, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
+func TestFileStorage_Watch(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "a", ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "b.jpg"), []byte("b"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "c d.jpg"), []byte("c"), 0644))
+
+	assert.False(t, New(dir).Watch(context.Background(), func(string) {}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan string, 10)
+	s := New(dir, WithPathPrefix("/assets"), WithWatchInterval(time.Millisecond*10))
+	require.True(t, s.Watch(ctx, func(image string) {
+		changes <- image
+	}))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "c d.jpg"), []byte("cc"), 0644))
+	assert.Equal(t, "assets/a/c d.jpg", <-changes)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "e.jpg"), []byte("e"), 0644))
+	assert.Equal(t, "assets/a/e.jpg", <-changes)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", ".git", "HEAD"), []byte("x"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "a", "b.jpg")))
+	assert.Equal(t, "assets/a/b.jpg", <-changes)
+
+	cancel()
+	time.Sleep(time.Millisecond * 30)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a", "f.jpg"), []byte("f"), 0644))
+	time.Sleep(time.Millisecond * 30)
+	assert.Empty(t, changes)
+}
+
+func TestFileStorage_Health(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "imagor-test")
+	require.NoError(t, err)
+
+	assert.NoError(t, New(dir).Health(ctx))
+	assert.Error(t, New(dir+"/not-exists").Health(ctx))
+
+	require.NoError(t, ioutil.WriteFile(dir+"/file", []byte("foo"), 0644))
+	assert.Error(t, New(dir+"/file").Health(ctx))
+}
+
+func TestFileStorage_DeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "imagor-test")
+	require.NoError(t, err)
+	s := New(dir)
+	for _, key := range []string{"foo/a.jpg", "foo/b/c.jpg", "foobar.jpg", "bar/a.jpg"} {
+		require.NoError(t, s.Put(ctx, key, imagor.NewBlobFromBytes([]byte(key))))
+	}
+	require.NoError(t, s.DeletePrefix(ctx, "foo/"))
+	for key, exists := range map[string]bool{
+		"foo/a.jpg": false, "foo/b/c.jpg": false, "foobar.jpg": true, "bar/a.jpg": true,
+	} {
+		_, err := s.Stat(ctx, key)
+		assert.Equal(t, exists, err == nil, key)
+	}
+	require.NoError(t, s.DeletePrefix(ctx, "foob"))
+	_, err = s.Stat(ctx, "foobar.jpg")
+	assert.Error(t, err)
+	assert.NoError(t, s.DeletePrefix(ctx, "not-exists/"))
+	assert.Equal(t, imagor.ErrInvalid, s.DeletePrefix(ctx, "/.git"))
+}
+
+func TestFileStorage_Dedup(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	s := New(dir, WithDedup(true))
+	countFiles := func(base string, skip string) (n int) {
+		_ = filepath.Walk(base, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.IsDir() && path == skip {
+				return filepath.SkipDir
+			}
+			if err == nil && !info.IsDir() {
+				n++
+			}
+			return nil
+		})
+		return
+	}
+	countContents := func() int {
+		return countFiles(filepath.Join(dir, ".dedup"), filepath.Join(dir, ".dedup", "saved"))
+	}
+	countSaved := func() int {
+		return countFiles(filepath.Join(dir, ".dedup", "saved"), "")
+	}
+	read := func(image string) string {
+		b, err := s.Get(&http.Request{}, image)
+		require.NoError(t, err)
+		buf, err := b.ReadAll()
+		require.NoError(t, err)
+		return string(buf)
+	}
+
+	require.NoError(t, s.Put(ctx, "a.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	require.NoError(t, s.Put(ctx, "b/c.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	require.NoError(t, s.Put(ctx, "b/c.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	require.NoError(t, s.Put(ctx, "d.jpg", imagor.NewBlobFromBytes([]byte("bar"))))
+	assert.Equal(t, 2, countContents())
+	a, err := os.Stat(filepath.Join(dir, "a.jpg"))
+	require.NoError(t, err)
+	c, err := os.Stat(filepath.Join(dir, "b", "c.jpg"))
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(a, c))
+
+	require.NoError(t, s.Delete(ctx, "a.jpg"))
+	assert.Equal(t, "foo", read("b/c.jpg"))
+	assert.Equal(t, 2, countContents())
+
+	// overwrite releases content of the last reference
+	require.NoError(t, s.Put(ctx, "b/c.jpg", imagor.NewBlobFromBytes([]byte("bar"))))
+	assert.Equal(t, "bar", read("b/c.jpg"))
+	assert.Equal(t, 1, countContents())
+
+	require.NoError(t, s.DeletePrefix(ctx, "b/"))
+	assert.Equal(t, "bar", read("d.jpg"))
+	assert.Equal(t, 1, countContents())
+	require.NoError(t, s.Delete(ctx, "d.jpg"))
+	assert.Equal(t, 0, countContents())
+	assert.Equal(t, 0, countSaved())
+
+	// delete prefix of all keys releases contents without walking into dedup dir
+	require.NoError(t, s.Put(ctx, "f.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	require.NoError(t, s.Put(ctx, "g/h.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	assert.Equal(t, 1, countContents())
+	assert.Equal(t, 2, countSaved())
+	require.NoError(t, s.DeletePrefix(ctx, "/"))
+	assert.Equal(t, 0, countContents())
+	assert.Equal(t, 0, countSaved())
+
+	s = New(dir, WithDedup(true), WithSaveErrIfExists(true))
+	require.NoError(t, s.Put(ctx, "e.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	assert.Error(t, s.Put(ctx, "e.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	assert.Error(t, s.Put(ctx, "e.jpg", imagor.NewBlobFromBytes([]byte("bar"))))
+	assert.Equal(t, "foo", read("e.jpg"))
+}
+
+func TestFileStorage_DedupExpiration(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+	s := New(dir, WithDedup(true), WithExpiration(time.Millisecond*500))
+
+	require.NoError(t, s.Put(ctx, "a.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	stat, err := s.Stat(ctx, "a.jpg")
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond * 600)
+
+	// saving same content under another key does not refresh saved time of a.jpg
+	require.NoError(t, s.Put(ctx, "b.jpg", imagor.NewBlobFromBytes([]byte("foo"))))
+	_, err = s.Stat(ctx, "a.jpg")
+	assert.Equal(t, imagor.ErrExpired, err)
+	_, err = checkBlob(s.Get(&http.Request{}, "a.jpg"))
+	assert.ErrorIs(t, err, imagor.ErrExpired)
+	_, err = checkBlob(s.Get(&http.Request{}, "b.jpg"))
+	assert.NoError(t, err)
+	b, err := s.Stat(ctx, "b.jpg")
+	require.NoError(t, err)
+	assert.True(t, b.ModifiedTime.After(stat.ModifiedTime))
+}
@@ -0,0 +1,11 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+
+package filestorage
+
+import "os"
+
+// linkCount returns 0 as number of hard links of file is not available on the platform,
+// content files are then kept on release
+func linkCount(_ os.FileInfo) uint64 {
+	return 0
+}
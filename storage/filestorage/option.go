@@ -22,14 +22,93 @@ func WithPathPrefix(prefix string) Option {
 	}
 }
 
-func WithBlacklist(blacklist *regexp.Regexp) Option {
+func WithBlacklist(blacklists ...*regexp.Regexp) Option {
 	return func(s *FileStorage) {
-		if blacklist != nil {
-			s.Blacklists = append(s.Blacklists, blacklist)
+		for _, blacklist := range blacklists {
+			if blacklist != nil {
+				s.Blacklists = append(s.Blacklists, blacklist)
+			}
 		}
 	}
 }
 
+// WithAllowedExtensions allows only image keys of file extensions e.g. .jpg, .png, case-insensitive
+func WithAllowedExtensions(extensions ...string) Option {
+	return func(s *FileStorage) {
+		for _, ext := range extensions {
+			if ext = strings.ToLower(strings.TrimSpace(ext)); ext != "" {
+				s.AllowedExtensions = append(s.AllowedExtensions, "."+strings.TrimPrefix(ext, "."))
+			}
+		}
+	}
+}
+
+// WithRejectUnsafeChars rejects image keys with control characters or backslash, instead of escaping them
+func WithRejectUnsafeChars(reject bool) Option {
+	return func(s *FileStorage) {
+		s.RejectUnsafeChars = reject
+	}
+}
+
+// WithMaxPathLength rejects image keys longer than the max length after escape
+func WithMaxPathLength(length int) Option {
+	return func(s *FileStorage) {
+		if length > 0 {
+			s.MaxPathLength = length
+		}
+	}
+}
+
+// WithDenySymlinkEscape rejects image keys resolving to path outside of base dir by symlinks,
+// e.g. symlinked directories on shared volumes
+func WithDenySymlinkEscape(deny bool) Option {
+	return func(s *FileStorage) {
+		s.DenySymlinkEscape = deny
+	}
+}
+
+// WithFsync flushes saved file to disk before save returns,
+// so that saved file is not lost on power failure
+func WithFsync(fsync bool) Option {
+	return func(s *FileStorage) {
+		s.Fsync = fsync
+	}
+}
+
+// WithFsyncDir flushes parent directory of saved file to disk,
+// so that the directory entry of newly created file is not lost on power failure
+func WithFsyncDir(fsyncDir bool) Option {
+	return func(s *FileStorage) {
+		s.FsyncDir = fsyncDir
+	}
+}
+
+// WithAsyncFsync runs fsync in background after save returns, instead of write-through.
+// Pending fsync are waited on Shutdown
+func WithAsyncFsync(async bool) Option {
+	return func(s *FileStorage) {
+		s.AsyncFsync = async
+	}
+}
+
+// WithWatchInterval polls BaseDir for changed files at interval when used as loader,
+// so that cached results of changed source images are evicted
+func WithWatchInterval(interval time.Duration) Option {
+	return func(s *FileStorage) {
+		if interval > 0 {
+			s.WatchInterval = interval
+		}
+	}
+}
+
+// WithDedup saves identical content of different image keys once, with image files hard linked
+// to content files by hash under .dedup of BaseDir. Content file is removed on delete of its last image file
+func WithDedup(dedup bool) Option {
+	return func(s *FileStorage) {
+		s.Dedup = dedup
+	}
+}
+
 func WithMkdirPermission(perm string) Option {
 	return func(h *FileStorage) {
 		if perm != "" {
@@ -0,0 +1,50 @@
+package filestorage
+
+import (
+	"errors"
+	"github.com/cshum/imagor"
+)
+
+func init() {
+	imagor.RegisterComponent("file", newComponent)
+}
+
+// newComponent creates FileStorage of component options, as loader or storage
+func newComponent(o imagor.ComponentOptions) (any, error) {
+	baseDir := o.String("base_dir")
+	if baseDir == "" {
+		return nil, errors.New("option base_dir required")
+	}
+	expiration, err := o.Duration("expiration")
+	if err != nil {
+		return nil, err
+	}
+	watchInterval, err := o.Duration("watch_interval")
+	if err != nil {
+		return nil, err
+	}
+	fsync, err := o.Bool("fsync")
+	if err != nil {
+		return nil, err
+	}
+	maxPathLength, err := o.Int("max_path_length")
+	if err != nil {
+		return nil, err
+	}
+	var options = []Option{
+		WithPathPrefix(o.String("path_prefix")),
+		WithSafeChars(o.String("safe_chars")),
+		WithAllowedExtensions(o.Strings("allowed_extensions")...),
+		WithExpiration(expiration),
+		WithWatchInterval(watchInterval),
+		WithFsync(fsync),
+		WithMaxPathLength(maxPathLength),
+	}
+	if perm := o.String("mkdir_permission"); perm != "" {
+		options = append(options, WithMkdirPermission(perm))
+	}
+	if perm := o.String("write_permission"); perm != "" {
+		options = append(options, WithWritePermission(perm))
+	}
+	return New(baseDir, options...), nil
+}
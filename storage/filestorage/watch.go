@@ -0,0 +1,87 @@
+package filestorage
+
+import (
+	"context"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileState modified time and size of file for change detection
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch polls BaseDir at WatchInterval in background until context done,
+// calling changed with image key of files added, modified or removed, implements imagor.Watcher.
+// Polling is used over file system events, which are not delivered for changes
+// made by other hosts on network shared volumes
+func (s *FileStorage) Watch(ctx context.Context, changed func(image string)) bool {
+	if s.WatchInterval <= 0 {
+		return false
+	}
+	files := s.scan()
+	go func() {
+		ticker := time.NewTicker(s.WatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				next := s.scan()
+				for path, state := range next {
+					if prev, ok := files[path]; !ok || prev != state {
+						changed(s.imageKey(path))
+					}
+				}
+				for path := range files {
+					if _, ok := next[path]; !ok {
+						changed(s.imageKey(path))
+					}
+				}
+				files = next
+			}
+		}
+	}()
+	return true
+}
+
+// scan returns state of files under BaseDir by relative path, excluding dot files
+func (s *FileStorage) scan() map[string]fileState {
+	files := map[string]fileState{}
+	_ = filepath.WalkDir(s.BaseDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != s.BaseDir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if rel, err := filepath.Rel(s.BaseDir, path); err == nil {
+			files[filepath.ToSlash(rel)] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+		return nil
+	})
+	return files
+}
+
+// imageKey returns image key of file relative path, reversing path prefix and escape
+func (s *FileStorage) imageKey(rel string) string {
+	if image, err := url.PathUnescape(rel); err == nil {
+		rel = image
+	}
+	return strings.TrimPrefix(s.PathPrefix+rel, "/")
+}
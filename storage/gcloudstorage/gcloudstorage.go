@@ -57,8 +57,12 @@ func (s *GCloudStorage) Get(r *http.Request, image string) (imageData *imagor.Bl
 		if attrs != nil {
 			size = attrs.Size
 		}
-		reader, err = object.NewReader(ctx)
-		return
+		r, err := object.NewReader(ctx)
+		if err != nil {
+			// avoid typed nil reader
+			return nil, size, err
+		}
+		return r, size, nil
 	})
 	if attrs != nil {
 		blob.Stat = &imagor.Stat{
@@ -133,3 +137,27 @@ func (s *GCloudStorage) Stat(ctx context.Context, image string) (stat *imagor.St
 		ModifiedTime: attrs.Updated,
 	}, nil
 }
+
+// Presign returns V4 signed GET URL of image expiring in duration, implements imagor.Presigner.
+// Signing requires service account credentials with private key, or IAM signBlob permission
+func (s *GCloudStorage) Presign(ctx context.Context, image string, expires time.Duration) (string, error) {
+	stat, err := s.Stat(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	if s.Expiration > 0 && time.Now().Sub(stat.ModifiedTime) > s.Expiration {
+		return "", imagor.ErrExpired
+	}
+	image, _ = s.Path(image)
+	return s.client.Bucket(s.Bucket).SignedURL(image, &storage.SignedURLOptions{
+		Method:  http.MethodGet,
+		Expires: time.Now().Add(expires),
+		Scheme:  storage.SigningSchemeV4,
+	})
+}
+
+// Health checks if bucket is reachable
+func (s *GCloudStorage) Health(ctx context.Context) error {
+	_, err := s.client.Bucket(s.Bucket).Attrs(ctx)
+	return err
+}
@@ -171,3 +171,38 @@ func TestExpiration(t *testing.T) {
 	_, err = s.Get(&http.Request{}, "/foo/bar/asdf")
 	require.ErrorIs(t, err, imagor.ErrExpired)
 }
+
+func TestComponent(t *testing.T) {
+	srv, err := fakestorage.NewServerWithOptions(fakestorage.Options{
+		InitialObjects: []fakestorage.Object{{
+			ObjectAttrs: fakestorage.ObjectAttrs{BucketName: "test", Name: "foo/bar"},
+			Content:     []byte("bar"),
+		}},
+		Scheme: "http",
+	})
+	require.NoError(t, err)
+	defer srv.Stop()
+	t.Setenv("STORAGE_EMULATOR_HOST", srv.URL())
+
+	c, err := imagor.NewComponent("gcloud", imagor.ComponentOptions{
+		"bucket":      "test",
+		"path_prefix": "/foo",
+		"base_dir":    "foo",
+		"expiration":  "1h",
+	})
+	require.NoError(t, err)
+	s := c.(*GCloudStorage)
+	assert.Equal(t, "test", s.Bucket)
+	assert.Equal(t, time.Hour, s.Expiration)
+	stat, err := s.Stat(context.Background(), "/foo/bar")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stat.Size)
+
+	for _, options := range []imagor.ComponentOptions{
+		{},
+		{"bucket": "test", "expiration": "abc"},
+	} {
+		_, err = imagor.NewComponent("gcloud", options)
+		assert.Error(t, err, options)
+	}
+}
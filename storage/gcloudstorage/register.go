@@ -0,0 +1,42 @@
+package gcloudstorage
+
+import (
+	"cloud.google.com/go/storage"
+	"context"
+	"errors"
+	"github.com/cshum/imagor"
+	"google.golang.org/api/option"
+)
+
+func init() {
+	imagor.RegisterComponent("gcloud", newComponent)
+}
+
+// newComponent creates GCloudStorage of component options, as loader or storage.
+// Credentials are of credentials_file if set,
+// or GOOGLE_APPLICATION_CREDENTIALS and the default credentials otherwise
+func newComponent(o imagor.ComponentOptions) (any, error) {
+	bucket := o.String("bucket")
+	if bucket == "" {
+		return nil, errors.New("option bucket required")
+	}
+	expiration, err := o.Duration("expiration")
+	if err != nil {
+		return nil, err
+	}
+	var clientOptions []option.ClientOption
+	if file := o.String("credentials_file"); file != "" {
+		clientOptions = append(clientOptions, option.WithCredentialsFile(file))
+	}
+	client, err := storage.NewClient(context.Background(), clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+	return New(client, bucket,
+		WithBaseDir(o.String("base_dir")),
+		WithPathPrefix(o.String("path_prefix")),
+		WithACL(o.String("acl")),
+		WithSafeChars(o.String("safe_chars")),
+		WithExpiration(expiration),
+	), nil
+}
@@ -0,0 +1,63 @@
+package s3storage
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/cshum/imagor"
+)
+
+func init() {
+	imagor.RegisterComponent("s3", newComponent)
+}
+
+// newComponent creates S3Storage of component options, as loader or storage.
+// Credentials are of access_key_id and secret_access_key if set,
+// or the default AWS credential chain otherwise
+func newComponent(o imagor.ComponentOptions) (any, error) {
+	bucket := o.String("bucket")
+	if bucket == "" {
+		return nil, errors.New("option bucket required")
+	}
+	expiration, err := o.Duration("expiration")
+	if err != nil {
+		return nil, err
+	}
+	forcePathStyle, err := o.Bool("force_path_style")
+	if err != nil {
+		return nil, err
+	}
+	var cfg = aws.Config{
+		S3ForcePathStyle: aws.Bool(forcePathStyle),
+	}
+	if region := o.String("region"); region != "" {
+		cfg.Region = aws.String(region)
+	}
+	if endpoint := o.String("endpoint"); endpoint != "" {
+		cfg.Endpoint = aws.String(endpoint)
+	}
+	if keyID, secret := o.String("access_key_id"), o.String("secret_access_key"); keyID != "" || secret != "" {
+		if keyID == "" || secret == "" {
+			return nil, errors.New("options access_key_id and secret_access_key required together")
+		}
+		cfg.Credentials = credentials.NewStaticCredentials(keyID, secret, "")
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var options = []Option{
+		WithBaseDir(o.String("base_dir")),
+		WithPathPrefix(o.String("path_prefix")),
+		WithSafeChars(o.String("safe_chars")),
+		WithExpiration(expiration),
+	}
+	if acl := o.String("acl"); acl != "" {
+		options = append(options, WithACL(acl))
+	}
+	return New(sess, bucket, options...), nil
+}
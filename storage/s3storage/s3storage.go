@@ -162,3 +162,28 @@ func (s *S3Storage) Stat(ctx context.Context, image string) (stat *imagor.Stat,
 		ModifiedTime: *head.LastModified,
 	}, nil
 }
+
+// Presign returns presigned GET URL of image expiring in duration, implements imagor.Presigner
+func (s *S3Storage) Presign(ctx context.Context, image string, expires time.Duration) (string, error) {
+	stat, err := s.Stat(ctx, image)
+	if err != nil {
+		return "", err
+	}
+	if s.Expiration > 0 && time.Now().Sub(stat.ModifiedTime) > s.Expiration {
+		return "", imagor.ErrExpired
+	}
+	image, _ = s.Path(image)
+	req, _ := s.S3.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(image),
+	})
+	return req.Presign(expires)
+}
+
+// Health checks if bucket is reachable
+func (s *S3Storage) Health(ctx context.Context) error {
+	_, err := s.S3.HeadBucketWithContext(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(s.Bucket),
+	})
+	return err
+}
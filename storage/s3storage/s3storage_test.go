@@ -11,6 +11,7 @@ import (
 	"github.com/johannesboyne/gofakes3/backend/s3mem"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -220,3 +221,67 @@ func TestExpiration(t *testing.T) {
 	_, err = b.ReadAll()
 	require.ErrorIs(t, err, imagor.ErrExpired)
 }
+
+func TestPresign(t *testing.T) {
+	ts := fakeS3Server()
+	defer ts.Close()
+
+	ctx := context.Background()
+	s := New(fakeS3Session(ts, "test"), "test", WithPathPrefix("/foo"))
+
+	_, err := s.Presign(ctx, "/bar/asdf", time.Minute*5)
+	assert.Equal(t, imagor.ErrInvalid, err)
+
+	require.NoError(t, s.Put(ctx, "/foo/asdf", imagor.NewBlobFromBytes([]byte("bar"))))
+	url, err := s.Presign(ctx, "/foo/asdf", time.Minute*5)
+	require.NoError(t, err)
+	assert.Contains(t, url, "/test/asdf?")
+	assert.Contains(t, url, "X-Amz-Expires=300")
+	assert.Contains(t, url, "X-Amz-Signature=")
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	buf, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(buf))
+}
+
+func TestComponent(t *testing.T) {
+	ts := fakeS3Server()
+	defer ts.Close()
+	fakeS3Session(ts, "test")
+	ctx := context.Background()
+
+	c, err := imagor.NewComponent("s3", imagor.ComponentOptions{
+		"bucket":            "test",
+		"region":            "eu-central-1",
+		"endpoint":          ts.URL,
+		"access_key_id":     "YOUR-ACCESSKEYID",
+		"secret_access_key": "YOUR-SECRETACCESSKEY",
+		"force_path_style":  "true",
+		"path_prefix":       "/foo",
+		"expiration":        "1h",
+	})
+	require.NoError(t, err)
+	s := c.(*S3Storage)
+	assert.Equal(t, "test", s.Bucket)
+	assert.Equal(t, "/foo/", s.PathPrefix)
+	assert.Equal(t, time.Hour, s.Expiration)
+	require.NoError(t, s.Put(ctx, "/foo/bar", imagor.NewBlobFromBytes([]byte("bar"))))
+	b, err := s.Get((&http.Request{}).WithContext(ctx), "/foo/bar")
+	require.NoError(t, err)
+	buf, err := b.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "bar", string(buf))
+
+	for _, options := range []imagor.ComponentOptions{
+		{},
+		{"bucket": "test", "expiration": "abc"},
+		{"bucket": "test", "force_path_style": "abc"},
+		{"bucket": "test", "access_key_id": "YOUR-ACCESSKEYID"},
+	} {
+		_, err = imagor.NewComponent("s3", options)
+		assert.Error(t, err, options)
+	}
+}
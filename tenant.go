@@ -0,0 +1,133 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Tenant configuration of image requests matched by Host header or path prefix,
+// with separate secret, allowed sources, storage prefix and base params,
+// for serving multiple brands from one imagor safely
+type Tenant struct {
+	// Name of tenant
+	Name string
+	// Hosts matched by Host header, accepts glob pattern e.g. *.example.com
+	Hosts []string
+	// PathPrefix matched and trimmed from request path e.g. /brand
+	PathPrefix string
+	// Signer of tenant URLs in place of app Signer if set
+	Signer imagorpath.Signer
+	// AllowedSources glob patterns of image sources, matched by image path or its parent directories
+	// e.g. brand/* or *.example.com. All sources are allowed if empty
+	AllowedSources []string
+	// StoragePrefix prefixed to keys of storages, result storages and caches.
+	// Loaders get the image as requested, that sources are matched by AllowedSources
+	StoragePrefix string
+	// BaseParams imagor params applied to all tenant images, on top of app BaseParams
+	BaseParams string
+}
+
+type tenantKey struct{}
+
+// withTenant returns request with tenant context
+func withTenant(r *http.Request, tenant *Tenant) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant))
+}
+
+// tenantFrom returns tenant of context, nil if not matched
+func tenantFrom(ctx context.Context) *Tenant {
+	tenant, _ := ctx.Value(tenantKey{}).(*Tenant)
+	return tenant
+}
+
+// TenantName returns name of tenant matched by the request context, empty if none
+func TenantName(ctx context.Context) string {
+	if tenant := tenantFrom(ctx); tenant != nil {
+		return tenant.Name
+	}
+	return ""
+}
+
+// matchTenant returns first tenant matched by Host header and path prefix, with path prefix trimmed.
+// Tenant without hosts and path prefix matches all requests
+func (app *Imagor) matchTenant(r *http.Request, p string) (*Tenant, string) {
+	host := strings.ToLower(r.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for i := range app.Tenants {
+		tenant := &app.Tenants[i]
+		if len(tenant.Hosts) > 0 && !matchHost(tenant.Hosts, host) {
+			continue
+		}
+		if tenant.PathPrefix == "" {
+			return tenant, p
+		}
+		if p == tenant.PathPrefix || strings.HasPrefix(p, tenant.PathPrefix+"/") {
+			return tenant, strings.TrimPrefix(p, tenant.PathPrefix)
+		}
+	}
+	return nil, p
+}
+
+// signer returns signer of tenant, or app Signer if tenant signer not set
+func (app *Imagor) signer(ctx context.Context) imagorpath.Signer {
+	if tenant := tenantFrom(ctx); tenant != nil && tenant.Signer != nil {
+		return tenant.Signer
+	}
+	return app.Signer
+}
+
+// isSourceAllowed checks image against allowed sources glob patterns,
+// matched by image path or any of its parent directories, with URL scheme stripped
+func (t *Tenant) isSourceAllowed(image string) bool {
+	if t == nil || len(t.AllowedSources) == 0 {
+		return true
+	}
+	if i := strings.Index(image, "://"); i >= 0 {
+		image = image[i+3:]
+	}
+	image = strings.Trim(image, "/")
+	for s := image; s != ""; {
+		for _, pattern := range t.AllowedSources {
+			if matched, e := path.Match(pattern, s); matched && e == nil {
+				return true
+			}
+		}
+		i := strings.LastIndexByte(s, '/')
+		if i < 0 {
+			break
+		}
+		s = s[:i]
+	}
+	return false
+}
+
+// checkSource checks image against allowed sources of tenant
+func (t *Tenant) checkSource(image string) error {
+	if !t.isSourceAllowed(image) {
+		return ErrSourceNotAllowed
+	}
+	return nil
+}
+
+// prefixKey returns storage, result storage or cache key with tenant storage prefix
+func (t *Tenant) prefixKey(key string) string {
+	if t == nil || key == "" {
+		return key
+	}
+	return t.StoragePrefix + key
+}
+
+func matchHost(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if matched, e := path.Match(pattern, host); matched && e == nil {
+			return true
+		}
+	}
+	return false
+}
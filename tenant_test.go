@@ -0,0 +1,113 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithTenant(t *testing.T) {
+	brandSigner := imagorpath.NewDefaultSigner("brand")
+	resultStore := newMapStore()
+	store := newMapStore()
+	app := New(
+		WithSigner(imagorpath.NewDefaultSigner("1234")),
+		WithTenant(Tenant{
+			Name:           "brand",
+			Hosts:          []string{"*.Brand.com "},
+			Signer:         brandSigner,
+			AllowedSources: []string{"images", "*.cdn.com"},
+			StoragePrefix:  "/brand",
+			BaseParams:     "filters:fill(white)",
+		}),
+		WithTenant(Tenant{Name: "shop", PathPrefix: "/shop/"}),
+		WithStorages(store),
+		WithResultStorages(resultStore),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte(TenantName(r.Context()) + ":" + image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			buf, err := blob.ReadAll()
+			if err != nil {
+				return nil, err
+			}
+			if f := p.Filters; len(f) > 0 && f[0].Name == "watermark" {
+				wm, err := load(f[0].Args)
+				if err != nil {
+					return nil, err
+				}
+				b, _ := wm.ReadAll()
+				buf = append(buf, b...)
+			}
+			return NewBlobFromBytes(append(buf, "|"+p.Path...)), nil
+		})),
+	)
+	assert.Equal(t, []string{"*.brand.com"}, app.Tenants[0].Hosts)
+
+	doGet := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	path := imagorpath.Generate(imagorpath.Params{Image: "images/foo.jpg", Width: 100}, brandSigner)
+	w := doGet("https://img.brand.com:8000/" + path)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "brand:images/foo.jpg|100x0/filters:fill(white)/images/foo.jpg", w.Body.String())
+	assert.Contains(t, resultStore.Map, "brand/100x0/filters:fill(white)/images/foo.jpg")
+	assert.Contains(t, store.Map, "brand/images/foo.jpg")
+
+	w = doGet("https://example.com/" + path)
+	assert.Equal(t, 403, w.Code, "signed by tenant secret")
+	assert.Equal(t, jsonStr(ErrSignatureMismatch), w.Body.String())
+
+	w = doGet("https://img.brand.com/" + imagorpath.Generate(
+		imagorpath.Params{Image: "images/foo.jpg", Width: 100}, imagorpath.NewDefaultSigner("1234")))
+	assert.Equal(t, 403, w.Code, "app secret not accepted by tenant")
+
+	w = doGet("https://img.brand.com/" + imagorpath.Generate(imagorpath.Params{Image: "other/foo.jpg"}, brandSigner))
+	assert.Equal(t, 403, w.Code)
+	assert.Equal(t, jsonStr(ErrSourceNotAllowed), w.Body.String())
+
+	w = doGet("https://img.brand.com/" + imagorpath.Generate(imagorpath.Params{Image: "https://a.cdn.com/foo.jpg"}, brandSigner))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "brand:https://a.cdn.com/foo.jpg|filters:fill(white)/https://a.cdn.com/foo.jpg", w.Body.String(),
+		"loader gets source URL without storage prefix")
+	assert.Contains(t, store.Map, "brand/https://a.cdn.com/foo.jpg")
+
+	w = doGet("https://img.brand.com/" + imagorpath.Generate(imagorpath.Params{
+		Image:   "images/foo.jpg",
+		Filters: imagorpath.Filters{{Name: "watermark", Args: "other/logo.png"}},
+	}, brandSigner))
+	assert.Equal(t, 403, w.Code, "source of filter not allowed")
+
+	w = doGet("https://img.brand.com/" + imagorpath.Generate(imagorpath.Params{
+		Image:   "images/foo.jpg",
+		Filters: imagorpath.Filters{{Name: "watermark", Args: "images/logo.png"}},
+	}, brandSigner))
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "brand:images/logo.png")
+
+	w = doGet("https://example.com/shop/" + imagorpath.Generate(imagorpath.Params{Image: "foo.jpg"}, app.Signer))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "shop:foo.jpg|foo.jpg", w.Body.String())
+
+	w = doGet("https://example.com/" + imagorpath.Generate(imagorpath.Params{Image: "bar.jpg"}, app.Signer))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, ":bar.jpg|bar.jpg", w.Body.String())
+}
+
+func TestTenantIsSourceAllowed(t *testing.T) {
+	tenant := &Tenant{AllowedSources: []string{"brand/*", "*.example.com"}}
+	assert.True(t, tenant.isSourceAllowed("brand/foo.jpg"))
+	assert.True(t, tenant.isSourceAllowed("brand/a/b/foo.jpg"))
+	assert.True(t, tenant.isSourceAllowed("https://img.example.com/foo.jpg"))
+	assert.True(t, tenant.isSourceAllowed("/brand/foo.jpg"))
+	assert.False(t, tenant.isSourceAllowed("brand"))
+	assert.False(t, tenant.isSourceAllowed("other/brand/foo.jpg"))
+	assert.False(t, tenant.isSourceAllowed("https://example.com.evil.com/foo.jpg"))
+	assert.True(t, (*Tenant)(nil).isSourceAllowed("any.jpg"))
+}
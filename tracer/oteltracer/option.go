@@ -0,0 +1,14 @@
+package oteltracer
+
+import "go.opentelemetry.io/otel/propagation"
+
+type Option func(t *Tracer)
+
+// WithPropagator with propagator of trace context in request headers, default W3C trace context
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(t *Tracer) {
+		if propagator != nil {
+			t.Propagator = propagator
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package oteltracer
+
+import (
+	"context"
+	"github.com/cshum/imagor"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+)
+
+// instrumentationName name of the OpenTelemetry tracer of imagor spans
+const instrumentationName = "github.com/cshum/imagor"
+
+// Tracer OpenTelemetry adapter of imagor.Tracer,
+// with W3C trace context propagation by default
+type Tracer struct {
+	Provider   trace.TracerProvider
+	Propagator propagation.TextMapPropagator
+
+	tracer trace.Tracer
+}
+
+// New creates Tracer of OpenTelemetry tracer provider
+func New(provider trace.TracerProvider, options ...Option) *Tracer {
+	t := &Tracer{
+		Provider:   provider,
+		Propagator: propagation.TraceContext{},
+	}
+	for _, option := range options {
+		option(t)
+	}
+	t.tracer = provider.Tracer(instrumentationName)
+	return t
+}
+
+// Extract implements imagor.Tracer
+func (t *Tracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return t.Propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// Inject implements imagor.Tracer
+func (t *Tracer) Inject(ctx context.Context, header http.Header) {
+	t.Propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Start implements imagor.Tracer
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, imagor.Span) {
+	ctx, s := t.tracer.Start(ctx, name)
+	return ctx, span{s}
+}
+
+// Shutdown implements imagor.Shutdowner,
+// flushes and shuts down the tracer provider if supported e.g. of OpenTelemetry SDK
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if p, ok := t.Provider.(interface {
+		Shutdown(ctx context.Context) error
+	}); ok {
+		return p.Shutdown(ctx)
+	}
+	return nil
+}
+
+type span struct {
+	trace.Span
+}
+
+func (s span) SetAttribute(key, value string) {
+	s.Span.SetAttributes(attribute.String(key, value))
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.Span.RecordError(err)
+		s.Span.SetStatus(codes.Error, err.Error())
+	}
+	s.Span.End()
+}
@@ -0,0 +1,106 @@
+package oteltracer
+
+import (
+	"context"
+	"errors"
+	"github.com/cshum/imagor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+type loaderFunc func(r *http.Request, image string) (*imagor.Blob, error)
+
+func (f loaderFunc) Get(r *http.Request, image string) (*imagor.Blob, error) {
+	return f(r, image)
+}
+
+// spanRecorder records ended spans and shutdown of span processor
+type spanRecorder struct {
+	*tracetest.SpanRecorder
+	shutdown bool
+}
+
+func (r *spanRecorder) Shutdown(ctx context.Context) error {
+	r.shutdown = true
+	return r.SpanRecorder.Shutdown(ctx)
+}
+
+func newSpanRecorder() (*spanRecorder, *sdktrace.TracerProvider) {
+	recorder := &spanRecorder{SpanRecorder: tracetest.NewSpanRecorder()}
+	return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+}
+
+func TestTracer(t *testing.T) {
+	recorder, provider := newSpanRecorder()
+	tracer := New(provider)
+
+	ctx := tracer.Extract(context.Background(), http.Header{"Traceparent": {traceparent}})
+	ctx, span := tracer.Start(ctx, "foo")
+	span.SetAttribute("imagor.image", "bar.jpg")
+	header := http.Header{}
+	tracer.Inject(ctx, header)
+	span.End(errors.New("boom"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	s := spans[0]
+	assert.Equal(t, "foo", s.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", s.Parent().SpanID().String())
+	assert.Contains(t, s.Attributes(), attribute.String("imagor.image", "bar.jpg"))
+	assert.Equal(t, codes.Error, s.Status().Code)
+	assert.Equal(t, "boom", s.Status().Description)
+	assert.Contains(t, header.Get("Traceparent"), s.SpanContext().SpanID().String())
+
+	require.NoError(t, tracer.Shutdown(context.Background()))
+	assert.True(t, recorder.shutdown)
+}
+
+func TestWithPropagator(t *testing.T) {
+	provider := sdktrace.NewTracerProvider()
+	tracer := New(provider, WithPropagator(propagation.Baggage{}))
+	assert.Equal(t, propagation.Baggage{}, tracer.Propagator)
+	assert.Equal(t, propagation.TraceContext{}, New(provider, WithPropagator(nil)).Propagator)
+}
+
+func TestImagor(t *testing.T) {
+	recorder, provider := newSpanRecorder()
+	var loaderHeader http.Header
+	app := imagor.New(
+		imagor.WithUnsafe(true),
+		imagor.WithTracer(New(provider)),
+		imagor.WithLoaders(loaderFunc(func(r *http.Request, image string) (*imagor.Blob, error) {
+			loaderHeader = http.Header{}
+			imagor.InjectTrace(r.Context(), loaderHeader)
+			return imagor.NewBlobFromBytes([]byte("foo")), nil
+		})),
+	)
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo.jpg", nil)
+	r.Header.Set("Traceparent", traceparent)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var names []string
+	for _, s := range recorder.Ended() {
+		names = append(names, s.Name())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", s.SpanContext().TraceID().String(), s.Name())
+	}
+	assert.Contains(t, names, "imagor.serve")
+	assert.Contains(t, names, "imagor.load")
+	assert.Contains(t, loaderHeader.Get("Traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736")
+
+	// tracer provider shut down with imagor
+	require.NoError(t, app.Shutdown(context.Background()))
+	assert.True(t, recorder.shutdown)
+}
@@ -0,0 +1,61 @@
+package imagor
+
+import (
+	"context"
+	"net/http"
+)
+
+// Span traced imagor operation
+type Span interface {
+	SetAttribute(key, value string)
+	End(err error)
+}
+
+// Tracer traces imagor operations as spans,
+// e.g. adapter of OpenTelemetry tracer with W3C trace context propagator
+type Tracer interface {
+	// Extract returns context with remote span extracted from incoming request headers
+	Extract(ctx context.Context, header http.Header) context.Context
+
+	// Inject injects span of the context to outgoing request headers
+	Inject(ctx context.Context, header http.Header)
+
+	// Start starts span of name as child of the context span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type tracerKey struct{}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, string) {}
+
+func (noopSpan) End(error) {}
+
+// InjectTrace injects span of imagor request context to outgoing request headers,
+// for loaders to propagate trace context to upstream sources
+func InjectTrace(ctx context.Context, header http.Header) {
+	if tracer, ok := ctx.Value(tracerKey{}).(Tracer); ok && tracer != nil {
+		tracer.Inject(ctx, header)
+	}
+}
+
+func (app *Imagor) startSpan(ctx context.Context, name string) (context.Context, Span) {
+	if app.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return app.Tracer.Start(ctx, name)
+}
+
+// traceRequest starts span of the request with trace context extracted from request headers
+func (app *Imagor) traceRequest(r *http.Request) (*http.Request, Span) {
+	if app.Tracer == nil {
+		return r, noopSpan{}
+	}
+	ctx := app.Tracer.Extract(r.Context(), r.Header)
+	ctx = context.WithValue(ctx, tracerKey{}, app.Tracer)
+	ctx, span := app.Tracer.Start(ctx, "imagor.serve")
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.EscapedPath())
+	return r.WithContext(ctx), span
+}
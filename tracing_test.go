@@ -0,0 +1,106 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	tracer *testTracer
+	name   string
+	parent string
+	attrs  map[string]string
+	err    error
+}
+
+func (s *testSpan) SetAttribute(key, value string) {
+	s.attrs[key] = value
+}
+
+func (s *testSpan) End(err error) {
+	s.err = err
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, s)
+	s.tracer.mu.Unlock()
+}
+
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+func (t *testTracer) Extract(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, testSpanKey{}, header.Get("Traceparent"))
+}
+
+func (t *testTracer) Inject(ctx context.Context, header http.Header) {
+	header.Set("Traceparent", ctx.Value(testSpanKey{}).(string))
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(string)
+	span := &testSpan{tracer: t, name: name, parent: parent, attrs: map[string]string{}}
+	return context.WithValue(ctx, testSpanKey{}, name), span
+}
+
+func (t *testTracer) names() (names []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range t.spans {
+		names = append(names, s.parent+">"+s.name)
+	}
+	return
+}
+
+func TestWithTracer(t *testing.T) {
+	tracer := &testTracer{}
+	var injected string
+	app := New(
+		WithUnsafe(true),
+		WithTracer(tracer),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			var header = http.Header{}
+			InjectTrace(r.Context(), header)
+			injected = header.Get("Traceparent")
+			if image == "missing" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return blob, nil
+		})),
+		WithResultStorages(newMapStore()),
+	)
+	r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/foo", nil)
+	r.Header.Set("Traceparent", "remote")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "imagor.load", injected)
+	assert.Equal(t, []string{
+		"imagor.serve>imagor.load",
+		"imagor.serve>imagor.process",
+		"imagor.serve>imagor.save",
+		"remote>imagor.serve",
+	}, tracer.names())
+	assert.Equal(t, "foo", tracer.spans[0].attrs["imagor.image"])
+	assert.Equal(t, "/unsafe/foo", tracer.spans[3].attrs["http.target"])
+
+	tracer.spans = nil
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/missing", nil))
+	assert.Equal(t, 404, w.Code)
+	assert.Equal(t, []string{"imagor.serve>imagor.load", ">imagor.serve"}, tracer.names())
+	assert.Equal(t, ErrNotFound, tracer.spans[0].err)
+
+	// no tracer in context
+	InjectTrace(context.Background(), http.Header{})
+}
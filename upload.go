@@ -0,0 +1,99 @@
+package imagor
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/cshum/imagor/bufpool"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type uploadResult struct {
+	Key         string `json:"key"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+}
+
+// serveUpload handles PUT /upload/<key>
+func (app *Imagor) serveUpload(w http.ResponseWriter, r *http.Request, path string) {
+	key := strings.TrimPrefix(path, "/upload/")
+	if key == path {
+		writeError(w, r, ErrMethodNotAllowed)
+		return
+	}
+	if k, err := url.PathUnescape(key); err == nil {
+		key = k
+	}
+	res, err := app.upload(r, key)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSONStatus(w, r, http.StatusCreated, res)
+}
+
+// isBearerAuthorized checks request bearer token against secret in constant time
+func isBearerAuthorized(r *http.Request, secret string) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return secret != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// upload validates and writes request body image through Storages
+func (app *Imagor) upload(r *http.Request, key string) (*uploadResult, error) {
+	if !isBearerAuthorized(r, app.UploadSecret) {
+		return nil, ErrUnauthorized
+	}
+	if key == "" || len(app.Storages) == 0 {
+		return nil, ErrInvalid
+	}
+	var maxSize = app.UploadMaxSize
+	if maxSize <= 0 || maxSize > maxMemorySize {
+		maxSize = maxMemorySize
+	}
+	if r.ContentLength > maxSize {
+		return nil, ErrMaxSizeExceeded
+	}
+	buf, err := bufpool.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(buf)) > maxSize {
+		return nil, ErrMaxSizeExceeded
+	}
+	blob := NewBlobFromBytes(buf)
+	if blob.BlobType() < BlobTypeJPEG {
+		return nil, ErrUnsupportedFormat
+	}
+	var storageKey = key
+	if app.StoragePathStyle != nil {
+		storageKey = app.StoragePathStyle.Hash(key)
+	}
+	ctx := r.Context()
+	if timeout := app.stageTimeout(ctx, app.SaveTimeout); timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	for _, storage := range app.Storages {
+		if err = storage.Put(ctx, storageKey, blob); err != nil {
+			app.Logger.Warn("upload", zap.String("key", key), zap.Error(err))
+			return nil, wrapStage(StageSave, err)
+		}
+	}
+	// stale source and cached not found of the key would otherwise be served over the upload
+	if err = app.deleteSourceCaches(ctx, tenantFrom(ctx).prefixKey(key)); err != nil {
+		app.Logger.Warn("upload-evict", zap.String("key", key), zap.Error(err))
+	}
+	if app.Debug {
+		app.Logger.Debug("uploaded", zap.String("key", key), zap.Int64("size", blob.Size()))
+	}
+	return &uploadResult{
+		Key:         key,
+		Size:        blob.Size(),
+		ContentType: blob.ContentType(),
+	}, nil
+}
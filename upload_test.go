@@ -0,0 +1,129 @@
+package imagor
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWithUpload(t *testing.T) {
+	buf, err := os.ReadFile("testdata/gopher.png")
+	require.NoError(t, err)
+
+	store := newMapStore()
+	app := New(
+		WithUnsafe(true),
+		WithStorages(store),
+		WithUpload("s3cret", int64(len(buf))),
+	)
+	doPut := func(path, token string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPut, "https://example.com"+path, bytes.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+
+	w := doPut("/upload/foo/gopher.png", "", buf)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, 401, w.Code)
+	assert.Equal(t, jsonStr(ErrUnauthorized), w.Body.String())
+
+	w = doPut("/upload/foo/gopher.png", "wrong", buf)
+	assert.Equal(t, 401, w.Code)
+
+	w = doPut("/upload/foo/bar.txt", "s3cret", []byte("not an image"))
+	assert.Equal(t, 406, w.Code)
+	assert.Equal(t, jsonStr(ErrUnsupportedFormat), w.Body.String())
+
+	w = doPut("/upload/foo/large.png", "s3cret", append(buf, 0))
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, jsonStr(ErrMaxSizeExceeded), w.Body.String())
+
+	w = doPut("/upload/", "s3cret", buf)
+	assert.Equal(t, 400, w.Code)
+
+	w = doPut("/unsafe/foo/gopher.png", "s3cret", buf)
+	assert.Equal(t, 405, w.Code)
+
+	assert.Empty(t, store.Map)
+
+	w = doPut("/upload/foo/gopher%20copy.png", "s3cret", buf)
+	assert.Equal(t, 201, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Equal(t, jsonStr(uploadResult{
+		Key:         "foo/gopher copy.png",
+		Size:        int64(len(buf)),
+		ContentType: "image/png",
+	}), w.Body.String())
+	require.NotNil(t, store.Map["foo/gopher copy.png"])
+	assert.Equal(t, int64(len(buf)), store.Map["foo/gopher copy.png"].Size())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet, "https://example.com/unsafe/foo/gopher%20copy.png", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, buf, w.Body.Bytes())
+
+	app = New(WithStorages(store))
+	w = doPut("/upload/foo/gopher.png", "s3cret", buf)
+	assert.Equal(t, 405, w.Code)
+}
+
+func TestUploadEvictSource(t *testing.T) {
+	png, err := os.ReadFile("testdata/gopher.png")
+	require.NoError(t, err)
+	jpg, err := os.ReadFile("testdata/demo1.jpg")
+	require.NoError(t, err)
+
+	store := newMapStore()
+	cache := newMapCache()
+	app := New(
+		WithUnsafe(true),
+		WithStorages(store),
+		WithCache(cache),
+		WithErrorCacheTTL(time.Minute),
+		WithSourceCache(cache, time.Minute),
+		WithUpload("s3cret", int64(len(png)+len(jpg))),
+	)
+	doPut := func(path string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPut, "https://example.com"+path, bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer s3cret")
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	doGet := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com"+path, nil))
+		return w
+	}
+
+	w := doGet("/unsafe/foo/image")
+	assert.Equal(t, 404, w.Code)
+	assert.NotNil(t, cache.Map[errorCacheKey("foo/image")])
+
+	w = doPut("/upload/foo/image", png)
+	assert.Equal(t, 201, w.Code)
+	assert.Nil(t, cache.Map[errorCacheKey("foo/image")])
+
+	w = doGet("/unsafe/foo/image")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, png, w.Body.Bytes())
+	assert.NotNil(t, cache.Map[sourceCacheKey("foo/image")])
+
+	w = doPut("/upload/foo/image", jpg)
+	assert.Equal(t, 201, w.Code)
+	assert.Nil(t, cache.Map[sourceCacheKey("foo/image")])
+
+	w = doGet("/unsafe/fit-in/foo/image")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, jpg, w.Body.Bytes())
+}
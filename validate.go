@@ -0,0 +1,58 @@
+package imagor
+
+import (
+	"github.com/cshum/imagor/imagorpath"
+	"strings"
+)
+
+// Policies of source not supported by any processor
+const (
+	UnsupportedSourcePassthrough = "passthrough"
+	UnsupportedSourceReject      = "reject"
+)
+
+// validateSource checks loaded source against maximum size and pixels,
+// and that it is an image or of the allowed passthrough types if source validation enabled
+func (app *Imagor) validateSource(blob *Blob) error {
+	if isBlobEmpty(blob) {
+		return nil
+	}
+	if app.MaxSourceSize > 0 && blob.Size() > app.MaxSourceSize {
+		return ErrMaxSizeExceeded
+	}
+	if app.MaxSourcePixels > 0 {
+		// fail closed that source of unknown dimensions e.g. SVG or malformed headers is rejected
+		if pixels, ok := sourcePixels(blob); !ok || pixels > app.MaxSourcePixels {
+			return ErrMaxResolutionExceeded
+		}
+	}
+	if !app.ValidateSource {
+		return nil
+	}
+	if t := blob.BlobType(); t == BlobTypeMemory || t >= BlobTypeJPEG {
+		return nil
+	}
+	contentType, _, _ := strings.Cut(blob.ContentType(), ";")
+	contentType = strings.TrimSpace(contentType)
+	for _, allowed := range app.SourcePassthroughTypes {
+		if allowed == contentType ||
+			(strings.HasSuffix(allowed, "/*") && strings.HasPrefix(contentType, allowed[:len(allowed)-1])) {
+			return nil
+		}
+	}
+	return ErrInvalidImage
+}
+
+// unsupportedSource applies UnsupportedSource policy to process error of source not supported by any processor,
+// returns source untouched for passthrough, or ErrUnsupportedMediaType for reject
+func (app *Imagor) unsupportedSource(
+	source, blob *Blob, p imagorpath.Params, err error,
+) (*Blob, bool, error) {
+	if err == nil || app.UnsupportedSource == "" || WrapError(err) != ErrUnsupportedFormat {
+		return blob, false, err
+	}
+	if app.UnsupportedSource == UnsupportedSourcePassthrough && !p.Meta && !isBlobEmpty(source) {
+		return source, true, nil
+	}
+	return nil, false, ErrUnsupportedMediaType
+}
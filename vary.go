@@ -0,0 +1,171 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxDPR upper bound of device pixel ratio client hint
+const maxDPR = 4
+
+type varyKey struct{}
+
+// varyParams request params of result before content negotiation, that Vary headers are derived from
+type varyParams struct {
+	params imagorpath.Params
+	ok     bool
+}
+
+// withVary returns request with context that records request params of result for Vary headers
+func withVary(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), varyKey{}, &varyParams{}))
+}
+
+// setVaryParams records request params of result to context if enabled
+func setVaryParams(ctx context.Context, p imagorpath.Params) {
+	if v, ok := ctx.Value(varyKey{}).(*varyParams); ok {
+		v.params = p
+		v.ok = true
+	}
+}
+
+// varyHeaders returns request headers that output of request params depends on
+func (app *Imagor) varyHeaders(p imagorpath.Params) (headers []string) {
+	if (app.AutoWebP || app.AutoAVIF || app.SaveDataQuality > 0) && !hasFilter(p, "format") {
+		headers = append(headers, "Accept")
+	}
+	if app.DPRClientHints && (p.Width > 0 || p.Height > 0) {
+		headers = append(headers, "Sec-CH-DPR", "DPR")
+	}
+	if app.MaxAutoWidth > 0 && hasFilter(p, "auto_width") {
+		headers = append(headers, "Sec-CH-Width", "Width")
+	}
+	if app.SaveDataQuality > 0 && !(hasFilter(p, "quality") && hasFilter(p, "format")) {
+		headers = append(headers, "Save-Data", "ECT")
+	}
+	return
+}
+
+// setVaryHeaders sets Vary and Accept-CH response headers of negotiated output,
+// by request params of result recorded to request context, or params with base params applied if not recorded
+func (app *Imagor) setVaryHeaders(w http.ResponseWriter, r *http.Request, p imagorpath.Params) {
+	if v, ok := r.Context().Value(varyKey{}).(*varyParams); ok && v.ok {
+		p = v.params
+	} else {
+		p = app.applyBaseParams(r, p)
+	}
+	for _, header := range app.varyHeaders(p) {
+		w.Header().Add("Vary", header)
+	}
+	var hints []string
+	if app.DPRClientHints {
+		hints = append(hints, "Sec-CH-DPR", "DPR")
+	}
+	if app.MaxAutoWidth > 0 {
+		hints = append(hints, "Sec-CH-Width", "Width")
+	}
+	if app.SaveDataQuality > 0 {
+		hints = append(hints, "ECT")
+	}
+	if len(hints) > 0 {
+		w.Header().Set("Accept-CH", strings.Join(hints, ", "))
+	}
+}
+
+// applyAutoWidth resizes width of params with auto_width([fallback]) filter
+// by Sec-CH-Width or Width client hint, or the fallback width if hint absent, capped by MaxAutoWidth.
+// Height is scaled proportionally if both dimensions set. Returns if width is resolved by the filter
+func (app *Imagor) applyAutoWidth(r *http.Request, p imagorpath.Params) (imagorpath.Params, bool) {
+	var fallback = -1
+	for _, f := range p.Filters {
+		if f.Name == "auto_width" {
+			fallback, _ = strconv.Atoi(strings.TrimSpace(f.Args))
+		}
+	}
+	if fallback < 0 {
+		return p, false
+	}
+	width := clientWidth(r)
+	if width <= 0 {
+		width = fallback
+	}
+	if width <= 0 {
+		return p, false
+	}
+	if width > app.MaxAutoWidth {
+		width = app.MaxAutoWidth
+	}
+	if p.Width > 0 && p.Height > 0 {
+		p.Height = int(math.Round(float64(p.Height) * float64(width) / float64(p.Width)))
+	}
+	p.Width = width
+	return p, true
+}
+
+// clientWidth returns layout width in device pixels from Sec-CH-Width or Width client hint, 0 if absent
+func clientWidth(r *http.Request) int {
+	v := r.Header.Get("Sec-CH-Width")
+	if v == "" {
+		v = r.Header.Get("Width")
+	}
+	width, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || math.IsNaN(width) || width <= 0 {
+		return 0
+	}
+	return int(math.Ceil(math.Min(width, math.MaxInt32)))
+}
+
+// isSaveData checks if client prefers reduced data usage by Save-Data: on,
+// or ECT client hint of slow effective connection type
+func isSaveData(r *http.Request) bool {
+	if strings.EqualFold(strings.TrimSpace(r.Header.Get("Save-Data")), "on") {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get("ECT"))) {
+	case "slow-2g", "2g", "3g":
+		return true
+	}
+	return false
+}
+
+// clientDPR returns device pixel ratio from Sec-CH-DPR or DPR client hint,
+// rounded to 1 decimal place within 1 and maxDPR
+func clientDPR(r *http.Request) float64 {
+	v := r.Header.Get("Sec-CH-DPR")
+	if v == "" {
+		v = r.Header.Get("DPR")
+	}
+	dpr, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || math.IsNaN(dpr) || dpr <= 1 {
+		return 1
+	}
+	if dpr > maxDPR {
+		dpr = maxDPR
+	}
+	return math.Round(dpr*10) / 10
+}
+
+// applyDPR scales dimensions of params by device pixel ratio client hint
+func applyDPR(r *http.Request, p imagorpath.Params) imagorpath.Params {
+	if p.Width == 0 && p.Height == 0 {
+		return p
+	}
+	if dpr := clientDPR(r); dpr > 1 {
+		p.Width = int(math.Round(float64(p.Width) * dpr))
+		p.Height = int(math.Round(float64(p.Height) * dpr))
+	}
+	return p
+}
+
+func hasFilter(p imagorpath.Params, name string) bool {
+	for _, f := range p.Filters {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
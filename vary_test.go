@@ -0,0 +1,264 @@
+package imagor
+
+import (
+	"context"
+	"github.com/cshum/imagor/imagorpath"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithDPRClientHints(t *testing.T) {
+	cache := newMapCache()
+	app := New(
+		WithUnsafe(true),
+		WithAutoWebP(true),
+		WithDPRClientHints(true),
+		WithCache(cache),
+		WithResultCacheTTL(time.Minute),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	doGet := func(path, dpr string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+path, nil)
+		r.Header.Set("Accept", "image/webp,*/*")
+		if dpr != "" {
+			r.Header.Set("Sec-CH-DPR", dpr)
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	for _, tt := range []struct {
+		path, dpr, expected string
+	}{
+		{"100x50/foo.png", "", "100x50/filters:format(webp)/foo.png"},
+		{"100x50/foo.png", "2", "200x100/filters:format(webp)/foo.png"},
+		{"100x0/foo.png", "1.5", "150x0/filters:format(webp)/foo.png"},
+		{"100x50/foo.png", "10", "400x200/filters:format(webp)/foo.png"},
+		{"100x50/foo.png", "0.5", "100x50/filters:format(webp)/foo.png"},
+		{"100x50/foo.png", "abc", "100x50/filters:format(webp)/foo.png"},
+		{"foo.png", "2", "filters:format(webp)/foo.png"},
+	} {
+		w := doGet(tt.path, tt.dpr)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, tt.expected, w.Body.String(), tt.dpr)
+		assert.Equal(t, "Sec-CH-DPR, DPR", w.Header().Get("Accept-CH"))
+	}
+	cache.l.Lock()
+	assert.NotNil(t, cache.Map["result:200x100/filters:format(webp)/foo.png"])
+	cache.l.Unlock()
+
+	w := doGet("100x50/foo.png", "2")
+	assert.Equal(t, []string{"Accept", "Sec-CH-DPR", "DPR"}, w.Header().Values("Vary"))
+	w = doGet("filters:format(png)/foo.png", "2")
+	assert.Empty(t, w.Header().Values("Vary"))
+}
+
+func TestWithDPRClientHintsAllowedSizes(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithDPRClientHints(true),
+		WithAllowedSizes("100x50", "200x100", "300x0"),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	for _, tt := range []struct {
+		path, dpr, expected string
+	}{
+		{"100x50/foo.png", "2", "200x100/foo.png"},
+		{"100x50/foo.png", "1.5", "200x100/foo.png"},
+		{"100x50/foo.png", "4", "200x100/foo.png"},
+		{"300x0/foo.png", "2", "300x0/foo.png"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+tt.path, nil)
+		r.Header.Set("Sec-CH-DPR", tt.dpr)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		assert.Equal(t, 200, w.Code)
+		assert.Equal(t, tt.expected, w.Body.String(), tt.path+" "+tt.dpr)
+	}
+}
+
+func TestWithSaveDataQuality(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithSaveDataQuality(40),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	assert.Equal(t, 40, app.SaveDataQuality)
+	doGet := func(path string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+path, nil)
+		r.Header.Set("Accept", "image/webp,*/*")
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	for _, tt := range []struct {
+		name, path string
+		header     http.Header
+		expected   string
+	}{
+		{"no hint", "100x50/foo.png", nil, "100x50/foo.png"},
+		{"save data", "100x50/foo.png", http.Header{"Save-Data": {"on"}}, "100x50/filters:format(webp):quality(40)/foo.png"},
+		{"save data off", "100x50/foo.png", http.Header{"Save-Data": {"off"}}, "100x50/foo.png"},
+		{"slow ect", "foo.png", http.Header{"Ect": {"2g"}}, "filters:format(webp):quality(40)/foo.png"},
+		{"fast ect", "foo.png", http.Header{"Ect": {"4g"}}, "foo.png"},
+		{"avif", "foo.png", http.Header{"Save-Data": {"on"}, "Accept": {"image/avif,image/webp"}}, "filters:format(avif):quality(40)/foo.png"},
+		{"explicit quality", "filters:quality(90)/foo.png", http.Header{"Save-Data": {"on"}}, "filters:format(webp):quality(90)/foo.png"},
+		{"explicit format", "filters:format(png)/foo.png", http.Header{"Save-Data": {"on"}}, "filters:format(png):quality(40)/foo.png"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doGet(tt.path, tt.header)
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, tt.expected, w.Body.String())
+			assert.Equal(t, "ECT", w.Header().Get("Accept-CH"))
+		})
+	}
+	w := doGet("foo.png", nil)
+	assert.Equal(t, []string{"Accept", "Save-Data", "ECT"}, w.Header().Values("Vary"))
+	w = doGet("filters:format(png):quality(80)/foo.png", nil)
+	assert.Empty(t, w.Header().Values("Vary"))
+}
+
+func TestWithMaxAutoWidth(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithMaxAutoWidth(1200),
+		WithDPRClientHints(true),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	assert.Equal(t, 1200, app.MaxAutoWidth)
+	doGet := func(path string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+path, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	for _, tt := range []struct {
+		name, path string
+		header     http.Header
+		expected   string
+	}{
+		{"width hint", "filters:auto_width()/foo.png", http.Header{"Sec-Ch-Width": {"640"}}, "640x0/foo.png"},
+		{"legacy width hint", "filters:auto_width()/foo.png", http.Header{"Width": {"320.5"}}, "321x0/foo.png"},
+		{"capped", "filters:auto_width()/foo.png", http.Header{"Sec-Ch-Width": {"5000"}}, "1200x0/foo.png"},
+		{"proportional height", "400x300/filters:auto_width()/foo.png", http.Header{"Sec-Ch-Width": {"800"}}, "800x600/foo.png"},
+		{"no dpr on hinted width", "filters:auto_width()/foo.png", http.Header{"Sec-Ch-Width": {"640"}, "Sec-Ch-Dpr": {"2"}}, "640x0/foo.png"},
+		{"fallback", "filters:auto_width(480)/foo.png", nil, "480x0/foo.png"},
+		{"url width", "300x0/filters:auto_width()/foo.png", nil, "300x0/foo.png"},
+		{"url width with dpr", "300x0/filters:auto_width()/foo.png", http.Header{"Sec-Ch-Dpr": {"2"}}, "600x0/foo.png"},
+		{"invalid hint", "filters:auto_width()/foo.png", http.Header{"Sec-Ch-Width": {"abc"}}, "foo.png"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doGet(tt.path, tt.header)
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, tt.expected, w.Body.String())
+			assert.Equal(t, "Sec-CH-DPR, DPR, Sec-CH-Width, Width", w.Header().Get("Accept-CH"))
+		})
+	}
+	w := doGet("filters:auto_width()/foo.png", nil)
+	assert.Equal(t, []string{"Sec-CH-Width", "Width"}, w.Header().Values("Vary"))
+	w = doGet("foo.png", nil)
+	assert.Empty(t, w.Header().Values("Vary"))
+}
+
+func TestWithMaxAutoWidthAllowedSizes(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithMaxAutoWidth(1200),
+		WithAllowedSizes("320x0", "640x0", "1024x0", "400x300", "800x600", "200x200"),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	for _, tt := range []struct {
+		name, path, width, expected string
+	}{
+		{"exact", "filters:auto_width()/foo.png", "640", "640x0/foo.png"},
+		{"snap up", "filters:auto_width()/foo.png", "641", "1024x0/foo.png"},
+		{"snap largest", "filters:auto_width()/foo.png", "1100", "1024x0/foo.png"},
+		{"snap aspect", "400x300/filters:auto_width()/foo.png", "500", "800x600/foo.png"},
+		{"snap largest aspect", "400x300/filters:auto_width()/foo.png", "2000", "800x600/foo.png"},
+		{"no matching size", "200x200/filters:auto_width()/foo.png", "500", "200x200/foo.png"},
+		{"no hint", "filters:auto_width()/foo.png", "", "foo.png"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "https://example.com/unsafe/"+tt.path, nil)
+			r.Header.Set("Sec-CH-Width", tt.width)
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, tt.expected, w.Body.String())
+		})
+	}
+}
+
+func TestVaryResultParams(t *testing.T) {
+	app := New(
+		WithUnsafe(true),
+		WithAutoWebP(true),
+		WithDPRClientHints(true),
+		WithTenant(Tenant{Name: "brand", Hosts: []string{"img.brand.com"}, BaseParams: "filters:format(png)"}),
+		WithParamsHook(func(r *http.Request, p imagorpath.Params) (imagorpath.Params, error) {
+			if strings.HasPrefix(p.Image, "static/") {
+				p.Width, p.Height = 0, 0
+				p.Filters = append(p.Filters, imagorpath.Filter{Name: "format", Args: "jpg"})
+			}
+			return p, nil
+		}),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			return NewBlobFromBytes([]byte("foo")), nil
+		})),
+		WithProcessors(processorFunc(func(ctx context.Context, blob *Blob, p imagorpath.Params, load LoadFunc) (*Blob, error) {
+			return NewBlobFromBytes([]byte(p.Path)), nil
+		})),
+	)
+	for _, tt := range []struct {
+		name, url string
+		vary      []string
+	}{
+		{"default", "https://example.com/unsafe/100x50/foo.png", []string{"Accept", "Sec-CH-DPR", "DPR"}},
+		{"tenant format", "https://img.brand.com/unsafe/100x50/foo.png", []string{"Sec-CH-DPR", "DPR"}},
+		{"params hook", "https://example.com/unsafe/100x50/static/foo.png", nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			r.Header.Set("Accept", "image/webp,*/*")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+			assert.Equal(t, 200, w.Code)
+			assert.Equal(t, tt.vary, w.Header().Values("Vary"))
+		})
+	}
+}
@@ -226,6 +226,11 @@ func (r *Image) Height() int {
 	return int(r.image.Ysize)
 }
 
+// Bands returns the number of bands of this image.
+func (r *Image) Bands() int {
+	return int(r.image.Bands)
+}
+
 // HasAlpha returns if the image has an alpha layer.
 func (r *Image) HasAlpha() bool {
 	return vipsHasAlpha(r.image)
@@ -717,6 +722,43 @@ func (r *Image) Replicate(across int, down int) error {
 	return nil
 }
 
+// FilterMemory passes the uchar pixel buffer of width x height x bands interleaved to fn,
+// then replaces the image by the pixel buffer modified in place.
+// Pages of animated image are stacked vertically.
+func (r *Image) FilterMemory(fn func(buf []byte, width, height, bands int) error) error {
+	// pixels are passed as uchar of sRGB or B_W,
+	// other color spaces e.g. 16-bit, scRGB, CMYK or LAB are converted
+	if i := r.Interpretation(); i != InterpretationSRGB && i != InterpretationBW {
+		space := InterpretationSRGB
+		if i == InterpretationGrey16 || r.Bands() < 3 {
+			space = InterpretationBW
+		}
+		if err := r.ToColorSpace(space); err != nil {
+			return err
+		}
+		// profile of the source color space does not apply to converted pixels
+		if err := r.RemoveICCProfile(); err != nil {
+			return err
+		}
+	}
+	buf, err := vipsImageToMemory(r.image)
+	if err != nil {
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	if err = fn(buf, r.Width(), r.Height(), r.Bands()); err != nil {
+		return err
+	}
+	out, err := vipsImageReplaceMemory(r.image, buf)
+	if err != nil {
+		return err
+	}
+	r.setImage(out)
+	return nil
+}
+
 // setImage resets the image for this image and frees the previous one
 func (r *Image) setImage(image *C.VipsImage) {
 	r.lock.Lock()
@@ -13,6 +13,14 @@ func WithFilter(name string, filter FilterFunc) Option {
 	}
 }
 
+// WithFilterPlugin registers filter of name, with plugin started up and shut down along with the processor
+func WithFilterPlugin(name string, filter FilterFunc, plugin Plugin) Option {
+	return func(v *Processor) {
+		v.Filters[name] = filter
+		v.Plugins = append(v.Plugins, plugin)
+	}
+}
+
 func WithDisableBlur(disabled bool) Option {
 	return func(v *Processor) {
 		v.DisableBlur = disabled
@@ -87,6 +95,36 @@ func WithMaxCacheMem(num int) Option {
 	}
 }
 
+// WithTolerant decodes truncated or corrupt images best-effort with missing regions filled,
+// instead of failing the request, flagged as damaged in metadata
+func WithTolerant(tolerant bool) Option {
+	return func(v *Processor) {
+		v.Tolerant = tolerant
+	}
+}
+
+// WithReportLeaks reports leaked vips objects and memory on shutdown, for debugging
+func WithReportLeaks(enabled bool) Option {
+	return func(v *Processor) {
+		v.ReportLeaks = enabled
+	}
+}
+
+// WithCacheTrace logs vips operation cache hits and misses, for debugging
+func WithCacheTrace(enabled bool) Option {
+	return func(v *Processor) {
+		v.CacheTrace = enabled
+	}
+}
+
+// WithDisableSIMD disables SIMD vector paths of vips operations,
+// e.g. for CPUs where the runtime generated code misbehaves
+func WithDisableSIMD(disabled bool) Option {
+	return func(v *Processor) {
+		v.DisableSIMD = disabled
+	}
+}
+
 func WithLogger(logger *zap.Logger) Option {
 	return func(v *Processor) {
 		if logger != nil {
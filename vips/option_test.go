@@ -20,6 +20,10 @@ func TestWithOption(t *testing.T) {
 			WithMaxHeight(998),
 			WithMaxResolution(1666667),
 			WithMozJPEG(true),
+			WithReportLeaks(true),
+			WithCacheTrace(true),
+			WithDisableSIMD(true),
+			WithTolerant(true),
 			WithDebug(true),
 			WithMaxAnimationFrames(3),
 			WithDisableFilters("rgb", "fill, watermark"),
@@ -37,6 +41,10 @@ func TestWithOption(t *testing.T) {
 		assert.Equal(t, 1666667, v.MaxResolution)
 		assert.Equal(t, 3, v.MaxAnimationFrames)
 		assert.Equal(t, true, v.MozJPEG)
+		assert.True(t, v.ReportLeaks)
+		assert.True(t, v.CacheTrace)
+		assert.True(t, v.DisableSIMD)
+		assert.True(t, v.Tolerant)
 		assert.Equal(t, []string{"rgb", "fill", "watermark"}, v.DisableFilters)
 
 	})
@@ -30,6 +30,20 @@ var imageTypeMap = map[string]ImageType{
 
 func (v *Processor) Process(
 	ctx context.Context, blob *imagor.Blob, p imagorpath.Params, load imagor.LoadFunc,
+) (*imagor.Blob, error) {
+	out, err := v.processBlob(ctx, blob, p, load)
+	if err != nil && v.Tolerant && isDecodeError(err) {
+		// retry with best-effort decoding of truncated or corrupt image
+		if v.Debug {
+			v.Logger.Debug("tolerant", zap.String("image", p.Image), zap.Error(err))
+		}
+		return v.processBlob(withTolerant(ctx), blob, p, load)
+	}
+	return out, err
+}
+
+func (v *Processor) processBlob(
+	ctx context.Context, blob *imagor.Blob, p imagorpath.Params, load imagor.LoadFunc,
 ) (*imagor.Blob, error) {
 	ctx = vipscontext.WithContext(ctx)
 	defer vipscontext.Done(ctx)
@@ -279,8 +293,16 @@ func (v *Processor) Process(
 		return nil, WrapErr(err)
 	}
 	if p.Meta {
-		// metadata without export
-		return imagor.NewBlobFromJsonMarshal(metadata(img, format, stripExif)), nil
+		meta := metadata(img, format, stripExif)
+		meta.SourceBytes = blob.Size()
+		meta.Damaged = isTolerant(ctx)
+		// byte size of output as if served without meta
+		buf, err := v.export(img, supportedSaveFormat(format), quality)
+		if err != nil {
+			return nil, WrapErr(err)
+		}
+		meta.OutputBytes = len(buf)
+		return imagor.NewBlobFromJsonMarshal(meta), nil
 	}
 	format = supportedSaveFormat(format) // convert to supported export format
 	for {
@@ -472,10 +494,7 @@ func (v *Processor) process(
 			break
 		}
 		start := time.Now()
-		var args []string
-		if filter.Args != "" {
-			args = strings.Split(filter.Args, ",")
-		}
+		args := filter.SplitArgs()
 		if fn := v.Filters[filter.Name]; fn != nil {
 			if err := fn(ctx, img, load, args...); err != nil {
 				return err
@@ -504,6 +523,10 @@ type Metadata struct {
 	Height      int            `json:"height"`
 	Orientation int            `json:"orientation"`
 	Pages       int            `json:"pages"`
+	HasAlpha    bool           `json:"has_alpha"`
+	Damaged     bool           `json:"damaged,omitempty"`
+	SourceBytes int64          `json:"source_bytes,omitempty"`
+	OutputBytes int            `json:"output_bytes"`
 	Exif        map[string]any `json:"exif"`
 }
 
@@ -523,6 +546,7 @@ func metadata(img *Image, format ImageType, stripExif bool) *Metadata {
 		Height:      img.PageHeight(),
 		Pages:       pages,
 		Orientation: img.Orientation(),
+		HasAlpha:    img.HasAlpha(),
 		Exif:        exif,
 	}
 }
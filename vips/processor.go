@@ -2,6 +2,7 @@ package vips
 
 import (
 	"context"
+	"errors"
 	"github.com/cshum/imagor"
 	"github.com/cshum/imagor/vips/vipscontext"
 	"go.uber.org/zap"
@@ -15,11 +16,18 @@ type FilterFunc func(ctx context.Context, img *Image, load imagor.LoadFunc, args
 
 type FilterMap map[string]FilterFunc
 
+// Plugin lifecycle of filter resources, started up and shut down along with the processor
+type Plugin interface {
+	Startup(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
 var processorLock sync.RWMutex
 var processorCount int
 
 type Processor struct {
 	Filters            FilterMap
+	Plugins            []Plugin
 	DisableBlur        bool
 	DisableFilters     []string
 	MaxFilterOps       int
@@ -33,6 +41,10 @@ type Processor struct {
 	MaxResolution      int
 	MaxAnimationFrames int
 	MozJPEG            bool
+	Tolerant           bool
+	ReportLeaks        bool
+	CacheTrace         bool
+	DisableSIMD        bool
 	Debug              bool
 
 	disableFilters map[string]bool
@@ -86,12 +98,22 @@ func NewProcessor(options ...Option) *Processor {
 	return v
 }
 
-func (v *Processor) Startup(_ context.Context) error {
+func (v *Processor) Startup(ctx context.Context) error {
+	v.startupVips()
+	for _, plugin := range v.Plugins {
+		if err := plugin.Startup(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *Processor) startupVips() {
 	processorLock.Lock()
 	defer processorLock.Unlock()
 	processorCount++
 	if processorCount > 1 {
-		return nil
+		return
 	}
 	if v.Debug {
 		SetLogging(func(domain string, level LogLevel, msg string) {
@@ -114,21 +136,32 @@ func (v *Processor) Startup(_ context.Context) error {
 		MaxCacheMem:      v.MaxCacheMem,
 		MaxCacheSize:     v.MaxCacheSize,
 		ConcurrencyLevel: v.Concurrency,
+		ReportLeaks:      v.ReportLeaks,
+		CacheTrace:       v.CacheTrace,
+		DisableVector:    v.DisableSIMD,
 	})
-	return nil
 }
 
-func (v *Processor) Shutdown(_ context.Context) error {
+func (v *Processor) Shutdown(ctx context.Context) (err error) {
+	for _, plugin := range v.Plugins {
+		if e := plugin.Shutdown(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	v.shutdownVips()
+	return
+}
+
+func (v *Processor) shutdownVips() {
 	processorLock.Lock()
 	defer processorLock.Unlock()
 	if processorCount <= 0 {
-		return nil
+		return
 	}
 	processorCount--
 	if processorCount == 0 {
 		Shutdown()
 	}
-	return nil
 }
 
 func newImageFromBlob(
@@ -147,7 +180,7 @@ func newImageFromBlob(
 		}
 		src := NewSource(reader)
 		vipscontext.Defer(ctx, src.Close)
-		return src.LoadImage(params)
+		return src.LoadImage(tolerantParams(ctx, params))
 	}
 }
 
@@ -164,7 +197,7 @@ func newThumbnailFromBlob(
 	}
 	src := NewSource(reader)
 	vipscontext.Defer(ctx, src.Close)
-	return src.LoadThumbnail(width, height, crop, size, params)
+	return src.LoadThumbnail(width, height, crop, size, tolerantParams(ctx, params))
 }
 
 func (v *Processor) NewThumbnail(
@@ -333,11 +366,20 @@ func isBlobAnimated(blob *imagor.Blob, n int) bool {
 	return blob != nil && blob.SupportsAnimation() && n != 1 && n != 0
 }
 
+// Health checks if libvips is initialized
+func (v *Processor) Health(_ context.Context) error {
+	if !IsRunning() {
+		return errors.New("vips: not running")
+	}
+	return nil
+}
+
 func WrapErr(err error) error {
 	if err == nil {
 		return nil
 	}
-	if e, ok := err.(imagor.Error); ok {
+	var e imagor.Error
+	if errors.As(err, &e) {
 		return e
 	}
 	msg := strings.TrimSpace(err.Error())
@@ -2,6 +2,7 @@ package vips
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/cshum/imagor"
 	"github.com/cshum/imagor/imagorpath"
@@ -388,6 +389,17 @@ func doGoldenTests(t *testing.T, resultDir string, tests []test, opts ...Option)
 			if reflect.DeepEqual(buf, w.Body.Bytes()) {
 				return
 			}
+			if bc.BlobType() == imagor.BlobTypeJSON {
+				// output bytes vary across encoder versions
+				var m1, m2 map[string]any
+				require.NoError(t, json.Unmarshal(buf, &m1))
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &m2))
+				assert.Greater(t, m2["output_bytes"], float64(0))
+				delete(m1, "output_bytes")
+				delete(m2, "output_bytes")
+				assert.Equal(t, m1, m2)
+				return
+			}
 			img1, err := LoadImageFromBuffer(buf, nil)
 			require.NoError(t, err)
 			img2, err := LoadImageFromBuffer(w.Body.Bytes(), nil)
@@ -0,0 +1,37 @@
+package vips
+
+import (
+	"github.com/cshum/imagor"
+)
+
+func init() {
+	imagor.RegisterComponent("vips", newComponent)
+}
+
+// newComponent creates Processor of component options
+func newComponent(o imagor.ComponentOptions) (any, error) {
+	var options []Option
+	for key, option := range map[string]func(int) Option{
+		"concurrency":          WithConcurrency,
+		"max_width":            WithMaxWidth,
+		"max_height":           WithMaxHeight,
+		"max_resolution":       WithMaxResolution,
+		"max_animation_frames": WithMaxAnimationFrames,
+		"max_filter_ops":       WithMaxFilterOps,
+	} {
+		n, err := o.Int(key)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option(n))
+	}
+	mozJPEG, err := o.Bool("mozjpeg")
+	if err != nil {
+		return nil, err
+	}
+	options = append(options,
+		WithMozJPEG(mozJPEG),
+		WithDisableFilters(o.Strings("disable_filters")...),
+	)
+	return NewProcessor(options...), nil
+}
@@ -46,6 +46,7 @@ type config struct {
 	MaxCacheSize     int
 	ReportLeaks      bool
 	CacheTrace       bool
+	DisableVector    bool
 }
 
 // Startup sets up the libvips support and ensures the versions are correct. Pass in nil for
@@ -111,6 +112,10 @@ func Startup(config *config) {
 		if config.CacheTrace {
 			C.vips_cache_set_trace(toGboolean(true))
 		}
+
+		if config.DisableVector {
+			C.vips_vector_set_enabled(toGboolean(false))
+		}
 	} else {
 		C.vips_concurrency_set(defaultConcurrencyLevel)
 		C.vips_cache_set_max(defaultMaxCacheSize)
@@ -118,12 +123,13 @@ func Startup(config *config) {
 		C.vips_cache_set_max_files(defaultMaxCacheFiles)
 	}
 
-	log("vips", LogLevelInfo, fmt.Sprintf("vips %s started with concurrency=%d cache_max_files=%d cache_max_mem=%d cache_max=%d",
+	log("vips", LogLevelInfo, fmt.Sprintf("vips %s started with concurrency=%d cache_max_files=%d cache_max_mem=%d cache_max=%d vector=%t",
 		Version,
 		int(C.vips_concurrency_get()),
 		int(C.vips_cache_get_max_files()),
 		int(C.vips_cache_get_max_mem()),
-		int(C.vips_cache_get_max())))
+		int(C.vips_cache_get_max()),
+		fromGboolean(C.vips_vector_isenabled())))
 
 	cType := C.CString("VipsOperation")
 	defer freeCString(cType)
@@ -188,3 +194,10 @@ func ReadVipsMemStats(stats *MemoryStats) {
 	stats.Allocs = int64(C.vips_tracked_get_allocs())
 	stats.Files = int64(C.vips_tracked_get_files())
 }
+
+// IsRunning returns true if libvips started up and not yet shut down
+func IsRunning() bool {
+	lock.Lock()
+	defer lock.Unlock()
+	return isStarted && !isShutdown
+}
@@ -0,0 +1,54 @@
+package vips
+
+import (
+	"context"
+	"errors"
+	"github.com/cshum/imagor"
+	"net/http"
+	"strings"
+)
+
+type tolerantKey struct{}
+
+// withTolerant returns context of tolerant decoding
+func withTolerant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tolerantKey{}, true)
+}
+
+// isTolerant checks if context is of tolerant decoding
+func isTolerant(ctx context.Context) bool {
+	tolerant, _ := ctx.Value(tolerantKey{}).(bool)
+	return tolerant
+}
+
+// tolerantParams returns import params not failing on decode errors for tolerant context,
+// where libvips decodes best-effort with missing regions filled
+func tolerantParams(ctx context.Context, params *ImportParams) *ImportParams {
+	if !isTolerant(ctx) {
+		return params
+	}
+	if params == nil {
+		params = NewImportParams()
+	}
+	params.FailOnError.Set(false)
+	return params
+}
+
+// isDecodeError checks if error is of decoding truncated or corrupt image,
+// excluding unsupported format, resolution limits and timeouts
+func isDecodeError(err error) bool {
+	if err == nil || errors.Is(err, imagor.ErrUnsupportedFormat) {
+		return false
+	}
+	var e imagor.Error
+	if errors.As(err, &e) && e.Code != http.StatusNotAcceptable {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"load", "premature end", "truncated", "corrupt", "libpng", "vipsjpeg", "read error"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
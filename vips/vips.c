@@ -49,6 +49,44 @@ int image_new_from_memory(const void *buf, size_t len, int width, int height, in
   return 0;
 }
 
+int image_write_to_memory(VipsImage *in, void **buf, size_t *len) {
+  VipsImage *base = vips_image_new();
+  VipsImage **t = (VipsImage **)vips_object_local_array(VIPS_OBJECT(base), 1);
+  if (vips_cast(in, &t[0], VIPS_FORMAT_UCHAR, NULL) ||
+      !(*buf = vips_image_write_to_memory(t[0], len))) {
+    g_object_unref(base);
+    return 1;
+  }
+  g_object_unref(base);
+  return 0;
+}
+
+// metadata kept by image_replace_memory, for animation and export of the replaced pixels
+static const char *replace_memory_fields[] = {
+  VIPS_META_PAGE_HEIGHT, VIPS_META_N_PAGES, "delay", "loop",
+  VIPS_META_ICC_NAME, VIPS_META_ORIENTATION, NULL
+};
+
+int image_replace_memory(VipsImage *in, const void *buf, size_t len, VipsImage **out) {
+  VipsImage *base = vips_image_new();
+  VipsImage **t = (VipsImage **)vips_object_local_array(VIPS_OBJECT(base), 1);
+  if (!(t[0] = vips_image_new_from_memory_copy(buf, len, in->Xsize, in->Ysize, in->Bands, VIPS_FORMAT_UCHAR)) ||
+      vips_copy(t[0], out, "interpretation", in->Type, "xres", in->Xres, "yres", in->Yres, NULL)) {
+    g_object_unref(base);
+    return 1;
+  }
+  g_object_unref(base);
+  for (int i = 0; replace_memory_fields[i]; i++) {
+    GValue value = { 0 };
+    if (vips_image_get_typeof(in, replace_memory_fields[i]) &&
+        !vips_image_get(in, replace_memory_fields[i], &value)) {
+      vips_image_set(*out, replace_memory_fields[i], &value);
+      g_value_unset(&value);
+    }
+  }
+  return 0;
+}
+
 int image_new_from_buffer_with_option(const void *buf, size_t len, VipsImage **out, const char *option_string) {
   *out = vips_image_new_from_buffer(buf, len, option_string, NULL);
   if (!*out) return 1;
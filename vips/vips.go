@@ -79,6 +79,30 @@ func vipsImageFromMemory(buf []byte, width, height, bands int) (*C.VipsImage, Im
 	return out, imageType, nil
 }
 
+// https://www.libvips.org/API/current/VipsImage.html#vips-image-write-to-memory
+func vipsImageToMemory(in *C.VipsImage) ([]byte, error) {
+	var buf unsafe.Pointer
+	var length C.size_t
+
+	if err := C.image_write_to_memory(in, &buf, &length); err != 0 {
+		return nil, handleVipsError()
+	}
+	defer gFreePointer(buf)
+
+	return C.GoBytes(buf, C.int(length)), nil
+}
+
+// https://www.libvips.org/API/current/VipsImage.html#vips-image-new-from-memory-copy
+func vipsImageReplaceMemory(in *C.VipsImage, buf []byte) (*C.VipsImage, error) {
+	var out *C.VipsImage
+
+	if err := C.image_replace_memory(in, unsafe.Pointer(&buf[0]), C.size_t(len(buf)), &out); err != 0 {
+		return nil, handleImageError(out)
+	}
+
+	return out, nil
+}
+
 // https://www.libvips.org/API/current/libvips-resample.html#vips-thumbnail-source
 func vipsThumbnailFromSource(
 	src *C.VipsSourceCustom, width, height int, crop Interesting, size Size, params *ImportParams) (*C.VipsImage, ImageType, error) {
@@ -20,6 +20,10 @@ int image_new_from_buffer_with_option(const void *buf, size_t len, VipsImage **o
 
 int image_new_from_memory(const void *buf, size_t len, int width, int height, int bands, VipsImage **out);
 
+int image_write_to_memory(VipsImage *in, void **buf, size_t *len);
+
+int image_replace_memory(VipsImage *in, const void *buf, size_t len, VipsImage **out);
+
 int thumbnail(const char *filename, VipsImage **out, int width, int height,
                     int crop, int size);
 int thumbnail_image(VipsImage *in, VipsImage **out, int width, int height,
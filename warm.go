@@ -0,0 +1,70 @@
+package imagor
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// WarmResult status of a derivative pre-generated by Warm
+type WarmResult struct {
+	Size   string `json:"size"`
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type warmRequest struct {
+	Image string   `json:"image"`
+	Sizes []string `json:"sizes"`
+}
+
+// Warm generates and stores derivatives of the image ahead of traffic.
+// Each size is either a preset name or imagor params e.g. fit-in/200x200/filters:format(webp)
+func (app *Imagor) Warm(ctx context.Context, image string, sizes []string) []WarmResult {
+	var results = make([]WarmResult, len(sizes))
+	parallel(len(sizes), app.BatchConcurrency, func(i int) {
+		results[i] = app.warm(ctx, image, sizes[i])
+	})
+	return results
+}
+
+func (app *Imagor) warm(ctx context.Context, image, size string) WarmResult {
+	var res = WarmResult{Size: size, Status: http.StatusOK}
+	var params = size
+	if preset, ok := app.Presets[size]; ok {
+		params = preset
+	}
+	params = strings.Trim(params, "/")
+	if params != "" {
+		params += "/"
+	}
+	p := app.trustedParams(params + strings.TrimLeft(image, "/"))
+	res.Path = p.Path
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// warm-up is trusted, authorized by warm secret or called in Go
+	if _, err := app.ServeBlob(ctx, p); err != nil {
+		e := WrapError(err)
+		res.Status = e.Code
+		res.Error = e.Message
+	}
+	return res
+}
+
+// serveWarm handles POST /warm of image and sizes
+func (app *Imagor) serveWarm(w http.ResponseWriter, r *http.Request) {
+	var req warmRequest
+	var err error
+	if !isBearerAuthorized(r, app.WarmSecret) {
+		err = ErrUnauthorized
+	} else if err = readJSONBody(r, &req); err == nil &&
+		(req.Image == "" || len(req.Sizes) == 0 || len(req.Sizes) > maxBatchSize) {
+		err = ErrInvalid
+	}
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, app.Warm(r.Context(), req.Image, req.Sizes))
+}
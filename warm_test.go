@@ -0,0 +1,59 @@
+package imagor
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWarm(t *testing.T) {
+	resultStore := newMapStore()
+	app := New(
+		WithWarm("warm3"),
+		WithPreset("thumb", "/fit-in/100x100/"),
+		WithPreset("webp", "200x200/filters:format(webp)"),
+		WithResultStorages(resultStore),
+		WithLoaders(loaderFunc(func(r *http.Request, image string) (*Blob, error) {
+			if image == "missing.jpg" {
+				return nil, ErrNotFound
+			}
+			return NewBlobFromBytes([]byte(image)), nil
+		})),
+	)
+	results := app.Warm(context.Background(), "foo/bar.jpg", []string{"thumb", "webp", "1000x1000"})
+	assert.Equal(t, []WarmResult{
+		{Size: "thumb", Path: "fit-in/100x100/foo/bar.jpg", Status: 200},
+		{Size: "webp", Path: "200x200/filters:format(webp)/foo/bar.jpg", Status: 200},
+		{Size: "1000x1000", Path: "1000x1000/foo/bar.jpg", Status: 200},
+	}, results)
+	assert.Contains(t, resultStore.Map, "fit-in/100x100/foo/bar.jpg")
+	assert.Contains(t, resultStore.Map, "200x200/filters:format(webp)/foo/bar.jpg")
+	assert.Contains(t, resultStore.Map, "1000x1000/foo/bar.jpg")
+
+	doPost := func(token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "https://example.com/warm", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		app.ServeHTTP(w, r)
+		return w
+	}
+	w := doPost("wrong", `{"image":"foo/bar.jpg","sizes":["thumb"]}`)
+	assert.Equal(t, 401, w.Code)
+
+	w = doPost("warm3", `{"image":"foo/bar.jpg"}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Equal(t, jsonStr(ErrInvalid), w.Body.String())
+
+	w = doPost("warm3", `{"image":"missing.jpg","sizes":["thumb"]}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, jsonStr([]WarmResult{
+		{Size: "thumb", Path: "fit-in/100x100/missing.jpg", Status: 404, Error: ErrNotFound.Message},
+	}), w.Body.String())
+
+	w = httptest.NewRecorder()
+	app.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "https://example.com/batch", strings.NewReader(`[]`)))
+	assert.Equal(t, 405, w.Code)
+}
@@ -0,0 +1,13 @@
+package wasmfilter
+
+// Option wasm filter runtime option
+type Option func(rt *Runtime)
+
+// WithMemoryLimitPages limits memory of each filter module instance by 64KB pages
+func WithMemoryLimitPages(pages uint32) Option {
+	return func(rt *Runtime) {
+		if pages > 0 {
+			rt.MemoryLimitPages = pages
+		}
+	}
+}
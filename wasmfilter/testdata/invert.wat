@@ -0,0 +1,29 @@
+;; invert filter inverts all bands of the pixel buffer,
+;; fails with code of the first args byte if args is set
+(module
+  (memory (export "memory") 1)
+
+  ;; alloc grows memory by pages of size, returns offset of the new pages
+  (func (export "alloc") (param $size i32) (result i32)
+    (i32.mul
+      (memory.grow
+        (i32.shr_u (i32.add (local.get $size) (i32.const 65535)) (i32.const 16)))
+      (i32.const 65536)))
+
+  (func (export "filter")
+    (param $ptr i32) (param $width i32) (param $height i32) (param $bands i32)
+    (param $args_ptr i32) (param $args_len i32) (result i32)
+    (local $end i32)
+    (if (local.get $args_len)
+      (then (return (i32.load8_u (local.get $args_ptr)))))
+    (local.set $end
+      (i32.add (local.get $ptr)
+        (i32.mul (i32.mul (local.get $width) (local.get $height)) (local.get $bands))))
+    (block $done
+      (loop $loop
+        (br_if $done (i32.ge_u (local.get $ptr) (local.get $end)))
+        (i32.store8 (local.get $ptr)
+          (i32.sub (i32.const 255) (i32.load8_u (local.get $ptr))))
+        (local.set $ptr (i32.add (local.get $ptr) (i32.const 1)))
+        (br $loop)))
+    (i32.const 0)))
@@ -0,0 +1,156 @@
+package wasmfilter
+
+import (
+	"context"
+	"fmt"
+	"github.com/cshum/imagor"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound wasm filter module of name not loaded
+var ErrNotFound = imagor.NewError("wasm filter not found", http.StatusBadRequest)
+
+// Runtime runs pixel buffer filters of WebAssembly modules, loaded by file name without .wasm extension.
+//
+// A filter module exports its "memory", and functions of the ABI:
+//
+//	alloc(size i32) i32
+//	filter(ptr, width, height, bands, args_ptr, args_len i32) i32
+//
+// The host allocates the uchar pixel buffer of width x height x bands interleaved,
+// and the args string, by alloc. filter transforms the pixel buffer in place,
+// returns 0 on success or non-zero error code.
+// Each filter call runs in a new module instance that shares no state with other calls.
+// Modules are compiled on Startup and closed on Shutdown
+type Runtime struct {
+	Dir              string
+	MemoryLimitPages uint32
+
+	runtime wazero.Runtime
+	modules map[string]wazero.CompiledModule
+}
+
+// New creates runtime of WebAssembly filter modules of *.wasm files in dir
+func New(dir string, options ...Option) *Runtime {
+	rt := &Runtime{Dir: dir}
+	for _, option := range options {
+		option(rt)
+	}
+	return rt
+}
+
+// Startup compiles the filter modules, fails on module not compiled or missing exports of the ABI
+func (rt *Runtime) Startup(ctx context.Context) error {
+	config := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if rt.MemoryLimitPages > 0 {
+		config = config.WithMemoryLimitPages(rt.MemoryLimitPages)
+	}
+	rt.runtime = wazero.NewRuntimeWithConfig(ctx, config)
+	rt.modules = map[string]wazero.CompiledModule{}
+	// WASI for modules of toolchains that depend on it, e.g. TinyGo or Rust wasm32-wasi
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, rt.runtime); err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(rt.Dir, "*.wasm"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err = rt.compile(ctx, file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rt *Runtime) compile(ctx context.Context, file string) error {
+	name := strings.TrimSuffix(filepath.Base(file), ".wasm")
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	mod, err := rt.runtime.CompileModule(ctx, buf)
+	if err != nil {
+		return fmt.Errorf("wasmfilter: %s: %w", name, err)
+	}
+	exports := mod.ExportedFunctions()
+	if _, ok := mod.ExportedMemories()["memory"]; !ok {
+		return fmt.Errorf("wasmfilter: %s: memory not exported", name)
+	}
+	for _, fn := range []string{"alloc", "filter"} {
+		if _, ok := exports[fn]; !ok {
+			return fmt.Errorf("wasmfilter: %s: function %s not exported", name, fn)
+		}
+	}
+	rt.modules[name] = mod
+	return nil
+}
+
+// Filter runs filter module of name over uchar pixel buffer of width, height and bands in place
+func (rt *Runtime) Filter(
+	ctx context.Context, name, args string, buf []byte, width, height, bands int,
+) error {
+	compiled, ok := rt.modules[name]
+	if !ok {
+		return ErrNotFound
+	}
+	if len(buf) != width*height*bands {
+		return fmt.Errorf("wasmfilter: %s: buffer size %d mismatch %dx%dx%d", name, len(buf), width, height, bands)
+	}
+	mod, err := rt.runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().
+		WithName("").WithStartFunctions("_initialize"))
+	if err != nil {
+		return fmt.Errorf("wasmfilter: %s: %w", name, err)
+	}
+	defer mod.Close(ctx)
+	mem := mod.Memory()
+	alloc := func(data []byte) (uint32, error) {
+		if len(data) == 0 {
+			return 0, nil
+		}
+		res, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(data)))
+		if err != nil {
+			return 0, err
+		}
+		ptr := uint32(res[0])
+		if !mem.Write(ptr, data) {
+			return 0, fmt.Errorf("alloc %d out of memory range", len(data))
+		}
+		return ptr, nil
+	}
+	ptr, err := alloc(buf)
+	if err != nil {
+		return fmt.Errorf("wasmfilter: %s: %w", name, err)
+	}
+	argsPtr, err := alloc([]byte(args))
+	if err != nil {
+		return fmt.Errorf("wasmfilter: %s: %w", name, err)
+	}
+	res, err := mod.ExportedFunction("filter").Call(ctx,
+		uint64(ptr), uint64(width), uint64(height), uint64(bands), uint64(argsPtr), uint64(len(args)))
+	if err != nil {
+		return fmt.Errorf("wasmfilter: %s: %w", name, err)
+	}
+	if code := int32(res[0]); code != 0 {
+		return fmt.Errorf("wasmfilter: %s: filter error code %d", name, code)
+	}
+	out, ok := mem.Read(ptr, uint32(len(buf)))
+	if !ok {
+		return fmt.Errorf("wasmfilter: %s: buffer out of memory range", name)
+	}
+	copy(buf, out)
+	return nil
+}
+
+// Shutdown closes the runtime and the compiled modules
+func (rt *Runtime) Shutdown(ctx context.Context) error {
+	if rt.runtime == nil {
+		return nil
+	}
+	return rt.runtime.Close(ctx)
+}
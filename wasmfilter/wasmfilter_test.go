@@ -0,0 +1,76 @@
+package wasmfilter
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	ctx := context.Background()
+	rt := New("testdata")
+	require.NoError(t, rt.Startup(ctx))
+	defer rt.Shutdown(ctx)
+
+	buf := []byte{0, 10, 20, 255, 128, 1}
+	require.NoError(t, rt.Filter(ctx, "invert", "", buf, 2, 1, 3))
+	assert.Equal(t, []byte{255, 245, 235, 0, 127, 254}, buf)
+
+	// large buffer across memory pages
+	buf = make([]byte, 300*300*4)
+	require.NoError(t, rt.Filter(ctx, "invert", "", buf, 300, 300, 4))
+	for _, b := range buf {
+		require.Equal(t, byte(255), b)
+	}
+
+	buf = []byte{1, 2, 3}
+	err := rt.Filter(ctx, "invert", "A", buf, 1, 1, 3)
+	assert.EqualError(t, err, "wasmfilter: invert: filter error code 65")
+	assert.Equal(t, []byte{1, 2, 3}, buf)
+
+	assert.Equal(t, ErrNotFound, rt.Filter(ctx, "foo", "", buf, 1, 1, 3))
+	assert.Error(t, rt.Filter(ctx, "invert", "", buf, 2, 1, 3))
+}
+
+func TestMemoryLimit(t *testing.T) {
+	ctx := context.Background()
+	rt := New("testdata", WithMemoryLimitPages(4))
+	require.NoError(t, rt.Startup(ctx))
+	defer rt.Shutdown(ctx)
+
+	assert.NoError(t, rt.Filter(ctx, "invert", "", make([]byte, 100*100*4), 100, 100, 4))
+	assert.EqualError(t, rt.Filter(ctx, "invert", "", make([]byte, 300*300*4), 300, 300, 4),
+		"wasmfilter: invert: alloc 360000 out of memory range")
+}
+
+func TestContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	rt := New("testdata")
+	require.NoError(t, rt.Startup(context.Background()))
+	defer rt.Shutdown(context.Background())
+
+	time.Sleep(time.Millisecond * 2)
+	assert.Error(t, rt.Filter(ctx, "invert", "", make([]byte, 3), 1, 1, 3))
+}
+
+func TestInvalidModule(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.wasm"), []byte("\x00asm\x01\x00\x00\x00"), 0644))
+	rt := New(dir)
+	assert.EqualError(t, rt.Startup(ctx), "wasmfilter: empty: memory not exported")
+	assert.NoError(t, rt.Shutdown(ctx))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "empty.wasm"), []byte("foo"), 0644))
+	assert.Error(t, New(dir).Startup(ctx))
+
+	// modules not available before startup
+	rt = New("testdata")
+	assert.Equal(t, ErrNotFound, rt.Filter(ctx, "invert", "", make([]byte, 3), 1, 1, 3))
+	assert.NoError(t, rt.Shutdown(ctx))
+}
@@ -0,0 +1,146 @@
+package imagor
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// maxWatchedImages upper bound of images tracked for result eviction on source change
+const maxWatchedImages = 100000
+
+// Watcher optional interface for Loader to watch changes of source images in background
+// until context done, e.g. files updated on shared volume.
+// Returns false if watch is not enabled
+type Watcher interface {
+	Watch(ctx context.Context, changed func(image string)) bool
+}
+
+// watchIndex tracks result cache and storage keys by source image,
+// for eviction of results on source change
+type watchIndex struct {
+	mu     sync.Mutex
+	images map[string]map[string]string // image -> path -> result key
+}
+
+func newWatchIndex() *watchIndex {
+	return &watchIndex{images: map[string]map[string]string{}}
+}
+
+// add records result path and key of image, skipped if exceeded maxWatchedImages
+func (idx *watchIndex) add(image, path, resultKey string) {
+	if idx == nil || image == "" {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	results, ok := idx.images[image]
+	if !ok {
+		if len(idx.images) >= maxWatchedImages {
+			return
+		}
+		results = map[string]string{}
+		idx.images[image] = results
+	}
+	results[path] = resultKey
+}
+
+// remove returns and removes results of image
+func (idx *watchIndex) remove(image string) map[string]string {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	results := idx.images[image]
+	delete(idx.images, image)
+	return results
+}
+
+// startWatch starts watch of loaders that implement Watcher,
+// returns stop func, nil if none is watching
+func (app *Imagor) startWatch() func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var watching bool
+	var queue *watchQueue
+	if app.WatchWarm {
+		queue = &watchQueue{images: make(chan string, watchQueueSize)}
+	}
+	for _, loader := range app.Loaders {
+		if watcher, ok := loader.(Watcher); ok {
+			loader := loader
+			if watcher.Watch(ctx, func(image string) {
+				app.evictSource(ctx, image)
+				if queue != nil && !isRemoved(ctx, loader, image) {
+					queue.push(ctx, image)
+				}
+			}) {
+				watching = true
+			}
+		}
+	}
+	if !watching {
+		cancel()
+		return nil
+	}
+	if queue != nil {
+		// consumed by queue workers the same way as job queues
+		app.watchQueue = queue
+	}
+	return cancel
+}
+
+// watchQueueSize buffer of changed images pending warm, watch blocks when full
+const watchQueueSize = 1000
+
+// watchQueue job queue of images added or modified on watched loaders,
+// that generates all presets of each image
+type watchQueue struct {
+	images chan string
+}
+
+func (q *watchQueue) push(ctx context.Context, image string) {
+	select {
+	case q.images <- image:
+	case <-ctx.Done():
+	}
+}
+
+func (q *watchQueue) Receive(ctx context.Context) (WarmJob, func(error), error) {
+	select {
+	case image := <-q.images:
+		return WarmJob{Image: image}, nil, nil
+	case <-ctx.Done():
+		return WarmJob{}, nil, ctx.Err()
+	}
+}
+
+// isRemoved checks if changed image no longer exists on loader that implements Stater
+func isRemoved(ctx context.Context, loader Loader, image string) bool {
+	if stater, ok := loader.(Stater); ok {
+		_, err := stater.Stat(ctx, image)
+		return errors.Is(err, ErrNotFound)
+	}
+	return false
+}
+
+// evictSource evicts storage copy, source cache, load error and results of changed source image
+func (app *Imagor) evictSource(ctx context.Context, image string) {
+	if app.Debug {
+		app.Logger.Debug("source-changed", zap.String("image", image))
+	}
+	_ = app.deleteSourceCaches(ctx, image)
+	if len(app.Storages) > 0 {
+		var storageKey = image
+		if app.StoragePathStyle != nil {
+			storageKey = app.StoragePathStyle.Hash(image)
+		}
+		_ = app.deleteAll(ctx, app.Storages, storageKey)
+	}
+	for path, resultKey := range app.watchIndex.remove(image) {
+		if app.Cache != nil {
+			_ = app.Cache.Delete(ctx, "result:"+path)
+		}
+		if resultKey != "" {
+			_ = app.deleteAll(ctx, app.ResultStorages, resultKey)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"github.com/cshum/imagor"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type Option func(h *Webhook)
+
+func WithEvents(events ...imagor.EventType) Option {
+	return func(h *Webhook) {
+		h.Events = append(h.Events, events...)
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(h *Webhook) {
+		if timeout > 0 {
+			h.Timeout = timeout
+		}
+	}
+}
+
+func WithQueueSize(size int) Option {
+	return func(h *Webhook) {
+		if size > 0 {
+			h.QueueSize = size
+		}
+	}
+}
+
+func WithClient(client *http.Client) Option {
+	return func(h *Webhook) {
+		if client != nil {
+			h.Client = client
+		}
+	}
+}
+
+func WithLogger(logger *zap.Logger) Option {
+	return func(h *Webhook) {
+		if logger != nil {
+			h.Logger = logger
+		}
+	}
+}
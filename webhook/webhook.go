@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cshum/imagor"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SignatureHeader request header of the hex encoded HMAC-SHA256 signature of the timestamp and request body
+const SignatureHeader = "X-Imagor-Signature"
+
+// TimestampHeader request header of the unix timestamp of delivery, signed together with the request body
+// that receivers reject deliveries out of tolerance as replay
+const TimestampHeader = "X-Imagor-Timestamp"
+
+var (
+	ErrSignatureMismatch = errors.New("webhook: signature mismatch")
+	ErrTimestampExpired  = errors.New("webhook: timestamp out of tolerance")
+)
+
+// Webhook posts imagor events as JSON to the URL, implements imagor.EventHandler
+type Webhook struct {
+	URL       string
+	Secret    string
+	Events    []imagor.EventType
+	Timeout   time.Duration
+	QueueSize int
+	Client    *http.Client
+	Logger    *zap.Logger
+
+	queue  chan imagor.Event
+	done   chan struct{}
+	mu     sync.RWMutex
+	closed bool
+}
+
+// New creates Webhook and starts the background sender
+func New(url, secret string, options ...Option) *Webhook {
+	h := &Webhook{
+		URL:       url,
+		Secret:    secret,
+		Timeout:   time.Second * 10,
+		QueueSize: 100,
+		Client:    http.DefaultClient,
+		Logger:    zap.NewNop(),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	h.queue = make(chan imagor.Event, h.QueueSize)
+	h.done = make(chan struct{})
+	go h.run()
+	return h
+}
+
+// HandleEvent enqueues event for delivery, dropping the event if queue is full or shut down
+func (h *Webhook) HandleEvent(_ context.Context, event imagor.Event) {
+	if !h.accepts(event.Type) {
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		return
+	}
+	select {
+	case h.queue <- event:
+	default:
+		h.Logger.Warn("webhook-dropped", zap.String("type", string(event.Type)))
+	}
+}
+
+// Shutdown implements imagor Shutdowner, stops accepting events
+// and waits for queued events delivered until context done
+func (h *Webhook) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.queue)
+	}
+	h.mu.Unlock()
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the background sender, waiting for queued events delivered
+func (h *Webhook) Close() {
+	_ = h.Shutdown(context.Background())
+}
+
+func (h *Webhook) accepts(t imagor.EventType) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Webhook) run() {
+	defer close(h.done)
+	for event := range h.queue {
+		if err := h.send(event); err != nil {
+			h.Logger.Warn("webhook", zap.String("type", string(event.Type)), zap.Error(err))
+		}
+	}
+}
+
+// Sign returns hex encoded HMAC-SHA256 signature of the unix timestamp and body, joined by a dot
+func (h *Webhook) Sign(timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(h.Secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify verifies signature and timestamp headers of delivery received,
+// with timestamp within tolerance of current time
+func (h *Webhook) Verify(header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return ErrSignatureMismatch
+	}
+	expected := "sha256=" + h.Sign(timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(SignatureHeader))) {
+		return ErrSignatureMismatch
+	}
+	if d := time.Since(time.Unix(timestamp, 0)); d > tolerance || d < -tolerance {
+		return ErrTimestampExpired
+	}
+	return nil
+}
+
+func (h *Webhook) send(event imagor.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.Secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(SignatureHeader, "sha256="+h.Sign(timestamp, body))
+	}
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/cshum/imagor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	type received struct {
+		Event  imagor.Event
+		Header http.Header
+		Body   []byte
+	}
+	ch := make(chan received, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event imagor.Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		ch <- received{event, r.Header, body}
+	}))
+	defer ts.Close()
+
+	hook := New(ts.URL, "s3cret",
+		WithEvents(imagor.EventProcessed, imagor.EventLoadFailed),
+		WithTimeout(time.Second),
+		WithQueueSize(10),
+	)
+	defer hook.Close()
+
+	ctx := context.Background()
+	hook.HandleEvent(ctx, imagor.Event{Type: imagor.EventSaveFailed, Key: "ignored"})
+	hook.HandleEvent(ctx, imagor.Event{Type: imagor.EventProcessed, Path: "100x100/foo.jpg", Image: "foo.jpg"})
+	hook.HandleEvent(ctx, imagor.Event{Type: imagor.EventLoadFailed, Image: "bar.jpg", Error: "boom"})
+
+	res := <-ch
+	assert.Equal(t, imagor.EventProcessed, res.Event.Type)
+	assert.Equal(t, "100x100/foo.jpg", res.Event.Path)
+	timestamp, err := strconv.ParseInt(res.Header.Get(TimestampHeader), 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, "sha256="+hook.Sign(timestamp, res.Body), res.Header.Get(SignatureHeader))
+	assert.NoError(t, hook.Verify(res.Header, res.Body, time.Minute))
+	assert.Equal(t, ErrSignatureMismatch, hook.Verify(res.Header, append(res.Body, ' '), time.Minute))
+	assert.Equal(t, ErrSignatureMismatch, New(ts.URL, "other").Verify(res.Header, res.Body, time.Minute))
+
+	replayed := res.Header.Clone()
+	old := time.Now().Add(-time.Hour).Unix()
+	replayed.Set(TimestampHeader, strconv.FormatInt(old, 10))
+	replayed.Set(SignatureHeader, "sha256="+hook.Sign(old, res.Body))
+	assert.Equal(t, ErrTimestampExpired, hook.Verify(replayed, res.Body, time.Minute))
+
+	res = <-ch
+	assert.Equal(t, imagor.EventLoadFailed, res.Event.Type)
+	assert.Equal(t, "boom", res.Event.Error)
+
+	select {
+	case res = <-ch:
+		t.Errorf("unexpected event %v", res.Event)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestWebhookQueueFull(t *testing.T) {
+	block := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer ts.Close()
+	defer close(block)
+
+	hook := New(ts.URL, "", WithQueueSize(1))
+	for i := 0; i < 5; i++ {
+		hook.HandleEvent(context.Background(), imagor.Event{Type: imagor.EventProcessed})
+	}
+	assert.LessOrEqual(t, len(hook.queue), 1)
+}
+
+func TestWebhookShutdown(t *testing.T) {
+	var received int32
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		atomic.AddInt32(&received, 1)
+	}))
+	defer ts.Close()
+
+	hook := New(ts.URL, "", WithQueueSize(10))
+	for i := 0; i < 3; i++ {
+		hook.HandleEvent(context.Background(), imagor.Event{Type: imagor.EventProcessed})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, hook.Shutdown(ctx))
+
+	close(release)
+	assert.NoError(t, hook.Shutdown(context.Background()))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&received))
+
+	// events after shutdown are dropped instead of panic
+	hook.HandleEvent(context.Background(), imagor.Event{Type: imagor.EventProcessed})
+	hook.Close()
+}